@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/toole-brendan/handreceipt-go/internal/services/ai"
+	"github.com/toole-brendan/handreceipt-go/internal/services/ai/evaluation"
+)
+
+// parser-eval runs a DA 2062 parsing provider over the golden dataset and reports
+// how well it read the forms. Recording a live run and replaying the recording lets
+// CI check the scores offline:
+//
+//	go run ./cmd/parser-eval -provider anthropic -record internal/services/ai/evaluation/testdata/recordings/anthropic.json
+//	go run ./cmd/parser-eval -replay internal/services/ai/evaluation/testdata/recordings/anthropic.json -min-serial-match 0.9
+//
+// Scanned forms are kept apart from the text forms, in testdata/scans, and recorded
+// to testdata/recordings/anthropic-scans.json the same way with -dataset.
+func main() {
+	// Command line flags
+	dataset := flag.String("dataset", "internal/services/ai/evaluation/testdata/golden", "Directory of golden cases")
+	providerName := flag.String("provider", ai.ProviderLocal, "Provider to evaluate: anthropic, azure_openai, local or fake")
+	model := flag.String("model", "", "Model, or Azure OpenAI deployment")
+	endpoint := flag.String("endpoint", os.Getenv("AZURE_OPENAI_ENDPOINT"), "Azure OpenAI resource endpoint")
+	ocrPath := flag.String("ocr-path", "", "Tesseract binary for the local provider")
+	replay := flag.String("replay", "", "Replay responses recorded in this file instead of calling a provider")
+	record := flag.String("record", "", "Record the provider's responses to this file")
+	asJSON := flag.Bool("json", false, "Print the report as JSON")
+	minSerialMatch := flag.Float64("min-serial-match", 0, "Fail if fewer serial numbers are read exactly")
+	minF1 := flag.Float64("min-f1", 0, "Fail if any field scores a lower F1")
+	flag.Parse()
+
+	cases, err := evaluation.LoadDataset(*dataset)
+	if err != nil {
+		log.Fatalf("Failed to load dataset: %v", err)
+	}
+
+	var provider ai.VisionProvider
+	if *replay != "" {
+		recording, err := ai.LoadRecording(*replay)
+		if err != nil {
+			log.Fatalf("Failed to load recording: %v", err)
+		}
+		provider = ai.NewReplayProvider(recording)
+	} else {
+		// Claude reads ANTHROPIC_API_KEY itself
+		var apiKey string
+		if *providerName == ai.ProviderAzureOpenAI {
+			apiKey = os.Getenv("AZURE_OPENAI_KEY")
+		}
+		provider, err = ai.NewVisionProvider(ai.ProviderConfig{
+			Name:     *providerName,
+			Endpoint: *endpoint,
+			APIKey:   apiKey,
+			Model:    *model,
+			OCRPath:  *ocrPath,
+		})
+		if err != nil {
+			log.Fatalf("Failed to create provider: %v", err)
+		}
+	}
+
+	var recorder *ai.RecordingProvider
+	if *record != "" {
+		recorder = ai.NewRecordingProvider(provider)
+		provider = recorder
+	}
+
+	report, err := evaluation.Run(context.Background(), provider, cases)
+	if err != nil {
+		log.Fatalf("Evaluation failed: %v", err)
+	}
+
+	if recorder != nil {
+		if err := recorder.Recording().Save(*record); err != nil {
+			log.Fatalf("Failed to save recording: %v", err)
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatalf("Failed to encode report: %v", err)
+		}
+	} else {
+		printReport(report)
+	}
+
+	// Gate CI on the thresholds
+	failed := report.Failed > 0
+	if report.SerialExactMatch < *minSerialMatch {
+		fmt.Fprintf(os.Stderr, "Serial exact match %.3f is below %.3f\n", report.SerialExactMatch, *minSerialMatch)
+		failed = true
+	}
+	for _, field := range report.Fields {
+		if field.F1 < *minF1 {
+			fmt.Fprintf(os.Stderr, "%s F1 %.3f is below %.3f\n", field.Field, field.F1, *minF1)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func printReport(report *evaluation.Report) {
+	fmt.Printf("Provider: %s\n\n", report.Provider)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CASE\tPAGES\tEXPECTED\tPARSED\tMATCHED\tCORRECT\tERROR")
+	for _, c := range report.Cases {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%s\n", c.Name, c.Pages, c.Expected, c.Parsed, c.Matched, c.Correct, c.Error)
+	}
+	w.Flush()
+
+	fmt.Println()
+	fmt.Fprintln(w, "FIELD\tPRECISION\tRECALL\tF1")
+	for _, f := range report.Fields {
+		fmt.Fprintf(w, "%s\t%.3f\t%.3f\t%.3f\n", f.Field, f.Precision, f.Recall, f.F1)
+	}
+	w.Flush()
+
+	fmt.Printf("\nSerial numbers read exactly: %d of %d (%.3f)\n", report.SerialsExact, report.SerialsExpected, report.SerialExactMatch)
+
+	fmt.Println()
+	fmt.Fprintln(w, "CONFIDENCE\tITEMS\tMEAN\tACCURACY")
+	for _, bin := range report.Calibration {
+		fmt.Fprintf(w, "%.1f-%.1f\t%d\t%.3f\t%.3f\n", bin.Low, bin.High, bin.Items, bin.MeanConfidence, bin.Accuracy)
+	}
+	w.Flush()
+	fmt.Printf("Expected calibration error: %.3f\n", report.CalibrationError)
+
+	if report.Usage.InputTokens > 0 || report.Usage.OutputTokens > 0 {
+		fmt.Printf("\nTokens: %d in, %d out, $%.4f\n", report.Usage.InputTokens, report.Usage.OutputTokens, report.Usage.CostUSD)
+	}
+}
@@ -0,0 +1,52 @@
+// Command pushstub runs a local stand-in for APNs and FCM that logs pushes instead
+// of delivering them. It writes throwaway credentials the API server can load:
+//
+//	go run ./cmd/pushstub -addr localhost:8089 -out ./tmp/push
+//
+// then set push.enabled, push.apns.base_url and push.fcm.base_url to
+// http://localhost:8089, push.apns.key_file to ./tmp/push/apns.p8 (with any key_id,
+// team_id and topic) and push.fcm.credentials_file to ./tmp/push/fcm.json.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/toole-brendan/handreceipt-go/internal/services/notification/pushstub"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8089", "address to listen on")
+	out := flag.String("out", "./tmp/push", "directory for the generated APNs key and FCM credentials")
+	flag.Parse()
+
+	if err := os.MkdirAll(*out, 0700); err != nil {
+		log.Fatal(err)
+	}
+	apnsKey, err := pushstub.GenerateAPNsKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(*out, "apns.p8"), apnsKey, 0600); err != nil {
+		log.Fatal(err)
+	}
+	fcmCredentials, err := pushstub.GenerateFCMCredentials("http://" + *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(*out, "fcm.json"), fcmCredentials, 0600); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Wrote stub credentials to %s", *out)
+
+	server := pushstub.New()
+	server.OnDelivery = func(delivery pushstub.Delivery) {
+		log.Printf("%s push to %s: %q %q %v", delivery.Platform, delivery.Token, delivery.Title, delivery.Body, delivery.Data)
+	}
+
+	log.Printf("Push stub listening on http://%s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, server))
+}
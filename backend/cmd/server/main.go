@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -13,9 +14,12 @@ import (
 	"github.com/spf13/viper"
 	"github.com/toole-brendan/handreceipt-go/internal/api/routes"
 	"github.com/toole-brendan/handreceipt-go/internal/config"
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
 	"github.com/toole-brendan/handreceipt-go/internal/ledger"
 	"github.com/toole-brendan/handreceipt-go/internal/platform/database"
 	"github.com/toole-brendan/handreceipt-go/internal/repository"
+	"github.com/toole-brendan/handreceipt-go/internal/services/auth"
+	"github.com/toole-brendan/handreceipt-go/internal/services/email"
 	"github.com/toole-brendan/handreceipt-go/internal/services/notification"
 	"github.com/toole-brendan/handreceipt-go/internal/services/nsn"
 	"github.com/toole-brendan/handreceipt-go/internal/services/storage"
@@ -225,9 +229,74 @@ func main() {
 
 	// Create notification hub and start it
 	notificationHub := notification.NewHub()
+	maxConnections := 10
+	if viper.IsSet("notifications.max_connections_per_user") {
+		maxConnections = viper.GetInt("notifications.max_connections_per_user")
+	}
+	notificationHub.SetMaxConnectionsPerUser(maxConnections)
+	notificationHub.SetSessionValidator(auth.NewSessionStore(db))
+	sessionCheckInterval := viper.GetDuration("notifications.session_check_interval")
+	if sessionCheckInterval <= 0 {
+		sessionCheckInterval = 30 * time.Second
+	}
 	go notificationHub.Run()
+	go notificationHub.WatchSessions(context.Background(), sessionCheckInterval)
 	log.Println("WebSocket notification hub started")
 
+	// Connect the hub to the other API instances so events reach users on any of them
+	backplaneChannel := viper.GetString("notifications.channel")
+	switch viper.GetString("notifications.backplane") {
+	case "redis":
+		redisAddr := fmt.Sprintf("%s:%d", viper.GetString("redis.host"), viper.GetInt("redis.port"))
+		notificationHub.AttachBackplane(context.Background(), notification.NewRedisBackplane(
+			redisAddr, viper.GetString("redis.password"), viper.GetInt("redis.db"), backplaneChannel))
+		log.Printf("Notification backplane using Redis at %s", redisAddr)
+	case "none":
+		log.Println("Notification backplane disabled - events reach only clients on this instance")
+	default:
+		notificationHub.AttachBackplane(context.Background(), notification.NewPostgresBackplane(
+			db, database.GetConnectionString(), backplaneChannel))
+		log.Println("Notification backplane using Postgres LISTEN/NOTIFY")
+	}
+
+	// Stream new ledger entries to clients watching the ledger topic
+	if postgresLedger, ok := ledgerService.(*ledger.PostgresLedgerService); ok {
+		postgresLedger.AddAppendListener(func(entry ledger.LedgerEntry) {
+			notificationHub.PublishTopic(notification.TopicKindLedger, notification.Event{
+				Type: notification.EventTypeLedgerAppend,
+				Data: notification.LedgerAppendData{
+					EventID:   entry.EventID,
+					EventType: entry.EventType,
+					CreatedBy: entry.CreatedBy,
+					Hash:      entry.Hash,
+				},
+				Timestamp: entry.CreatedAt,
+			})
+		})
+	}
+
+	// Push notifications to mobile devices that are not connected
+	var pushSender *notification.PushSender
+	if viper.GetBool("push.enabled") {
+		pushSender = setupPushSender()
+	} else {
+		log.Println("Push notifications disabled")
+	}
+
+	// Email notifications to users who chose email for them
+	var mailer email.EmailService
+	if viper.GetBool("email.enabled") && viper.GetString("email.smtp_host") != "" {
+		mailer = email.NewSMTPEmailService(
+			viper.GetString("email.smtp_host"),
+			viper.GetInt("email.smtp_port"),
+			viper.GetString("email.username"),
+			viper.GetString("email.password"),
+			viper.GetString("email.from"),
+		)
+	} else {
+		log.Println("Email notifications disabled")
+	}
+
 	// Azure OCR service removed - Claude AI is now used for document processing
 	/*
 	azureOCREndpoint := os.Getenv("AZURE_OCR_ENDPOINT")
@@ -327,8 +396,8 @@ func main() {
 	// CORS middleware
 	router.Use(corsMiddleware())
 
-	// Setup routes, passing the LedgerService interface, Repository, Storage Service, NSN Service, and Notification Hub
-	routes.SetupRoutes(router, ledgerService, repo, storageService, nsnService, notificationHub)
+	// Setup routes, passing the LedgerService interface, Repository, Storage Service, NSN Service, Notification Hub and Push Sender
+	routes.SetupRoutes(router, ledgerService, repo, storageService, nsnService, notificationHub, pushSender, mailer)
 
 	// Get server port, prioritizing environment variable, then config, then default
 	var port int
@@ -480,3 +549,47 @@ func corsMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// setupPushSender configures APNs and FCM from the push settings. A platform whose
+// credentials are missing or unreadable is skipped with a warning.
+func setupPushSender() *notification.PushSender {
+	sender := notification.NewPushSender(viper.GetInt("push.max_attempts"), 0)
+
+	if keyFile := viper.GetString("push.apns.key_file"); keyFile != "" {
+		key, err := notification.LoadAPNsKey(keyFile)
+		if err != nil {
+			log.Printf("WARNING: APNs push disabled: %v", err)
+		} else {
+			provider, err := notification.NewAPNsProvider(notification.APNsConfig{
+				BaseURL: viper.GetString("push.apns.base_url"),
+				Topic:   viper.GetString("push.apns.topic"),
+				KeyID:   viper.GetString("push.apns.key_id"),
+				TeamID:  viper.GetString("push.apns.team_id"),
+				Key:     key,
+			}, nil)
+			if err != nil {
+				log.Printf("WARNING: APNs push disabled: %v", err)
+			} else {
+				sender.SetProvider(domain.DevicePlatformIOS, provider)
+				log.Printf("APNs push enabled via %s", viper.GetString("push.apns.base_url"))
+			}
+		}
+	}
+
+	if credentialsFile := viper.GetString("push.fcm.credentials_file"); credentialsFile != "" {
+		credentials, err := notification.LoadFCMCredentials(credentialsFile)
+		if err != nil {
+			log.Printf("WARNING: FCM push disabled: %v", err)
+		} else {
+			provider, err := notification.NewFCMProvider(viper.GetString("push.fcm.base_url"), credentials, nil)
+			if err != nil {
+				log.Printf("WARNING: FCM push disabled: %v", err)
+			} else {
+				sender.SetProvider(domain.DevicePlatformAndroid, provider)
+				log.Printf("FCM push enabled via %s", viper.GetString("push.fcm.base_url"))
+			}
+		}
+	}
+
+	return sender
+}
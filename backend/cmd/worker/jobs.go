@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/toole-brendan/handreceipt-go/internal/config"
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"github.com/toole-brendan/handreceipt-go/internal/ledger"
+	"github.com/toole-brendan/handreceipt-go/internal/platform/database"
+	"github.com/toole-brendan/handreceipt-go/internal/repository"
+	"github.com/toole-brendan/handreceipt-go/internal/services/ai"
+	"github.com/toole-brendan/handreceipt-go/internal/services/documents"
+	"github.com/toole-brendan/handreceipt-go/internal/services/email"
+	"github.com/toole-brendan/handreceipt-go/internal/services/inventory"
+	"github.com/toole-brendan/handreceipt-go/internal/services/jobs"
+	"github.com/toole-brendan/handreceipt-go/internal/services/notification"
+	"github.com/toole-brendan/handreceipt-go/internal/services/nsn"
+	"github.com/toole-brendan/handreceipt-go/internal/services/storage"
+	"gorm.io/gorm"
+)
+
+// registerJobs adds a handler for every job type this worker has the services to
+// run. Types left unregistered stay queued for a worker that can run them.
+func registerJobs(worker *jobs.Worker, cfg *config.Config, db *gorm.DB, nsnService *nsn.NSNService, logger *logrus.Logger) error {
+	repo := repository.NewPostgresRepository(db)
+	ledgerService, err := ledger.NewPostgresLedgerService(db)
+	if err != nil {
+		return err
+	}
+
+	storageService := initStorage(logger)
+	if storageService == nil {
+		logger.Warn("No storage service configured - DA 2062 jobs will not run in this worker")
+		return nil
+	}
+
+	var mailer *email.DA2062EmailService
+	if cfg.Email.Enabled && cfg.Email.SMTPHost != "" {
+		sender := email.NewSMTPEmailService(cfg.Email.SMTPHost, cfg.Email.SMTPPort, cfg.Email.Username, cfg.Email.Password, cfg.Email.From)
+		mailer = email.NewDA2062EmailService(sender)
+	}
+
+	receipts := inventory.NewTransferReceiptService(repo, ledgerService, documents.NewDA2062Generator(repo), storageService, mailer)
+	jobs.Handle(worker, inventory.JobIssueTransferReceipt, func(ctx context.Context, job inventory.TransferReceiptJob) error {
+		return permanentIfUnrecoverable(receipts.Issue(ctx, job.TransferID))
+	})
+	if mailer != nil {
+		jobs.Handle(worker, inventory.JobEmailDocument, func(ctx context.Context, job inventory.DocumentEmailJob) error {
+			return permanentIfUnrecoverable(receipts.EmailDocument(ctx, job))
+		})
+	} else {
+		logger.Info("Email is not configured - document email jobs will not run in this worker")
+	}
+
+	parser, err := initFormParser(cfg.AI)
+	if err != nil {
+		logger.WithError(err).Warn("No form parsing provider available - DA 2062 import jobs will not run in this worker")
+		return nil
+	}
+	logger.WithField("providers", parser.Name()).Info("DA 2062 form parsing providers configured")
+
+	// Parsed items are checked against PUB LOG only if its data was loaded
+	var catalog inventory.NSNCatalog
+	if publogService := nsnService.PubLog(); publogService != nil {
+		catalog = publogService
+	} else {
+		logger.Info("No PUB LOG data loaded - DA 2062 imports will not be checked against PUB LOG")
+	}
+	checker := inventory.NewDA2062ItemChecker(db, catalog)
+
+	importer := inventory.NewDA2062ImportService(db, storageService, nil, parser, checker, inventory.NewDA2062LearningService(db), initNotificationHub(db, logger))
+	worker.Register(inventory.JobProcessDA2062Import, func(ctx context.Context, job *domain.Job) error {
+		var payload inventory.DA2062ImportJob
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return jobs.Permanent(fmt.Errorf("invalid %s payload: %w", job.Type, err))
+		}
+		err := permanentIfUnrecoverable(importer.Process(ctx, payload.ImportID))
+		// Let the client know once the job has given up on the import
+		if err != nil && (jobs.IsPermanent(err) || job.Attempts >= job.MaxAttempts) {
+			if failErr := importer.Fail(payload.ImportID, err); failErr != nil {
+				logger.WithError(failErr).Warn("Failed to mark DA 2062 import failed")
+			}
+		}
+		return err
+	})
+	return nil
+}
+
+// permanentIfUnrecoverable stops retrying jobs whose records are gone or no longer
+// in a state the job applies to
+func permanentIfUnrecoverable(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) ||
+		errors.Is(err, inventory.ErrTransferNotAccepted) ||
+		errors.Is(err, inventory.ErrImportUnreadable) ||
+		errors.Is(err, inventory.ErrImportNoItems) {
+		return jobs.Permanent(err)
+	}
+	return err
+}
+
+// initFormParser builds the chain of form parsing providers from ai.providers.
+// With none configured, Claude is used if ANTHROPIC_API_KEY is set, falling back
+// to the local OCR parser.
+func initFormParser(cfg config.AIConfig) (*ai.ProviderChain, error) {
+	configs := make([]ai.ProviderConfig, 0, len(cfg.Providers))
+	for _, p := range cfg.Providers {
+		configs = append(configs, ai.ProviderConfig{
+			Name:              p.Name,
+			Endpoint:          p.Endpoint,
+			APIKey:            p.APIKey,
+			Model:             p.Model,
+			MaxTokens:         p.MaxTokens,
+			Timeout:           p.Timeout,
+			InputCostPerMTok:  p.InputCostPerMTok,
+			OutputCostPerMTok: p.OutputCostPerMTok,
+			OCRPath:           p.OCRPath,
+			OCRLanguage:       p.OCRLanguage,
+		})
+	}
+	if len(configs) == 0 {
+		if os.Getenv("ANTHROPIC_API_KEY") != "" {
+			configs = append(configs, ai.ProviderConfig{Name: ai.ProviderAnthropic})
+		}
+		// Forms can still be read offline if the cloud model cannot be reached
+		configs = append(configs, ai.ProviderConfig{Name: ai.ProviderLocal})
+	}
+	return ai.NewProviderChain(configs)
+}
+
+// initNotificationHub returns a hub connected to the API servers' backplane, so
+// progress published here reaches clients connected to any of them. It returns nil
+// when the backplane is disabled, as nothing published here could reach a client.
+func initNotificationHub(db *gorm.DB, logger *logrus.Logger) *notification.Hub {
+	hub := notification.NewHub()
+	channel := viper.GetString("notifications.channel")
+	switch viper.GetString("notifications.backplane") {
+	case "redis":
+		redisAddr := fmt.Sprintf("%s:%d", viper.GetString("redis.host"), viper.GetInt("redis.port"))
+		hub.AttachBackplane(context.Background(), notification.NewRedisBackplane(
+			redisAddr, viper.GetString("redis.password"), viper.GetInt("redis.db"), channel))
+	case "none":
+		logger.Info("Notification backplane disabled - import progress will not be streamed from this worker")
+		return nil
+	default:
+		hub.AttachBackplane(context.Background(), notification.NewPostgresBackplane(
+			db, database.GetConnectionString(), channel))
+	}
+	return hub
+}
+
+// initStorage connects to the same document store as the API server, reading the
+// same settings
+func initStorage(logger *logrus.Logger) storage.StorageService {
+	storageType := viper.GetString("storage.type")
+	if storageType == "" {
+		storageType = os.Getenv("HANDRECEIPT_STORAGE_TYPE")
+	}
+
+	if storageType == "azure_blob" {
+		connectionString := viper.GetString("storage.connection_string")
+		if connectionString == "" {
+			connectionString = os.Getenv("HANDRECEIPT_STORAGE_CONNECTION_STRING")
+		}
+		containerName := viper.GetString("storage.container_name")
+		if containerName == "" {
+			containerName = os.Getenv("HANDRECEIPT_STORAGE_CONTAINER_NAME")
+		}
+		if containerName == "" {
+			containerName = "documents"
+		}
+		if connectionString == "" {
+			return nil
+		}
+		service, err := storage.NewAzureBlobService(connectionString, containerName)
+		if err != nil {
+			logger.WithError(err).Warn("Failed to initialize Azure Blob storage service")
+			return nil
+		}
+		return service
+	}
+
+	endpoint := viper.GetString("minio.endpoint")
+	if endpoint == "" {
+		endpoint = "localhost:9000"
+	}
+	accessKey := viper.GetString("minio.access_key")
+	if accessKey == "" {
+		accessKey = os.Getenv("MINIO_ACCESS_KEY")
+	}
+	secretKey := viper.GetString("minio.secret_key")
+	if secretKey == "" {
+		secretKey = os.Getenv("MINIO_SECRET_KEY")
+	}
+	bucket := viper.GetString("minio.bucket")
+	if bucket == "" {
+		bucket = "handreceipt-photos"
+	}
+
+	service, err := storage.NewMinIOService(endpoint, accessKey, secretKey, bucket, viper.GetBool("minio.use_ssl"))
+	if err != nil {
+		logger.WithError(err).Warn("Failed to initialize MinIO storage service")
+		return nil
+	}
+	return service
+}
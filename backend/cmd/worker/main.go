@@ -10,7 +10,14 @@ import (
 	"github.com/robfig/cron/v3"
 	"github.com/sirupsen/logrus"
 	"github.com/toole-brendan/handreceipt-go/internal/config"
+	"github.com/toole-brendan/handreceipt-go/internal/ledger"
+	"github.com/toole-brendan/handreceipt-go/internal/repository"
+	"github.com/toole-brendan/handreceipt-go/internal/services/email"
+	"github.com/toole-brendan/handreceipt-go/internal/services/inventory"
+	"github.com/toole-brendan/handreceipt-go/internal/services/jobs"
+	"github.com/toole-brendan/handreceipt-go/internal/services/notification"
 	"github.com/toole-brendan/handreceipt-go/internal/services/nsn"
+	"github.com/toole-brendan/handreceipt-go/internal/services/webhook"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -39,6 +46,9 @@ func main() {
 
 	// Initialize NSN service
 	nsnService := nsn.NewNSNService(&cfg.NSN, db, logger)
+	if err := nsnService.Initialize(); err != nil {
+		logger.WithError(err).Warn("Failed to initialize NSN service - DA 2062 imports will not be checked against PUB LOG")
+	}
 
 	// Create cron scheduler
 	c := cron.New(cron.WithLogger(cron.VerbosePrintfLogger(logger)))
@@ -87,6 +97,68 @@ func main() {
 		logger.WithError(err).Error("Failed to schedule cache cleanup")
 	}
 
+	// Schedule overdue inventory reminders - daily at 7 AM
+	_, err = c.AddFunc("0 7 * * *", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+
+		sent, err := inventory.RemindOverdueCounters(ctx, db, time.Now().UTC())
+		if err != nil {
+			logger.WithError(err).Error("Overdue inventory reminders failed")
+		} else if sent > 0 {
+			logger.WithField("reminders", sent).Info("Sent overdue inventory reminders")
+		}
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to schedule overdue inventory reminders")
+	}
+
+	// Schedule recurring inventory campaigns - hourly
+	campaignLedger, err := ledger.NewPostgresLedgerService(db)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize ledger service")
+	}
+	campaigns := inventory.NewCampaignService(db, repository.NewPostgresRepository(db), campaignLedger, nil, nil)
+	_, err = c.AddFunc("15 * * * *", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+
+		opened, err := campaigns.ScheduleRecurring(ctx, time.Now().UTC())
+		if err != nil {
+			logger.WithError(err).Error("Recurring inventory campaigns failed")
+		} else if opened > 0 {
+			logger.WithField("campaigns", opened).Info("Opened recurring inventory campaigns")
+		}
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to schedule recurring inventory campaigns")
+	}
+
+	// Schedule notification digests - daily at 6 AM, and weekly on Monday for users who chose it
+	if cfg.Email.Enabled && cfg.Email.SMTPHost != "" {
+		sender := email.NewSMTPEmailService(cfg.Email.SMTPHost, cfg.Email.SMTPPort, cfg.Email.Username, cfg.Email.Password, cfg.Email.From)
+		scheduleDigest := func(spec, frequency string) {
+			_, err := c.AddFunc(spec, func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+				defer cancel()
+
+				sent, err := notification.SendDigests(ctx, db, sender, frequency, time.Now().UTC())
+				if err != nil {
+					logger.WithError(err).WithField("frequency", frequency).Error("Notification digests failed")
+				} else if sent > 0 {
+					logger.WithFields(logrus.Fields{"frequency": frequency, "digests": sent}).Info("Sent notification digests")
+				}
+			})
+			if err != nil {
+				logger.WithError(err).WithField("frequency", frequency).Error("Failed to schedule notification digests")
+			}
+		}
+		scheduleDigest("0 6 * * *", "daily")
+		scheduleDigest("0 6 * * 1", "weekly")
+	} else {
+		logger.Info("Email is not configured - notification digests disabled")
+	}
+
 	// Schedule health checks - every 5 minutes
 	_, err = c.AddFunc("*/5 * * * *", func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -100,6 +172,19 @@ func main() {
 		logger.WithError(err).Error("Failed to schedule health checks")
 	}
 
+	// Run queued background jobs, webhook deliveries among them, until shutdown
+	jobWorker := jobs.NewWorker(db)
+	webhook.NewDispatcher(db).Register(jobWorker)
+	if err := registerJobs(jobWorker, cfg, db, nsnService, logger); err != nil {
+		logger.WithError(err).Error("Failed to register job handlers")
+	}
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	jobsDone := make(chan struct{})
+	go func() {
+		defer close(jobsDone)
+		jobWorker.Run(jobsCtx, cfg.Jobs.Concurrency, cfg.Jobs.PollInterval)
+	}()
+
 	// Start the cron scheduler
 	c.Start()
 	logger.Info("Background worker started successfully")
@@ -111,6 +196,10 @@ func main() {
 
 	logger.Info("Shutting down background worker...")
 
+	// Let jobs already running finish
+	stopJobs()
+	<-jobsDone
+
 	// Stop the cron scheduler
 	ctx := c.Stop()
 	<-ctx.Done()
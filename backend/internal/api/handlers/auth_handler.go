@@ -20,10 +20,11 @@ import (
 type AuthHandler struct {
 	repo       repository.Repository
 	jwtService *auth.JWTService
+	sessions   *auth.SessionStore
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler(repo repository.Repository) *AuthHandler {
+func NewAuthHandler(repo repository.Repository, sessions *auth.SessionStore) *AuthHandler {
 	// Initialize JWT service
 	accessExpiry := viper.GetDuration("jwt.access_expiry")
 	refreshExpiry := viper.GetDuration("jwt.refresh_expiry")
@@ -44,6 +45,7 @@ func NewAuthHandler(repo repository.Repository) *AuthHandler {
 	return &AuthHandler{
 		repo:       repo,
 		jwtService: jwtService,
+		sessions:   sessions,
 	}
 }
 
@@ -331,6 +333,20 @@ func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
 // Logout handles user logout
 func (h *AuthHandler) Logout(c *gin.Context) {
 	session := sessions.Default(c)
+
+	// Revoke the sign-in session, whether the client used the cookie or a bearer
+	// token, so WebSocket connections opened from it are closed as well
+	if sessionID, ok := session.Get("sessionID").(string); ok {
+		if userID, ok := session.Get("userID").(uint); ok {
+			h.revokeSession(userID, sessionID)
+		}
+	}
+	if token, err := h.jwtService.ExtractTokenFromHeader(c.GetHeader("Authorization")); err == nil {
+		if claims, err := h.jwtService.ValidateToken(token); err == nil {
+			h.revokeSession(claims.UserID, claims.SessionID)
+		}
+	}
+
 	session.Clear()
 	if err := session.Save(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear session"})
@@ -339,3 +355,9 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
 }
+
+func (h *AuthHandler) revokeSession(userID uint, sessionID string) {
+	if err := h.sessions.RevokeSession(userID, sessionID); err != nil {
+		log.Printf("WARNING: Failed to revoke session for user %d: %v", userID, err)
+	}
+}
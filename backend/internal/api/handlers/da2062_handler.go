@@ -3,7 +3,6 @@
 package handlers
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -23,19 +22,26 @@ import (
 	"github.com/toole-brendan/handreceipt-go/internal/models"
 	"github.com/toole-brendan/handreceipt-go/internal/repository"
 	"github.com/toole-brendan/handreceipt-go/internal/services/ai"
-	"github.com/toole-brendan/handreceipt-go/internal/services/email"
 	"github.com/toole-brendan/handreceipt-go/internal/services/documents"
+	"github.com/toole-brendan/handreceipt-go/internal/services/inventory"
+	"github.com/toole-brendan/handreceipt-go/internal/services/jobs"
+	"github.com/toole-brendan/handreceipt-go/internal/services/notification"
 	"github.com/toole-brendan/handreceipt-go/internal/services/storage"
 	"gorm.io/gorm"
 )
 
+// How long a synchronous upload waits for its form to be parsed. Most forms take
+// well under this; longer ones are left to finish in the background.
+const uploadWaitTimeout = 2 * time.Minute
+
 // DA2062Handler handles DA2062-related operations
 type DA2062Handler struct {
 	Ledger         ledger.LedgerService
 	Repo           repository.Repository
 	PDFGenerator   *documents.DA2062Generator
-	EmailService   *email.DA2062EmailService
+	Jobs           *jobs.Queue
 	StorageService storage.StorageService
+	Imports        *inventory.DA2062ImportService
 }
 
 // NewDA2062Handler creates a new DA2062 handler
@@ -43,15 +49,17 @@ func NewDA2062Handler(
 	ledgerService ledger.LedgerService,
 	repo repository.Repository,
 	pdfGenerator *documents.DA2062Generator,
-	emailService *email.DA2062EmailService,
+	jobQueue *jobs.Queue,
 	storageService storage.StorageService,
+	importService *inventory.DA2062ImportService,
 ) *DA2062Handler {
 	return &DA2062Handler{
 		Ledger:         ledgerService,
 		Repo:           repo,
 		PDFGenerator:   pdfGenerator,
-		EmailService:   emailService,
+		Jobs:           jobQueue,
 		StorageService: storageService,
+		Imports:        importService,
 	}
 }
 
@@ -453,130 +461,168 @@ func (h *DA2062Handler) UploadDA2062(c *gin.Context) {
 }
 */
 
-/* ------------------------------------------------------------------
-   ⭐ NEW: ImportDA2062 – upload + parse with Claude
------------------------------------------------------------------- */
+// ImportDA2062 stores an uploaded DA 2062 and queues it to be parsed in the
+// background. The response carries the import and its job; clients follow progress
+// on the import's WebSocket topic or by polling the import, then fetch its items.
+// With mode=reconcile the form is compared with the user's hand receipt instead.
 func (h *DA2062Handler) ImportDA2062(c *gin.Context) {
-	userIDVal, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	// A printout can be reconciled against the hand receipt instead of imported
+	mode := c.DefaultPostForm("mode", domain.DA2062ImportModeReview)
+	if mode != domain.DA2062ImportModeReview && mode != domain.DA2062ImportModeReconcile {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Mode must be review or reconcile"})
 		return
 	}
-	userID, ok := userIDVal.(uint)
+
+	importRecord, ok := h.startImport(c, mode)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
 		return
 	}
 
-	// Get uploaded file
-	file, header, err := c.Request.FormFile("file")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
-		return
-	}
-	defer file.Close()
+	c.JSON(http.StatusAccepted, gin.H{
+		"import": importRecord,
+		"jobId":  importRecord.JobID,
+		"topic":  notification.ImportTopic(importRecord.ID),
+	})
+}
 
-	// Validate file type
-	contentType := header.Header.Get("Content-Type")
-	if !isValidImageType(contentType) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file type. Only images and PDFs are supported"})
+// UploadDA2062 is the synchronous upload older app builds use. The form is imported
+// as with ImportDA2062, but the request waits for the worker to parse it and
+// answers with the items read, in the response those builds decode. If parsing
+// takes too long the import carries on and the response says where to follow it.
+func (h *DA2062Handler) UploadDA2062(c *gin.Context) {
+	importRecord, ok := h.startImport(c, domain.DA2062ImportModeReview)
+	if !ok {
 		return
 	}
 
-	// Read file data
-	fileData, err := io.ReadAll(file)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), uploadWaitTimeout)
+	defer cancel()
+	finished, err := h.Imports.Wait(ctx, importRecord.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		if errors.Is(err, context.DeadlineExceeded) {
+			c.JSON(http.StatusGatewayTimeout, gin.H{
+				"error":    "DA 2062 is still being processed",
+				"importId": importRecord.ID,
+				"topic":    notification.ImportTopic(importRecord.ID),
+			})
+			return
+		}
+		log.Printf("Failed to wait for DA 2062 import %d: %v", importRecord.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process DA 2062"})
 		return
 	}
-
-	// Upload to storage for record keeping
-	ctx := context.Background()
-	objectName := fmt.Sprintf("da2062-scans/%d/%d-%s", userID, time.Now().Unix(), header.Filename)
-	err = h.StorageService.UploadFile(ctx, objectName, bytes.NewReader(fileData), int64(len(fileData)), contentType)
-	if err != nil {
-		log.Printf("Failed to upload file to storage: %v", err)
-		// Continue anyway - storage is not critical for import
+	if finished.Status == domain.DA2062ImportFailed {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":    "Failed to parse DA 2062",
+			"importId": finished.ID,
+			"details":  finished.ErrorLog,
+		})
+		return
 	}
 
-	// Parse with Claude AI
-	items, meta, err := ai.ParseDA2062WithRetry(bytes.NewReader(fileData), contentType, 3)
+	items, err := h.Imports.Items(c.Request.Context(), finished.ID)
 	if err != nil {
-		log.Printf("Claude AI parsing failed: %v", err)
-		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to parse DA 2062: " + err.Error()})
+		log.Printf("Failed to get items of DA 2062 import %d: %v", finished.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch import items"})
 		return
 	}
 
-	// Convert parsed items to API format
-	documentURL := fmt.Sprintf("/storage/%s", objectName)
-	var importItems []models.DA2062ImportItem
+	var meta ai.ParseMeta
+	if len(finished.FormInfo) > 0 {
+		_ = json.Unmarshal(finished.FormInfo, &meta)
+	}
+	confidence := 0.0
+	requiresVerification := false
 	for _, item := range items {
-		importMetadata := models.ImportMetadata{
-			Source:               "claude_ai",
-			FormNumber:           meta.FormNumber,
-			ScanConfidence:       item.Confidence,
-			SerialSource:         "ai_extracted",
-			OriginalQuantity:     item.Quantity,
-			RequiresVerification: item.Confidence < 0.8,
-			VerificationReasons:  []string{},
-			SourceDocumentURL:    documentURL,
-		}
-
-		if item.SerialNumber == "" || item.SerialNumber == "N/A" {
-			importMetadata.VerificationReasons = append(importMetadata.VerificationReasons, "missing_serial_number")
-			importMetadata.RequiresVerification = true
-		}
-
-		importItem := models.DA2062ImportItem{
-			Name:           item.Name,
-			Description:    item.Name,
-			SerialNumber:   item.SerialNumber,
-			NSN:            item.NSN,
-			Quantity:       item.Quantity,
-			SourceRef:      meta.FormNumber,
-			ImportMetadata: &importMetadata,
+		if item.ImportMetadata == nil {
+			continue
 		}
-		importItems = append(importItems, importItem)
+		confidence += item.ImportMetadata.ItemConfidence
+		requiresVerification = requiresVerification || item.ImportMetadata.RequiresVerification
+	}
+	if len(items) > 0 {
+		confidence /= float64(len(items))
+	}
+	sourceURL := ""
+	if finished.FileURL != nil {
+		sourceURL = *finished.FileURL
 	}
 
-	// Log the import with monitoring
-	log.Printf("Claude API DA2062 import: %d items extracted, confidence avg: %.2f", 
-		len(items), calculateAverageConfidence(items))
-
-	// Build response
-	response := gin.H{
-		"success": true,
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"import_id": finished.ID,
 		"form_info": gin.H{
-			"from_unit":   meta.From,
-			"to_unit":     meta.To,
-			"date":        meta.Date,
+			"unit_name":   meta.From,
 			"form_number": meta.FormNumber,
+			"confidence":  confidence,
 		},
-		"items":       importItems,
-		"total_items": len(importItems),
+		"items":       items,
+		"total_items": len(items),
 		"metadata": gin.H{
-			"parsed_by": "claude_ai",
-			"timestamp": time.Now().Format(time.RFC3339),
+			"total_lines":           len(items),
+			"processed_at":          finished.CompletedAt,
+			"source_image_url":      sourceURL,
+			"source_document_url":   sourceURL,
+			"ocr_confidence":        confidence,
+			"requires_verification": requiresVerification,
+			"pages":                 finished.PageCount,
 		},
 		"next_steps": gin.H{
-			"verification_needed":  countItemsNeedingReview(importItems),
+			"verification_needed":  requiresVerification,
+			"items_needing_review": countItemsNeedingReview(items),
 			"suggested_action":     "Review items and submit for creation",
+			"message":              "Review items and submit for creation", // Keep for backwards compatibility
 		},
+	})
+}
+
+// startImport stores the uploaded form and queues its import, writing the error
+// response itself if it cannot
+func (h *DA2062Handler) startImport(c *gin.Context, mode string) (*domain.DA2062Import, bool) {
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, false
+	}
+	userID, ok := userIDVal.(uint)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return nil, false
 	}
 
-	c.JSON(http.StatusOK, response)
-}
+	// Get uploaded file
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return nil, false
+	}
+	defer file.Close()
 
-// calculateAverageConfidence calculates the average confidence score
-func calculateAverageConfidence(items []ai.ParsedItem) float64 {
-	if len(items) == 0 {
-		return 0
+	// Validate file type
+	contentType := header.Header.Get("Content-Type")
+	if !isValidImageType(contentType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file type. Only images and PDFs are supported"})
+		return nil, false
 	}
-	total := 0.0
-	for _, item := range items {
-		total += item.Confidence
+
+	// Read file data
+	fileData, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return nil, false
 	}
-	return total / float64(len(items))
+
+	importRecord, err := h.Imports.Start(c.Request.Context(), userID, header.Filename, contentType, mode, fileData)
+	if err != nil {
+		if errors.Is(err, inventory.ErrStorageUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Document storage is not available"})
+			return nil, false
+		}
+		log.Printf("Failed to start DA 2062 import: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start DA 2062 import"})
+		return nil, false
+	}
+	return importRecord, true
 }
 
 // isValidImageType checks if the content type is supported
@@ -1061,69 +1107,65 @@ func (h *DA2062Handler) ExportDA2062(c *gin.Context) {
 
 	// Handle email or download
 	if req.SendEmail && len(req.Recipients) > 0 {
-		// Send email
-		senderInfo := email.UserInfo{
-			Name:  fromUserInfo.Name,
-			Rank:  fromUserInfo.Rank,
-			Title: fromUserInfo.Title,
-			Phone: fromUserInfo.Phone,
+		// Store the HTML for the sender's Documents inbox; the worker mails it from there
+		ctx := c.Request.Context()
+		fileKey := fmt.Sprintf("da2062/email_%d_%d.html", userID, time.Now().UnixNano())
+		if err := h.StorageService.UploadFile(ctx, fileKey, strings.NewReader(htmlContent), int64(len(htmlContent)), "text/html"); err != nil {
+			log.Printf("Failed to upload DA 2062 HTML for email: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store DA 2062 for email"})
+			return
 		}
 
-		// Convert HTML to buffer for email attachment
-		htmlBuffer := bytes.NewBufferString(htmlContent)
-		
-		// TODO: Update email service to send HTML attachments
-		// For now, we'll use the existing email service but note it needs updating
-		err = h.EmailService.SendDA2062Email(req.Recipients, htmlBuffer, formNumber, senderInfo)
+		// Get a presigned URL for the HTML
+		fileURL, err := h.StorageService.GetPresignedURL(ctx, fileKey, 7*24*time.Hour)
 		if err != nil {
-			log.Printf("Email sending failed: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send email"})
-			return
+			log.Printf("Warning: could not get presigned URL for sender's copy: %v", err)
+			fileURL = ""
 		}
 
-		// Upload HTML to storage for sender's Documents inbox
-		ctx := c.Request.Context()
-		fileKey := fmt.Sprintf("da2062/email_%d_%d.html", userID, time.Now().UnixNano())
-		err = h.StorageService.UploadFile(ctx, fileKey, strings.NewReader(htmlContent), int64(len(htmlContent)), "text/html")
-		if err != nil {
-			log.Printf("WARNING: Failed to upload HTML for sender's Documents: %v", err)
+		// Create document record for sender's inbox
+		var title string
+		if len(properties) == 1 {
+			title = fmt.Sprintf("Emailed Hand Receipt for %s", properties[0].Name)
 		} else {
-			// Get a presigned URL for the HTML
-			fileURL, err := h.StorageService.GetPresignedURL(ctx, fileKey, 7*24*time.Hour)
-			if err != nil {
-				log.Printf("Warning: could not get presigned URL for sender's copy: %v", err)
-				fileURL = ""
-			}
+			title = fmt.Sprintf("Emailed Hand Receipt - %d Items", len(properties))
+		}
 
-			// Create document record for sender's inbox
-			var title string
-			if len(properties) == 1 {
-				title = fmt.Sprintf("Emailed Hand Receipt for %s", properties[0].Name)
-			} else {
-				title = fmt.Sprintf("Emailed Hand Receipt - %d Items", len(properties))
-			}
+		subtype := "DA2062"
+		senderDoc := &domain.Document{
+			Type:            domain.DocumentTypeTransferForm,
+			Subtype:         &subtype,
+			Title:           title,
+			SenderUserID:    userID,
+			RecipientUserID: userID, // Sender gets a copy
+			PropertyID:      nil,
+			FormData:        "{}",
+			Description:     nil,
+			Attachments:     domain.JSONStringArray{},
+			Status:          domain.DocumentStatusRead, // Mark as read since sender created it
+			SentAt:          time.Now(),
+		}
+		// Attach HTML URL if available
+		if fileURL != "" {
+			senderDoc.Attachments = domain.JSONStringArray{fileURL}
+		}
+		if err := h.Repo.CreateDocument(senderDoc); err != nil {
+			log.Printf("Failed to create document record for sender's email copy: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create document record"})
+			return
+		}
 
-			subtype := "DA2062"
-			senderDoc := &domain.Document{
-				Type:            domain.DocumentTypeTransferForm,
-				Subtype:         &subtype,
-				Title:           title,
-				SenderUserID:    userID,
-				RecipientUserID: userID, // Sender gets a copy
-				PropertyID:      nil,
-				FormData:        "{}",
-				Description:     nil,
-				Attachments:     domain.JSONStringArray{},
-				Status:          domain.DocumentStatusRead, // Mark as read since sender created it
-				SentAt:          time.Now(),
-			}
-			// Attach HTML URL if available
-			if fileURL != "" {
-				senderDoc.Attachments = domain.JSONStringArray{fileURL}
-			}
-			if err := h.Repo.CreateDocument(senderDoc); err != nil {
-				log.Printf("WARNING: Failed to create document record for sender's email copy: %v", err)
-			}
+		// Mailing is done by the worker so a slow mail server does not hold up the request
+		job := inventory.DocumentEmailJob{
+			DocumentID: senderDoc.ID,
+			UserID:     userID,
+			Recipients: req.Recipients,
+			FileKey:    fileKey,
+			FormNumber: formNumber,
+		}
+		if _, err := h.Jobs.Enqueue(ctx, inventory.JobEmailDocument, job, jobs.EnqueueOptions{CreatedBy: userID}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue email"})
+			return
 		}
 
 		// Log to ledger
@@ -1131,11 +1173,12 @@ func (h *DA2062Handler) ExportDA2062(c *gin.Context) {
 			log.Printf("WARNING: Failed to log DA2062 export to ledger: %v", err)
 		}
 
-		c.JSON(http.StatusOK, gin.H{
-			"message":     "DA 2062 emailed successfully (copy saved to your Documents)",
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":     "DA 2062 will be emailed (copy saved to your Documents)",
 			"form_number": formNumber,
 			"recipients":  req.Recipients,
 			"item_count":  len(properties),
+			"document_id": senderDoc.ID,
 		})
 	} else {
 		// Return HTML for download
@@ -1335,7 +1378,7 @@ func (h *DA2062Handler) RegisterRoutes(router *gin.RouterGroup) {
 	da2062 := router.Group("/da2062")
 	{
 		da2062.POST("/import", h.ImportDA2062) // New Claude-powered import
-		da2062.POST("/upload", h.ImportDA2062) // Keep old route for compatibility
+		da2062.POST("/upload", h.UploadDA2062) // Synchronous, for older app builds
 		da2062.POST("/generate-pdf", h.ExportDA2062) // Keep old route name for compatibility
 		da2062.GET("/search", h.SearchDA2062Forms)
 		da2062.GET("/unverified", h.GetUnverifiedItems)
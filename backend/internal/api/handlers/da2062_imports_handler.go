@@ -2,79 +2,62 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"github.com/toole-brendan/handreceipt-go/internal/services/flipl"
+	"github.com/toole-brendan/handreceipt-go/internal/services/inventory"
+	"github.com/toole-brendan/handreceipt-go/internal/services/notification"
 	"gorm.io/gorm"
 )
 
-// DA2062Import represents a DA2062 import job
-type DA2062Import struct {
-	ID             int64           `json:"id" gorm:"primaryKey;column:id"`
-	FileName       string          `json:"fileName" gorm:"column:file_name;not null"`
-	FileURL        *string         `json:"fileUrl,omitempty" gorm:"column:file_url"`
-	ImportedByUser int64           `json:"importedByUserId" gorm:"column:imported_by_user_id;not null"`
-	Status         string          `json:"status" gorm:"column:status;default:pending"`
-	TotalItems     int             `json:"totalItems" gorm:"column:total_items;default:0"`
-	ProcessedItems int             `json:"processedItems" gorm:"column:processed_items;default:0"`
-	FailedItems    int             `json:"failedItems" gorm:"column:failed_items;default:0"`
-	ErrorLog       json.RawMessage `json:"errorLog,omitempty" gorm:"column:error_log;type:jsonb"`
-	CreatedAt      time.Time       `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
-	CompletedAt    *time.Time      `json:"completedAt,omitempty" gorm:"column:completed_at"`
-}
-
-// TableName specifies the table name for GORM
-func (DA2062Import) TableName() string {
-	return "da2062_imports"
-}
-
-// DA2062ImportItem represents an individual item from a DA2062 import
-type DA2062ImportItem struct {
-	ID           int64           `json:"id" gorm:"primaryKey;column:id"`
-	ImportID     int64           `json:"importId" gorm:"column:import_id;not null"`
-	LineNumber   int             `json:"lineNumber" gorm:"column:line_number;not null"`
-	RawData      json.RawMessage `json:"rawData" gorm:"column:raw_data;type:jsonb;not null"`
-	PropertyID   *int64          `json:"propertyId,omitempty" gorm:"column:property_id"`
-	Status       string          `json:"status" gorm:"column:status;default:pending"`
-	ErrorMessage *string         `json:"errorMessage,omitempty" gorm:"column:error_message"`
-	CreatedAt    time.Time       `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
-}
-
-// TableName specifies the table name for GORM
-func (DA2062ImportItem) TableName() string {
-	return "da2062_import_items"
-}
-
 // DA2062ImportsHandler handles DA2062 import operations
 type DA2062ImportsHandler struct {
-	db *gorm.DB
+	db         *gorm.DB
+	reviews    *inventory.DA2062ReviewService
+	reconciles *inventory.DA2062ReconcileService
+	learning   *inventory.DA2062LearningService
+	hub        *notification.Hub
+}
+
+// NewDA2062ImportsHandler creates a new DA2062 imports handler. Progress is
+// published to WebSocket clients watching the import when hub is not nil.
+func NewDA2062ImportsHandler(db *gorm.DB, reviews *inventory.DA2062ReviewService, reconciles *inventory.DA2062ReconcileService, learning *inventory.DA2062LearningService, hub *notification.Hub) *DA2062ImportsHandler {
+	return &DA2062ImportsHandler{db: db, reviews: reviews, reconciles: reconciles, learning: learning, hub: hub}
 }
 
-// NewDA2062ImportsHandler creates a new DA2062 imports handler
-func NewDA2062ImportsHandler(db *gorm.DB) *DA2062ImportsHandler {
-	return &DA2062ImportsHandler{db: db}
+// publishProgress sends the import's current counts to clients watching it
+func (h *DA2062ImportsHandler) publishProgress(importID int64) {
+	inventory.PublishImportProgress(h.db, h.hub, importID)
 }
 
-// GetImports returns all DA2062 imports with optional filtering
+// GetImports returns the current user's DA2062 imports with optional filtering
 func (h *DA2062ImportsHandler) GetImports(c *gin.Context) {
-	userID := c.Query("userId")
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
 	status := c.Query("status")
+	mode := c.Query("mode")
 	limit := c.DefaultQuery("limit", "50")
 	offset := c.DefaultQuery("offset", "0")
 
-	var imports []DA2062Import
-	query := h.db.Model(&DA2062Import{})
-
-	if userID != "" {
-		query = query.Where("imported_by_user_id = ?", userID)
-	}
+	var imports []domain.DA2062Import
+	query := h.db.Model(&domain.DA2062Import{}).Where("imported_by_user_id = ?", userID)
 
 	if status != "" {
 		query = query.Where("status = ?", status)
 	}
 
+	if mode != "" {
+		query = query.Where("mode = ?", mode)
+	}
+
 	// Parse limit and offset
 	limitInt, _ := strconv.Atoi(limit)
 	offsetInt, _ := strconv.Atoi(offset)
@@ -100,7 +83,7 @@ func (h *DA2062ImportsHandler) GetImports(c *gin.Context) {
 	})
 }
 
-// GetImport returns a specific DA2062 import by ID
+// GetImport returns one of the current user's DA2062 imports by ID
 func (h *DA2062ImportsHandler) GetImport(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
@@ -110,17 +93,8 @@ func (h *DA2062ImportsHandler) GetImport(c *gin.Context) {
 		return
 	}
 
-	var importRecord DA2062Import
-	if err := h.db.First(&importRecord, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "Import not found",
-			})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to fetch import",
-		})
+	importRecord, ok := h.ownImport(c, id)
+	if !ok {
 		return
 	}
 
@@ -137,10 +111,13 @@ func (h *DA2062ImportsHandler) GetImportItems(c *gin.Context) {
 		return
 	}
 
+	if _, ok := h.ownImport(c, importID); !ok {
+		return
+	}
 	status := c.Query("status")
 
-	var items []DA2062ImportItem
-	query := h.db.Model(&DA2062ImportItem{}).Where("import_id = ?", importID)
+	var items []domain.DA2062ImportItem
+	query := h.db.Model(&domain.DA2062ImportItem{}).Where("import_id = ?", importID)
 
 	if status != "" {
 		query = query.Where("status = ?", status)
@@ -170,7 +147,7 @@ func (h *DA2062ImportsHandler) CreateImport(c *gin.Context) {
 		return
 	}
 
-	var importRecord DA2062Import
+	var importRecord domain.DA2062Import
 	if err := c.ShouldBindJSON(&importRecord); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Invalid request body",
@@ -202,7 +179,8 @@ func (h *DA2062ImportsHandler) CreateImport(c *gin.Context) {
 	c.JSON(http.StatusCreated, importRecord)
 }
 
-// UpdateImportStatus updates the status of an import
+// UpdateImportStatus updates the status of one of the current user's imports once
+// the worker has finished with it
 func (h *DA2062ImportsHandler) UpdateImportStatus(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
@@ -227,14 +205,12 @@ func (h *DA2062ImportsHandler) UpdateImportStatus(c *gin.Context) {
 		return
 	}
 
-	// Validate status
+	// Pending and processing belong to the worker parsing the form
 	if update.Status != "" &&
-		update.Status != "pending" &&
-		update.Status != "processing" &&
-		update.Status != "completed" &&
-		update.Status != "failed" {
+		update.Status != domain.DA2062ImportCompleted &&
+		update.Status != domain.DA2062ImportFailed {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid status. Must be: pending, processing, completed, or failed",
+			"error": "Invalid status. Must be: completed or failed",
 		})
 		return
 	}
@@ -272,7 +248,14 @@ func (h *DA2062ImportsHandler) UpdateImportStatus(c *gin.Context) {
 		return
 	}
 
-	result := h.db.Model(&DA2062Import{}).Where("id = ?", id).Updates(updates)
+	if _, ok := h.ownImport(c, id); !ok {
+		return
+	}
+
+	// Checked in the update so it cannot race the worker picking the import up
+	result := h.db.Model(&domain.DA2062Import{}).
+		Where("id = ? AND status NOT IN ?", id, []string{domain.DA2062ImportPending, domain.DA2062ImportProcessing}).
+		Updates(updates)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to update import",
@@ -281,20 +264,22 @@ func (h *DA2062ImportsHandler) UpdateImportStatus(c *gin.Context) {
 	}
 
 	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Import not found",
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Import is still being processed",
 		})
 		return
 	}
 
+	h.publishProgress(id)
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Import updated successfully",
 	})
 }
 
-// AddImportItem adds an item to an import
-func (h *DA2062ImportsHandler) AddImportItem(c *gin.Context) {
-	importID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+// DeleteImport deletes one of the current user's DA2062 imports and all its items
+func (h *DA2062ImportsHandler) DeleteImport(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Invalid import ID",
@@ -302,173 +287,316 @@ func (h *DA2062ImportsHandler) AddImportItem(c *gin.Context) {
 		return
 	}
 
-	// Check if import exists
-	var importRecord DA2062Import
-	if err := h.db.First(&importRecord, importID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "Import not found",
-			})
-			return
-		}
+	if _, ok := h.ownImport(c, id); !ok {
+		return
+	}
+
+	// Items will be deleted automatically due to CASCADE
+	result := h.db.Delete(&domain.DA2062Import{}, id)
+	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to verify import",
+			"error": "Failed to delete import",
 		})
 		return
 	}
 
-	var item DA2062ImportItem
-	if err := c.ShouldBindJSON(&item); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request body",
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Import not found",
 		})
 		return
 	}
 
-	// Set import ID and validate
-	item.ImportID = importID
-	if item.LineNumber == 0 || item.RawData == nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "lineNumber and rawData are required",
-		})
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Import deleted successfully",
+	})
+}
+
+// GetItemImage returns a crop of the scanned form around one line, for reviewing
+// the line against what was read from it
+func (h *DA2062ImportsHandler) GetItemImage(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	importID, itemID, ok := importItemParams(c)
+	if !ok {
 		return
 	}
 
-	if item.Status == "" {
-		item.Status = "pending"
+	data, contentType, err := h.reviews.RowImage(c.Request.Context(), importID, itemID, userID)
+	if err != nil {
+		respondImportReviewError(c, err)
+		return
 	}
 
-	if err := h.db.Create(&item).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to create import item",
-		})
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// GetItemFieldImage returns a crop of the scanned form around the text one field of
+// a line was read from, such as its serial number
+func (h *DA2062ImportsHandler) GetItemFieldImage(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	importID, itemID, ok := importItemParams(c)
+	if !ok {
 		return
 	}
 
-	// Update total items count
-	h.db.Model(&DA2062Import{}).Where("id = ?", importID).
-		Update("total_items", gorm.Expr("total_items + ?", 1))
+	data, contentType, err := h.reviews.FieldImage(c.Request.Context(), importID, itemID, c.Param("field"), userID)
+	if err != nil {
+		respondImportReviewError(c, err)
+		return
+	}
 
-	c.JSON(http.StatusCreated, item)
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.Data(http.StatusOK, contentType, data)
 }
 
-// UpdateImportItem updates the status of an import item
-func (h *DA2062ImportsHandler) UpdateImportItem(c *gin.Context) {
-	itemID, err := strconv.ParseInt(c.Param("itemId"), 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid item ID",
-		})
+// ReviewImportItem corrects a staged line and accepts, ignores or reopens it
+func (h *DA2062ImportsHandler) ReviewImportItem(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	importID, itemID, ok := importItemParams(c)
+	if !ok {
 		return
 	}
 
-	var update struct {
-		PropertyID   *int64  `json:"propertyId,omitempty"`
-		Status       string  `json:"status"`
-		ErrorMessage *string `json:"errorMessage,omitempty"`
+	var input inventory.ReviewItemInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
 	}
 
-	if err := c.ShouldBindJSON(&update); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request body",
-		})
+	item, err := h.reviews.ReviewItem(c.Request.Context(), importID, itemID, input, userID)
+	if err != nil {
+		respondImportReviewError(c, err)
 		return
 	}
 
-	// Validate status
-	if update.Status != "" &&
-		update.Status != "pending" &&
-		update.Status != "processed" &&
-		update.Status != "failed" &&
-		update.Status != "duplicate" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid status. Must be: pending, processed, failed, or duplicate",
-		})
+	c.JSON(http.StatusOK, gin.H{"item": item})
+}
+
+// MergeImportItems folds lines that split one item's quantity into a single line
+func (h *DA2062ImportsHandler) MergeImportItems(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	importID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import ID"})
 		return
 	}
 
-	updates := make(map[string]interface{})
+	var input inventory.MergeItemsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
 
-	if update.Status != "" {
-		updates["status"] = update.Status
+	item, err := h.reviews.MergeItems(c.Request.Context(), importID, input, userID)
+	if err != nil {
+		respondImportReviewError(c, err)
+		return
 	}
 
-	if update.PropertyID != nil {
-		updates["property_id"] = *update.PropertyID
+	c.JSON(http.StatusOK, gin.H{"item": item})
+}
+
+// CommitImport creates property from the accepted lines of a reviewed import
+func (h *DA2062ImportsHandler) CommitImport(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	importID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import ID"})
+		return
 	}
 
-	if update.ErrorMessage != nil {
-		updates["error_message"] = *update.ErrorMessage
+	result, err := h.reviews.Commit(c.Request.Context(), importID, userID)
+	if err != nil {
+		respondImportReviewError(c, err)
+		return
 	}
 
-	if len(updates) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "No updates provided",
-		})
+	c.JSON(http.StatusCreated, result)
+}
+
+// GetReconciliation compares a reconciliation import with the user's hand receipt
+func (h *DA2062ImportsHandler) GetReconciliation(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	importID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import ID"})
 		return
 	}
 
-	result := h.db.Model(&DA2062ImportItem{}).Where("id = ?", itemID).Updates(updates)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to update import item",
-		})
+	report, err := h.reconciles.Report(c.Request.Context(), importID, userID)
+	if err != nil {
+		respondImportReviewError(c, err)
 		return
 	}
 
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Import item not found",
-		})
+	c.JSON(http.StatusOK, report)
+}
+
+// ReconcileImport creates property, requests an inventory or opens loss cases for
+// differences in the reconciliation report
+func (h *DA2062ImportsHandler) ReconcileImport(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	importID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import ID"})
 		return
 	}
 
-	// Update import counters if status changed
-	if update.Status == "processed" || update.Status == "failed" {
-		var item DA2062ImportItem
-		h.db.First(&item, itemID)
+	var input inventory.ReconcileActionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-		if update.Status == "processed" {
-			h.db.Model(&DA2062Import{}).Where("id = ?", item.ImportID).
-				Update("processed_items", gorm.Expr("processed_items + ?", 1))
-		} else if update.Status == "failed" {
-			h.db.Model(&DA2062Import{}).Where("id = ?", item.ImportID).
-				Update("failed_items", gorm.Expr("failed_items + ?", 1))
-		}
+	actions, err := h.reconciles.Act(c.Request.Context(), importID, input, userID)
+	if err != nil {
+		respondImportReviewError(c, err)
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Import item updated successfully",
-	})
+	c.JSON(http.StatusCreated, gin.H{"actions": actions})
 }
 
-// DeleteImport deletes a DA2062 import and all its items
-func (h *DA2062ImportsHandler) DeleteImport(c *gin.Context) {
-	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid import ID",
-		})
+// GetImportAccuracy reports how much of the user's unit's parsed lines reviewers
+// had to correct, by day, week or month, over the last six months unless a start
+// date is given. Other units' imports are not reported.
+func (h *DA2062ImportsHandler) GetImportAccuracy(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
 		return
 	}
 
-	// Items will be deleted automatically due to CASCADE
-	result := h.db.Delete(&DA2062Import{}, id)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to delete import",
-		})
+	var user domain.User
+	if err := h.db.Select("id", "unit").First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+	// An empty unit would report every unit
+	if user.Unit == "" {
+		c.JSON(http.StatusOK, gin.H{"accuracy": []inventory.UnitAccuracy{}})
 		return
 	}
 
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Import not found",
-		})
+	query := inventory.AccuracyQuery{
+		Unit:   user.Unit,
+		Period: c.DefaultQuery("period", "month"),
+		Since:  time.Now().AddDate(0, -6, 0),
+	}
+	if since := c.Query("since"); since != "" {
+		parsedDate, err := time.Parse("2006-01-02", since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be a date in YYYY-MM-DD format"})
+			return
+		}
+		query.Since = parsedDate
+	}
+
+	report, err := h.learning.Accuracy(c.Request.Context(), query)
+	if err != nil {
+		respondImportReviewError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Import deleted successfully",
-	})
-}
\ No newline at end of file
+	c.JSON(http.StatusOK, gin.H{"accuracy": report})
+}
+
+// ownImport loads an import the current user imported, writing the error response
+// itself
+func (h *DA2062ImportsHandler) ownImport(c *gin.Context, importID int64) (*domain.DA2062Import, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return nil, false
+	}
+
+	var importRecord domain.DA2062Import
+	if err := h.db.First(&importRecord, importID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Import not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch import"})
+		return nil, false
+	}
+	if importRecord.ImportedByUser != int64(userID) {
+		respondImportReviewError(c, inventory.ErrNotImporter)
+		return nil, false
+	}
+	return &importRecord, true
+}
+
+func importItemParams(c *gin.Context) (int64, int64, bool) {
+	importID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import ID"})
+		return 0, 0, false
+	}
+	itemID, err := strconv.ParseInt(c.Param("itemId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		return 0, 0, false
+	}
+	return importID, itemID, true
+}
+
+func respondImportReviewError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import or item not found"})
+	case errors.Is(err, inventory.ErrNotImporter),
+		errors.Is(err, inventory.ErrNotCampaignOwner),
+		errors.Is(err, inventory.ErrNotResponsible),
+		errors.Is(err, flipl.ErrNotHolderOrLeader):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, inventory.ErrNoRowImage),
+		errors.Is(err, inventory.ErrNoFieldBox):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, inventory.ErrInvalidReview),
+		errors.Is(err, inventory.ErrLineInvalid),
+		errors.Is(err, inventory.ErrMergeMismatch),
+		errors.Is(err, inventory.ErrNotADifference),
+		errors.Is(err, inventory.ErrInvalidReconcileAct),
+		errors.Is(err, inventory.ErrInvalidAccuracyPeriod),
+		errors.Is(err, flipl.ErrApproverIsHolder),
+		errors.Is(err, flipl.ErrApproverIsInitiator),
+		errors.Is(err, flipl.ErrPropertyNotOnHand):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, inventory.ErrImportNotReady),
+		errors.Is(err, inventory.ErrImportCommitted),
+		errors.Is(err, inventory.ErrLineLocked),
+		errors.Is(err, inventory.ErrLinesUnreviewed),
+		errors.Is(err, inventory.ErrNothingAccepted),
+		errors.Is(err, inventory.ErrDuplicateSerial),
+		errors.Is(err, inventory.ErrSerialTaken),
+		errors.Is(err, inventory.ErrNotReconciliation),
+		errors.Is(err, inventory.ErrReconciliationMode),
+		errors.Is(err, flipl.ErrOpenCaseExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, inventory.ErrStorageUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Document storage is not available"})
+	default:
+		log.Printf("DA 2062 import review failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Import review failed"})
+	}
+}
@@ -13,7 +13,8 @@ import (
 	"github.com/toole-brendan/handreceipt-go/internal/domain"
 	"github.com/toole-brendan/handreceipt-go/internal/ledger"
 	"github.com/toole-brendan/handreceipt-go/internal/repository"
-	"github.com/toole-brendan/handreceipt-go/internal/services/email"
+	"github.com/toole-brendan/handreceipt-go/internal/services/inventory"
+	"github.com/toole-brendan/handreceipt-go/internal/services/jobs"
 	"github.com/toole-brendan/handreceipt-go/internal/services/storage"
 )
 
@@ -21,16 +22,16 @@ import (
 type DocumentHandler struct {
 	Repo           repository.Repository
 	Ledger         ledger.LedgerService
-	EmailService   *email.DA2062EmailService
+	Jobs           *jobs.Queue
 	StorageService storage.StorageService
 }
 
 // NewDocumentHandler creates a new document handler
-func NewDocumentHandler(repo repository.Repository, ledger ledger.LedgerService, emailService *email.DA2062EmailService, storageService storage.StorageService) *DocumentHandler {
+func NewDocumentHandler(repo repository.Repository, ledger ledger.LedgerService, jobQueue *jobs.Queue, storageService storage.StorageService) *DocumentHandler {
 	return &DocumentHandler{
 		Repo:           repo,
 		Ledger:         ledger,
-		EmailService:   emailService,
+		Jobs:           jobQueue,
 		StorageService: storageService,
 	}
 }
@@ -265,54 +266,15 @@ func (h *DocumentHandler) EmailDocument(c *gin.Context) {
 		return
 	}
 
-	// Extract file key from URL (assuming URL contains the file key)
-	// For now, we'll need to implement a way to extract the file key from the presigned URL
-	// or store the file key separately. For simplicity, let's assume the URL is the file key
-	fileKey := fmt.Sprintf("da2062/transfer_%d.pdf", document.ID) // Reconstruct file key
-
-	// Download PDF from storage
-	reader, err := h.StorageService.DownloadFile(c.Request.Context(), fileKey)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve document"})
-		return
-	}
-	defer reader.Close()
-
-	// Read PDF content into a buffer
-	pdfData := &bytes.Buffer{}
-	if _, err := io.Copy(pdfData, reader); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read document"})
-		return
-	}
-
-	// Get user info for email
-	user, err := h.Repo.GetUserByID(userID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user information"})
+	// Mailing is done by the worker so a slow mail server does not hold up the request
+	job := inventory.DocumentEmailJob{DocumentID: document.ID, UserID: userID, Email: req.Email}
+	if _, err := h.Jobs.Enqueue(c.Request.Context(), inventory.JobEmailDocument, job, jobs.EnqueueOptions{CreatedBy: userID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue email"})
 		return
 	}
 
-	// Generate form number from document title or use document ID
-	formNumber := fmt.Sprintf("DOC-%d", document.ID)
-
-	// Send email
-	senderInfo := email.UserInfo{
-		Name:  user.FirstName + " " + user.LastName,
-		Rank:  user.Rank,
-		Title: user.Unit,
-		Phone: user.Phone,
-	}
-
-	if err := h.EmailService.SendDA2062Email([]string{req.Email}, pdfData, formNumber, senderInfo); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send email"})
-		return
-	}
-
-	// Log the email action
-	h.Ledger.LogDocumentEvent(document.ID, "DOCUMENT_EMAILED", userID, 0)
-
-	c.JSON(http.StatusOK, gin.H{
-		"message": fmt.Sprintf("DA 2062 emailed successfully to %s", req.Email),
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": fmt.Sprintf("DA 2062 will be emailed to %s", req.Email),
 	})
 }
 
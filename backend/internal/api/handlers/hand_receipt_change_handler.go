@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/toole-brendan/handreceipt-go/internal/repository"
+	"github.com/toole-brendan/handreceipt-go/internal/services/inventory"
+	"gorm.io/gorm"
+)
+
+// HandReceiptChangeHandler handles change of hand receipt holder inventories
+type HandReceiptChangeHandler struct {
+	Service *inventory.HandReceiptChangeService
+}
+
+// NewHandReceiptChangeHandler creates a new hand receipt change handler
+func NewHandReceiptChangeHandler(service *inventory.HandReceiptChangeService) *HandReceiptChangeHandler {
+	return &HandReceiptChangeHandler{Service: service}
+}
+
+// StartChange opens a joint inventory between the outgoing and incoming holders
+func (h *HandReceiptChangeHandler) StartChange(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input inventory.StartChangeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	change, err := h.Service.Start(c.Request.Context(), input, userID)
+	if err != nil {
+		respondHandReceiptChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"change":  change,
+		"summary": inventory.Summarize(change),
+	})
+}
+
+// ListChanges returns the changes the current user is party to
+func (h *HandReceiptChangeHandler) ListChanges(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	changes, err := h.Service.ListForUser(c.Request.Context(), userID, c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch hand receipt changes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"changes": changes})
+}
+
+// GetChange returns a change with every inventory line and running totals
+func (h *HandReceiptChangeHandler) GetChange(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	changeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid change ID"})
+		return
+	}
+
+	change, err := h.Service.Get(c.Request.Context(), uint(changeID))
+	if err != nil {
+		respondHandReceiptChangeError(c, err)
+		return
+	}
+	if change.OutgoingUserID != userID && change.IncomingUserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to view this hand receipt change"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"change":  change,
+		"summary": inventory.Summarize(change),
+	})
+}
+
+// MarkItem records the current user's count of one inventory line
+func (h *HandReceiptChangeHandler) MarkItem(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	changeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid change ID"})
+		return
+	}
+	itemID, err := strconv.ParseUint(c.Param("itemId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		return
+	}
+
+	var req struct {
+		Mark    string  `json:"mark" binding:"required,oneof=found missing discrepant"`
+		Remarks *string `json:"remarks"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	item, err := h.Service.MarkItem(c.Request.Context(), uint(changeID), uint(itemID), userID, req.Mark, req.Remarks)
+	if err != nil {
+		respondHandReceiptChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"item": item})
+}
+
+// GetShortageAnnex streams the shortage annex PDF for the items agreed missing so far
+func (h *HandReceiptChangeHandler) GetShortageAnnex(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	changeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid change ID"})
+		return
+	}
+
+	change, err := h.Service.Get(c.Request.Context(), uint(changeID))
+	if err != nil {
+		respondHandReceiptChangeError(c, err)
+		return
+	}
+	if change.OutgoingUserID != userID && change.IncomingUserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to view this hand receipt change"})
+		return
+	}
+
+	pdf, err := h.Service.ShortageAnnex(c.Request.Context(), change)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate shortage annex: " + err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=shortage_annex_%d.pdf", change.ID))
+	c.Data(http.StatusOK, "application/pdf", pdf.Bytes())
+}
+
+// CompleteChange transfers the found items and issues the signed DA 2062 set
+func (h *HandReceiptChangeHandler) CompleteChange(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	changeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid change ID"})
+		return
+	}
+
+	change, err := h.Service.Complete(c.Request.Context(), uint(changeID), userID)
+	if err != nil {
+		respondHandReceiptChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"change":  change,
+		"summary": inventory.Summarize(change),
+	})
+}
+
+// CancelChange abandons an open change
+func (h *HandReceiptChangeHandler) CancelChange(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	changeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid change ID"})
+		return
+	}
+
+	change, err := h.Service.Cancel(c.Request.Context(), uint(changeID), userID)
+	if err != nil {
+		respondHandReceiptChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"change": change})
+}
+
+func respondHandReceiptChangeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Hand receipt change or item not found"})
+	case errors.Is(err, inventory.ErrNotChangeParty), errors.Is(err, inventory.ErrCannotStartChange):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, inventory.ErrSameHolder), errors.Is(err, inventory.ErrInvalidMark):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, inventory.ErrChangeNotOpen),
+		errors.Is(err, inventory.ErrOpenChangeExists),
+		errors.Is(err, inventory.ErrItemsUnresolved),
+		errors.Is(err, inventory.ErrItemsGone),
+		errors.Is(err, repository.ErrPropertyHeldByOtherUser):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Hand receipt change failed: " + err.Error()})
+	}
+}
+
+// currentUserID reads the authenticated user from the context, writing the error
+// response itself when the user is missing.
+func currentUserID(c *gin.Context) (uint, bool) {
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return 0, false
+	}
+	userID, ok := userIDVal.(uint)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format in context"})
+		return 0, false
+	}
+	return userID, true
+}
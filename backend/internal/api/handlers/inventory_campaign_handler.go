@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/toole-brendan/handreceipt-go/internal/services/inventory"
+	"gorm.io/gorm"
+)
+
+// InventoryCampaignHandler handles cyclic and sensitive-item inventory campaigns
+type InventoryCampaignHandler struct {
+	Service *inventory.CampaignService
+}
+
+// NewInventoryCampaignHandler creates a new inventory campaign handler
+func NewInventoryCampaignHandler(service *inventory.CampaignService) *InventoryCampaignHandler {
+	return &InventoryCampaignHandler{Service: service}
+}
+
+// CreateCampaign selects property and opens a new inventory campaign
+func (h *InventoryCampaignHandler) CreateCampaign(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input inventory.CreateCampaignInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	campaign, err := h.Service.Create(c.Request.Context(), input, userID)
+	if err != nil {
+		respondInventoryCampaignError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"campaign": campaign,
+		"stats":    inventory.ComputeStats(campaign.Items),
+	})
+}
+
+// ListCampaigns returns campaigns the current user created or is counting for
+func (h *InventoryCampaignHandler) ListCampaigns(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	campaigns, err := h.Service.ListForUser(c.Request.Context(), userID, c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch inventory campaigns"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"campaigns": campaigns})
+}
+
+// GetCampaign returns a campaign with its items and completion statistics
+func (h *InventoryCampaignHandler) GetCampaign(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	campaignID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return
+	}
+
+	campaign, err := h.Service.Get(c.Request.Context(), uint(campaignID), userID)
+	if err != nil {
+		respondInventoryCampaignError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"campaign": campaign,
+		"stats":    inventory.ComputeStats(campaign.Items),
+	})
+}
+
+// AssignCounters assigns a counter to items in the campaign
+func (h *InventoryCampaignHandler) AssignCounters(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	campaignID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return
+	}
+
+	var input inventory.AssignCountersInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	assigned, err := h.Service.AssignCounters(c.Request.Context(), uint(campaignID), userID, input)
+	if err != nil {
+		respondInventoryCampaignError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"assigned": assigned})
+}
+
+// RecordCount records the current user's count of one item. Accepts JSON, or a
+// multipart form with an optional "photo" file alongside the count fields.
+func (h *InventoryCampaignHandler) RecordCount(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	campaignID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return
+	}
+	itemID, err := strconv.ParseUint(c.Param("itemId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		return
+	}
+
+	var input inventory.RecordCountInput
+	if err := c.ShouldBind(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	var photo *inventory.CountPhoto
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		if file, header, err := c.Request.FormFile("photo"); err == nil {
+			defer file.Close()
+
+			// Same limits as property photos
+			if header.Size > 10*1024*1024 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Photo size exceeds 10MB limit"})
+				return
+			}
+			contentType := header.Header.Get("Content-Type")
+			if contentType != "image/jpeg" && contentType != "image/png" && contentType != "image/webp" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file type. Only JPEG, PNG, and WebP are allowed"})
+				return
+			}
+
+			photo = &inventory.CountPhoto{
+				Reader:      file,
+				Size:        header.Size,
+				ContentType: contentType,
+				Filename:    header.Filename,
+			}
+		}
+	}
+
+	item, err := h.Service.RecordCount(c.Request.Context(), uint(campaignID), uint(itemID), userID, input, photo)
+	if err != nil {
+		respondInventoryCampaignError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"item": item})
+}
+
+// CloseCampaign closes the campaign and issues the signed inventory report
+func (h *InventoryCampaignHandler) CloseCampaign(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	campaignID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return
+	}
+
+	campaign, err := h.Service.Close(c.Request.Context(), uint(campaignID), userID)
+	if err != nil {
+		respondInventoryCampaignError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"campaign": campaign,
+		"stats":    inventory.ComputeStats(campaign.Items),
+	})
+}
+
+// CancelCampaign abandons an active campaign
+func (h *InventoryCampaignHandler) CancelCampaign(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	campaignID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return
+	}
+
+	campaign, err := h.Service.Cancel(c.Request.Context(), uint(campaignID), userID)
+	if err != nil {
+		respondInventoryCampaignError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"campaign": campaign})
+}
+
+func respondInventoryCampaignError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Inventory campaign, item, or user not found"})
+	case errors.Is(err, inventory.ErrNotCampaignOwner), errors.Is(err, inventory.ErrNotAssignedCounter),
+		errors.Is(err, inventory.ErrNotCampaignParty), errors.Is(err, inventory.ErrNotResponsible),
+		errors.Is(err, inventory.ErrCounterNotSubordinate):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, inventory.ErrInvalidSamplePercent),
+		errors.Is(err, inventory.ErrNoPropertySelected),
+		errors.Is(err, inventory.ErrCounterIsHolder),
+		errors.Is(err, inventory.ErrCreatorIsCounter),
+		errors.Is(err, inventory.ErrInvalidRepeat):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, inventory.ErrCampaignNotActive):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Inventory campaign failed: " + err.Error()})
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/toole-brendan/handreceipt-go/internal/services/jobs"
+	"gorm.io/gorm"
+)
+
+// JobHandler is the operator view of the background job queue
+type JobHandler struct {
+	Queue *jobs.Queue
+}
+
+// NewJobHandler creates a new job handler
+func NewJobHandler(queue *jobs.Queue) *JobHandler {
+	return &JobHandler{Queue: queue}
+}
+
+// ListJobs returns jobs newest first, optionally filtered by status and type
+func (h *JobHandler) ListJobs(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	list, err := h.Queue.List(c.Query("status"), c.Query("type"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch jobs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"jobs": list})
+}
+
+// Stats returns job counts by type and status
+func (h *JobHandler) Stats(c *gin.Context) {
+	stats, err := h.Queue.Stats()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch job stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
+
+// GetJob returns a single job, including its payload and last error
+func (h *JobHandler) GetJob(c *gin.Context) {
+	jobID, ok := jobIDParam(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.Queue.Get(jobID)
+	if err != nil {
+		respondJobError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
+
+// RetryJob queues a dead-lettered or cancelled job again
+func (h *JobHandler) RetryJob(c *gin.Context) {
+	jobID, ok := jobIDParam(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.Queue.Retry(jobID)
+	if err != nil {
+		respondJobError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"job": job})
+}
+
+// CancelJob stops a queued job from running
+func (h *JobHandler) CancelJob(c *gin.Context) {
+	jobID, ok := jobIDParam(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.Queue.Cancel(jobID)
+	if err != nil {
+		respondJobError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
+
+func jobIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondJobError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+	case errors.Is(err, jobs.ErrNotRetryable), errors.Is(err, jobs.ErrNotCancellable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Job request failed: " + err.Error()})
+	}
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/toole-brendan/handreceipt-go/internal/services/flipl"
+	"gorm.io/gorm"
+)
+
+// LossCaseHandler handles Financial Liability Investigation of Property Loss cases
+type LossCaseHandler struct {
+	Service *flipl.LossCaseService
+}
+
+// NewLossCaseHandler creates a new loss case handler
+func NewLossCaseHandler(service *flipl.LossCaseService) *LossCaseHandler {
+	return &LossCaseHandler{Service: service}
+}
+
+// OpenCase reports property lost, damaged or destroyed
+func (h *LossCaseHandler) OpenCase(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input flipl.OpenCaseInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	lossCase, err := h.Service.Open(c.Request.Context(), input, userID)
+	if err != nil {
+		respondLossCaseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"case": lossCase})
+}
+
+// ListCases returns the cases the current user is party to
+func (h *LossCaseHandler) ListCases(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	cases, err := h.Service.ListForUser(c.Request.Context(), userID, c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch loss cases"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cases": cases})
+}
+
+// GetCase returns a case with its approval steps and the property's ledger history
+func (h *LossCaseHandler) GetCase(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	caseID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid case ID"})
+		return
+	}
+
+	lossCase, err := h.Service.Get(c.Request.Context(), uint(caseID))
+	if err != nil {
+		respondLossCaseError(c, err)
+		return
+	}
+	if !flipl.IsParty(lossCase, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to view this loss case"})
+		return
+	}
+
+	history, err := h.Service.History(lossCase)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch property history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"case":    lossCase,
+		"history": history,
+	})
+}
+
+// AssignInvestigator appoints the investigating officer
+func (h *LossCaseHandler) AssignInvestigator(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	caseID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid case ID"})
+		return
+	}
+
+	var req struct {
+		InvestigatingOfficerID uint `json:"investigatingOfficerId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	lossCase, err := h.Service.AssignInvestigator(c.Request.Context(), uint(caseID), userID, req.InvestigatingOfficerID)
+	if err != nil {
+		respondLossCaseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"case": lossCase})
+}
+
+// SubmitFindings records the investigating officer's findings and liability recommendation
+func (h *LossCaseHandler) SubmitFindings(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	caseID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid case ID"})
+		return
+	}
+
+	var input flipl.FindingsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	lossCase, err := h.Service.SubmitFindings(c.Request.Context(), uint(caseID), userID, input)
+	if err != nil {
+		respondLossCaseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"case": lossCase})
+}
+
+// DecideCase approves the findings or returns them to the investigating officer
+func (h *LossCaseHandler) DecideCase(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	caseID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid case ID"})
+		return
+	}
+
+	var input flipl.DecisionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	lossCase, err := h.Service.Decide(c.Request.Context(), uint(caseID), userID, input)
+	if err != nil {
+		respondLossCaseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"case": lossCase})
+}
+
+// WithdrawCase closes an unapproved case, typically because the item was found
+func (h *LossCaseHandler) WithdrawCase(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	caseID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid case ID"})
+		return
+	}
+
+	var req struct {
+		Comments *string `json:"comments"`
+	}
+	_ = c.ShouldBindJSON(&req)
+
+	lossCase, err := h.Service.Withdraw(c.Request.Context(), uint(caseID), userID, req.Comments)
+	if err != nil {
+		respondLossCaseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"case": lossCase})
+}
+
+// GetDD200 streams the DD Form 200 for the case as it currently stands
+func (h *LossCaseHandler) GetDD200(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	caseID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid case ID"})
+		return
+	}
+
+	lossCase, err := h.Service.Get(c.Request.Context(), uint(caseID))
+	if err != nil {
+		respondLossCaseError(c, err)
+		return
+	}
+	if !flipl.IsParty(lossCase, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to view this loss case"})
+		return
+	}
+
+	pdf, err := h.Service.Form(c.Request.Context(), lossCase)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate DD Form 200: " + err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=DD200_%s.pdf", lossCase.CaseNumber))
+	c.Data(http.StatusOK, "application/pdf", pdf.Bytes())
+}
+
+func respondLossCaseError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Loss case, property or user not found"})
+	case errors.Is(err, flipl.ErrNotCaseParty),
+		errors.Is(err, flipl.ErrNotHolderOrLeader):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, flipl.ErrInvestigatorConflict),
+		errors.Is(err, flipl.ErrApproverIsHolder),
+		errors.Is(err, flipl.ErrApproverIsInitiator),
+		errors.Is(err, flipl.ErrInvalidLiabilityValue),
+		errors.Is(err, flipl.ErrPropertyNotOnHand):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, flipl.ErrCaseNotOpen),
+		errors.Is(err, flipl.ErrInvalidCaseStep),
+		errors.Is(err, flipl.ErrOpenCaseExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Loss case failed: " + err.Error()})
+	}
+}
@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"github.com/toole-brendan/handreceipt-go/internal/services/notification"
 )
 
 // NotificationHandlers handles notification-related endpoints
@@ -123,4 +125,129 @@ func (h *NotificationHandlers) ClearOldNotifications(c *gin.Context) {
 		"message": "Old notifications cleared",
 		"deleted": deletedCount,
 	})
-}
\ No newline at end of file
+}
+// GetPreferences returns the user's quiet hours and how each notification type is delivered
+func (h *NotificationHandlers) GetPreferences(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	settings, preferences, err := h.notificationService.GetNotificationPreferences(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notification preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"settings":    settings,
+		"preferences": preferences,
+	})
+}
+
+// UpdatePreferences saves quiet hours and per-type delivery preferences. Types left
+// out of the request keep their current preference.
+func (h *NotificationHandlers) UpdatePreferences(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Settings    *domain.NotificationSettings   `json:"settings"`
+		Preferences []domain.NotificationPreference `json:"preferences"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	err := h.notificationService.UpdateNotificationPreferences(userID, req.Settings, req.Preferences)
+	switch {
+	case errors.Is(err, notification.ErrInvalidTimezone),
+		errors.Is(err, notification.ErrInvalidQuietHours),
+		errors.Is(err, notification.ErrUnknownNotificationType),
+		errors.Is(err, notification.ErrInvalidDigestFrequency):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save notification preferences"})
+		return
+	}
+
+	h.GetPreferences(c)
+}
+
+// RegisterDevice registers the device's APNs or FCM token for push notifications.
+// Apps call it on every launch since the platforms may rotate tokens.
+func (h *NotificationHandlers) RegisterDevice(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Token      string `json:"token" binding:"required"`
+		Platform   string `json:"platform" binding:"required"`
+		DeviceID   string `json:"deviceId"`
+		AppVersion string `json:"appVersion"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	device := &domain.DeviceToken{
+		Token:      req.Token,
+		Platform:   req.Platform,
+		DeviceID:   req.DeviceID,
+		AppVersion: req.AppVersion,
+	}
+	err := h.notificationService.RegisterDeviceToken(userID, device)
+	switch {
+	case errors.Is(err, notification.ErrUnsupportedPlatform):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register device"})
+		return
+	}
+
+	c.JSON(http.StatusOK, device)
+}
+
+// GetDevices lists the devices registered for the user's push notifications
+func (h *NotificationHandlers) GetDevices(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	devices, err := h.notificationService.GetDeviceTokens(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch devices"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"devices": devices})
+}
+
+// UnregisterDevice stops push notifications to a device, typically on sign out
+func (h *NotificationHandlers) UnregisterDevice(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	err := h.notificationService.UnregisterDeviceToken(userID, c.Param("token"))
+	switch {
+	case errors.Is(err, notification.ErrDeviceTokenNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unregister device"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Device unregistered"})
+}
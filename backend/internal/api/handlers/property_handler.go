@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"log"
 
@@ -151,6 +152,13 @@ func (h *PropertyHandler) UpdatePropertyStatus(c *gin.Context) {
 		return
 	}
 
+	// Loss, damage and disposal go through a loss case so there is an accountability record
+	switch strings.ToLower(updateData.Status) {
+	case domain.LossCaseLost, domain.LossCaseDamaged, domain.LossCaseDestroyed, domain.PropertyStatusDisposed:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Open a loss case to report property lost, damaged or destroyed"})
+		return
+	}
+
 	// Fetch property from repository
 	property, err := h.Repo.GetPropertyByID(uint(id))
 	if err != nil {
@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/toole-brendan/handreceipt-go/internal/api/middleware"
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"github.com/toole-brendan/handreceipt-go/internal/repository"
+	"gorm.io/gorm"
+)
+
+// AmendTransfer lets the sender correct a rejected transfer and resubmit it. The
+// rejected version is kept as a revision so both parties can see what changed.
+func (h *TransferHandler) AmendTransfer(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	transfer, ok := h.loadTransfer(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		PropertyID        *uint   `json:"propertyId"`
+		IncludeComponents *bool   `json:"includeComponents"`
+		Notes             *string `json:"notes"`
+		AmendmentNotes    *string `json:"amendmentNotes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	if transferSender(transfer) != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the sender can amend this transfer"})
+		return
+	}
+	if !strings.EqualFold(transfer.Status, "rejected") {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only rejected transfers can be amended"})
+		return
+	}
+
+	previous := &domain.TransferRevision{
+		TransferID:        transfer.ID,
+		Revision:          transfer.Revision,
+		PropertyID:        transfer.PropertyID,
+		IncludeComponents: transfer.IncludeComponents,
+		Notes:             transfer.Notes,
+		Status:            transfer.Status,
+		RejectionReason:   transfer.RejectionReason,
+		AmendedByUserID:   userID,
+		AmendmentNotes:    req.AmendmentNotes,
+	}
+
+	if req.PropertyID != nil {
+		transfer.PropertyID = *req.PropertyID
+	}
+	if req.IncludeComponents != nil {
+		transfer.IncludeComponents = *req.IncludeComponents
+	}
+	if req.Notes != nil {
+		transfer.Notes = req.Notes
+	}
+
+	// The amended transfer must still describe property the sender holds
+	item, err := h.Repo.GetPropertyByID(transfer.PropertyID)
+	if err != nil || item == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Property not found"})
+		return
+	}
+	if item.AssignedToUserID == nil || *item.AssignedToUserID != transfer.FromUserID {
+		c.JSON(http.StatusConflict, gin.H{"error": "Property is not held by the sender"})
+		return
+	}
+	if !h.checkComponentHolders(c, transfer) {
+		return
+	}
+
+	transfer.Status = "pending"
+	transfer.Revision++
+	transfer.RejectionReason = nil
+	transfer.ResolvedDate = nil
+	transfer.RequestDate = time.Now().UTC()
+
+	if err := h.Repo.AmendTransfer(transfer, previous); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to amend transfer: " + err.Error()})
+		return
+	}
+
+	if err := h.Ledger.LogTransferEvent(*transfer, item.SerialNumber); err != nil {
+		log.Printf("WARNING: Failed to log transfer amendment (ID: %d, Revision: %d) to Ledger: %v", transfer.ID, transfer.Revision, err)
+	}
+
+	if h.NotificationService != nil {
+		transfer.Property = item
+		h.NotificationService.NotifyTransferUpdate(transfer)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"transfer": transfer})
+}
+
+// GetTransferRevisions returns the earlier revisions of a transfer, oldest first
+func (h *TransferHandler) GetTransferRevisions(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	transfer, ok := h.loadTransfer(c)
+	if !ok {
+		return
+	}
+	if _, ok := h.authorizeTransferFollower(c, transfer, userID); !ok {
+		return
+	}
+
+	revisions, err := h.Repo.ListTransferRevisions(transfer.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transfer revisions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"currentRevision": transfer.Revision,
+		"revisions":       revisions,
+	})
+}
+
+// GetTransferComments returns the comments on a transfer arranged into threads
+func (h *TransferHandler) GetTransferComments(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	transfer, ok := h.loadTransfer(c)
+	if !ok {
+		return
+	}
+	if _, ok := h.authorizeTransferFollower(c, transfer, userID); !ok {
+		return
+	}
+
+	comments, err := h.Repo.ListTransferComments(transfer.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transfer comments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"comments": threadComments(comments)})
+}
+
+// AddTransferComment posts a comment, or a reply when parentCommentId is set, and
+// pushes it to both parties and any approvers.
+func (h *TransferHandler) AddTransferComment(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	transfer, ok := h.loadTransfer(c)
+	if !ok {
+		return
+	}
+	followers, ok := h.authorizeTransferFollower(c, transfer, userID)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Body            string `json:"body" binding:"required"`
+		ParentCommentID *uint  `json:"parentCommentId"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Body) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment body is required"})
+		return
+	}
+
+	if req.ParentCommentID != nil {
+		comments, err := h.Repo.ListTransferComments(transfer.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transfer comments"})
+			return
+		}
+		found := false
+		for _, comment := range comments {
+			if comment.ID == *req.ParentCommentID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent comment does not belong to this transfer"})
+			return
+		}
+	}
+
+	comment := &domain.TransferComment{
+		TransferID:      transfer.ID,
+		ParentCommentID: req.ParentCommentID,
+		AuthorUserID:    userID,
+		Body:            strings.TrimSpace(req.Body),
+	}
+	if err := h.Repo.CreateTransferComment(comment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add comment: " + err.Error()})
+		return
+	}
+	if author, err := h.Repo.GetUserByID(userID); err == nil {
+		comment.Author = author
+	}
+
+	if h.NotificationService != nil {
+		if err := h.NotificationService.NotifyTransferComment(comment, followers); err != nil {
+			log.Printf("WARNING: Failed to notify followers of comment %d on transfer %d: %v", comment.ID, transfer.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"comment": comment})
+}
+
+// AddTransferApprover lets either party bring another user, such as the supply
+// sergeant, into the transfer so they can follow and comment on it. Approvers
+// must be in the chain of command of one of the parties, or an administrator.
+func (h *TransferHandler) AddTransferApprover(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	transfer, ok := h.loadTransfer(c)
+	if !ok {
+		return
+	}
+	if userID != transfer.FromUserID && userID != transfer.ToUserID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the transfer parties can add approvers"})
+		return
+	}
+
+	var req struct {
+		UserID uint `json:"userId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+	if req.UserID == transfer.FromUserID || req.UserID == transfer.ToUserID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Transfer parties are already following this transfer"})
+		return
+	}
+	approverUser, err := h.Repo.GetUserByID(req.UserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if !middleware.IsAdminEmail(approverUser.Email) {
+		db, _ := h.Repo.DB().(*gorm.DB)
+		allowed := false
+		for _, partyID := range []uint{transfer.FromUserID, transfer.ToUserID} {
+			inChain, err := repository.InChainOfCommand(db, req.UserID, partyID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check chain of command"})
+				return
+			}
+			if inChain {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Approvers must be in the chain of command of a transfer party"})
+			return
+		}
+	}
+
+	approver := &domain.TransferApprover{
+		TransferID:    transfer.ID,
+		UserID:        req.UserID,
+		AddedByUserID: userID,
+	}
+	if err := h.Repo.AddTransferApprover(approver); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add approver: " + err.Error()})
+		return
+	}
+
+	approvers, err := h.Repo.ListTransferApprovers(transfer.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch approvers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"approvers": approvers})
+}
+
+// loadTransfer fetches the transfer named in the URL, writing the error response itself
+func (h *TransferHandler) loadTransfer(c *gin.Context) (*domain.Transfer, bool) {
+	transferID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transfer ID"})
+		return nil, false
+	}
+
+	transfer, err := h.Repo.GetTransferByID(uint(transferID))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && !strings.Contains(err.Error(), "not found") {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transfer: " + err.Error()})
+		return nil, false
+	}
+	if transfer == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transfer not found"})
+		return nil, false
+	}
+	return transfer, true
+}
+
+// authorizeTransferFollower checks the user is a party or approver on the transfer and
+// returns everyone following it.
+func (h *TransferHandler) authorizeTransferFollower(c *gin.Context, transfer *domain.Transfer, userID uint) ([]uint, bool) {
+	approvers, err := h.Repo.ListTransferApprovers(transfer.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch approvers"})
+		return nil, false
+	}
+
+	followers := []uint{transfer.FromUserID, transfer.ToUserID}
+	for _, approver := range approvers {
+		followers = append(followers, approver.UserID)
+	}
+	for _, id := range followers {
+		if id == userID {
+			return followers, true
+		}
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to view this transfer"})
+	return nil, false
+}
+
+// transferSender is the party who put the transfer forward: the initiator when
+// recorded, otherwise the current holder.
+func transferSender(transfer *domain.Transfer) uint {
+	if transfer.InitiatorID != nil {
+		return *transfer.InitiatorID
+	}
+	return transfer.FromUserID
+}
+
+// threadComments nests replies under the comment they answer, keeping time order
+func threadComments(comments []domain.TransferComment) []domain.TransferComment {
+	children := make(map[uint][]domain.TransferComment)
+	var roots []domain.TransferComment
+	for _, comment := range comments {
+		if comment.ParentCommentID == nil {
+			roots = append(roots, comment)
+		} else {
+			children[*comment.ParentCommentID] = append(children[*comment.ParentCommentID], comment)
+		}
+	}
+
+	var attach func(comment domain.TransferComment) domain.TransferComment
+	attach = func(comment domain.TransferComment) domain.TransferComment {
+		for _, reply := range children[comment.ID] {
+			comment.Replies = append(comment.Replies, attach(reply))
+		}
+		return comment
+	}
+
+	threads := make([]domain.TransferComment, 0, len(roots))
+	for _, root := range roots {
+		threads = append(threads, attach(root))
+	}
+	return threads
+}
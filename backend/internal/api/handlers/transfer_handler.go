@@ -1,13 +1,12 @@
 package handlers
 
 import (
-	"bytes"
-	"context"
 	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -15,9 +14,8 @@ import (
 	"github.com/toole-brendan/handreceipt-go/internal/ledger"
 	"github.com/toole-brendan/handreceipt-go/internal/repository"
 	"github.com/toole-brendan/handreceipt-go/internal/services"
-	"github.com/toole-brendan/handreceipt-go/internal/services/email"
-	"github.com/toole-brendan/handreceipt-go/internal/services/documents"
-	"github.com/toole-brendan/handreceipt-go/internal/services/storage"
+	"github.com/toole-brendan/handreceipt-go/internal/services/inventory"
+	"github.com/toole-brendan/handreceipt-go/internal/services/jobs"
 	"gorm.io/gorm"
 )
 
@@ -26,9 +24,7 @@ type TransferHandler struct {
 	Ledger               ledger.LedgerService
 	Repo                 repository.Repository
 	ComponentService     services.ComponentService
-	PDFGenerator         *documents.DA2062Generator
-	EmailService         *email.DA2062EmailService
-	StorageService       storage.StorageService
+	Jobs                 *jobs.Queue
 	NotificationService  domain.NotificationService
 }
 
@@ -37,18 +33,14 @@ func NewTransferHandler(
 	ledgerService ledger.LedgerService,
 	repo repository.Repository,
 	componentService services.ComponentService,
-	pdfGenerator *documents.DA2062Generator,
-	emailService *email.DA2062EmailService,
-	storageService storage.StorageService,
+	jobQueue *jobs.Queue,
 	notificationService domain.NotificationService,
 ) *TransferHandler {
 	return &TransferHandler{
 		Ledger:              ledgerService,
 		Repo:                repo,
 		ComponentService:    componentService,
-		PDFGenerator:        pdfGenerator,
-		EmailService:        emailService,
-		StorageService:      storageService,
+		Jobs:                jobQueue,
 		NotificationService: notificationService,
 	}
 }
@@ -101,6 +93,10 @@ func (h *TransferHandler) CreateTransfer(c *gin.Context) {
 		// ResolvedDate is null initially
 	}
 
+	if !h.checkComponentHolders(c, transfer) {
+		return
+	}
+
 	// Insert into database using repository
 	if err := h.Repo.CreateTransfer(transfer); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transfer: " + err.Error()})
@@ -150,8 +146,9 @@ func (h *TransferHandler) UpdateTransferStatus(c *gin.Context) {
 
 	// Parse status from request body
 	var req struct {
-		Status string  `json:"status" binding:"required,oneof=accepted rejected cancelled"`
-		Reason *string `json:"reason"`
+		Status          string  `json:"status" binding:"required,oneof=accepted rejected cancelled"`
+		Reason          *string `json:"reason"`
+		RejectionReason *string `json:"rejectionReason" binding:"omitempty,oneof=wrong_serial damaged missing_components not_mine other"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -159,6 +156,18 @@ func (h *TransferHandler) UpdateTransferStatus(c *gin.Context) {
 		return
 	}
 
+	// Rejections carry a structured reason so the sender knows what to amend
+	if req.Status == "rejected" {
+		if req.RejectionReason == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rejectionReason is required when rejecting a transfer"})
+			return
+		}
+		if *req.RejectionReason == domain.RejectionReasonOther && (req.Reason == nil || strings.TrimSpace(*req.Reason) == "") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "reason must describe the problem when rejectionReason is other"})
+			return
+		}
+	}
+
 	// Convert to domain type for compatibility with existing code
 	updateData := domain.UpdateTransferInput{
 		Status: req.Status,
@@ -240,6 +249,9 @@ func (h *TransferHandler) UpdateTransferStatus(c *gin.Context) {
 	// Update fields
 	previousStatus := transfer.Status
 	transfer.Status = updateData.Status
+	if transfer.Status == "rejected" {
+		transfer.RejectionReason = req.RejectionReason
+	}
 
 	// Append reason to existing notes if provided
 	if req.Reason != nil && *req.Reason != "" {
@@ -259,51 +271,31 @@ func (h *TransferHandler) UpdateTransferStatus(c *gin.Context) {
 		transfer.ResolvedDate = nil // Reset if moved back to pending?
 	}
 
-	// Save updated transfer using repository
-	if err := h.Repo.UpdateTransfer(transfer); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update transfer status: " + err.Error()})
-		return
-	}
-
-	// If accepted, update the property ownership
 	if transfer.Status == "accepted" && previousStatus != "accepted" {
-		// Update the property's current holder
-		item.AssignedToUserID = &transfer.ToUserID
-		item.UpdatedAt = time.Now().UTC()
-
-		if err := h.Repo.UpdateProperty(item); err != nil {
-			// Rollback transfer status change
-			transfer.Status = previousStatus
-			transfer.ResolvedDate = nil
-			h.Repo.UpdateTransfer(transfer)
-
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update property ownership"})
+		// Save the transfer and move the property and its component tree atomically,
+		// queueing the DA 2062 Hand Receipt for the worker in the same transaction so
+		// an accepted transfer always gets one
+		plan, err := h.Repo.CompleteTransfer(transfer, func(tx *gorm.DB) error {
+			_, err := h.Jobs.EnqueueTx(tx, inventory.JobIssueTransferReceipt, inventory.TransferReceiptJob{TransferID: transfer.ID}, jobs.EnqueueOptions{CreatedBy: currentUserID})
+			return err
+		})
+		if err != nil {
+			respondTreeTransferError(c, err)
 			return
 		}
+		item.AssignedToUserID = &transfer.ToUserID
+		item.UpdatedAt = time.Now().UTC()
 
-		log.Printf("Property %d ownership transferred from user %d to user %d", item.ID, transfer.FromUserID, transfer.ToUserID)
-
-		// If the transfer includes components, transfer them too
-		if transfer.IncludeComponents {
-			if err := h.ComponentService.TransferComponents(c.Request.Context(), transfer.PropertyID, transfer.FromUserID, transfer.ToUserID); err != nil {
-				log.Printf("WARNING: Failed to transfer components for property %d: %v", transfer.PropertyID, err)
-				// Note: We continue with the transfer even if component transfer fails
-				// The main property transfer has already succeeded
-			} else {
-				log.Printf("Successfully transferred components for property %d from user %d to user %d", transfer.PropertyID, transfer.FromUserID, transfer.ToUserID)
-			}
-		}
-
-		// Generate and send DA 2062 Hand Receipt
-		if err := h.generateAndSendDA2062(c.Request.Context(), transfer, item); err != nil {
-			log.Printf("WARNING: Failed to generate/send DA 2062 for transfer %d: %v", transfer.ID, err)
-			// Don't fail the transfer - just log the error
-		}
+		log.Printf("Property %d and %d component(s) transferred from user %d to user %d", item.ID, len(plan.Components), transfer.FromUserID, transfer.ToUserID)
+		h.logTreeTransfer(transfer, plan, currentUserID)
 
 		// Log successful transfer completion
 		if err := h.Ledger.LogTransferEvent(*transfer, item.SerialNumber); err != nil {
 			log.Printf("WARNING: Failed to log transfer completion to ledger: %v", err)
 		}
+	} else if err := h.Repo.UpdateTransfer(transfer); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update transfer status: " + err.Error()})
+		return
 	}
 
 	// Log status updates (except for accepted status which is logged above with ownership change)
@@ -496,6 +488,10 @@ func (h *TransferHandler) RequestTransferBySerial(c *gin.Context) {
 		RequestDate:           time.Now(),
 	}
 
+	if !h.checkComponentHolders(c, transfer) {
+		return
+	}
+
 	if err := h.Repo.CreateTransfer(transfer); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transfer request"})
 		return
@@ -595,6 +591,10 @@ func (h *TransferHandler) OfferTransfer(c *gin.Context) {
 		RequestDate:       time.Now(),
 	}
 
+	if !h.checkComponentHolders(c, transfer) {
+		return
+	}
+
 	if err := h.Repo.CreateTransfer(transfer); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transfer offer"})
 		return
@@ -665,6 +665,10 @@ func (h *TransferHandler) RequestBySerial(c *gin.Context) {
 		Notes:                 input.Notes,
 	}
 
+	if !h.checkComponentHolders(c, transfer) {
+		return
+	}
+
 	if err := h.Repo.CreateTransfer(transfer); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transfer request"})
 		return
@@ -848,12 +852,15 @@ func (h *TransferHandler) AcceptOffer(c *gin.Context) {
 	}
 	*transfer.ResolvedDate = time.Now()
 
-	// Transaction: create transfer, update offer, update property ownership
-	err = h.Repo.CreateTransfer(transfer)
+	// Transaction: create transfer and move the property (and any components) to the recipient
+	plan, err := h.Repo.CompleteTransfer(transfer, nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transfer"})
+		respondTreeTransferError(c, err)
 		return
 	}
+	property := offer.Property
+	property.AssignedToUserID = &acceptingUserID
+	property.UpdatedAt = time.Now()
 
 	// Update offer status
 	offer.OfferStatus = domain.OfferStatusAccepted
@@ -866,25 +873,7 @@ func (h *TransferHandler) AcceptOffer(c *gin.Context) {
 		return
 	}
 
-	// Update property ownership
-	property := offer.Property
-	property.AssignedToUserID = &acceptingUserID
-	property.UpdatedAt = time.Now()
-
-	if err := h.Repo.UpdateProperty(property); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update property ownership"})
-		return
-	}
-
-	// If the transfer includes components, transfer them too
-	if transfer.IncludeComponents {
-		if err := h.ComponentService.TransferComponents(c.Request.Context(), transfer.PropertyID, transfer.FromUserID, transfer.ToUserID); err != nil {
-			log.Printf("WARNING: Failed to transfer components for property %d: %v", transfer.PropertyID, err)
-			// Note: We continue with the transfer even if component transfer fails
-		} else {
-			log.Printf("Successfully transferred components for property %d from user %d to user %d", transfer.PropertyID, transfer.FromUserID, transfer.ToUserID)
-		}
-	}
+	h.logTreeTransfer(transfer, plan, acceptingUserID)
 
 	// Log to ledger
 	if err := h.Ledger.LogTransferEvent(*transfer, property.SerialNumber); err != nil {
@@ -897,160 +886,46 @@ func (h *TransferHandler) AcceptOffer(c *gin.Context) {
 	})
 }
 
-func (h *TransferHandler) generateAndSendDA2062(ctx context.Context, transfer *domain.Transfer, property *domain.Property) error {
-	// Fetch user information for both FROM and TO users
-	fromUser, err := h.Repo.GetUserByID(transfer.FromUserID)
-	if err != nil {
-		return fmt.Errorf("failed to get FROM user: %w", err)
+// checkComponentHolders rejects a transfer up front when a component that would move
+// with the property is held by someone other than the sender. It writes the error
+// response and returns false if the transfer cannot go ahead.
+func (h *TransferHandler) checkComponentHolders(c *gin.Context, transfer *domain.Transfer) bool {
+	if !transfer.IncludeComponents {
+		return true
 	}
-
-	toUser, err := h.Repo.GetUserByID(transfer.ToUserID)
-	if err != nil {
-		return fmt.Errorf("failed to get TO user: %w", err)
+	if _, err := h.ComponentService.PlanTreeTransfer(c.Request.Context(), transfer.PropertyID, transfer.FromUserID, true); err != nil {
+		respondTreeTransferError(c, err)
+		return false
 	}
+	return true
+}
 
-	// Gather all properties to include in the DA 2062 (main item + components)
-	properties := []domain.Property{*property}
-
-	// If transfer includes components, get all attached components
-	if transfer.IncludeComponents {
-		components, err := h.ComponentService.GetPropertyComponents(ctx, transfer.PropertyID)
-		if err != nil {
-			log.Printf("WARNING: Failed to get components for property %d: %v", transfer.PropertyID, err)
-			// Continue without components rather than failing
-		} else {
-			// Add components to the properties list
-			for _, comp := range components {
-				if comp.ComponentProperty != nil {
-					properties = append(properties, *comp.ComponentProperty)
-				}
-			}
+// logTreeTransfer records the attachments that moved with the property and the ones
+// that had to be broken, keeping the ledger's view of the component hierarchy in step
+// with the database.
+func (h *TransferHandler) logTreeTransfer(transfer *domain.Transfer, plan *services.TreeTransferPlan, userID uint) {
+	for _, detachment := range plan.Detachments {
+		if err := h.Ledger.LogComponentDetached(detachment.ParentPropertyID, detachment.ComponentPropertyID, userID); err != nil {
+			log.Printf("WARNING: Failed to log component detachment (parent %d, component %d) to ledger: %v", detachment.ParentPropertyID, detachment.ComponentPropertyID, err)
 		}
 	}
 
-	// Prepare user info for PDF generation
-	fromInfo := documents.UserInfo{
-		Name:         fromUser.FirstName + " " + fromUser.LastName,
-		Rank:         fromUser.Rank,
-		Title:        fromUser.Unit, // Use unit as title
-		Phone:        fromUser.Phone,
-		SignatureURL: "",
-	}
-	if fromUser.SignatureURL != nil {
-		fromInfo.SignatureURL = *fromUser.SignatureURL
-	}
-
-	toInfo := documents.UserInfo{
-		Name:         toUser.FirstName + " " + toUser.LastName,
-		Rank:         toUser.Rank,
-		Title:        toUser.Unit, // Use unit as title
-		Phone:        toUser.Phone,
-		SignatureURL: "",
-	}
-	if toUser.SignatureURL != nil {
-		toInfo.SignatureURL = *toUser.SignatureURL
-	}
-
-	// Prepare unit info (using FROM user's unit)
-	unitInfo := documents.UnitInfo{
-		UnitName:    fromUser.Unit,
-		DODAAC:      "", // Could be stored in user profile if needed
-		StockNumber: "", // Could be property book number if available
-		Location:    "", // Could be stored in user profile if needed
-	}
-
-	// Generate PDF options
-	options := documents.GenerateOptions{
-		GroupByCategory:   false,
-		IncludeSignatures: true,
-		IncludeQRCodes:    false,
-	}
-
-	// Generate the DA 2062 PDF
-	pdfBuffer, err := h.PDFGenerator.GenerateDA2062(
-		properties,
-		fromInfo,
-		toInfo,
-		unitInfo,
-		options,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to generate DA 2062 PDF: %w", err)
-	}
-
-	// Generate form number
-	formNumber := fmt.Sprintf("HR-%s-%d", time.Now().Format("20060102"), transfer.ID)
-
-	// Upload PDF to storage once (shared by both documents)
-	fileKey := fmt.Sprintf("da2062/transfer_%d.pdf", transfer.ID)
-	err = h.StorageService.UploadFile(ctx, fileKey, bytes.NewReader(pdfBuffer.Bytes()), int64(pdfBuffer.Len()), "application/pdf")
-	if err != nil {
-		log.Printf("WARNING: Failed to upload PDF to storage: %v", err)
-		return fmt.Errorf("failed to upload DA 2062 PDF: %w", err)
-	}
-
-	// Get presigned URL for access
-	fileURL, err := h.StorageService.GetPresignedURL(ctx, fileKey, 7*24*time.Hour) // 7 days
-	if err != nil {
-		log.Printf("WARNING: Failed to get presigned URL for DA 2062: %v", err)
-		fileURL = "" // Continue without URL
-	}
-
-	// Create in-app documents for BOTH sender and recipient
-	// This gives both parties immediate access to the hand receipt in their inbox
-
-	// Create document for recipient (TO user)
-	if err := h.createInAppDocumentRecord(ctx, transfer, property, formNumber, fileURL, transfer.ToUserID, "received"); err != nil {
-		log.Printf("WARNING: Failed to create in-app document for recipient: %v", err)
-	}
-
-	// Create document for sender (FROM user)
-	if err := h.createInAppDocumentRecord(ctx, transfer, property, formNumber, fileURL, transfer.FromUserID, "sent"); err != nil {
-		log.Printf("WARNING: Failed to create in-app document for sender: %v", err)
-	}
-
-	// Log DA 2062 export to ledger
-	if err := h.Ledger.LogDA2062Export(transfer.ToUserID, len(properties), "email_and_app", toUser.Email); err != nil {
-		log.Printf("WARNING: Failed to log DA 2062 export to ledger: %v", err)
+	if len(plan.Components) > 0 {
+		if err := h.Ledger.LogComponentTreeTransfer(transfer.ID, plan.RootPropertyID, plan.Components, transfer.FromUserID, transfer.ToUserID); err != nil {
+			log.Printf("WARNING: Failed to log component tree transfer for transfer %d to ledger: %v", transfer.ID, err)
+		}
 	}
-
-	log.Printf("Successfully generated and sent DA 2062 for transfer %d (%d items)", transfer.ID, len(properties))
-	return nil
 }
 
-func (h *TransferHandler) createInAppDocumentRecord(ctx context.Context, transfer *domain.Transfer, property *domain.Property, formNumber string, fileURL string, recipientUserID uint, documentType string) error {
-
-	// Create document record with appropriate title based on document type
-	var title string
-	if documentType == "received" {
-		title = fmt.Sprintf("Hand Receipt Received - %s (SN:%s)", property.Name, property.SerialNumber)
-	} else {
-		title = fmt.Sprintf("Hand Receipt Sent - %s (SN:%s)", property.Name, property.SerialNumber)
-	}
-
-	doc := &domain.Document{
-		Type:            domain.DocumentTypeTransferForm,
-		Subtype:         stringPtr("DA2062"),
-		Title:           title,
-		SenderUserID:    transfer.FromUserID,
-		RecipientUserID: recipientUserID,
-		PropertyID:      &property.ID,
-		Status:          domain.DocumentStatusUnread,
-		SentAt:          time.Now(),
-		FormData:        "{}",
-		Attachments:     domain.JSONStringArray{}, // Initialize as empty JSONStringArray
+// respondTreeTransferError maps component tree transfer failures to HTTP responses
+func respondTreeTransferError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrPropertyHeldByOtherUser):
+		c.JSON(http.StatusConflict, gin.H{"error": "Transfer blocked: " + err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Property or component not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer property: " + err.Error()})
 	}
-
-	// Add attachment URL if available
-	if fileURL != "" {
-		doc.Attachments = domain.JSONStringArray{fileURL}
-	}
-
-	if err := h.Repo.CreateDocument(doc); err != nil {
-		return fmt.Errorf("failed to create document record: %w", err)
-	}
-
-	log.Printf("Created in-app document %d (%s) for transfer %d, recipient %d", doc.ID, documentType, transfer.ID, recipientUserID)
-	return nil
 }
 
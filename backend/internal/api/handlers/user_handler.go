@@ -361,6 +361,13 @@ func (h *UserHandler) UpdateUserProfile(c *gin.Context) {
 	if updateReq.Unit != nil {
 		user.Unit = *updateReq.Unit
 	}
+	if updateReq.SupervisorID != nil {
+		if err := h.checkSupervisor(user.ID, *updateReq.SupervisorID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		user.SupervisorID = updateReq.SupervisorID
+	}
 
 	// Save changes to database
 	if err := h.repo.UpdateUser(user); err != nil {
@@ -375,13 +382,14 @@ func (h *UserHandler) UpdateUserProfile(c *gin.Context) {
 	response := models.UserDTO{
 		ID: user.ID,
 		// Username:  user.Username, // REMOVED: Username field
-		Email:     user.Email,
-		FirstName: firstName,
-		LastName:  lastName,
-		Rank:      user.Rank,
-		Unit:      user.Unit,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		Email:        user.Email,
+		FirstName:    firstName,
+		LastName:     lastName,
+		Rank:         user.Rank,
+		Unit:         user.Unit,
+		SupervisorID: user.SupervisorID,
+		CreatedAt:    user.CreatedAt,
+		UpdatedAt:    user.UpdatedAt,
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -390,6 +398,30 @@ func (h *UserHandler) UpdateUserProfile(c *gin.Context) {
 	})
 }
 
+// checkSupervisor makes sure supervisorID exists and is not the user or anyone
+// below them, which would make the chain of command a loop
+func (h *UserHandler) checkSupervisor(userID, supervisorID uint) error {
+	visited := make(map[uint]bool)
+	for id := supervisorID; ; {
+		if id == userID {
+			return fmt.Errorf("supervisor cannot be yourself or someone you supervise")
+		}
+		if visited[id] {
+			return nil
+		}
+		visited[id] = true
+
+		supervisor, err := h.repo.GetUserByID(id)
+		if err != nil || supervisor == nil {
+			return fmt.Errorf("supervisor not found")
+		}
+		if supervisor.SupervisorID == nil {
+			return nil
+		}
+		id = *supervisor.SupervisorID
+	}
+}
+
 // ChangePassword godoc
 // @Summary Change user password
 // @Description Change the authenticated user's password
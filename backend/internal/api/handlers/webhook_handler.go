@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/toole-brendan/handreceipt-go/internal/services/webhook"
+	"gorm.io/gorm"
+)
+
+// WebhookHandler manages outbound webhook subscriptions and their delivery logs
+type WebhookHandler struct {
+	Service *webhook.Service
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(service *webhook.Service) *WebhookHandler {
+	return &WebhookHandler{Service: service}
+}
+
+// ListEventTypes returns the event types a subscription can choose from
+func (h *WebhookHandler) ListEventTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"eventTypes": webhook.EventTypes})
+}
+
+// CreateSubscription registers a URL to receive events for the caller's unit. The
+// signing secret is only returned here and when it is replaced.
+func (h *WebhookHandler) CreateSubscription(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input webhook.SubscriptionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	subscription, secret, err := h.Service.CreateSubscription(userID, input)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"subscription": subscription,
+		"secret":       secret,
+	})
+}
+
+// ListSubscriptions returns the caller's subscriptions
+func (h *WebhookHandler) ListSubscriptions(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	subscriptions, err := h.Service.ListSubscriptions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhook subscriptions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"subscriptions": subscriptions})
+}
+
+// GetSubscription returns one of the caller's subscriptions
+func (h *WebhookHandler) GetSubscription(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	subscriptionID, ok := webhookIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	subscription, err := h.Service.GetSubscription(userID, subscriptionID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"subscription": subscription})
+}
+
+// UpdateSubscription changes a subscription's URL, event types, secret or state
+func (h *WebhookHandler) UpdateSubscription(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	subscriptionID, ok := webhookIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var input webhook.SubscriptionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+		return
+	}
+
+	subscription, secret, err := h.Service.UpdateSubscription(userID, subscriptionID, input)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	response := gin.H{"subscription": subscription}
+	if secret != "" {
+		response["secret"] = secret
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteSubscription removes a subscription and its delivery log
+func (h *WebhookHandler) DeleteSubscription(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	subscriptionID, ok := webhookIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.Service.DeleteSubscription(userID, subscriptionID); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook subscription deleted"})
+}
+
+// ListDeliveries returns a subscription's delivery log, optionally filtered by status
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	subscriptionID, ok := webhookIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	deliveries, err := h.Service.ListDeliveries(userID, subscriptionID, c.Query("status"), limit, offset)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// Redeliver queues a dead-lettered delivery again
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	subscriptionID, ok := webhookIDParam(c, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := webhookIDParam(c, "deliveryId")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.Service.Redeliver(userID, subscriptionID, deliveryID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"delivery": delivery})
+}
+
+// Ping queues a test event for a subscription
+func (h *WebhookHandler) Ping(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	subscriptionID, ok := webhookIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.Service.SendPing(userID, subscriptionID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"delivery": delivery})
+}
+
+func webhookIDParam(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondWebhookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook subscription or delivery not found"})
+	case errors.Is(err, webhook.ErrNotSubscriptionOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, webhook.ErrInvalidURL),
+		errors.Is(err, webhook.ErrInsecureURL),
+		errors.Is(err, webhook.ErrBlockedAddress),
+		errors.Is(err, webhook.ErrUnknownEventType),
+		errors.Is(err, webhook.ErrNoUnit):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, webhook.ErrDeliveryNotDead):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Webhook request failed: " + err.Error()})
+	}
+}
@@ -1,74 +1,192 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/toole-brendan/handreceipt-go/internal/api/middleware"
+	"github.com/toole-brendan/handreceipt-go/internal/services/auth"
 	"github.com/toole-brendan/handreceipt-go/internal/services/notification"
 )
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		// Configure this based on your CORS requirements
-		return true
-	},
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-}
-
 type WebSocketHandler struct {
-	hub *notification.Hub
+	hub      *notification.Hub
+	sessions *auth.SessionStore
+	upgrader websocket.Upgrader
 }
 
-func NewWebSocketHandler(hub *notification.Hub) *WebSocketHandler {
+func NewWebSocketHandler(hub *notification.Hub, sessions *auth.SessionStore) *WebSocketHandler {
 	return &WebSocketHandler{
-		hub: hub,
+		hub:      hub,
+		sessions: sessions,
+		upgrader: websocket.Upgrader{
+			CheckOrigin:     originChecker(middleware.AllowedOrigins()),
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+		},
 	}
 }
 
-func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
-	// Try to authenticate from JWT token first (from query param for WebSocket)
-	var userID int
-	token := c.Query("token")
-	
-	if token != "" {
-		// Validate JWT token
-		claims, err := middleware.ValidateToken(token)
-		if err == nil {
-			userID = int(claims.UserID)
+// wsCredential is who a WebSocket connection is opened as
+type wsCredential struct {
+	userID    int
+	sessionID string    // Sign-in session, checked for revocation while the connection is open
+	expiresAt time.Time // Zero when the credential does not expire on its own
+}
+
+// IssueTicket returns a single-use ticket for opening a WebSocket connection.
+// Browsers cannot set headers on the handshake, so the ticket goes in the URL in
+// place of the access token; it is worthless once used or after a few seconds.
+func (h *WebSocketHandler) IssueTicket(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var credentialExpiresAt *time.Time
+	if expiresAt, ok := c.Get("authExpiresAt"); ok {
+		if t, ok := expiresAt.(time.Time); ok {
+			credentialExpiresAt = &t
 		}
 	}
-	
-	// Fall back to session authentication if no JWT
-	if userID == 0 {
-		session := sessions.Default(c)
-		userIDInterface := session.Get("userID")
-		if userIDInterface == nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+
+	ticket, expiresAt, err := h.sessions.IssueWebSocketTicket(userID, c.GetString("sessionID"), credentialExpiresAt)
+	if err != nil {
+		if errors.Is(err, auth.ErrSessionRevoked) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
 			return
 		}
+		log.Printf("Failed to issue WebSocket ticket for user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue WebSocket ticket"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ticket":    ticket,
+		"expiresAt": expiresAt,
+	})
+}
 
-		// Convert userID to int
-		switch v := userIDInterface.(type) {
+// authenticate identifies the user opening a connection from, in order, a ticket,
+// a bearer token (native apps, which can set headers) or the session cookie
+func (h *WebSocketHandler) authenticate(c *gin.Context) (wsCredential, bool) {
+	if ticket := c.Query("ticket"); ticket != "" {
+		record, err := h.sessions.RedeemWebSocketTicket(ticket)
+		if err != nil {
+			if !errors.Is(err, auth.ErrInvalidTicket) && !errors.Is(err, auth.ErrSessionRevoked) {
+				log.Printf("Failed to redeem WebSocket ticket: %v", err)
+			}
+			return wsCredential{}, false
+		}
+		credential := wsCredential{userID: int(record.UserID), sessionID: record.SessionID}
+		if record.CredentialExpiresAt != nil {
+			credential.expiresAt = *record.CredentialExpiresAt
+		}
+		return credential, true
+	}
+
+	var credential wsCredential
+	if header := c.GetHeader("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		claims, err := middleware.ValidateToken(strings.TrimPrefix(header, "Bearer "))
+		if err != nil {
+			return wsCredential{}, false
+		}
+		credential = wsCredential{userID: int(claims.UserID), sessionID: claims.SessionID}
+		if claims.ExpiresAt != nil {
+			credential.expiresAt = claims.ExpiresAt.Time
+		}
+	} else {
+		session := sessions.Default(c)
+		switch v := session.Get("userID").(type) {
 		case int:
-			userID = v
+			credential.userID = v
 		case uint:
-			userID = int(v)
+			credential.userID = int(v)
 		case float64:
-			userID = int(v)
+			credential.userID = int(v)
 		default:
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID type"})
-			return
+			return wsCredential{}, false
+		}
+		credential.sessionID, _ = session.Get("sessionID").(string)
+	}
+
+	if credential.sessionID != "" {
+		revoked, err := h.sessions.IsSessionRevoked(credential.sessionID)
+		if err != nil {
+			log.Printf("Failed to check session for WebSocket connection: %v", err)
+			return wsCredential{}, false
+		}
+		if revoked {
+			return wsCredential{}, false
 		}
 	}
+	return credential, true
+}
+
+// originChecker accepts handshakes from the configured CORS origins and from the
+// API's own host. A handshake without an Origin header does not come from a browser
+// page, so it is left to authentication alone.
+func originChecker(allowedOrigins []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		for _, allowed := range allowedOrigins {
+			if allowed == "*" || strings.EqualFold(origin, allowed) {
+				return true
+			}
+		}
+		if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+		log.Printf("WARNING: Rejected WebSocket connection from origin %s", origin)
+		return false
+	}
+}
+
+func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
+	// Access tokens in the URL end up in proxy and access logs
+	if c.Query("token") != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tokens are not accepted in the URL; connect with a ticket from POST /api/ws/ticket"})
+		return
+	}
+
+	credential, ok := h.authenticate(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	userID := credential.userID
+
+	// Each connection gets its own session; the device ID lets a reconnecting app
+	// replace its previous connection instead of leaving a stale one behind
+	sessionID := uuid.New().String()
+	deviceID := c.Query("deviceId")
+	if deviceID == "" {
+		deviceID = c.GetHeader("X-Device-ID")
+	}
+	if deviceID == "" {
+		deviceID = sessionID
+	}
+
+	if h.hub.AtConnectionLimit(userID, deviceID) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many open connections"})
+		return
+	}
 
 	// Upgrade the connection
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
 		return
@@ -76,10 +194,35 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 
 	// Create client and register with hub
 	client := &notification.Client{
-		UserID: userID,
-		Conn:   conn,
-		Send:   make(chan []byte, 256),
-		Hub:    h.hub,
+		UserID:      userID,
+		DeviceID:    deviceID,
+		SessionID:   sessionID,
+		ConnectedAt: time.Now().UTC(),
+		AuthSession: credential.sessionID,
+		AuthExpiry:  credential.expiresAt,
+		Conn:        conn,
+		Send:        make(chan []byte, 256),
+		Hub:         h.hub,
+	}
+
+	// A reconnecting client sends the last sequence number it saw; everything after it
+	// is replayed before live events resume. New clients start live.
+	if raw := c.Query("lastSeq"); raw != "" {
+		lastSeq, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || lastSeq < 0 {
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "invalid lastSeq"))
+			conn.Close()
+			return
+		}
+
+		go client.WritePump()
+		if err := h.hub.Resume(client, lastSeq); err != nil {
+			log.Printf("WebSocket replay failed for user %d: %v", userID, err)
+			h.hub.UnregisterClient(client)
+			return
+		}
+		go client.ReadPump()
+		return
 	}
 
 	h.hub.RegisterClient(client)
@@ -89,6 +232,23 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 	go client.ReadPump()
 }
 
+// GetPresence reports which devices the caller is connected from. Other users'
+// device and session details are never exposed.
+func (h *WebSocketHandler) GetPresence(c *gin.Context) {
+	callerID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	userID := int(callerID)
+	devices := h.hub.GetUserDevices(userID)
+	c.JSON(http.StatusOK, gin.H{
+		"userId":  userID,
+		"online":  len(devices) > 0,
+		"devices": devices,
+	})
+}
+
 // Client connection manager
 type ConnectionManager struct {
 	clients    map[int]*notification.Client
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/toole-brendan/handreceipt-go/internal/repository"
+)
+
+// AdminEmails returns the addresses of users allowed into operator views, taken
+// from security.admin_emails, overridden by the ADMIN_EMAILS environment variable
+func AdminEmails() []string {
+	emails := viper.GetStringSlice("security.admin_emails")
+	if envEmails := os.Getenv("ADMIN_EMAILS"); envEmails != "" {
+		emails = strings.Split(envEmails, ",")
+	}
+
+	var admins []string
+	for _, email := range emails {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			admins = append(admins, email)
+		}
+	}
+	return admins
+}
+
+// IsAdminEmail reports whether email belongs to one of the AdminEmails
+func IsAdminEmail(email string) bool {
+	email = strings.ToLower(strings.TrimSpace(email))
+	for _, admin := range AdminEmails() {
+		if admin == email {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireAdmin only lets through authenticated users whose email is in
+// adminEmails. It must run after SessionAuthMiddleware.
+func RequireAdmin(repo repository.Repository, adminEmails []string) gin.HandlerFunc {
+	admins := make(map[string]bool, len(adminEmails))
+	for _, email := range adminEmails {
+		admins[strings.ToLower(email)] = true
+	}
+
+	return func(c *gin.Context) {
+		userID, ok := c.Get("userID")
+		id, isUint := userID.(uint)
+		if !ok || !isUint {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+
+		user, err := repo.GetUserByID(id)
+		if err != nil || !admins[strings.ToLower(user.Email)] {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Administrator access required"})
+			return
+		}
+		c.Next()
+	}
+}
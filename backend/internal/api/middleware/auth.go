@@ -77,6 +77,10 @@ func SessionAuthMiddleware() gin.HandlerFunc {
 					// Set user ID from JWT claims
 					fmt.Printf("[Auth Middleware] JWT validation successful, userID: %d\n", claims.UserID)
 					c.Set("userID", claims.UserID)
+					c.Set("sessionID", claims.SessionID)
+					if claims.ExpiresAt != nil {
+						c.Set("authExpiresAt", claims.ExpiresAt.Time)
+					}
 					c.Next()
 					return
 				} else {
@@ -108,6 +112,9 @@ func SessionAuthMiddleware() gin.HandlerFunc {
 
 		// Set user ID in context
 		c.Set("userID", userID)
+		if sessionID, ok := session.Get("sessionID").(string); ok {
+			c.Set("sessionID", sessionID)
+		}
 		c.Next()
 	}
 }
@@ -146,7 +153,8 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 
 // Claims represents the JWT claims
 type Claims struct {
-	UserID uint `json:"user_id"`
+	UserID    uint   `json:"user_id"`
+	SessionID string `json:"session_id,omitempty"` // Set on tokens issued at login
 	jwt.RegisteredClaims
 }
 
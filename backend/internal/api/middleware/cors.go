@@ -11,34 +11,7 @@ import (
 
 // CORSMiddleware returns a configured CORS middleware handler
 func CORSMiddleware() gin.HandlerFunc {
-	// Get allowed origins from config
-	var allowedOrigins []string
-
-	// First try to get from config file
-	configOrigins := viper.GetStringSlice("security.cors_allowed_origins")
-	if len(configOrigins) > 0 {
-		allowedOrigins = configOrigins
-		log.Printf("Using CORS origins from config: %v", allowedOrigins)
-	}
-
-	// Override with environment variable if set
-	if envOrigins := os.Getenv("CORS_ORIGINS"); envOrigins != "" {
-		allowedOrigins = strings.Split(envOrigins, ",")
-		for i := range allowedOrigins {
-			allowedOrigins[i] = strings.TrimSpace(allowedOrigins[i])
-		}
-		log.Printf("Using CORS origins from environment: %v", allowedOrigins)
-	}
-
-	// If still empty, use defaults
-	if len(allowedOrigins) == 0 {
-		allowedOrigins = []string{
-			"capacitor://localhost", // For iOS app
-			"http://localhost:3000", // For local development
-			"http://localhost:5173", // For Vite development server
-		}
-		log.Printf("Using default CORS origins: %v", allowedOrigins)
-	}
+	allowedOrigins := AllowedOrigins()
 
 	return func(c *gin.Context) {
 		origin := c.Request.Header.Get("Origin")
@@ -76,6 +49,41 @@ func CORSMiddleware() gin.HandlerFunc {
 	}
 }
 
+// AllowedOrigins returns the origins browsers may call the API from, taken from
+// security.cors_allowed_origins, overridden by the CORS_ORIGINS environment variable
+func AllowedOrigins() []string {
+	// Get allowed origins from config
+	var allowedOrigins []string
+
+	// First try to get from config file
+	configOrigins := viper.GetStringSlice("security.cors_allowed_origins")
+	if len(configOrigins) > 0 {
+		allowedOrigins = configOrigins
+		log.Printf("Using CORS origins from config: %v", allowedOrigins)
+	}
+
+	// Override with environment variable if set
+	if envOrigins := os.Getenv("CORS_ORIGINS"); envOrigins != "" {
+		allowedOrigins = strings.Split(envOrigins, ",")
+		for i := range allowedOrigins {
+			allowedOrigins[i] = strings.TrimSpace(allowedOrigins[i])
+		}
+		log.Printf("Using CORS origins from environment: %v", allowedOrigins)
+	}
+
+	// If still empty, use defaults
+	if len(allowedOrigins) == 0 {
+		allowedOrigins = []string{
+			"capacitor://localhost", // For iOS app
+			"http://localhost:3000", // For local development
+			"http://localhost:5173", // For Vite development server
+		}
+		log.Printf("Using default CORS origins: %v", allowedOrigins)
+	}
+
+	return allowedOrigins
+}
+
 // SimpleCORSMiddleware provides a simple CORS implementation without external dependencies
 func SimpleCORSMiddleware() gin.HandlerFunc {
 	// Get allowed origins from environment or use defaults
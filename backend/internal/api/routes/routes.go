@@ -5,21 +5,27 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 	"gorm.io/gorm"
 	"github.com/toole-brendan/handreceipt-go/internal/api/handlers"
 	"github.com/toole-brendan/handreceipt-go/internal/api/middleware"
 	"github.com/toole-brendan/handreceipt-go/internal/ledger"
 	"github.com/toole-brendan/handreceipt-go/internal/repository"
 	"github.com/toole-brendan/handreceipt-go/internal/services"
+	"github.com/toole-brendan/handreceipt-go/internal/services/auth"
 	"github.com/toole-brendan/handreceipt-go/internal/services/email"
+	"github.com/toole-brendan/handreceipt-go/internal/services/flipl"
+	"github.com/toole-brendan/handreceipt-go/internal/services/inventory"
+	"github.com/toole-brendan/handreceipt-go/internal/services/jobs"
 	"github.com/toole-brendan/handreceipt-go/internal/services/nsn"
 	"github.com/toole-brendan/handreceipt-go/internal/services/notification"
 	"github.com/toole-brendan/handreceipt-go/internal/services/documents"
 	"github.com/toole-brendan/handreceipt-go/internal/services/storage"
+	"github.com/toole-brendan/handreceipt-go/internal/services/webhook"
 )
 
 // SetupRoutes configures all the API routes for the application
-func SetupRoutes(router *gin.Engine, ledgerService ledger.LedgerService, repo repository.Repository, storageService storage.StorageService, nsnService *nsn.NSNService, notificationHub *notification.Hub) {
+func SetupRoutes(router *gin.Engine, ledgerService ledger.LedgerService, repo repository.Repository, storageService storage.StorageService, nsnService *nsn.NSNService, notificationHub *notification.Hub, pushSender *notification.PushSender, mailer email.EmailService) {
 	// Health check endpoint (no authentication required)
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -34,18 +40,27 @@ func SetupRoutes(router *gin.Engine, ledgerService ledger.LedgerService, repo re
 
 	// Create notification service with database support
 	notificationService := notification.NewDBService(notificationHub, repo.DB().(*gorm.DB))
+	if pushSender != nil {
+		notificationService.SetPushSender(pushSender)
+	}
+	if mailer != nil {
+		notificationService.SetEmailSender(mailer)
+	}
 
 	// Add component service first (needed by transfer handler)
 	componentService := services.NewComponentService(repo)
 
-	// Create PDF and email services for DA2062 functionality
+	// Create the PDF generator for DA2062 functionality
 	pdfGenerator := documents.NewDA2062Generator(repo)
-	emailService := &email.DA2062EmailService{} // TODO: Initialize with proper email service
+
+	// Long-running work is queued for the background worker
+	jobQueue := jobs.NewQueue(repo.DB().(*gorm.DB))
 
 	// Create handlers
-	authHandler := handlers.NewAuthHandler(repo)
+	sessionStore := auth.NewSessionStore(repo.DB().(*gorm.DB))
+	authHandler := handlers.NewAuthHandler(repo, sessionStore)
 	propertyHandler := handlers.NewPropertyHandler(ledgerService, repo)
-	transferHandler := handlers.NewTransferHandler(ledgerService, repo, componentService, pdfGenerator, emailService, storageService, notificationService)
+	transferHandler := handlers.NewTransferHandler(ledgerService, repo, componentService, jobQueue, notificationService)
 	activityHandler := handlers.NewActivityHandler() // No ledger needed
 	verificationHandler := handlers.NewVerificationHandler(ledgerService)
 	correctionHandler := handlers.NewCorrectionHandler(ledgerService)
@@ -55,19 +70,20 @@ func SetupRoutes(router *gin.Engine, ledgerService ledger.LedgerService, repo re
 	photoHandler := handlers.NewPhotoHandler(storageService, repo, ledgerService) // Add photo handler
 
 	// Create DA2062 handler without OCR service
-	da2062Handler := handlers.NewDA2062Handler(ledgerService, repo, pdfGenerator, emailService, storageService)
+	da2062ImportService := inventory.NewDA2062ImportService(repo.DB().(*gorm.DB), storageService, jobQueue, nil, nil, nil, notificationHub)
+	da2062Handler := handlers.NewDA2062Handler(ledgerService, repo, pdfGenerator, jobQueue, storageService, da2062ImportService)
 
 	// Add component handler
 	componentHandler := handlers.NewComponentHandler(componentService, ledgerService)
 
 	// Add document handler for maintenance forms
-	documentHandler := handlers.NewDocumentHandler(repo, ledgerService, emailService, storageService)
+	documentHandler := handlers.NewDocumentHandler(repo, ledgerService, jobQueue, storageService)
 	
 	// Add offline sync handler
 	offlineSyncHandler := handlers.NewOfflineSyncHandler(repo.DB().(*gorm.DB))
 	
 	// Add DA2062 imports handler
-	da2062ImportsHandler := handlers.NewDA2062ImportsHandler(repo.DB().(*gorm.DB))
+	da2062ReviewService := inventory.NewDA2062ReviewService(repo.DB().(*gorm.DB), storageService, ledgerService, notificationHub)
 	
 	// Add component events handler
 	componentEventsHandler := handlers.NewComponentEventsHandler(repo.DB().(*gorm.DB))
@@ -80,11 +96,32 @@ func SetupRoutes(router *gin.Engine, ledgerService ledger.LedgerService, repo re
 	nsnHandler := handlers.NewNSNHandler(nsnService, logger)
 	
 	// Add WebSocket handler
-	webSocketHandler := handlers.NewWebSocketHandler(notificationHub)
+	webSocketHandler := handlers.NewWebSocketHandler(notificationHub, sessionStore)
 	
 	// Add notification handler
 	notificationHandler := handlers.NewNotificationHandlers(notificationService)
 
+	// Add change of hand receipt holder handler
+	handReceiptChangeService := inventory.NewHandReceiptChangeService(repo.DB().(*gorm.DB), repo, ledgerService, pdfGenerator, storageService)
+	handReceiptChangeHandler := handlers.NewHandReceiptChangeHandler(handReceiptChangeService)
+	inventoryCampaignService := inventory.NewCampaignService(repo.DB().(*gorm.DB), repo, ledgerService, pdfGenerator, storageService)
+	inventoryCampaignHandler := handlers.NewInventoryCampaignHandler(inventoryCampaignService)
+	lossCaseService := flipl.NewLossCaseService(repo.DB().(*gorm.DB), repo, ledgerService, pdfGenerator, storageService)
+	lossCaseHandler := handlers.NewLossCaseHandler(lossCaseService)
+	da2062ReconcileService := inventory.NewDA2062ReconcileService(repo.DB().(*gorm.DB), repo, ledgerService, inventoryCampaignService, lossCaseService, notificationHub)
+	da2062LearningService := inventory.NewDA2062LearningService(repo.DB().(*gorm.DB))
+	da2062ImportsHandler := handlers.NewDA2062ImportsHandler(repo.DB().(*gorm.DB), da2062ReviewService, da2062ReconcileService, da2062LearningService, notificationHub)
+
+	// Queue webhooks from the notification service and the ledger
+	requireHTTPS := viper.GetString("server.environment") == "production" && !viper.GetBool("webhooks.allow_http")
+	webhookService := webhook.NewService(repo.DB().(*gorm.DB), jobQueue, viper.GetInt("webhooks.max_attempts"), requireHTTPS)
+	notificationService.SetEventListener(webhookService.PublishNotificationEvent)
+	if postgresLedger, ok := ledgerService.(*ledger.PostgresLedgerService); ok {
+		postgresLedger.AddAppendListener(webhookService.PublishLedgerEntry)
+	}
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	jobHandler := handlers.NewJobHandler(jobQueue)
+
 	// TODO: Update other handlers to use repository when needed
 
 	// Public routes (no authentication required)
@@ -99,6 +136,9 @@ func SetupRoutes(router *gin.Engine, ledgerService ledger.LedgerService, repo re
 			auth.POST("/logout", authHandler.Logout)                                        // Added logout route
 			auth.GET("/me", middleware.SessionAuthMiddleware(), authHandler.GetCurrentUser) // Use SessionAuthMiddleware
 		}
+
+		// WebSocket route; authenticates itself with a ticket, bearer token or session
+		public.GET("/ws", webSocketHandler.HandleWebSocket)
 	}
 
 	// Protected routes (authentication required)
@@ -106,8 +146,9 @@ func SetupRoutes(router *gin.Engine, ledgerService ledger.LedgerService, repo re
 	protected := router.Group("/api")
 	protected.Use(middleware.SessionAuthMiddleware())
 	{
-		// WebSocket route
-		protected.GET("/ws", webSocketHandler.HandleWebSocket)
+		// WebSocket tickets and presence
+		protected.POST("/ws/ticket", webSocketHandler.IssueTicket)
+		protected.GET("/ws/presence", webSocketHandler.GetPresence)
 		
 		// Current user route can now be removed as it's handled above
 
@@ -142,6 +183,11 @@ func SetupRoutes(router *gin.Engine, ledgerService ledger.LedgerService, repo re
 			transfer.GET("", transferHandler.GetAllTransfers)
 			transfer.GET("/:id", transferHandler.GetTransferByID)
 			transfer.GET("/user/:userId", transferHandler.GetTransfersByUser)
+			transfer.POST("/:id/amend", transferHandler.AmendTransfer)
+			transfer.GET("/:id/revisions", transferHandler.GetTransferRevisions)
+			transfer.GET("/:id/comments", transferHandler.GetTransferComments)
+			transfer.POST("/:id/comments", transferHandler.AddTransferComment)
+			transfer.POST("/:id/approvers", transferHandler.AddTransferApprover)
 
 			// New routes for serial number and offer functionality
 			transfer.POST("/request-by-serial", transferHandler.RequestBySerial)
@@ -151,6 +197,68 @@ func SetupRoutes(router *gin.Engine, ledgerService ledger.LedgerService, repo re
 
 		}
 
+		// Change of hand receipt holder (joint inventory) routes
+		handReceiptChanges := protected.Group("/hand-receipt-changes")
+		{
+			handReceiptChanges.POST("", handReceiptChangeHandler.StartChange)
+			handReceiptChanges.GET("", handReceiptChangeHandler.ListChanges)
+			handReceiptChanges.GET("/:id", handReceiptChangeHandler.GetChange)
+			handReceiptChanges.PATCH("/:id/items/:itemId", handReceiptChangeHandler.MarkItem)
+			handReceiptChanges.GET("/:id/shortage-annex", handReceiptChangeHandler.GetShortageAnnex)
+			handReceiptChanges.POST("/:id/complete", handReceiptChangeHandler.CompleteChange)
+			handReceiptChanges.POST("/:id/cancel", handReceiptChangeHandler.CancelChange)
+		}
+
+		// Cyclic and sensitive-item inventory campaign routes
+		inventoryCampaigns := protected.Group("/inventory-campaigns")
+		{
+			inventoryCampaigns.POST("", inventoryCampaignHandler.CreateCampaign)
+			inventoryCampaigns.GET("", inventoryCampaignHandler.ListCampaigns)
+			inventoryCampaigns.GET("/:id", inventoryCampaignHandler.GetCampaign)
+			inventoryCampaigns.POST("/:id/counters", inventoryCampaignHandler.AssignCounters)
+			inventoryCampaigns.PATCH("/:id/items/:itemId", inventoryCampaignHandler.RecordCount)
+			inventoryCampaigns.POST("/:id/close", inventoryCampaignHandler.CloseCampaign)
+			inventoryCampaigns.POST("/:id/cancel", inventoryCampaignHandler.CancelCampaign)
+		}
+
+		// Loss, damage and destruction (FLIPL) case routes
+		lossCases := protected.Group("/loss-cases")
+		{
+			lossCases.POST("", lossCaseHandler.OpenCase)
+			lossCases.GET("", lossCaseHandler.ListCases)
+			lossCases.GET("/:id", lossCaseHandler.GetCase)
+			lossCases.POST("/:id/investigator", lossCaseHandler.AssignInvestigator)
+			lossCases.POST("/:id/findings", lossCaseHandler.SubmitFindings)
+			lossCases.POST("/:id/decision", lossCaseHandler.DecideCase)
+			lossCases.POST("/:id/withdraw", lossCaseHandler.WithdrawCase)
+			lossCases.GET("/:id/dd200", lossCaseHandler.GetDD200)
+		}
+
+		// Outbound webhook routes. A subscription receives every event for its unit,
+		// so only operators may manage them.
+		webhooks := protected.Group("/webhooks", middleware.RequireAdmin(repo, middleware.AdminEmails()))
+		{
+			webhooks.GET("/event-types", webhookHandler.ListEventTypes)
+			webhooks.POST("", webhookHandler.CreateSubscription)
+			webhooks.GET("", webhookHandler.ListSubscriptions)
+			webhooks.GET("/:id", webhookHandler.GetSubscription)
+			webhooks.PUT("/:id", webhookHandler.UpdateSubscription)
+			webhooks.DELETE("/:id", webhookHandler.DeleteSubscription)
+			webhooks.POST("/:id/ping", webhookHandler.Ping)
+			webhooks.GET("/:id/deliveries", webhookHandler.ListDeliveries)
+			webhooks.POST("/:id/deliveries/:deliveryId/redeliver", webhookHandler.Redeliver)
+		}
+
+		// Background job queue (operators only)
+		adminJobs := protected.Group("/admin/jobs", middleware.RequireAdmin(repo, middleware.AdminEmails()))
+		{
+			adminJobs.GET("", jobHandler.ListJobs)
+			adminJobs.GET("/stats", jobHandler.Stats)
+			adminJobs.GET("/:id", jobHandler.GetJob)
+			adminJobs.POST("/:id/retry", jobHandler.RetryJob)
+			adminJobs.POST("/:id/cancel", jobHandler.CancelJob)
+		}
+
 		// Activity routes
 		activity := protected.Group("/activities")
 		{
@@ -258,9 +366,21 @@ func SetupRoutes(router *gin.Engine, ledgerService ledger.LedgerService, repo re
 			da2062Imports.GET("/:id/items", da2062ImportsHandler.GetImportItems)
 			da2062Imports.POST("", da2062ImportsHandler.CreateImport)
 			da2062Imports.PATCH("/:id", da2062ImportsHandler.UpdateImportStatus)
-			da2062Imports.POST("/:id/items", da2062ImportsHandler.AddImportItem)
-			da2062Imports.PATCH("/items/:itemId", da2062ImportsHandler.UpdateImportItem)
 			da2062Imports.DELETE("/:id", da2062ImportsHandler.DeleteImport)
+
+			// Review of staged lines before they become property
+			da2062Imports.GET("/:id/items/:itemId/image", da2062ImportsHandler.GetItemImage)
+			da2062Imports.GET("/:id/items/:itemId/fields/:field/image", da2062ImportsHandler.GetItemFieldImage)
+			da2062Imports.PATCH("/:id/items/:itemId", da2062ImportsHandler.ReviewImportItem)
+			da2062Imports.POST("/:id/items/merge", da2062ImportsHandler.MergeImportItems)
+			da2062Imports.POST("/:id/commit", da2062ImportsHandler.CommitImport)
+
+			// Reconciliation of a printout against the hand receipt
+			da2062Imports.GET("/:id/reconciliation", da2062ImportsHandler.GetReconciliation)
+			da2062Imports.POST("/:id/reconciliation/actions", da2062ImportsHandler.ReconcileImport)
+
+			// Parsing accuracy measured from review corrections
+			da2062Imports.GET("/accuracy", da2062ImportsHandler.GetImportAccuracy)
 		}
 
 		// Component events routes
@@ -296,6 +416,11 @@ func SetupRoutes(router *gin.Engine, ledgerService ledger.LedgerService, repo re
 			notifications.POST("/mark-all-read", notificationHandler.MarkAllAsRead)
 			notifications.DELETE("/:id", notificationHandler.DeleteNotification)
 			notifications.DELETE("/clear-old", notificationHandler.ClearOldNotifications)
+			notifications.GET("/preferences", notificationHandler.GetPreferences)
+			notifications.PUT("/preferences", notificationHandler.UpdatePreferences)
+			notifications.GET("/devices", notificationHandler.GetDevices)
+			notifications.POST("/devices", notificationHandler.RegisterDevice)
+			notifications.DELETE("/devices/:token", notificationHandler.UnregisterDevice)
 		}
 
 		// Register NSN routes
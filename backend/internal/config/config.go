@@ -9,15 +9,21 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	ImmuDB   ImmuDBConfig   `mapstructure:"immudb"`
-	MinIO    MinIOConfig    `mapstructure:"minio"`
-	NSN      NSNConfig      `mapstructure:"nsn"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	Logging  LoggingConfig  `mapstructure:"logging"`
-	Security SecurityConfig `mapstructure:"security"`
+	Server        ServerConfig        `mapstructure:"server"`
+	Database      DatabaseConfig      `mapstructure:"database"`
+	JWT           JWTConfig           `mapstructure:"jwt"`
+	ImmuDB        ImmuDBConfig        `mapstructure:"immudb"`
+	MinIO         MinIOConfig         `mapstructure:"minio"`
+	NSN           NSNConfig           `mapstructure:"nsn"`
+	Redis         RedisConfig         `mapstructure:"redis"`
+	Notifications NotificationsConfig `mapstructure:"notifications"`
+	Email         EmailConfig         `mapstructure:"email"`
+	Push          PushConfig          `mapstructure:"push"`
+	Webhooks      WebhooksConfig      `mapstructure:"webhooks"`
+	Jobs          JobsConfig          `mapstructure:"jobs"`
+	AI            AIConfig            `mapstructure:"ai"`
+	Logging       LoggingConfig       `mapstructure:"logging"`
+	Security      SecurityConfig      `mapstructure:"security"`
 }
 
 // ServerConfig holds server configuration
@@ -107,6 +113,78 @@ type RedisConfig struct {
 	Enabled  bool   `mapstructure:"enabled"`
 }
 
+// NotificationsConfig holds real-time notification configuration
+type NotificationsConfig struct {
+	Backplane             string        `mapstructure:"backplane"` // postgres, redis or none
+	Channel               string        `mapstructure:"channel"`
+	MaxConnectionsPerUser int           `mapstructure:"max_connections_per_user"` // Per API instance; 0 means no limit
+	SessionCheckInterval  time.Duration `mapstructure:"session_check_interval"`   // How often open connections are re-validated
+}
+
+// EmailConfig holds outbound SMTP configuration
+type EmailConfig struct {
+	SMTPHost string `mapstructure:"smtp_host"`
+	SMTPPort int    `mapstructure:"smtp_port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+	Enabled  bool   `mapstructure:"enabled"`
+}
+
+// PushConfig holds mobile push notification configuration
+type PushConfig struct {
+	Enabled     bool           `mapstructure:"enabled"`
+	MaxAttempts int            `mapstructure:"max_attempts"`
+	APNs        APNsPushConfig `mapstructure:"apns"`
+	FCM         FCMPushConfig  `mapstructure:"fcm"`
+}
+
+// APNsPushConfig holds Apple Push Notification service credentials
+type APNsPushConfig struct {
+	BaseURL string `mapstructure:"base_url"`
+	Topic   string `mapstructure:"topic"` // iOS bundle id
+	KeyID   string `mapstructure:"key_id"`
+	TeamID  string `mapstructure:"team_id"`
+	KeyFile string `mapstructure:"key_file"` // .p8 signing key
+}
+
+// FCMPushConfig holds Firebase Cloud Messaging credentials
+type FCMPushConfig struct {
+	BaseURL         string `mapstructure:"base_url"`
+	CredentialsFile string `mapstructure:"credentials_file"` // Service account key JSON
+}
+
+// WebhooksConfig holds outbound webhook delivery configuration
+type WebhooksConfig struct {
+	MaxAttempts int  `mapstructure:"max_attempts"` // Failed requests before a delivery is dead-lettered
+	AllowHTTP   bool `mapstructure:"allow_http"`   // Permit plain http URLs in production
+}
+
+// JobsConfig holds background job worker configuration
+type JobsConfig struct {
+	Concurrency  int           `mapstructure:"concurrency"` // Jobs one worker process runs at once
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+}
+
+// AIConfig holds DA 2062 form parsing configuration
+type AIConfig struct {
+	Providers []VisionProviderConfig `mapstructure:"providers"` // Tried in order until one parses the page
+}
+
+// VisionProviderConfig holds one form parsing back end's settings
+type VisionProviderConfig struct {
+	Name              string        `mapstructure:"name"` // anthropic, azure_openai, local or fake
+	Endpoint          string        `mapstructure:"endpoint"`
+	APIKey            string        `mapstructure:"api_key"`
+	Model             string        `mapstructure:"model"` // Model, or Azure OpenAI deployment
+	MaxTokens         int           `mapstructure:"max_tokens"`
+	Timeout           time.Duration `mapstructure:"timeout"`
+	InputCostPerMTok  float64       `mapstructure:"input_cost_per_mtok"` // USD per million tokens
+	OutputCostPerMTok float64       `mapstructure:"output_cost_per_mtok"`
+	OCRPath           string        `mapstructure:"ocr_path"` // Tesseract binary for the local provider
+	OCRLanguage       string        `mapstructure:"ocr_language"`
+}
+
 // LoggingConfig holds logging configuration
 type LoggingConfig struct {
 	Level      string `mapstructure:"level"`
@@ -129,6 +207,7 @@ type SecurityConfig struct {
 	MaxLoginAttempts      int           `mapstructure:"max_login_attempts"`
 	LockoutDuration       time.Duration `mapstructure:"lockout_duration"`
 	CORSAllowedOrigins    []string      `mapstructure:"cors_allowed_origins"`
+	AdminEmails           []string      `mapstructure:"admin_emails"` // Users allowed into operator views such as the job queue
 	RateLimitEnabled      bool          `mapstructure:"rate_limit_enabled"`
 	RateLimitRPS          int           `mapstructure:"rate_limit_rps"`
 }
@@ -223,6 +302,31 @@ func setDefaults() {
 	viper.SetDefault("redis.db", 0)
 	viper.SetDefault("redis.enabled", false)
 
+	// Notification defaults
+	viper.SetDefault("notifications.backplane", "postgres")
+	viper.SetDefault("notifications.channel", "handreceipt_notifications")
+	viper.SetDefault("notifications.max_connections_per_user", 10)
+	viper.SetDefault("notifications.session_check_interval", "30s")
+
+	// Email defaults
+	viper.SetDefault("email.smtp_port", 587)
+	viper.SetDefault("email.from", "HandReceipt <no-reply@handreceipt.mil>")
+	viper.SetDefault("email.enabled", false)
+
+	// Push defaults
+	viper.SetDefault("push.enabled", false)
+	viper.SetDefault("push.max_attempts", 3)
+	viper.SetDefault("push.apns.base_url", "https://api.push.apple.com")
+	viper.SetDefault("push.fcm.base_url", "https://fcm.googleapis.com")
+
+	// Webhook defaults
+	viper.SetDefault("webhooks.max_attempts", 8)
+	viper.SetDefault("webhooks.allow_http", false)
+
+	// Job worker defaults
+	viper.SetDefault("jobs.concurrency", 4)
+	viper.SetDefault("jobs.poll_interval", "2s")
+
 	// Logging defaults
 	viper.SetDefault("logging.level", "info")
 	viper.SetDefault("logging.format", "json")
@@ -0,0 +1,174 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DA2062Import is one scanned or exported DA 2062 being read into inventory. Uploads
+// are parsed in the background; the counts track how far that has got.
+type DA2062Import struct {
+	ID             int64           `json:"id" gorm:"primaryKey;column:id"`
+	FileName       string          `json:"fileName" gorm:"column:file_name;not null"`
+	FileURL        *string         `json:"fileUrl,omitempty" gorm:"column:file_url"`
+	ImportedByUser int64           `json:"importedByUserId" gorm:"column:imported_by_user_id;not null"`
+	Status         string          `json:"status" gorm:"column:status;default:pending"`
+	Mode           string          `json:"mode" gorm:"column:mode;default:review"` // DA2062ImportModeReview or DA2062ImportModeReconcile
+	TotalItems     int             `json:"totalItems" gorm:"column:total_items;default:0"`
+	ProcessedItems int             `json:"processedItems" gorm:"column:processed_items;default:0"`
+	FailedItems    int             `json:"failedItems" gorm:"column:failed_items;default:0"`
+	ErrorLog       json.RawMessage `json:"errorLog,omitempty" gorm:"column:error_log;type:jsonb"`
+	JobID          *uint           `json:"jobId,omitempty" gorm:"column:job_id"` // Background job parsing the upload
+	StorageKey     *string         `json:"-" gorm:"column:storage_key"`
+	ContentType    *string         `json:"contentType,omitempty" gorm:"column:content_type"`
+	PageCount      int             `json:"pageCount" gorm:"column:page_count;not null;default:0"`
+	PagesParsed    int             `json:"pagesParsed" gorm:"column:pages_parsed;not null;default:0"`
+	PagesFailed    int             `json:"pagesFailed" gorm:"column:pages_failed;not null;default:0"`    // Pages that could not be read, such as undecodable scans
+	FormInfo       json.RawMessage `json:"formInfo,omitempty" gorm:"column:form_info;type:jsonb"`        // From/to unit, date and form number read from the form
+	ParseCostUSD   float64         `json:"parseCostUsd" gorm:"column:parse_cost_usd;not null;default:0"` // Provider charges for parsing the pages
+	CreatedAt      time.Time       `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+	CompletedAt    *time.Time      `json:"completedAt,omitempty" gorm:"column:completed_at"`
+	CommittedAt    *time.Time      `json:"committedAt,omitempty" gorm:"column:committed_at"` // When the reviewed lines were created as property
+	CommittedBy    *uint           `json:"committedByUserId,omitempty" gorm:"column:committed_by_user_id"`
+}
+
+// TableName specifies the table name for GORM
+func (DA2062Import) TableName() string {
+	return "da2062_imports"
+}
+
+// DA2062ImportItem is one line read from an imported DA 2062. Lines are staged for
+// review and only become property when the import is committed.
+type DA2062ImportItem struct {
+	ID           int64           `json:"id" gorm:"primaryKey;column:id"`
+	ImportID     int64           `json:"importId" gorm:"column:import_id;not null"`
+	LineNumber   int             `json:"lineNumber" gorm:"column:line_number;not null"`
+	PageNumber   *int            `json:"pageNumber,omitempty" gorm:"column:page_number"`            // Page of a multi-page form
+	RowRegion    json.RawMessage `json:"rowRegion,omitempty" gorm:"column:row_region;type:jsonb"`   // Band of the scanned page the line was read from
+	FieldBoxes   json.RawMessage `json:"fieldBoxes,omitempty" gorm:"column:field_boxes;type:jsonb"` // Where each field was read on the scanned page
+	RawData      json.RawMessage `json:"rawData" gorm:"column:raw_data;type:jsonb;not null"`
+	PropertyID   *int64          `json:"propertyId,omitempty" gorm:"column:property_id"`
+	Status       string          `json:"status" gorm:"column:status;default:pending"`
+	ErrorMessage *string         `json:"errorMessage,omitempty" gorm:"column:error_message"`
+	Corrections  json.RawMessage `json:"corrections,omitempty" gorm:"column:corrections;type:jsonb"` // []DA2062ItemCorrection
+	MergedInto   *int64          `json:"mergedIntoItemId,omitempty" gorm:"column:merged_into_item_id"`
+	ReviewedBy   *uint           `json:"reviewedByUserId,omitempty" gorm:"column:reviewed_by_user_id"`
+	ReviewedAt   *time.Time      `json:"reviewedAt,omitempty" gorm:"column:reviewed_at"`
+	CreatedAt    time.Time       `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+}
+
+// DA2062ItemCorrection is one change a reviewer made to a line as it was read from
+// the form
+type DA2062ItemCorrection struct {
+	Line   int       `json:"line"`
+	Field  string    `json:"field"`
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	Note   string    `json:"note,omitempty"`
+	UserID uint      `json:"userId"`
+	At     time.Time `json:"at"`
+}
+
+// TableName specifies the table name for GORM
+func (DA2062ImportItem) TableName() string {
+	return "da2062_import_items"
+}
+
+// DA2062CorrectionExample is a reviewer's correction of a value read from an
+// imported form, kept by unit as a labeled example for parsing the unit's later forms
+type DA2062CorrectionExample struct {
+	ID             int64     `json:"id" gorm:"primaryKey;column:id"`
+	Unit           string    `json:"unit" gorm:"column:unit;not null"`
+	ImportID       int64     `json:"importId" gorm:"column:import_id;not null"`
+	ItemID         *int64    `json:"itemId,omitempty" gorm:"column:item_id"`
+	UserID         uint      `json:"userId" gorm:"column:user_id;not null"`
+	Field          string    `json:"field" gorm:"column:field;not null"`
+	ReadValue      string    `json:"readValue" gorm:"column:read_value;not null"`
+	CorrectedValue string    `json:"correctedValue" gorm:"column:corrected_value;not null"`
+	NSN            *string   `json:"nsn,omitempty" gorm:"column:nsn"`
+	Source         *string   `json:"source,omitempty" gorm:"column:source"`
+	CreatedAt      time.Time `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+}
+
+// TableName specifies the table name for GORM
+func (DA2062CorrectionExample) TableName() string {
+	return "da2062_correction_examples"
+}
+
+// DA2062ImportPage holds what was read from one page of an import. Pages are saved
+// as they are parsed, so an import interrupted part way resumes at the next page.
+type DA2062ImportPage struct {
+	ID           int64           `json:"id" gorm:"primaryKey;column:id"`
+	ImportID     int64           `json:"importId" gorm:"column:import_id;not null;uniqueIndex:idx_da2062_import_pages_page"`
+	PageNumber   int             `json:"pageNumber" gorm:"column:page_number;not null;uniqueIndex:idx_da2062_import_pages_page"`
+	Items        json.RawMessage `json:"items" gorm:"column:items;type:jsonb;not null"`
+	Meta         json.RawMessage `json:"meta,omitempty" gorm:"column:meta;type:jsonb"`
+	Provider     string          `json:"provider" gorm:"column:provider"` // Back end that read the page
+	InputTokens  int64           `json:"inputTokens" gorm:"column:input_tokens;not null;default:0"`
+	OutputTokens int64           `json:"outputTokens" gorm:"column:output_tokens;not null;default:0"`
+	CostUSD      float64         `json:"costUsd" gorm:"column:cost_usd;not null;default:0"`
+	Error        *string         `json:"error,omitempty" gorm:"column:error"` // Why the page could not be read; such a page has no items
+	ImageKey     *string         `json:"-" gorm:"column:image_key"`           // Stored image the page was read from, for review crops; empty if it has none
+	ImageType    *string         `json:"-" gorm:"column:image_content_type"`
+	CreatedAt    time.Time       `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+}
+
+// TableName specifies the table name for GORM
+func (DA2062ImportPage) TableName() string {
+	return "da2062_import_pages"
+}
+
+// Constants for DA 2062 import status
+const (
+	DA2062ImportPending    = "pending"
+	DA2062ImportProcessing = "processing"
+	DA2062ImportCompleted  = "completed"
+	DA2062ImportFailed     = "failed"
+)
+
+// Constants for DA 2062 import modes. A review import stages the form's lines to be
+// created as property; a reconcile import compares a property book printout with
+// the importer's hand receipt.
+const (
+	DA2062ImportModeReview    = "review"
+	DA2062ImportModeReconcile = "reconcile"
+)
+
+// DA2062ReconciliationAction records something done about a difference found when
+// reconciling an import: property created from a line, an inventory of property
+// requested, or a loss case opened
+type DA2062ReconciliationAction struct {
+	ID         int64     `json:"id" gorm:"primaryKey;column:id"`
+	ImportID   int64     `json:"importId" gorm:"column:import_id;not null;index"`
+	Action     string    `json:"action" gorm:"column:action;not null"`
+	ItemID     *int64    `json:"itemId,omitempty" gorm:"column:item_id"`
+	PropertyID *uint     `json:"propertyId,omitempty" gorm:"column:property_id"`
+	CampaignID *uint     `json:"campaignId,omitempty" gorm:"column:campaign_id"`
+	LossCaseID *uint     `json:"lossCaseId,omitempty" gorm:"column:loss_case_id"`
+	UserID     uint      `json:"userId" gorm:"column:user_id;not null"`
+	CreatedAt  time.Time `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+}
+
+// TableName specifies the table name for GORM
+func (DA2062ReconciliationAction) TableName() string {
+	return "da2062_reconciliation_actions"
+}
+
+// Constants for DA 2062 reconciliation actions
+const (
+	DA2062ReconcileCreate    = "create"
+	DA2062ReconcileInventory = "inventory"
+	DA2062ReconcileLossCase  = "loss_case"
+)
+
+// Constants for DA 2062 import line status. Pending and failed lines still need
+// review; failed lines cannot be accepted until they are corrected.
+const (
+	DA2062ItemPending   = "pending"
+	DA2062ItemFailed    = "failed"
+	DA2062ItemAccepted  = "accepted"
+	DA2062ItemIgnored   = "ignored"
+	DA2062ItemMerged    = "merged"
+	DA2062ItemProcessed = "processed"
+	DA2062ItemDuplicate = "duplicate"
+)
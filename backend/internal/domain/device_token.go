@@ -0,0 +1,24 @@
+package domain
+
+import "time"
+
+// DeviceToken is a mobile device registered to receive push notifications. A token
+// belongs to whichever user registered it last, so signing in as someone else on the
+// same phone moves it.
+type DeviceToken struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	UserID     uint      `json:"userId" gorm:"column:user_id;not null;index"`
+	Platform   string    `json:"platform" gorm:"column:platform;not null"`       // ios or android
+	Token      string    `json:"token" gorm:"column:token;not null;uniqueIndex"` // APNs device token or FCM registration token
+	DeviceID   string    `json:"deviceId,omitempty" gorm:"column:device_id"`     // Same id the device uses for its WebSocket connection
+	AppVersion string    `json:"appVersion,omitempty" gorm:"column:app_version"`
+	LastSeenAt time.Time `json:"lastSeenAt" gorm:"column:last_seen_at;not null;default:CURRENT_TIMESTAMP"`
+	CreatedAt  time.Time `json:"createdAt" gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt  time.Time `json:"updatedAt" gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP"`
+}
+
+// Constants for push notification platforms
+const (
+	DevicePlatformIOS     = "ios"
+	DevicePlatformAndroid = "android"
+)
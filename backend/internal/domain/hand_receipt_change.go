@@ -0,0 +1,69 @@
+package domain
+
+import "time"
+
+// HandReceiptChange tracks a change of primary hand receipt holder: a joint 100%
+// inventory of everything signed to the outgoing holder, ending in a bulk transfer
+// of the items found to the incoming holder.
+type HandReceiptChange struct {
+	ID                uint       `json:"id" gorm:"primaryKey"`
+	OutgoingUserID    uint       `json:"outgoingUserId" gorm:"column:outgoing_user_id;not null;index"`
+	IncomingUserID    uint       `json:"incomingUserId" gorm:"column:incoming_user_id;not null;index"`
+	InitiatedByUserID uint       `json:"initiatedByUserId" gorm:"column:initiated_by_user_id;not null"`
+	Status            string     `json:"status" gorm:"column:status;default:'open';not null"`
+	DueDate           *time.Time `json:"dueDate" gorm:"column:due_date"`
+	Notes             *string    `json:"notes"`
+	CompletedAt       *time.Time `json:"completedAt" gorm:"column:completed_at"`
+	HandReceiptURL    *string    `json:"handReceiptUrl" gorm:"column:hand_receipt_url"`     // Signed DA 2062 for the items found
+	ShortageAnnexURL  *string    `json:"shortageAnnexUrl" gorm:"column:shortage_annex_url"` // Shortage annex for the items missing
+	CreatedAt         time.Time  `json:"createdAt" gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt         time.Time  `json:"updatedAt" gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP"`
+
+	// Relationships
+	OutgoingUser *User                   `json:"outgoingUser,omitempty" gorm:"foreignKey:OutgoingUserID"`
+	IncomingUser *User                   `json:"incomingUser,omitempty" gorm:"foreignKey:IncomingUserID"`
+	Items        []HandReceiptChangeItem `json:"items,omitempty" gorm:"foreignKey:ChangeID"`
+}
+
+// HandReceiptChangeItem is one line of the joint inventory. Property details are
+// snapshotted when the change starts so later edits don't alter what was counted.
+type HandReceiptChangeItem struct {
+	ID               uint       `json:"id" gorm:"primaryKey"`
+	ChangeID         uint       `json:"changeId" gorm:"column:change_id;not null;index"`
+	PropertyID       uint       `json:"propertyId" gorm:"column:property_id;not null"`
+	ParentPropertyID *uint      `json:"parentPropertyId" gorm:"column:parent_property_id"` // Set when the item is a component
+	Name             string     `json:"name" gorm:"not null"`
+	SerialNumber     string     `json:"serialNumber" gorm:"column:serial_number;not null"`
+	NSN              *string    `json:"nsn" gorm:"column:nsn"`
+	Quantity         int        `json:"quantity" gorm:"default:1"`
+	UnitPrice        float64    `json:"unitPrice" gorm:"column:unit_price;default:0"`
+	OutgoingMark     *string    `json:"outgoingMark" gorm:"column:outgoing_mark"`
+	IncomingMark     *string    `json:"incomingMark" gorm:"column:incoming_mark"`
+	Status           string     `json:"status" gorm:"column:status;default:'pending';not null"`
+	Remarks          *string    `json:"remarks"`
+	LastMarkedAt     *time.Time `json:"lastMarkedAt" gorm:"column:last_marked_at"`
+	LastMarkedBy     *uint      `json:"lastMarkedBy" gorm:"column:last_marked_by"`
+	CreatedAt        time.Time  `json:"createdAt" gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt        time.Time  `json:"updatedAt" gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP"`
+
+	// Relationships
+	Property *Property `json:"property,omitempty" gorm:"foreignKey:PropertyID"`
+}
+
+// Constants for hand receipt change status
+const (
+	HandReceiptChangeStatusOpen      = "open"
+	HandReceiptChangeStatusCompleted = "completed"
+	HandReceiptChangeStatusCancelled = "cancelled"
+)
+
+// Constants for inventory marks and item status. An item settles on a mark once
+// both holders agree on it; until then it is pending or disputed.
+const (
+	InventoryMarkFound      = "found"
+	InventoryMarkMissing    = "missing"
+	InventoryMarkDiscrepant = "discrepant"
+
+	InventoryItemPending  = "pending"
+	InventoryItemDisputed = "disputed"
+)
@@ -0,0 +1,74 @@
+package domain
+
+import "time"
+
+// InventoryCampaign is a scheduled cyclic or sensitive-item inventory. Property is
+// selected when the campaign is created and each item is counted by an assigned counter.
+// A recurring campaign opens the next one over the same selection every RepeatEveryDays.
+type InventoryCampaign struct {
+	ID                 uint       `json:"id" gorm:"primaryKey"`
+	Name               string     `json:"name" gorm:"not null"`
+	CampaignType       string     `json:"campaignType" gorm:"column:campaign_type;not null"`
+	Status             string     `json:"status" gorm:"column:status;default:'active';not null"`
+	SelectionCriteria  string     `json:"selectionCriteria" gorm:"column:selection_criteria;type:jsonb;not null;default:'{}'"`
+	CreatedByUserID    uint       `json:"createdByUserId" gorm:"column:created_by_user_id;not null"`
+	DueDate            time.Time  `json:"dueDate" gorm:"column:due_date;not null"`
+	ClosedAt           *time.Time `json:"closedAt" gorm:"column:closed_at"`
+	ClosedByUserID     *uint      `json:"closedByUserId" gorm:"column:closed_by_user_id"`
+	ReportURL          *string    `json:"reportUrl" gorm:"column:report_url"`
+	ReportSHA256       *string    `json:"reportSha256" gorm:"column:report_sha256"`
+	RepeatEveryDays    *int       `json:"repeatEveryDays" gorm:"column:repeat_every_days"`       // Nil for a one-off campaign
+	NextRunAt          *time.Time `json:"nextRunAt" gorm:"column:next_run_at"`                   // When the next campaign in the series opens
+	PreviousCampaignID *uint      `json:"previousCampaignId" gorm:"column:previous_campaign_id"` // Campaign this one was scheduled from
+	CreatedAt          time.Time  `json:"createdAt" gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt          time.Time  `json:"updatedAt" gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP"`
+
+	// Relationships
+	CreatedByUser *User                   `json:"createdByUser,omitempty" gorm:"foreignKey:CreatedByUserID"`
+	Items         []InventoryCampaignItem `json:"items,omitempty" gorm:"foreignKey:CampaignID"`
+}
+
+// InventoryCampaignItem records the count of one property item within a campaign
+type InventoryCampaignItem struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	CampaignID     uint       `json:"campaignId" gorm:"column:campaign_id;not null;index"`
+	PropertyID     uint       `json:"propertyId" gorm:"column:property_id;not null"`
+	HolderUserID   *uint      `json:"holderUserId" gorm:"column:holder_user_id"` // Holder when the item was selected
+	CounterUserID  *uint      `json:"counterUserId" gorm:"column:counter_user_id;index"`
+	SerialNumber   string     `json:"serialNumber" gorm:"column:serial_number;not null"`
+	Status         string     `json:"status" gorm:"column:status;default:'pending';not null"`
+	ScannedSerial  *string    `json:"scannedSerial" gorm:"column:scanned_serial"`
+	SerialMatched  *bool      `json:"serialMatched" gorm:"column:serial_matched"`
+	PhotoURL       *string    `json:"photoUrl" gorm:"column:photo_url"`
+	Condition      *string    `json:"condition"`
+	Notes          *string    `json:"notes"`
+	CountedAt      *time.Time `json:"countedAt" gorm:"column:counted_at"`
+	ReminderSentAt *time.Time `json:"reminderSentAt" gorm:"column:reminder_sent_at"`
+	CreatedAt      time.Time  `json:"createdAt" gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt      time.Time  `json:"updatedAt" gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP"`
+
+	// Relationships
+	Property    *Property `json:"property,omitempty" gorm:"foreignKey:PropertyID"`
+	CounterUser *User     `json:"counterUser,omitempty" gorm:"foreignKey:CounterUserID"`
+}
+
+// Constants for inventory campaign types
+const (
+	InventoryCampaignCyclic        = "cyclic"
+	InventoryCampaignSensitiveItem = "sensitive_item"
+)
+
+// Constants for inventory campaign status
+const (
+	InventoryCampaignStatusActive    = "active"
+	InventoryCampaignStatusClosed    = "closed"
+	InventoryCampaignStatusCancelled = "cancelled"
+)
+
+// Constants for inventory count results
+const (
+	InventoryCountPending    = "pending"
+	InventoryCountVerified   = "verified"
+	InventoryCountDiscrepant = "discrepant"
+	InventoryCountNotFound   = "not_found"
+)
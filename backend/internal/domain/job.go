@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Job is a unit of background work queued by the API and run by the worker. A
+// running job is hidden from other workers until LockedUntil; if its worker dies
+// the job becomes visible again and is retried.
+type Job struct {
+	ID              uint            `json:"id" gorm:"primaryKey"`
+	Type            string          `json:"type" gorm:"column:type;not null"`
+	Payload         json.RawMessage `json:"payload" gorm:"column:payload;type:jsonb;not null"`
+	Status          string          `json:"status" gorm:"column:status;not null;default:'queued'"`
+	Attempts        int             `json:"attempts" gorm:"column:attempts;not null;default:0"`
+	MaxAttempts     int             `json:"maxAttempts" gorm:"column:max_attempts;not null;default:5"`
+	TimeoutSeconds  int             `json:"timeoutSeconds" gorm:"column:timeout_seconds;not null;default:300"`
+	RunAt           time.Time       `json:"runAt" gorm:"column:run_at;not null"` // Not run before this time; pushed back after each failure
+	LockedUntil     *time.Time      `json:"lockedUntil,omitempty" gorm:"column:locked_until"`
+	LockedBy        *string         `json:"lockedBy,omitempty" gorm:"column:locked_by"` // Worker running the job
+	LastError       *string         `json:"lastError,omitempty" gorm:"column:last_error"`
+	CreatedByUserID *uint           `json:"createdByUserId,omitempty" gorm:"column:created_by_user_id"`
+	StartedAt       *time.Time      `json:"startedAt,omitempty" gorm:"column:started_at"`
+	FinishedAt      *time.Time      `json:"finishedAt,omitempty" gorm:"column:finished_at"`
+	CreatedAt       time.Time       `json:"createdAt" gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt       time.Time       `json:"updatedAt" gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP"`
+}
+
+// Constants for job status
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobDead      = "dead" // Failed on its last attempt or with a permanent error
+	JobCancelled = "cancelled"
+)
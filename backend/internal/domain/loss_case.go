@@ -0,0 +1,90 @@
+package domain
+
+import "time"
+
+// LossCase is a Financial Liability Investigation of Property Loss (FLIPL) opened
+// when an item is lost, damaged or destroyed. The case carries the item from the
+// initial report through investigation and approval to adjustment of the hand receipt.
+type LossCase struct {
+	ID                     uint       `json:"id" gorm:"primaryKey"`
+	CaseNumber             string     `json:"caseNumber" gorm:"column:case_number;uniqueIndex;not null"`
+	PropertyID             uint       `json:"propertyId" gorm:"column:property_id;not null;index"`
+	HolderUserID           *uint      `json:"holderUserId" gorm:"column:holder_user_id"` // Hand receipt holder when the case was opened
+	InitiatedByUserID      uint       `json:"initiatedByUserId" gorm:"column:initiated_by_user_id;not null"`
+	InvestigatingOfficerID *uint      `json:"investigatingOfficerId" gorm:"column:investigating_officer_id"`
+	ApprovingAuthorityID   uint       `json:"approvingAuthorityId" gorm:"column:approving_authority_id;not null"`
+	CaseType               string     `json:"caseType" gorm:"column:case_type;not null"`
+	Status                 string     `json:"status" gorm:"column:status;default:'initiated';not null"`
+	Circumstances          string     `json:"circumstances" gorm:"not null"`
+	IncidentDate           time.Time  `json:"incidentDate" gorm:"column:incident_date;not null"`
+	IncidentLocation       *string    `json:"incidentLocation" gorm:"column:incident_location"`
+	PreviousStatus         string     `json:"previousStatus" gorm:"column:previous_status;not null"` // Property status restored if the case is withdrawn
+	Quantity               int        `json:"quantity" gorm:"default:1"`
+	UnitPrice              float64    `json:"unitPrice" gorm:"column:unit_price;default:0"`
+	TotalLoss              float64    `json:"totalLoss" gorm:"column:total_loss;default:0"`
+	Findings               *string    `json:"findings"`
+	LiabilityDecision      *string    `json:"liabilityDecision" gorm:"column:liability_decision"`
+	LiabilityAmount        float64    `json:"liabilityAmount" gorm:"column:liability_amount;default:0"`
+	FormURL                *string    `json:"formUrl" gorm:"column:form_url"`
+	ApprovedAt             *time.Time `json:"approvedAt" gorm:"column:approved_at"`
+	CreatedAt              time.Time  `json:"createdAt" gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt              time.Time  `json:"updatedAt" gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP"`
+
+	// Relationships
+	Property             *Property        `json:"property,omitempty" gorm:"foreignKey:PropertyID"`
+	HolderUser           *User            `json:"holderUser,omitempty" gorm:"foreignKey:HolderUserID"`
+	InitiatedByUser      *User            `json:"initiatedByUser,omitempty" gorm:"foreignKey:InitiatedByUserID"`
+	InvestigatingOfficer *User            `json:"investigatingOfficer,omitempty" gorm:"foreignKey:InvestigatingOfficerID"`
+	ApprovingAuthority   *User            `json:"approvingAuthority,omitempty" gorm:"foreignKey:ApprovingAuthorityID"`
+	Actions              []LossCaseAction `json:"actions,omitempty" gorm:"foreignKey:CaseID"`
+}
+
+// LossCaseAction records one step taken on a loss case, in order
+type LossCaseAction struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	CaseID      uint      `json:"caseId" gorm:"column:case_id;not null;index"`
+	Action      string    `json:"action" gorm:"not null"`
+	ActorUserID uint      `json:"actorUserId" gorm:"column:actor_user_id;not null"`
+	FromStatus  string    `json:"fromStatus" gorm:"column:from_status"`
+	ToStatus    string    `json:"toStatus" gorm:"column:to_status;not null"`
+	Comments    *string   `json:"comments"`
+	CreatedAt   time.Time `json:"createdAt" gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+
+	// Relationships
+	ActorUser *User `json:"actorUser,omitempty" gorm:"foreignKey:ActorUserID"`
+}
+
+// Constants for loss case types
+const (
+	LossCaseLost      = "lost"
+	LossCaseDamaged   = "damaged"
+	LossCaseDestroyed = "destroyed"
+)
+
+// Constants for loss case status
+const (
+	LossCaseStatusInitiated       = "initiated"
+	LossCaseStatusInvestigating   = "investigating"
+	LossCaseStatusPendingApproval = "pending_approval"
+	LossCaseStatusApproved        = "approved"
+	LossCaseStatusWithdrawn       = "withdrawn"
+)
+
+// Constants for loss case actions
+const (
+	LossCaseActionOpened               = "opened"
+	LossCaseActionInvestigatorAssigned = "investigator_assigned"
+	LossCaseActionFindingsSubmitted    = "findings_submitted"
+	LossCaseActionApproved             = "approved"
+	LossCaseActionReturned             = "returned"
+	LossCaseActionWithdrawn            = "withdrawn"
+)
+
+// Constants for liability decisions
+const (
+	LiabilityDecisionLiable    = "liable"
+	LiabilityDecisionNotLiable = "not_liable"
+)
+
+// PropertyStatusDisposed marks property that has been dropped from accountability
+const PropertyStatusDisposed = "disposed"
@@ -60,6 +60,7 @@ type User struct {
 	Phone        string    `json:"phone"`                                    // NEW: Added for contact info
 	DoDID        *string   `json:"dodid" gorm:"column:dodid;unique"`         // NEW: Department of Defense ID
 	SignatureURL *string   `json:"signatureUrl" gorm:"column:signature_url"` // NEW: URL to stored signature image
+	SupervisorID *uint     `json:"supervisorId" gorm:"column:supervisor_id"` // Immediate supervisor in the chain of command
 	CreatedAt    time.Time `json:"createdAt" gorm:"not null;default:CURRENT_TIMESTAMP"`
 	UpdatedAt    time.Time `json:"updatedAt" gorm:"not null;default:CURRENT_TIMESTAMP"`
 }
@@ -192,6 +193,8 @@ type Transfer struct {
 	RequestDate           time.Time  `json:"requestDate" gorm:"column:request_date;not null;default:CURRENT_TIMESTAMP"`
 	ResolvedDate          *time.Time `json:"resolvedDate" gorm:"column:resolved_date"`
 	Notes                 *string    `json:"notes"`
+	RejectionReason       *string    `json:"rejectionReason" gorm:"column:rejection_reason"`                        // wrong_serial, damaged, missing_components, not_mine, other
+	Revision              int        `json:"revision" gorm:"default:1;not null"`                                    // Incremented each time the sender amends and resubmits
 	CreatedAt             time.Time  `json:"createdAt" gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"` // Added CreatedAt
 	UpdatedAt             time.Time  `json:"updatedAt" gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP"` // Added UpdatedAt
 
@@ -533,6 +536,7 @@ type MaintenanceFormData struct {
 const (
 	DocumentTypeMaintenanceForm = "maintenance_form"
 	DocumentTypeTransferForm    = "transfer_form"
+	DocumentTypeLossForm        = "loss_form"
 )
 
 // Constants for document status
@@ -588,18 +592,27 @@ type BulkDocumentOperationRequest struct {
 
 // Notification represents a persistent notification
 type Notification struct {
-	ID        uint            `json:"id" gorm:"primaryKey"`
-	UserID    uint            `json:"userId" gorm:"column:user_id;not null"`
-	Type      string          `json:"type" gorm:"column:type;not null"`
-	Title     string          `json:"title" gorm:"column:title;not null"`
-	Message   string          `json:"message" gorm:"column:message;not null"`
-	Data      json.RawMessage `json:"data,omitempty" gorm:"column:data;type:jsonb"`
-	Read      bool            `json:"read" gorm:"column:read;default:false;not null"`
-	ReadAt    *time.Time      `json:"readAt,omitempty" gorm:"column:read_at"`
-	Priority  string          `json:"priority" gorm:"column:priority;default:'normal';not null"`
-	ExpiresAt *time.Time      `json:"expiresAt,omitempty" gorm:"column:expires_at"`
-	CreatedAt time.Time       `json:"createdAt" gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
-	UpdatedAt time.Time       `json:"updatedAt" gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP"`
+	ID         uint            `json:"id" gorm:"primaryKey"`
+	UserID     uint            `json:"userId" gorm:"column:user_id;not null"`
+	Type       string          `json:"type" gorm:"column:type;not null"`
+	Title      string          `json:"title" gorm:"column:title;not null"`
+	Message    string          `json:"message" gorm:"column:message;not null"`
+	Data       json.RawMessage `json:"data,omitempty" gorm:"column:data;type:jsonb"`
+	Read       bool            `json:"read" gorm:"column:read;default:false;not null"`
+	ReadAt     *time.Time      `json:"readAt,omitempty" gorm:"column:read_at"`
+	Priority   string          `json:"priority" gorm:"column:priority;default:'normal';not null"`
+	ExpiresAt  *time.Time      `json:"expiresAt,omitempty" gorm:"column:expires_at"`
+	Sequence   int64           `json:"seq" gorm:"column:sequence;not null;default:0"`  // Per-user delivery order; clients resume from the last one they saw
+	Event      json.RawMessage `json:"-" gorm:"column:event;type:jsonb"`               // WebSocket event as it was pushed, for replay
+	DigestedAt *time.Time      `json:"digestedAt,omitempty" gorm:"column:digested_at"` // Rolled up into an email digest
+	CreatedAt  time.Time       `json:"createdAt" gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt  time.Time       `json:"updatedAt" gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP"`
+}
+
+// NotificationSequence holds the last sequence number handed out for a user's notifications
+type NotificationSequence struct {
+	UserID       uint  `json:"userId" gorm:"column:user_id;primaryKey;autoIncrement:false"`
+	LastSequence int64 `json:"lastSequence" gorm:"column:last_sequence;not null;default:0"`
 }
 
 // Constants for notification types
@@ -610,6 +623,8 @@ const (
 	NotificationTypeConnectionRequest  = "connection_request"
 	NotificationTypeConnectionAccepted = "connection_accepted"
 	NotificationTypeDocumentReceived   = "document_received"
+	NotificationTypeInventoryReminder  = "inventory_reminder"
+	NotificationTypeTransferComment    = "transfer_comment"
 	NotificationTypeGeneral            = "general"
 )
 
@@ -626,6 +641,7 @@ type NotificationService interface {
 	// Existing real-time methods
 	NotifyTransferUpdate(transfer *Transfer) error
 	NotifyTransferCreated(transfer *Transfer) error
+	NotifyTransferComment(comment *TransferComment, recipientIDs []uint) error
 	NotifyPropertyUpdate(property *Property) error
 	NotifyConnectionRequest(requesterID, targetUserID int) error
 	NotifyConnectionAccepted(acceptorID, requesterID int) error
@@ -642,6 +658,15 @@ type NotificationService interface {
 	MarkAllAsRead(userID int) error
 	DeleteNotification(userID, notificationID int) error
 	ClearOldNotifications(userID int, days int) (int64, error)
+
+	// Preferences
+	GetNotificationPreferences(userID uint) (*NotificationSettings, []NotificationPreference, error)
+	UpdateNotificationPreferences(userID uint, settings *NotificationSettings, preferences []NotificationPreference) error
+
+	// Mobile push devices
+	RegisterDeviceToken(userID uint, device *DeviceToken) error
+	UnregisterDeviceToken(userID uint, token string) error
+	GetDeviceTokens(userID uint) ([]DeviceToken, error)
 }
 
 // UserSearchFilters represents filters for searching users
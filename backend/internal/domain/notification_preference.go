@@ -0,0 +1,75 @@
+package domain
+
+import "time"
+
+// NotificationPreference controls how one type of notification reaches a user.
+// Types the user has not configured use DefaultNotificationPreference.
+type NotificationPreference struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	UserID           uint      `json:"userId" gorm:"column:user_id;not null;uniqueIndex:idx_notification_preferences_user_type"`
+	NotificationType string    `json:"notificationType" gorm:"column:notification_type;not null;uniqueIndex:idx_notification_preferences_user_type"`
+	InApp            bool      `json:"inApp" gorm:"column:in_app;not null"`         // Kept in the notification list
+	WebSocket        bool      `json:"webSocket" gorm:"column:web_socket;not null"` // Pushed live to open sessions
+	Email            bool      `json:"email" gorm:"column:email;not null"`
+	Push             bool      `json:"push" gorm:"column:push;not null"`              // Sent to registered mobile devices
+	DigestOnly       bool      `json:"digestOnly" gorm:"column:digest_only;not null"` // Held for the periodic digest instead of sent immediately
+	CreatedAt        time.Time `json:"createdAt" gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt        time.Time `json:"updatedAt" gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP"`
+}
+
+// NotificationSettings holds the delivery settings that apply to all of a user's
+// notifications. Quiet hours are wall-clock times in the user's timezone and may
+// span midnight.
+type NotificationSettings struct {
+	UserID            uint      `json:"userId" gorm:"column:user_id;primaryKey;autoIncrement:false"`
+	Timezone          string    `json:"timezone" gorm:"column:timezone;not null"`
+	QuietHoursEnabled bool      `json:"quietHoursEnabled" gorm:"column:quiet_hours_enabled;not null"`
+	QuietHoursStart   string    `json:"quietHoursStart" gorm:"column:quiet_hours_start;not null"` // HH:MM
+	QuietHoursEnd     string    `json:"quietHoursEnd" gorm:"column:quiet_hours_end;not null"`     // HH:MM
+	DigestFrequency   string    `json:"digestFrequency" gorm:"column:digest_frequency;not null"`
+	CreatedAt         time.Time `json:"createdAt" gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt         time.Time `json:"updatedAt" gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP"`
+}
+
+// ConfigurableNotificationTypes are the notification types a user can set preferences for
+var ConfigurableNotificationTypes = []string{
+	NotificationTypeTransferUpdate,
+	NotificationTypeTransferCreated,
+	NotificationTypeTransferComment,
+	NotificationTypePropertyUpdate,
+	NotificationTypeConnectionRequest,
+	NotificationTypeConnectionAccepted,
+	NotificationTypeDocumentReceived,
+	NotificationTypeInventoryReminder,
+	NotificationTypeGeneral,
+}
+
+// DefaultNotificationPreference is how a notification type is delivered until the
+// user changes it
+func DefaultNotificationPreference(userID uint, notificationType string) NotificationPreference {
+	return NotificationPreference{
+		UserID:           userID,
+		NotificationType: notificationType,
+		InApp:            true,
+		WebSocket:        true,
+		Push:             true,
+	}
+}
+
+// DefaultNotificationSettings are a user's settings until they change them
+func DefaultNotificationSettings(userID uint) NotificationSettings {
+	return NotificationSettings{
+		UserID:          userID,
+		Timezone:        "UTC",
+		QuietHoursStart: "22:00",
+		QuietHoursEnd:   "06:00",
+		DigestFrequency: DigestFrequencyDaily,
+	}
+}
+
+// Constants for how often unread notifications are rolled up into an email digest
+const (
+	DigestFrequencyNone   = "none"
+	DigestFrequencyDaily  = "daily"
+	DigestFrequencyWeekly = "weekly"
+)
@@ -0,0 +1,58 @@
+package domain
+
+import "time"
+
+// TransferRevision is a snapshot of a transfer taken before the sender amended and
+// resubmitted it, along with why the previous revision was rejected.
+type TransferRevision struct {
+	ID                uint      `json:"id" gorm:"primaryKey"`
+	TransferID        uint      `json:"transferId" gorm:"column:transfer_id;not null;index"`
+	Revision          int       `json:"revision" gorm:"not null"`
+	PropertyID        uint      `json:"propertyId" gorm:"column:property_id;not null"`
+	IncludeComponents bool      `json:"includeComponents" gorm:"column:include_components"`
+	Notes             *string   `json:"notes"`
+	Status            string    `json:"status" gorm:"not null"`
+	RejectionReason   *string   `json:"rejectionReason" gorm:"column:rejection_reason"`
+	AmendedByUserID   uint      `json:"amendedByUserId" gorm:"column:amended_by_user_id;not null"`
+	AmendmentNotes    *string   `json:"amendmentNotes" gorm:"column:amendment_notes"`
+	CreatedAt         time.Time `json:"createdAt" gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+
+	// Relationships
+	Property *Property `json:"property,omitempty" gorm:"foreignKey:PropertyID"`
+}
+
+// TransferComment is a comment on a transfer. Replies point at the comment they answer.
+type TransferComment struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	TransferID      uint      `json:"transferId" gorm:"column:transfer_id;not null;index"`
+	ParentCommentID *uint     `json:"parentCommentId" gorm:"column:parent_comment_id"`
+	AuthorUserID    uint      `json:"authorUserId" gorm:"column:author_user_id;not null"`
+	Body            string    `json:"body" gorm:"not null"`
+	CreatedAt       time.Time `json:"createdAt" gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+
+	// Relationships
+	Author  *User             `json:"author,omitempty" gorm:"foreignKey:AuthorUserID"`
+	Replies []TransferComment `json:"replies,omitempty" gorm:"-"`
+}
+
+// TransferApprover is a user, such as the supply sergeant or commander, added to a
+// transfer so they can follow and comment on it.
+type TransferApprover struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	TransferID    uint      `json:"transferId" gorm:"column:transfer_id;not null;uniqueIndex:idx_transfer_approver"`
+	UserID        uint      `json:"userId" gorm:"column:user_id;not null;uniqueIndex:idx_transfer_approver"`
+	AddedByUserID uint      `json:"addedByUserId" gorm:"column:added_by_user_id;not null"`
+	CreatedAt     time.Time `json:"createdAt" gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+
+	// Relationships
+	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// Constants for transfer rejection reasons
+const (
+	RejectionReasonWrongSerial       = "wrong_serial"
+	RejectionReasonDamaged           = "damaged"
+	RejectionReasonMissingComponents = "missing_components"
+	RejectionReasonNotMine           = "not_mine"
+	RejectionReasonOther             = "other"
+)
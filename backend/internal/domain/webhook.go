@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookSubscription sends hand receipt events for the owner's unit to an outside
+// URL. Requests are signed with the secret so the receiver can verify them.
+type WebhookSubscription struct {
+	ID          uint            `json:"id" gorm:"primaryKey"`
+	UserID      uint            `json:"userId" gorm:"column:user_id;not null;index"`
+	Unit        string          `json:"unit" gorm:"column:unit;not null;index"` // Events involving members of this unit are sent
+	URL         string          `json:"url" gorm:"column:url;not null"`
+	Secret      string          `json:"-" gorm:"column:secret;not null"`
+	EventTypes  JSONStringArray `json:"eventTypes" gorm:"column:event_types;type:jsonb;not null"` // Webhook event types, or "*" for all
+	Description string          `json:"description,omitempty" gorm:"column:description"`
+	Active      bool            `json:"active" gorm:"column:active;not null;default:true"`
+	CreatedAt   time.Time       `json:"createdAt" gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt   time.Time       `json:"updatedAt" gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP"`
+}
+
+// WebhookDelivery is one event queued for one subscription. A background job sends
+// it until the receiver accepts it or the job runs out of attempts and the delivery
+// is dead-lettered.
+type WebhookDelivery struct {
+	ID             uint            `json:"id" gorm:"primaryKey"`
+	SubscriptionID uint            `json:"subscriptionId" gorm:"column:subscription_id;not null;index"`
+	EventID        string          `json:"eventId" gorm:"column:event_id;not null"` // Same for every subscription the event went to
+	EventType      string          `json:"eventType" gorm:"column:event_type;not null"`
+	Payload        json.RawMessage `json:"payload" gorm:"column:payload;type:jsonb;not null"`
+	Status         string          `json:"status" gorm:"column:status;not null;default:'pending'"`
+	Attempts       int             `json:"attempts" gorm:"column:attempts;not null;default:0"`
+	JobID          *uint           `json:"jobId,omitempty" gorm:"column:job_id"` // Background job sending it; retries are scheduled there
+	LastStatusCode *int            `json:"lastStatusCode,omitempty" gorm:"column:last_status_code"`
+	LastError      *string         `json:"lastError,omitempty" gorm:"column:last_error"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty" gorm:"column:delivered_at"`
+	CreatedAt      time.Time       `json:"createdAt" gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt      time.Time       `json:"updatedAt" gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP"`
+
+	AttemptLog []WebhookDeliveryAttempt `json:"attemptLog,omitempty" gorm:"foreignKey:DeliveryID"`
+}
+
+// WebhookDeliveryAttempt records one request made for a delivery
+type WebhookDeliveryAttempt struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	DeliveryID uint      `json:"deliveryId" gorm:"column:delivery_id;not null;index"`
+	Attempt    int       `json:"attempt" gorm:"column:attempt;not null"`
+	StatusCode *int      `json:"statusCode,omitempty" gorm:"column:status_code"`
+	Error      *string   `json:"error,omitempty" gorm:"column:error"`
+	DurationMs int64     `json:"durationMs" gorm:"column:duration_ms;not null"`
+	CreatedAt  time.Time `json:"createdAt" gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+}
+
+// Constants for webhook delivery status
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryDead      = "dead" // Gave up after the last attempt
+)
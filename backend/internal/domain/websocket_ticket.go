@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// WebSocketTicket is a single-use credential for opening a WebSocket connection. Only
+// a hash of the ticket is stored.
+type WebSocketTicket struct {
+	ID                  uint       `json:"id" gorm:"primaryKey"`
+	TokenHash           string     `json:"-" gorm:"column:token_hash;not null;uniqueIndex"`
+	UserID              uint       `json:"userId" gorm:"column:user_id;not null"`
+	SessionID           string     `json:"-" gorm:"column:session_id"`            // Sign-in session the ticket was issued under
+	CredentialExpiresAt *time.Time `json:"-" gorm:"column:credential_expires_at"` // When the access token used to get the ticket expires
+	ExpiresAt           time.Time  `json:"expiresAt" gorm:"column:expires_at;not null;index"`
+	UsedAt              *time.Time `json:"usedAt,omitempty" gorm:"column:used_at"`
+	CreatedAt           time.Time  `json:"createdAt" gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+}
+
+func (WebSocketTicket) TableName() string { return "websocket_tickets" }
+
+// RevokedSession is a sign-in session that has been logged out
+type RevokedSession struct {
+	SessionID string    `json:"sessionId" gorm:"column:session_id;primaryKey"`
+	UserID    uint      `json:"userId" gorm:"column:user_id;not null"`
+	RevokedAt time.Time `json:"revokedAt" gorm:"column:revoked_at;not null;default:CURRENT_TIMESTAMP;index"`
+}
@@ -2,6 +2,8 @@ package ledger
 
 import (
 	"time"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
 )
 
 // DA2062Event represents a DA2062-specific ledger event
@@ -16,6 +18,23 @@ type DA2062Event struct {
 	Corrections   int       `json:"corrections"`
 	ProcessingMs  int64     `json:"processingMs"`
 	Timestamp     time.Time `json:"timestamp"`
+
+	// Set when a reviewed import is committed
+	ImportID      int64                         `json:"importId,omitempty"`
+	PropertyIDs   []uint                        `json:"propertyIds,omitempty"`
+	CorrectionLog []domain.DA2062ItemCorrection `json:"correctionLog,omitempty"`
+}
+
+// InventoryCountEvent records one item counted during an inventory campaign
+type InventoryCountEvent struct {
+	CampaignID    uint
+	CampaignType  string // cyclic or sensitive_item
+	PropertyID    uint
+	SerialNumber  string
+	UserID        uint   // Counter
+	Result        string // verified, discrepant or not_found
+	ScannedSerial *string
+	SerialMatched *bool
 }
 
 // PropertyEvent represents a property creation/update event
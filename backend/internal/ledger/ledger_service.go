@@ -31,6 +31,9 @@ type LedgerService interface {
 	// LogVerificationEvent logs a verification event for a property.
 	LogVerificationEvent(propertyID uint, serialNumber string, userID uint, verificationType string) error
 
+	// LogInventoryCount logs a counter's result for one property item in an inventory campaign.
+	LogInventoryCount(count InventoryCountEvent) error
+
 	// LogMaintenanceEvent logs a maintenance event for a property.
 	LogMaintenanceEvent(maintenanceRecordID string, propertyID uint, initiatingUserID uint, performingUserID sql.NullInt64, eventType string, maintenanceType sql.NullString, description string) error
 
@@ -43,6 +46,12 @@ type LedgerService interface {
 	// LogComponentDetached logs when a component is detached from a parent property.
 	LogComponentDetached(parentPropertyID uint, componentPropertyID uint, userID uint) error
 
+	// LogComponentTreeTransfer logs the attachments that moved intact with a parent property during a transfer.
+	LogComponentTreeTransfer(transferID uint, rootPropertyID uint, attachments []domain.PropertyComponent, fromUserID uint, toUserID uint) error
+
+	// LogLossCaseEvent logs a step in a loss, damage or destruction case against the property.
+	LogLossCaseEvent(caseID uint, caseNumber string, propertyID uint, serialNumber string, action string, userID uint, liabilityAmount float64) error
+
 	// LogDocumentEvent logs a document event (creation, read, etc.).
 	LogDocumentEvent(documentID uint, eventType string, senderUserID uint, recipientUserID uint) error
 
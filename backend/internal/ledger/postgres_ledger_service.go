@@ -20,8 +20,9 @@ var _ LedgerService = (*PostgresLedgerService)(nil)
 
 // PostgresLedgerService implements the LedgerService interface using PostgreSQL
 type PostgresLedgerService struct {
-	db  *gorm.DB
-	ctx context.Context
+	db       *gorm.DB
+	ctx      context.Context
+	onAppend []func(LedgerEntry) // Called after each entry is stored
 }
 
 // LedgerEntry represents an immutable ledger entry in PostgreSQL
@@ -187,9 +188,18 @@ func (s *PostgresLedgerService) storeEvent(eventID string, event map[string]inte
 	}
 
 	log.Printf("Successfully logged %s event to PostgreSQL Ledger", event["event_type"])
+	for _, listener := range s.onAppend {
+		listener(entry)
+	}
 	return nil
 }
 
+// AddAppendListener registers a function called with every entry appended to the
+// ledger, used to stream the ledger tail to live clients and to queue webhooks
+func (s *PostgresLedgerService) AddAppendListener(listener func(LedgerEntry)) {
+	s.onAppend = append(s.onAppend, listener)
+}
+
 // LogPropertyCreation logs an equipment creation/registration event
 func (s *PostgresLedgerService) LogPropertyCreation(property domain.Property, userID uint) error {
 	event := map[string]interface{}{
@@ -225,6 +235,12 @@ func (s *PostgresLedgerService) LogTransferEvent(transfer domain.Transfer, seria
 	if transfer.Notes != nil {
 		event["notes"] = *transfer.Notes
 	}
+	if transfer.RejectionReason != nil {
+		event["rejection_reason"] = *transfer.RejectionReason
+	}
+	if transfer.Revision > 1 {
+		event["revision"] = transfer.Revision
+	}
 
 	return s.storeEvent(fmt.Sprintf("transfer_%d_%d", transfer.ID, time.Now().Unix()), event)
 }
@@ -258,6 +274,28 @@ func (s *PostgresLedgerService) LogVerificationEvent(itemID uint, serialNumber s
 	return s.storeEvent(fmt.Sprintf("verification_%d_%d", itemID, time.Now().Unix()), event)
 }
 
+// LogInventoryCount logs an inventory campaign count against the property
+func (s *PostgresLedgerService) LogInventoryCount(count InventoryCountEvent) error {
+	event := map[string]interface{}{
+		"event_type":    "InventoryCount",
+		"item_id":       count.PropertyID,
+		"serial_number": count.SerialNumber,
+		"user_id":       count.UserID,
+		"campaign_id":   count.CampaignID,
+		"campaign_type": count.CampaignType,
+		"result":        count.Result,
+		"timestamp":     time.Now().UTC(),
+	}
+	if count.ScannedSerial != nil {
+		event["scanned_serial"] = *count.ScannedSerial
+	}
+	if count.SerialMatched != nil {
+		event["serial_matched"] = *count.SerialMatched
+	}
+
+	return s.storeEvent(fmt.Sprintf("inventory_count_%d_%d_%d", count.CampaignID, count.PropertyID, time.Now().Unix()), event)
+}
+
 // LogMaintenanceEvent logs a maintenance event
 func (s *PostgresLedgerService) LogMaintenanceEvent(maintenanceRecordID string, itemID uint, initiatingUserID uint, performingUserID sql.NullInt64, eventType string, maintenanceType sql.NullString, description string) error {
 	event := map[string]interface{}{
@@ -330,6 +368,48 @@ func (s *PostgresLedgerService) LogComponentDetached(parentPropertyID uint, comp
 	return s.storeEvent(fmt.Sprintf("component_detached_%d_%d_%d", parentPropertyID, componentPropertyID, time.Now().Unix()), event)
 }
 
+// LogComponentTreeTransfer records which attachments travelled with a property so the
+// component hierarchy can be reconstructed for the new holder.
+func (s *PostgresLedgerService) LogComponentTreeTransfer(transferID uint, rootPropertyID uint, attachments []domain.PropertyComponent, fromUserID uint, toUserID uint) error {
+	links := make([]map[string]interface{}, 0, len(attachments))
+	for _, attachment := range attachments {
+		links = append(links, map[string]interface{}{
+			"parent_property_id":    attachment.ParentPropertyID,
+			"component_property_id": attachment.ComponentPropertyID,
+		})
+	}
+
+	event := map[string]interface{}{
+		"event_type":   "ComponentTreeTransferred",
+		"transfer_id":  transferID,
+		"property_id":  rootPropertyID,
+		"attachments":  links,
+		"from_user_id": fromUserID,
+		"to_user_id":   toUserID,
+		"timestamp":    time.Now().UTC(),
+	}
+
+	return s.storeEvent(fmt.Sprintf("component_tree_%d_%d", transferID, time.Now().Unix()), event)
+}
+
+// LogLossCaseEvent records a FLIPL case step with the property at the top level, so the
+// case appears in the property's own history.
+func (s *PostgresLedgerService) LogLossCaseEvent(caseID uint, caseNumber string, propertyID uint, serialNumber string, action string, userID uint, liabilityAmount float64) error {
+	event := map[string]interface{}{
+		"event_type":       "LossCase",
+		"case_id":          caseID,
+		"case_number":      caseNumber,
+		"property_id":      propertyID,
+		"serial_number":    serialNumber,
+		"action":           action,
+		"user_id":          userID,
+		"liability_amount": liabilityAmount,
+		"timestamp":        time.Now().UTC(),
+	}
+
+	return s.storeEvent(fmt.Sprintf("loss_case_%d_%s_%d", caseID, action, time.Now().Unix()), event)
+}
+
 // LogDocumentEvent logs a document event (creation, read, etc.)
 func (s *PostgresLedgerService) LogDocumentEvent(documentID uint, eventType string, senderUserID uint, recipientUserID uint) error {
 	event := map[string]interface{}{
@@ -625,6 +705,11 @@ func (s *PostgresLedgerService) LogDA2062Import(ctx context.Context, event DA206
 		"processing_ms":  event.ProcessingMs,
 		"timestamp":      event.Timestamp,
 	}
+	if event.ImportID != 0 {
+		eventData["import_id"] = event.ImportID
+		eventData["property_ids"] = event.PropertyIDs
+		eventData["correction_log"] = event.CorrectionLog
+	}
 	
 	return s.storeEvent(fmt.Sprintf("da2062_import_%s_%d", event.FormNumber, time.Now().Unix()), eventData)
 }
@@ -18,6 +18,38 @@ type ImportMetadata struct {
 	RequiresVerification bool      `json:"requires_verification"`
 	VerificationReasons  []string  `json:"verification_reasons"`
 	SourceDocumentURL    string    `json:"source_document_url,omitempty"` // URL to original scanned form
+
+	// Findings explains each reason from cross-checking the item against PUB LOG
+	// and existing property
+	Findings []VerificationFinding `json:"findings,omitempty"`
+}
+
+// VerificationReason says why an imported item needs checking before it becomes property
+type VerificationReason string
+
+const (
+	VerificationLowConfidence        VerificationReason = "low_confidence"
+	VerificationMissingSerial        VerificationReason = "missing_serial_number"
+	VerificationNSNNotFound          VerificationReason = "nsn_not_found"
+	VerificationNSNNotChecked        VerificationReason = "nsn_not_checked" // PUB LOG could not be searched
+	VerificationNomenclatureMismatch VerificationReason = "nomenclature_mismatch"
+	VerificationUnitOfIssueMismatch  VerificationReason = "unit_of_issue_mismatch"
+	VerificationDuplicateSerial      VerificationReason = "duplicate_serial_in_import"
+	VerificationSerialOnHandReceipt  VerificationReason = "serial_on_hand_receipt"  // Already on the importer's hand receipt
+	VerificationSerialHeldByOther    VerificationReason = "serial_held_by_other"    // Already signed to someone else
+	VerificationNSNDiffersFromRecord VerificationReason = "nsn_differs_from_record" // Serial is held under another NSN
+	VerificationQuantityDiffers      VerificationReason = "quantity_differs_from_hand_receipt"
+)
+
+// VerificationFinding is one problem found with an imported item, with what the
+// form says and what the record it was checked against says
+type VerificationFinding struct {
+	Reason     VerificationReason `json:"reason"`
+	Field      string             `json:"field,omitempty"` // nsn, name, unit, serial_number or quantity
+	Message    string             `json:"message"`
+	Found      string             `json:"found,omitempty"`
+	Expected   string             `json:"expected,omitempty"`
+	PropertyID *uint              `json:"property_id,omitempty"` // Existing property the item conflicts with
 }
 
 // DA2062ImportItem represents an item being imported from a DA-2062 form
@@ -29,7 +61,8 @@ type DA2062ImportItem struct {
 	Quantity       int             `json:"quantity"`
 	Unit           string          `json:"unit"`
 	Category       string          `json:"category"`
-	SourceRef      string          `json:"source_ref"` // Reference to source document
+	SourceRef      string          `json:"source_ref"`            // Reference to source document
+	PageNumber     int             `json:"page_number,omitempty"` // Page of the source form the item was read from
 	ImportMetadata *ImportMetadata `json:"import_metadata,omitempty"`
 }
 
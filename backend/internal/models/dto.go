@@ -31,17 +31,18 @@ type ChangePasswordRequest struct {
 
 // User DTOs
 type UserDTO struct {
-	ID          uint       `json:"id"`
-	UUID        uuid.UUID  `json:"uuid"`
-	Email       string     `json:"email"`
-	FirstName   string     `json:"first_name"`
-	LastName    string     `json:"last_name"`
-	Rank        string     `json:"rank"`
-	Unit        string     `json:"unit"`
-	Status      UserStatus `json:"status"`
-	LastLoginAt *time.Time `json:"last_login_at"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	ID           uint       `json:"id"`
+	UUID         uuid.UUID  `json:"uuid"`
+	Email        string     `json:"email"`
+	FirstName    string     `json:"first_name"`
+	LastName     string     `json:"last_name"`
+	Rank         string     `json:"rank"`
+	Unit         string     `json:"unit"`
+	SupervisorID *uint      `json:"supervisor_id"`
+	Status       UserStatus `json:"status"`
+	LastLoginAt  *time.Time `json:"last_login_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
 }
 
 type CreateUserRequest struct {
@@ -54,12 +55,14 @@ type CreateUserRequest struct {
 }
 
 type UpdateUserRequest struct {
-	Email     *string     `json:"email,omitempty" validate:"omitempty,email"`
-	FirstName *string     `json:"first_name,omitempty" validate:"omitempty,min=2,max=50"`
-	LastName  *string     `json:"last_name,omitempty" validate:"omitempty,min=2,max=50"`
-	Rank      *string     `json:"rank,omitempty" validate:"omitempty,max=20"`
-	Unit      *string     `json:"unit,omitempty" validate:"omitempty,max=100"`
-	Status    *UserStatus `json:"status,omitempty" validate:"omitempty,oneof=active inactive suspended pending"`
+	Email     *string `json:"email,omitempty" validate:"omitempty,email"`
+	FirstName *string `json:"first_name,omitempty" validate:"omitempty,min=2,max=50"`
+	LastName  *string `json:"last_name,omitempty" validate:"omitempty,min=2,max=50"`
+	Rank      *string `json:"rank,omitempty" validate:"omitempty,max=20"`
+	Unit      *string `json:"unit,omitempty" validate:"omitempty,max=100"`
+	// Immediate supervisor in the chain of command
+	SupervisorID *uint       `json:"supervisor_id,omitempty"`
+	Status       *UserStatus `json:"status,omitempty" validate:"omitempty,oneof=active inactive suspended pending"`
 }
 
 type UserListResponse struct {
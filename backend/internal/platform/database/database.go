@@ -110,6 +110,30 @@ func Migrate(db *gorm.DB) error {
 		&domain.Document{},
 		&domain.CorrectionEvent{},
 		&domain.Notification{},
+		&domain.NotificationSequence{},
+		&domain.NotificationPreference{},
+		&domain.NotificationSettings{},
+		&domain.DeviceToken{},
+		&domain.WebSocketTicket{},
+		&domain.RevokedSession{},
+		&domain.WebhookSubscription{},
+		&domain.WebhookDelivery{},
+		&domain.WebhookDeliveryAttempt{},
+		&domain.Job{},
+		&domain.DA2062Import{},
+		&domain.DA2062ImportItem{},
+		&domain.DA2062ImportPage{},
+		&domain.DA2062ReconciliationAction{},
+		&domain.DA2062CorrectionExample{},
+		&domain.HandReceiptChange{},
+		&domain.HandReceiptChangeItem{},
+		&domain.InventoryCampaign{},
+		&domain.InventoryCampaignItem{},
+		&domain.LossCase{},
+		&domain.LossCaseAction{},
+		&domain.TransferRevision{},
+		&domain.TransferComment{},
+		&domain.TransferApprover{},
 	)
 }
 
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -8,6 +9,9 @@ import (
 	"github.com/toole-brendan/handreceipt-go/internal/publog/models"
 )
 
+// ErrNSNNotFound is returned when a stock number is not in the loaded data
+var ErrNSNNotFound = errors.New("NSN not found")
+
 // Store manages PUB LOG data in memory with indexing for fast searches
 type Store struct {
 	mu sync.RWMutex
@@ -137,7 +141,7 @@ func (s *Store) SearchByNSN(nsn string) (*models.SearchResult, error) {
 
 	item, exists := s.nsnItems[nsn]
 	if !exists {
-		return nil, fmt.Errorf("NSN not found: %s", nsn)
+		return nil, fmt.Errorf("%w: %s", ErrNSNNotFound, nsn)
 	}
 
 	return s.buildSearchResult(nsn, item), nil
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// SubordinateIDs returns the users below userID in the chain of command, following
+// supervisor links down. The user is not included. A cycle of supervisors ends
+// the walk rather than looping.
+func SubordinateIDs(db *gorm.DB, userID uint) ([]uint, error) {
+	var ids []uint
+	err := db.Raw(`
+		WITH RECURSIVE subordinates(id) AS (
+			SELECT id FROM users WHERE supervisor_id = ?
+			UNION
+			SELECT u.id FROM users u JOIN subordinates s ON u.supervisor_id = s.id
+		)
+		SELECT id FROM subordinates WHERE id <> ? ORDER BY id`, userID, userID).
+		Scan(&ids).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subordinates: %w", err)
+	}
+	return ids, nil
+}
+
+// InChainOfCommand reports whether leaderID is above userID in the chain of
+// command, following userID's supervisor links up. A user is not in their own
+// chain of command.
+func InChainOfCommand(db *gorm.DB, leaderID, userID uint) (bool, error) {
+	if leaderID == userID {
+		return false, nil
+	}
+	var found int64
+	err := db.Raw(`
+		WITH RECURSIVE supervisors(id) AS (
+			SELECT supervisor_id FROM users WHERE id = ? AND supervisor_id IS NOT NULL
+			UNION
+			SELECT u.supervisor_id FROM users u JOIN supervisors s ON u.id = s.id WHERE u.supervisor_id IS NOT NULL
+		)
+		SELECT COUNT(*) FROM supervisors WHERE id = ?`, userID, leaderID).
+		Scan(&found).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to check chain of command: %w", err)
+	}
+	return found > 0, nil
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrPropertyHeldByOtherUser is returned when a property that is part of a transfer
+// is no longer assigned to the transferring user.
+var ErrPropertyHeldByOtherUser = errors.New("property is held by another user")
+
+// TreeTransferPlan lists the ownership and attachment changes needed to hand a
+// property to a new holder without leaving a component tree split between users.
+type TreeTransferPlan struct {
+	RootPropertyID uint
+	Components     []domain.PropertyComponent // Attachments moving with the root, parents first
+	Detachments    []domain.PropertyComponent // Attachments broken because only part of a tree moves
+}
+
+// PropertyIDs returns the root property followed by every component moving with it
+func (p *TreeTransferPlan) PropertyIDs() []uint {
+	ids := []uint{p.RootPropertyID}
+	for _, component := range p.Components {
+		ids = append(ids, component.ComponentPropertyID)
+	}
+	return ids
+}
+
+// BuildTreeTransferPlan works out which properties move and which attachments break
+// when propertyID leaves fromUserID, given the attachment linking it to a parent
+// (nil if none) and the tree beneath it. With includeComponents the whole tree
+// moves and every component must still be held by fromUserID; otherwise the direct
+// components are detached and stay behind. A property that is itself attached to a
+// parent is always detached from it.
+func BuildTreeTransferPlan(propertyID, fromUserID uint, includeComponents bool, parentAttachment *domain.PropertyComponent, tree []domain.PropertyComponent) (*TreeTransferPlan, error) {
+	plan := &TreeTransferPlan{RootPropertyID: propertyID}
+	if parentAttachment != nil {
+		plan.Detachments = append(plan.Detachments, *parentAttachment)
+	}
+
+	if !includeComponents {
+		for _, attachment := range tree {
+			if attachment.ParentPropertyID == propertyID {
+				plan.Detachments = append(plan.Detachments, attachment)
+			}
+		}
+		return plan, nil
+	}
+
+	for _, attachment := range tree {
+		component := attachment.ComponentProperty
+		if component == nil {
+			return nil, fmt.Errorf("component property %d not found", attachment.ComponentPropertyID)
+		}
+		if component.AssignedToUserID == nil || *component.AssignedToUserID != fromUserID {
+			return nil, fmt.Errorf("%w: component %s (SN %s) attached to property %d",
+				ErrPropertyHeldByOtherUser, component.Name, component.SerialNumber, attachment.ParentPropertyID)
+		}
+	}
+	plan.Components = tree
+	return plan, nil
+}
+
+// loadComponentTree walks the component hierarchy beneath propertyID one level at a
+// time and returns every attachment found, parents before children.
+func loadComponentTree(db *gorm.DB, propertyID uint) ([]domain.PropertyComponent, error) {
+	var tree []domain.PropertyComponent
+	visited := map[uint]bool{propertyID: true}
+	level := []uint{propertyID}
+
+	for len(level) > 0 {
+		var attachments []domain.PropertyComponent
+		err := db.Where("parent_property_id IN ?", level).
+			Preload("ComponentProperty").
+			Order("parent_property_id, id").
+			Find(&attachments).Error
+		if err != nil {
+			return nil, err
+		}
+
+		level = nil
+		for _, attachment := range attachments {
+			// Guard against cycles left behind by bad data
+			if visited[attachment.ComponentPropertyID] {
+				continue
+			}
+			visited[attachment.ComponentPropertyID] = true
+			tree = append(tree, attachment)
+			level = append(level, attachment.ComponentPropertyID)
+		}
+	}
+
+	return tree, nil
+}
+
+// findComponentAttachment returns the attachment linking componentID to its parent,
+// or nil if the property is not attached to anything.
+func findComponentAttachment(db *gorm.DB, componentID uint) (*domain.PropertyComponent, error) {
+	var attachment domain.PropertyComponent
+	err := db.Where("component_property_id = ?", componentID).First(&attachment).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// completeTransfer saves the transfer, reassigns the transferred property and the
+// components moving with it from the transfer's sender to its recipient, and
+// removes the attachments the move breaks, inside a single transaction. The plan is
+// worked out inside the transaction: the property is locked first, then the
+// attachments and every property in the plan, so a concurrent transfer or
+// attachment change cannot move part of the tree underneath us.
+func completeTransfer(db *gorm.DB, transfer *domain.Transfer, also func(tx *gorm.DB) error) (*TreeTransferPlan, error) {
+	var plan *TreeTransferPlan
+	err := db.Transaction(func(tx *gorm.DB) error {
+		locking := clause.Locking{Strength: "UPDATE"}
+
+		var root domain.Property
+		if err := tx.Clauses(locking).Select("id").First(&root, transfer.PropertyID).Error; err != nil {
+			return err
+		}
+		parentAttachment, err := findComponentAttachment(tx.Clauses(locking), transfer.PropertyID)
+		if err != nil {
+			return fmt.Errorf("failed to check parent attachment: %w", err)
+		}
+		tree, err := loadComponentTree(tx.Clauses(locking), transfer.PropertyID)
+		if err != nil {
+			return fmt.Errorf("failed to get component tree: %w", err)
+		}
+
+		// Components are checked again below once their rows are locked
+		plan, err = BuildTreeTransferPlan(transfer.PropertyID, transfer.FromUserID, transfer.IncludeComponents, parentAttachment, tree)
+		if err != nil {
+			return err
+		}
+		propertyIDs := plan.PropertyIDs()
+
+		var properties []domain.Property
+		if err := tx.Clauses(locking).
+			Select("id", "assigned_to_user_id").
+			Where("id IN ?", propertyIDs).
+			Find(&properties).Error; err != nil {
+			return err
+		}
+		if len(properties) != len(propertyIDs) {
+			return gorm.ErrRecordNotFound
+		}
+
+		for _, property := range properties {
+			if property.AssignedToUserID == nil || *property.AssignedToUserID != transfer.FromUserID {
+				return fmt.Errorf("%w: property %d", ErrPropertyHeldByOtherUser, property.ID)
+			}
+		}
+
+		if err := tx.Model(&domain.Property{}).
+			Where("id IN ?", propertyIDs).
+			Updates(map[string]interface{}{
+				"assigned_to_user_id": transfer.ToUserID,
+				"updated_at":          time.Now().UTC(),
+			}).Error; err != nil {
+			return err
+		}
+
+		for _, detachment := range plan.Detachments {
+			if err := tx.Where("parent_property_id = ? AND component_property_id = ?", detachment.ParentPropertyID, detachment.ComponentPropertyID).
+				Delete(&domain.PropertyComponent{}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Omit(clause.Associations).Save(transfer).Error; err != nil {
+			return err
+		}
+		if also != nil {
+			return also(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestBuildTreeTransferPlan(t *testing.T) {
+	holder, other := uint(1), uint(2)
+	rifle := &domain.Property{ID: 10, Name: "RIFLE", SerialNumber: "W1", AssignedToUserID: &holder}
+	optic := &domain.Property{ID: 11, Name: "OPTIC", SerialNumber: "O1", AssignedToUserID: &holder}
+	mount := &domain.Property{ID: 12, Name: "MOUNT", SerialNumber: "M1", AssignedToUserID: &holder}
+	tree := []domain.PropertyComponent{
+		{ParentPropertyID: 10, ComponentPropertyID: 11, ComponentProperty: optic},
+		{ParentPropertyID: 11, ComponentPropertyID: 12, ComponentProperty: mount},
+	}
+	parent := &domain.PropertyComponent{ParentPropertyID: 9, ComponentPropertyID: 10, ComponentProperty: rifle}
+
+	// The whole tree moves and the rifle leaves the property it was attached to
+	plan, err := BuildTreeTransferPlan(10, holder, true, parent, tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := plan.PropertyIDs(); len(ids) != 3 || ids[0] != 10 || ids[1] != 11 || ids[2] != 12 {
+		t.Errorf("moving properties: %v", ids)
+	}
+	if len(plan.Detachments) != 1 || plan.Detachments[0].ParentPropertyID != 9 {
+		t.Errorf("detachments: %+v", plan.Detachments)
+	}
+
+	// Without components only the direct attachment is broken; the mount stays on the optic
+	plan, err = BuildTreeTransferPlan(10, holder, false, nil, tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Components) != 0 || len(plan.Detachments) != 1 || plan.Detachments[0].ComponentPropertyID != 11 {
+		t.Errorf("plan without components: %+v", plan)
+	}
+
+	// A component held by someone else blocks the whole tree
+	mount.AssignedToUserID = &other
+	if _, err := BuildTreeTransferPlan(10, holder, true, nil, tree); !errors.Is(err, ErrPropertyHeldByOtherUser) {
+		t.Errorf("expected ErrPropertyHeldByOtherUser, got %v", err)
+	}
+}
+
+// TestCompleteTransferMovesTree applies a tree transfer against a real database and
+// checks a component reassigned after the transfer was requested blocks it. Set
+// HANDRECEIPT_TEST_DATABASE_URL to run it.
+func TestCompleteTransferMovesTree(t *testing.T) {
+	db := openTestDB(t)
+	suffix := time.Now().UnixNano()
+
+	users := []domain.User{
+		{Email: fmt.Sprintf("from-%d@test.mil", suffix), PasswordHash: "x", FirstName: "A", LastName: "A", Rank: "SGT"},
+		{Email: fmt.Sprintf("to-%d@test.mil", suffix), PasswordHash: "x", FirstName: "B", LastName: "B", Rank: "SSG"},
+	}
+	if err := db.Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	from, to := users[0].ID, users[1].ID
+	properties := []domain.Property{
+		{Name: "RIFLE", SerialNumber: fmt.Sprintf("R-%d", suffix), CurrentStatus: "active", AssignedToUserID: &from},
+		{Name: "OPTIC", SerialNumber: fmt.Sprintf("O-%d", suffix), CurrentStatus: "active", AssignedToUserID: &from},
+	}
+	if err := db.Create(&properties).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Where("from_user_id = ?", from).Delete(&domain.Transfer{})
+		db.Where("parent_property_id = ?", properties[0].ID).Delete(&domain.PropertyComponent{})
+		db.Delete(&properties)
+		db.Delete(&users)
+	})
+	attachment := domain.PropertyComponent{ParentPropertyID: properties[0].ID, ComponentPropertyID: properties[1].ID, AttachedByUserID: from}
+	if err := db.Create(&attachment).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// The optic changed hands after the transfer was requested
+	if err := db.Model(&properties[1]).Update("assigned_to_user_id", to).Error; err != nil {
+		t.Fatal(err)
+	}
+	transfer := &domain.Transfer{PropertyID: properties[0].ID, FromUserID: from, ToUserID: to, Status: "accepted", IncludeComponents: true}
+	if _, err := completeTransfer(db, transfer, nil); !errors.Is(err, ErrPropertyHeldByOtherUser) {
+		t.Fatalf("expected ErrPropertyHeldByOtherUser, got %v", err)
+	}
+
+	if err := db.Model(&properties[1]).Update("assigned_to_user_id", from).Error; err != nil {
+		t.Fatal(err)
+	}
+	plan, err := completeTransfer(db, transfer, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Components) != 1 {
+		t.Errorf("plan: %+v", plan)
+	}
+	var moved int64
+	db.Model(&domain.Property{}).Where("id IN ? AND assigned_to_user_id = ?", plan.PropertyIDs(), to).Count(&moved)
+	if moved != 2 {
+		t.Errorf("expected rifle and optic with the recipient, %d moved", moved)
+	}
+}
+
+// openTestDB connects to the database named by HANDRECEIPT_TEST_DATABASE_URL, or
+// skips the test
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("HANDRECEIPT_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("Skipping database integration test - HANDRECEIPT_TEST_DATABASE_URL not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&domain.User{}, &domain.Property{}, &domain.PropertyComponent{}, &domain.Transfer{}); err != nil {
+		t.Fatalf("Failed to migrate tables: %v", err)
+	}
+	return db
+}
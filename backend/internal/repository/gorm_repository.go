@@ -466,6 +466,45 @@ func (r *gormRepository) UpdateComponentPosition(parentID, componentID uint, pos
 		Update("position", position).Error
 }
 
+// GetComponentTree retrieves every component nested beneath a property
+func (r *gormRepository) GetComponentTree(propertyID uint) ([]domain.PropertyComponent, error) {
+	return loadComponentTree(r.db, propertyID)
+}
+
+// GetComponentAttachment retrieves the attachment linking a component to its parent
+func (r *gormRepository) GetComponentAttachment(componentID uint) (*domain.PropertyComponent, error) {
+	return findComponentAttachment(r.db, componentID)
+}
+
+// CompleteTransfer saves an accepted transfer and moves the property tree in one transaction
+func (r *gormRepository) CompleteTransfer(transfer *domain.Transfer, also func(tx *gorm.DB) error) (*TreeTransferPlan, error) {
+	return completeTransfer(r.db, transfer, also)
+}
+
+func (r *gormRepository) AmendTransfer(transfer *domain.Transfer, previous *domain.TransferRevision) error {
+	return amendTransfer(r.db, transfer, previous)
+}
+
+func (r *gormRepository) ListTransferRevisions(transferID uint) ([]domain.TransferRevision, error) {
+	return listTransferRevisions(r.db, transferID)
+}
+
+func (r *gormRepository) CreateTransferComment(comment *domain.TransferComment) error {
+	return r.db.Create(comment).Error
+}
+
+func (r *gormRepository) ListTransferComments(transferID uint) ([]domain.TransferComment, error) {
+	return listTransferComments(r.db, transferID)
+}
+
+func (r *gormRepository) AddTransferApprover(approver *domain.TransferApprover) error {
+	return addTransferApprover(r.db, approver)
+}
+
+func (r *gormRepository) ListTransferApprovers(transferID uint) ([]domain.TransferApprover, error) {
+	return listTransferApprovers(r.db, transferID)
+}
+
 // --- Document Operations ---
 
 // CreateDocument creates a new document
@@ -432,6 +432,42 @@ func (r *PostgresRepository) UpdateComponentPosition(parentID, componentID uint,
 		Update("position", position).Error
 }
 
+func (r *PostgresRepository) GetComponentTree(propertyID uint) ([]domain.PropertyComponent, error) {
+	return loadComponentTree(r.db, propertyID)
+}
+
+func (r *PostgresRepository) GetComponentAttachment(componentID uint) (*domain.PropertyComponent, error) {
+	return findComponentAttachment(r.db, componentID)
+}
+
+func (r *PostgresRepository) CompleteTransfer(transfer *domain.Transfer, also func(tx *gorm.DB) error) (*TreeTransferPlan, error) {
+	return completeTransfer(r.db, transfer, also)
+}
+
+func (r *PostgresRepository) AmendTransfer(transfer *domain.Transfer, previous *domain.TransferRevision) error {
+	return amendTransfer(r.db, transfer, previous)
+}
+
+func (r *PostgresRepository) ListTransferRevisions(transferID uint) ([]domain.TransferRevision, error) {
+	return listTransferRevisions(r.db, transferID)
+}
+
+func (r *PostgresRepository) CreateTransferComment(comment *domain.TransferComment) error {
+	return r.db.Create(comment).Error
+}
+
+func (r *PostgresRepository) ListTransferComments(transferID uint) ([]domain.TransferComment, error) {
+	return listTransferComments(r.db, transferID)
+}
+
+func (r *PostgresRepository) AddTransferApprover(approver *domain.TransferApprover) error {
+	return addTransferApprover(r.db, approver)
+}
+
+func (r *PostgresRepository) ListTransferApprovers(transferID uint) ([]domain.TransferApprover, error) {
+	return listTransferApprovers(r.db, transferID)
+}
+
 // CheckUserConnection checks if two users are connected (same as AreUsersConnected)
 func (r *PostgresRepository) CheckUserConnection(userID1, userID2 uint) (bool, error) {
 	var count int64
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"gorm.io/gorm"
 )
 
 // Repository defines the interface for data access operations.
@@ -52,6 +53,19 @@ type Repository interface {
 	GetTransferByID(id uint) (*domain.Transfer, error)
 	UpdateTransfer(transfer *domain.Transfer) error
 	ListTransfers(userID uint, status *string) ([]domain.Transfer, error) // List transfers involving a user (from/to), optionally filter by status
+	// CompleteTransfer saves an accepted transfer and, in the same transaction, plans
+	// and applies the move of the property and its component tree to the recipient.
+	// When also is not nil it runs last in that transaction, so what it writes, such
+	// as follow-up jobs, commits only with the transfer. It returns the plan that was
+	// applied.
+	CompleteTransfer(transfer *domain.Transfer, also func(tx *gorm.DB) error) (*TreeTransferPlan, error)
+	// AmendTransfer stores the previous revision of a rejected transfer and saves the resubmitted one.
+	AmendTransfer(transfer *domain.Transfer, previous *domain.TransferRevision) error
+	ListTransferRevisions(transferID uint) ([]domain.TransferRevision, error)
+	CreateTransferComment(comment *domain.TransferComment) error
+	ListTransferComments(transferID uint) ([]domain.TransferComment, error)
+	AddTransferApprover(approver *domain.TransferApprover) error
+	ListTransferApprovers(transferID uint) ([]domain.TransferApprover, error)
 
 	// Property queries
 	GetPropertyBySerial(serialNumber string) (*domain.Property, error)
@@ -71,6 +85,8 @@ type Repository interface {
 	IsComponentAttached(componentID uint) (bool, error)
 	IsPositionOccupied(parentID uint, position string) (bool, error)
 	UpdateComponentPosition(parentID, componentID uint, position string) error
+	GetComponentTree(propertyID uint) ([]domain.PropertyComponent, error)      // All nested attachments, parents first
+	GetComponentAttachment(componentID uint) (*domain.PropertyComponent, error) // nil if the component is unattached
 
 	// Document operations
 	CreateDocument(document *domain.Document) error
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// amendTransfer records the snapshot of the previous revision and saves the
+// resubmitted transfer in one transaction.
+func amendTransfer(db *gorm.DB, transfer *domain.Transfer, previous *domain.TransferRevision) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(previous).Error; err != nil {
+			return err
+		}
+		return tx.Omit(clause.Associations).Save(transfer).Error
+	})
+}
+
+func listTransferRevisions(db *gorm.DB, transferID uint) ([]domain.TransferRevision, error) {
+	var revisions []domain.TransferRevision
+	err := db.Preload("Property").
+		Where("transfer_id = ?", transferID).
+		Order("revision ASC").
+		Find(&revisions).Error
+	return revisions, err
+}
+
+func listTransferComments(db *gorm.DB, transferID uint) ([]domain.TransferComment, error) {
+	var comments []domain.TransferComment
+	err := db.Preload("Author").
+		Where("transfer_id = ?", transferID).
+		Order("created_at ASC, id ASC").
+		Find(&comments).Error
+	return comments, err
+}
+
+func listTransferApprovers(db *gorm.DB, transferID uint) ([]domain.TransferApprover, error) {
+	var approvers []domain.TransferApprover
+	err := db.Preload("User").
+		Where("transfer_id = ?", transferID).
+		Order("created_at ASC").
+		Find(&approvers).Error
+	return approvers, err
+}
+
+// addTransferApprover is a no-op when the user already follows the transfer
+func addTransferApprover(db *gorm.DB, approver *domain.TransferApprover) error {
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(approver).Error
+}
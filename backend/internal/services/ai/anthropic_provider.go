@@ -0,0 +1,102 @@
+package ai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+)
+
+// AnthropicProvider parses forms with Claude
+type AnthropicProvider struct {
+	config ProviderConfig
+	client anthropic.Client
+}
+
+// NewAnthropicProvider creates a Claude provider. The API key falls back to the
+// ANTHROPIC_API_KEY environment variable.
+func NewAnthropicProvider(cfg ProviderConfig) (*AnthropicProvider, error) {
+	if cfg.APIKey == "" {
+		cfg.APIKey = os.Getenv("ANTHROPIC_API_KEY")
+	}
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("anthropic provider requires an API key")
+	}
+	if cfg.Model == "" {
+		cfg.Model = string(anthropic.ModelClaude3_5Sonnet20241022)
+	}
+	if cfg.MaxTokens == 0 {
+		cfg.MaxTokens = 4096
+	}
+
+	return &AnthropicProvider{
+		config: cfg,
+		// Timeouts and fallback are handled by the provider chain
+		client: anthropic.NewClient(option.WithAPIKey(cfg.APIKey), option.WithMaxRetries(0)),
+	}, nil
+}
+
+// Name implements VisionProvider
+func (p *AnthropicProvider) Name() string {
+	return ProviderAnthropic
+}
+
+// ExtractDA2062 implements VisionProvider
+func (p *AnthropicProvider) ExtractDA2062(ctx context.Context, page PDFPage) (*Extraction, error) {
+	response, err := p.client.Messages.New(ctx, anthropic.MessageNewParams{
+		Model:     anthropic.Model(p.config.Model),
+		MaxTokens: int64(p.config.MaxTokens),
+		Messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(
+				anthropicPageBlock(page),
+				anthropic.NewTextBlock(pagePrompt(page)),
+			),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Claude API error: %w", err)
+	}
+
+	usage := Usage{
+		InputTokens:  response.Usage.InputTokens,
+		OutputTokens: response.Usage.OutputTokens,
+	}
+	usage.CostUSD = p.config.cost(usage.InputTokens, usage.OutputTokens)
+
+	var responseText string
+	for _, content := range response.Content {
+		if content.Type == "text" && content.Text != "" {
+			responseText = content.Text
+			break
+		}
+	}
+	if responseText == "" {
+		return nil, fmt.Errorf("no text content in Claude response")
+	}
+
+	var parsed DA2062Response
+	if err := json.Unmarshal([]byte(extractJSON(responseText)), &parsed); err != nil {
+		log.Printf("Raw Claude response: %s", responseText)
+		return nil, fmt.Errorf("failed to parse Claude response: %w", err)
+	}
+	return newExtraction(parsed, page, usage), nil
+}
+
+// anthropicPageBlock sends a page's text layer when it has one and its image
+// otherwise
+func anthropicPageBlock(page PDFPage) anthropic.ContentBlockParamUnion {
+	if page.Text != "" {
+		return anthropic.NewTextBlock(pageTextPrompt(page))
+	}
+
+	b64Image := base64.StdEncoding.EncodeToString(page.Image)
+	if determineMediaType(page.ContentType) == "image/png" {
+		return anthropic.NewImageBlockBase64(string(anthropic.Base64ImageSourceMediaTypeImagePNG), b64Image)
+	}
+	return anthropic.NewImageBlockBase64(string(anthropic.Base64ImageSourceMediaTypeImageJPEG), b64Image)
+}
@@ -200,17 +200,23 @@ func (s *AzureOpenAIDA2062Service) SuggestCompletions(ctx context.Context, parti
 
 // callAPI makes the actual API call to Azure OpenAI
 func (s *AzureOpenAIDA2062Service) callAPI(ctx context.Context, body interface{}) ([]byte, error) {
+	content, _, err := s.callAPIWithUsage(ctx, body)
+	return content, err
+}
+
+// callAPIWithUsage makes the API call and also returns the tokens it used
+func (s *AzureOpenAIDA2062Service) callAPIWithUsage(ctx context.Context, body interface{}) ([]byte, Usage, error) {
 	url := fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=2024-02-15-preview",
 		s.config.Endpoint, s.config.Model)
 
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
-		return nil, err
+		return nil, Usage{}, err
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
 	if err != nil {
-		return nil, err
+		return nil, Usage{}, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -218,16 +224,16 @@ func (s *AzureOpenAIDA2062Service) callAPI(ctx context.Context, body interface{}
 
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, Usage{}, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return nil, fmt.Errorf("API returned status %d: failed to read response body: %w", resp.StatusCode, err)
+			return nil, Usage{}, fmt.Errorf("API returned status %d: failed to read response body: %w", resp.StatusCode, err)
 		}
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, Usage{}, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
 	}
 
 	// Extract the content from the response
@@ -237,17 +243,25 @@ func (s *AzureOpenAIDA2062Service) callAPI(ctx context.Context, body interface{}
 				Content string `json:"content"`
 			} `json:"message"`
 		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int64 `json:"prompt_tokens"`
+			CompletionTokens int64 `json:"completion_tokens"`
+		} `json:"usage"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
-		return nil, err
+		return nil, Usage{}, err
 	}
 
 	if len(apiResponse.Choices) == 0 {
-		return nil, fmt.Errorf("no response from AI")
+		return nil, Usage{}, fmt.Errorf("no response from AI")
 	}
 
-	return []byte(apiResponse.Choices[0].Message.Content), nil
+	usage := Usage{
+		InputTokens:  apiResponse.Usage.PromptTokens,
+		OutputTokens: apiResponse.Usage.CompletionTokens,
+	}
+	return []byte(apiResponse.Choices[0].Message.Content), usage, nil
 }
 
 // postProcessDA2062 applies DA 2062-specific post-processing
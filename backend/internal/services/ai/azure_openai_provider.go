@@ -0,0 +1,85 @@
+package ai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// AzureOpenAIProvider parses forms with a vision-capable Azure OpenAI deployment
+// such as gpt-4o
+type AzureOpenAIProvider struct {
+	config  ProviderConfig
+	service *AzureOpenAIDA2062Service
+}
+
+// NewAzureOpenAIProvider creates an Azure OpenAI provider. Model names the
+// deployment to call.
+func NewAzureOpenAIProvider(cfg ProviderConfig) (*AzureOpenAIProvider, error) {
+	if cfg.Endpoint == "" || cfg.APIKey == "" {
+		return nil, fmt.Errorf("azure_openai provider requires an endpoint and API key")
+	}
+	if cfg.Model == "" {
+		cfg.Model = "gpt-4o"
+	}
+	if cfg.MaxTokens == 0 {
+		cfg.MaxTokens = 4096
+	}
+
+	// No client timeout; each call runs under the provider chain's deadline
+	service, err := NewAzureOpenAIDA2062Service(Config{
+		Provider:  ProviderAzureOpenAI,
+		Endpoint:  cfg.Endpoint,
+		APIKey:    cfg.APIKey,
+		Model:     cfg.Model,
+		MaxTokens: cfg.MaxTokens,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &AzureOpenAIProvider{config: cfg, service: service}, nil
+}
+
+// Name implements VisionProvider
+func (p *AzureOpenAIProvider) Name() string {
+	return ProviderAzureOpenAI
+}
+
+// ExtractDA2062 implements VisionProvider
+func (p *AzureOpenAIProvider) ExtractDA2062(ctx context.Context, page PDFPage) (*Extraction, error) {
+	var pageContent map[string]interface{}
+	if page.Text != "" {
+		pageContent = map[string]interface{}{"type": "text", "text": pageTextPrompt(page)}
+	} else {
+		dataURL := fmt.Sprintf("data:%s;base64,%s", determineMediaType(page.ContentType), base64.StdEncoding.EncodeToString(page.Image))
+		pageContent = map[string]interface{}{"type": "image_url", "image_url": map[string]string{"url": dataURL}}
+	}
+
+	requestBody := map[string]interface{}{
+		"messages": []map[string]interface{}{
+			{
+				"role": "user",
+				"content": []map[string]interface{}{
+					pageContent,
+					{"type": "text", "text": pagePrompt(page)},
+				},
+			},
+		},
+		"temperature":     0,
+		"max_tokens":      p.config.MaxTokens,
+		"response_format": map[string]string{"type": "json_object"},
+	}
+
+	response, usage, err := p.service.callAPIWithUsage(ctx, requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("Azure OpenAI API error: %w", err)
+	}
+	usage.CostUSD = p.config.cost(usage.InputTokens, usage.OutputTokens)
+
+	var parsed DA2062Response
+	if err := json.Unmarshal([]byte(extractJSON(string(response))), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse Azure OpenAI response: %w", err)
+	}
+	return newExtraction(parsed, page, usage), nil
+}
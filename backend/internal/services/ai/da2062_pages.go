@@ -0,0 +1,447 @@
+package ai
+
+import (
+	"bytes"
+	"fmt"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/disintegration/imaging"
+	"github.com/ledongthuc/pdf"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+const (
+	// A page with fewer characters of text than this is treated as a scan
+	minTextLayerChars = 80
+
+	// Scanned pages larger than this are scaled down before being sent for parsing
+	maxPageImageBytes = 3 << 20
+	maxPageImageWidth = 2200
+)
+
+func init() {
+	// Keep pdfcpu from reading or writing a configuration directory
+	model.ConfigPath = "disable"
+}
+
+// PDFPage is one page of a PDF prepared for parsing. Pages with a text layer, as
+// exported from PBUSE or GCSS-Army, carry their text; scanned pages carry the
+// scanned image instead.
+type PDFPage struct {
+	Number      int
+	Text        string
+	Image       []byte
+	ContentType string // Content type of Image
+
+	// Why the page could not be prepared for parsing, such as a scan encoded in a
+	// format that cannot be decoded. Such a page has neither Text nor Image.
+	Unreadable string
+
+	// Profile of the importing unit's forms, if any has been learned
+	Profile *UnitProfile
+}
+
+// SplitPDF breaks a PDF into pages, reading each page's text layer where there is
+// one and extracting the scanned image where there is not. Pages with neither are
+// returned marked Unreadable, so the caller can report them rather than lose them.
+func SplitPDF(data []byte) ([]PDFPage, error) {
+	texts, err := pdfPageTexts(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var scanned []string
+	for i, text := range texts {
+		if countTextChars(text) < minTextLayerChars {
+			scanned = append(scanned, strconv.Itoa(i+1))
+		}
+	}
+
+	images := map[int]pageImage{}
+	failures := map[int]string{}
+	if len(scanned) > 0 {
+		images, failures, err = pdfPageImages(data, scanned)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var pages []PDFPage
+	readable := 0
+	for i, text := range texts {
+		number := i + 1
+		if countTextChars(text) >= minTextLayerChars {
+			pages = append(pages, PDFPage{Number: number, Text: text})
+			readable++
+			continue
+		}
+		image, ok := images[number]
+		if !ok {
+			reason, ok := failures[number]
+			if !ok {
+				reason = "page has no text layer or scanned image"
+			}
+			log.Printf("WARNING: DA 2062 PDF page %d cannot be read: %s", number, reason)
+			pages = append(pages, PDFPage{Number: number, Unreadable: reason})
+			continue
+		}
+		pages = append(pages, PDFPage{Number: number, Image: image.data, ContentType: image.contentType})
+		readable++
+	}
+
+	if readable == 0 {
+		return nil, fmt.Errorf("PDF has no readable pages")
+	}
+	return pages, nil
+}
+
+// SplitDA2062 breaks an uploaded form into the pages to parse. A photo or scan
+// uploaded as an image is a single page.
+func SplitDA2062(data []byte, contentType string) ([]PDFPage, error) {
+	if strings.Contains(contentType, "pdf") {
+		return SplitPDF(data)
+	}
+	return []PDFPage{{Number: 1, Image: data, ContentType: contentType}}, nil
+}
+
+// pdfPageTexts returns the text layer of every page, one line per row of text
+func pdfPageTexts(data []byte) (texts []string, err error) {
+	// The PDF reader panics on malformed input and unsupported filters
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to read PDF: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	texts = make([]string, reader.NumPage())
+	for i := range texts {
+		texts[i] = pdfPageText(reader.Page(i + 1))
+	}
+	return texts, nil
+}
+
+// pdfPageText reads one page's text layer, rebuilding rows from the position of
+// each character. A page whose text cannot be read is treated as having none, so
+// it is handled as a scan.
+func pdfPageText(page pdf.Page) (text string) {
+	defer func() {
+		if r := recover(); r != nil {
+			text = ""
+		}
+	}()
+	if page.V.IsNull() {
+		return ""
+	}
+
+	glyphs := page.Content().Text
+	// Top of the page first; characters on the same point line share a row
+	sort.SliceStable(glyphs, func(i, j int) bool {
+		if yi, yj := math.Round(glyphs[i].Y), math.Round(glyphs[j].Y); yi != yj {
+			return yi > yj
+		}
+		return glyphs[i].X < glyphs[j].X
+	})
+
+	var lines []string
+	var line strings.Builder
+	for i, glyph := range glyphs {
+		if i > 0 {
+			prev := glyphs[i-1]
+			if math.Round(glyph.Y) != math.Round(prev.Y) {
+				lines = append(lines, strings.TrimSpace(line.String()))
+				line.Reset()
+			} else if glyph.X-(prev.X+prev.W) > glyph.FontSize*0.2 && !strings.HasSuffix(line.String(), " ") {
+				line.WriteString(" ")
+			}
+		}
+		line.WriteString(glyph.S)
+	}
+	lines = append(lines, strings.TrimSpace(line.String()))
+
+	var rows []string
+	for _, l := range lines {
+		if l != "" {
+			rows = append(rows, l)
+		}
+	}
+	return strings.Join(rows, "\n")
+}
+
+type pageImage struct {
+	data        []byte
+	contentType string
+	pixels      int
+}
+
+// pdfPageImages extracts the scanned image of each selected page. When a page holds
+// several images the largest is taken as the scan. Pages whose only images cannot
+// be decoded, such as JBIG2 or CCITT fax scans, are returned in failures with the
+// reason.
+func pdfPageImages(data []byte, pages []string) (map[int]pageImage, map[int]string, error) {
+	conf := model.NewDefaultConfiguration()
+	conf.ValidationMode = model.ValidationRelaxed
+
+	extracted, err := api.ExtractImagesRaw(bytes.NewReader(data), pages, conf)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to extract PDF page images: %w", err)
+	}
+
+	images := make(map[int]pageImage)
+	failures := make(map[int]string)
+	for _, pageImages := range extracted {
+		for _, img := range pageImages {
+			if img.Thumb || img.IsImgMask {
+				continue
+			}
+			pixels := img.Width * img.Height
+			if current, ok := images[img.PageNr]; ok && current.pixels >= pixels {
+				continue
+			}
+			raw, err := io.ReadAll(img)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to read image on page %d: %w", img.PageNr, err)
+			}
+			data, contentType, err := normalizePageImage(raw, img.FileType)
+			if err != nil {
+				failures[img.PageNr] = fmt.Sprintf("scanned image (%s) could not be decoded: %v", imageEncoding(img), err)
+				continue
+			}
+			images[img.PageNr] = pageImage{data: data, contentType: contentType, pixels: pixels}
+		}
+	}
+	for page := range images {
+		delete(failures, page)
+	}
+	return images, failures, nil
+}
+
+// imageEncoding names how an extracted image is encoded, for error messages
+func imageEncoding(img model.Image) string {
+	if img.Filter != "" {
+		return img.Filter
+	}
+	if img.FileType != "" {
+		return img.FileType
+	}
+	return "unknown encoding"
+}
+
+// normalizePageImage converts an extracted image to JPEG or PNG, scaling down scans
+// too large to send for parsing
+func normalizePageImage(raw []byte, fileType string) ([]byte, string, error) {
+	if len(raw) <= maxPageImageBytes {
+		switch fileType {
+		case "jpg":
+			return raw, "image/jpeg", nil
+		case "png":
+			return raw, "image/png", nil
+		}
+	}
+
+	decoded, err := imaging.Decode(bytes.NewReader(raw), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, "", err
+	}
+	if decoded.Bounds().Dx() > maxPageImageWidth {
+		decoded = imaging.Resize(decoded, maxPageImageWidth, 0, imaging.Lanczos)
+	}
+
+	var buf bytes.Buffer
+	if len(raw) > maxPageImageBytes {
+		if err := jpeg.Encode(&buf, decoded, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	}
+	if err := png.Encode(&buf, decoded); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/png", nil
+}
+
+func countTextChars(text string) int {
+	count := 0
+	for _, r := range text {
+		if !unicode.IsSpace(r) {
+			count++
+		}
+	}
+	return count
+}
+
+// MergePageItems joins the items parsed from each page of a form into one list,
+// recording each item's page. A row carried over from the previous page, or
+// repeated at the top of the next, is folded into the item it continues, and an
+// item listed twice by serial number is kept once.
+func MergePageItems(pages [][]ParsedItem, pageNumbers []int) []ParsedItem {
+	var merged []ParsedItem
+	bySerial := make(map[string]int)
+
+	for p, items := range pages {
+		for i, item := range items {
+			item.Page = pageNumbers[p]
+
+			if i == 0 && p > 0 && len(merged) > 0 && isContinuationRow(merged[len(merged)-1], item) {
+				prev := &merged[len(merged)-1]
+				if extra, ok := mergeContinuationRow(prev, item); ok {
+					item = extra
+				} else {
+					if key := serialKey(*prev); key != "" {
+						bySerial[key] = len(merged) - 1
+					}
+					continue
+				}
+			}
+
+			if key := serialKey(item); key != "" {
+				if existing, ok := bySerial[key]; ok {
+					fillBlanks(&merged[existing], item)
+					continue
+				}
+				bySerial[key] = len(merged)
+			}
+			merged = append(merged, item)
+		}
+	}
+	return merged
+}
+
+// MergePageMeta takes each form field from the first page that has it
+func MergePageMeta(metas []ParseMeta) ParseMeta {
+	var meta ParseMeta
+	for _, m := range metas {
+		if meta.From == "" {
+			meta.From = m.From
+		}
+		if meta.To == "" {
+			meta.To = m.To
+		}
+		if meta.Date == "" {
+			meta.Date = m.Date
+		}
+		if meta.FormNumber == "" {
+			meta.FormNumber = m.FormNumber
+		}
+	}
+	return meta
+}
+
+// isContinuationRow reports whether the first row on a page continues the last row
+// of the page before: either the same line repeated, or a row with no stock number
+// holding only overflow description or serial numbers
+func isContinuationRow(prev, item ParsedItem) bool {
+	if item.NSN != "" {
+		return normalizeNSN(item.NSN) == normalizeNSN(prev.NSN) &&
+			normalizeText(item.Name) == normalizeText(prev.Name) &&
+			(item.SerialNumber == "" || prev.SerialNumber == "" || item.SerialNumber == prev.SerialNumber)
+	}
+	if prev.NSN == "" {
+		return false
+	}
+	name := stripContinuationMarker(item.Name)
+	return name == "" || name != item.Name
+}
+
+// mergeContinuationRow folds a continuation row into prev. A row carrying a further
+// serial number for the same line is a separate item; it is returned with the
+// line's stock number and description.
+func mergeContinuationRow(prev *ParsedItem, item ParsedItem) (ParsedItem, bool) {
+	if item.SerialNumber != "" && prev.SerialNumber != "" && item.SerialNumber != prev.SerialNumber {
+		extra := item
+		extra.NSN = prev.NSN
+		extra.Name = prev.Name
+		if extra.Quantity <= 0 {
+			extra.Quantity = 1
+		}
+		return extra, true
+	}
+
+	if text := stripContinuationMarker(item.Name); text != "" && normalizeText(text) != normalizeText(prev.Name) {
+		prev.Name = strings.TrimSpace(prev.Name + " " + text)
+	}
+	if prev.SerialNumber == "" {
+		prev.SerialNumber = item.SerialNumber
+	}
+	if item.Quantity > prev.Quantity {
+		prev.Quantity = item.Quantity
+	}
+	// A row split across pages is less certain than either half
+	if item.Confidence > 0 && item.Confidence < prev.Confidence {
+		prev.Confidence = item.Confidence
+	}
+	return ParsedItem{}, false
+}
+
+// fillBlanks completes an item from a duplicate listing of it, keeping the higher
+// confidence
+func fillBlanks(item *ParsedItem, duplicate ParsedItem) {
+	if item.Name == "" {
+		item.Name = duplicate.Name
+	}
+	if item.NSN == "" {
+		item.NSN = duplicate.NSN
+	}
+	if item.Quantity <= 0 {
+		item.Quantity = duplicate.Quantity
+	}
+	if duplicate.Confidence > item.Confidence {
+		item.Confidence = duplicate.Confidence
+	}
+}
+
+func serialKey(item ParsedItem) string {
+	serial := strings.ToUpper(strings.TrimSpace(item.SerialNumber))
+	if serial == "" || serial == "N/A" {
+		return ""
+	}
+	return normalizeNSN(item.NSN) + "|" + serial
+}
+
+// stripContinuationMarker removes a "continued" marker from a description. Markers
+// only count as whole words, so a description such as "DISCONTINUED" is kept.
+func stripContinuationMarker(name string) string {
+	text := strings.TrimSpace(name)
+	for _, marker := range []string{"(CONTINUED)", "(CONT'D)", "(CONTD)", "(CONT)", "CONTINUED", "CONT'D", "CONT."} {
+		upper := strings.ToUpper(text)
+		for from := 0; from < len(upper); {
+			i := strings.Index(upper[from:], marker)
+			if i < 0 {
+				break
+			}
+			i += from
+			end := i + len(marker)
+			if (i == 0 || !isWordChar(upper[i-1])) && (end == len(upper) || !isWordChar(upper[end])) {
+				text = strings.TrimSpace(text[:i] + text[end:])
+				break
+			}
+			from = i + 1
+		}
+	}
+	return text
+}
+
+func isWordChar(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func normalizeNSN(nsn string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(nsn)
+}
+
+func normalizeText(text string) string {
+	return strings.Join(strings.Fields(strings.ToUpper(text)), " ")
+}
@@ -0,0 +1,134 @@
+package ai
+
+import (
+	"reflect"
+	"testing"
+)
+
+const radioNSN = "5820-01-451-8250"
+
+func TestStripContinuationMarker(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"(CONT'D) AN/PRC-152", "AN/PRC-152"},
+		{"RADIO SET (CONTINUED)", "RADIO SET"},
+		{"cont'd antenna", "antenna"},
+		{"CONT. W/ BATTERY BOX", "W/ BATTERY BOX"},
+		{"(CONTD)", ""},
+		{"  CONTINUED  ", ""},
+		{"VALVE, DISCONTINUED", "VALVE, DISCONTINUED"},
+		{"CONTAINER, WATER", "CONTAINER, WATER"},
+		{"RIFLE, 5.56MM, M4", "RIFLE, 5.56MM, M4"},
+	}
+	for _, tt := range tests {
+		if got := stripContinuationMarker(tt.name); got != tt.want {
+			t.Errorf("stripContinuationMarker(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsContinuationRow(t *testing.T) {
+	radio := ParsedItem{NSN: radioNSN, Name: "RADIO SET", SerialNumber: "R1001", Quantity: 1}
+	tests := []struct {
+		name       string
+		prev, item ParsedItem
+		want       bool
+	}{
+		{"line repeated at the top of the page", radio, ParsedItem{NSN: "5820 01 451 8250", Name: "radio  set", SerialNumber: "R1001"}, true},
+		{"repeated line without its serial", radio, ParsedItem{NSN: radioNSN, Name: "RADIO SET"}, true},
+		{"same stock number with another serial", radio, ParsedItem{NSN: radioNSN, Name: "RADIO SET", SerialNumber: "R1002"}, false},
+		{"different stock number", radio, ParsedItem{NSN: "1005-01-231-0973", Name: "RIFLE, 5.56MM, M4"}, false},
+		{"marked overflow description", radio, ParsedItem{Name: "(CONT'D) AN/PRC-152"}, true},
+		{"overflow serial with no description", radio, ParsedItem{SerialNumber: "R1002"}, true},
+		{"unmarked row without a stock number", radio, ParsedItem{Name: "TOOL KIT"}, false},
+		{"previous line has no stock number", ParsedItem{Name: "TOOL KIT"}, ParsedItem{Name: "(CONT)"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isContinuationRow(tt.prev, tt.item); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergePageItems(t *testing.T) {
+	radio := ParsedItem{NSN: radioNSN, Name: "RADIO SET", SerialNumber: "R1001", Quantity: 1, Confidence: 0.9}
+	rifle := ParsedItem{NSN: "1005-01-231-0973", Name: "RIFLE, 5.56MM, M4", SerialNumber: "W123456", Quantity: 1, Confidence: 0.95}
+	onPage := func(item ParsedItem, page int) ParsedItem {
+		item.Page = page
+		return item
+	}
+
+	tests := []struct {
+		name    string
+		pages   [][]ParsedItem
+		numbers []int // Page numbers, when not 1, 2, ...
+		want    []ParsedItem
+	}{
+		{
+			name:  "separate lines on each page",
+			pages: [][]ParsedItem{{radio}, {rifle}},
+			want:  []ParsedItem{onPage(radio, 1), onPage(rifle, 2)},
+		},
+		{
+			name:  "description carried over to the next page",
+			pages: [][]ParsedItem{{radio}, {{Name: "(CONT'D) AN/PRC-152", Confidence: 0.7}, rifle}},
+			want: []ParsedItem{
+				{NSN: radioNSN, Name: "RADIO SET AN/PRC-152", SerialNumber: "R1001", Quantity: 1, Confidence: 0.7, Page: 1},
+				onPage(rifle, 2),
+			},
+		},
+		{
+			name:  "line repeated at the top of the next page",
+			pages: [][]ParsedItem{{radio}, {{NSN: radioNSN, Name: "RADIO SET", SerialNumber: "R1001", Quantity: 1, Confidence: 0.8}, rifle}},
+			want: []ParsedItem{
+				{NSN: radioNSN, Name: "RADIO SET", SerialNumber: "R1001", Quantity: 1, Confidence: 0.8, Page: 1},
+				onPage(rifle, 2),
+			},
+		},
+		{
+			name:  "serial number carried over to the next page",
+			pages: [][]ParsedItem{{radio}, {{SerialNumber: "R1002", Confidence: 0.85}}},
+			want: []ParsedItem{
+				onPage(radio, 1),
+				{NSN: radioNSN, Name: "RADIO SET", SerialNumber: "R1002", Quantity: 1, Confidence: 0.85, Page: 2},
+			},
+		},
+		{
+			name:  "quantity completed by the carried-over row",
+			pages: [][]ParsedItem{{{NSN: "8465-01-524-7632", Name: "TOOL KIT", Quantity: 1, Confidence: 0.9}}, {{Name: "CONTINUED", Quantity: 4, Confidence: 0.9}}},
+			want:  []ParsedItem{{NSN: "8465-01-524-7632", Name: "TOOL KIT", Quantity: 4, Confidence: 0.9, Page: 1}},
+		},
+		{
+			// Page 2 had no items, so the next page read is page 3
+			name:    "row without a stock number after a line without one",
+			pages:   [][]ParsedItem{{{Name: "TOOL KIT", Quantity: 1}}, {{Name: "(CONT) TOP TRAY", Quantity: 1}}},
+			numbers: []int{1, 3},
+			want:    []ParsedItem{{Name: "TOOL KIT", Quantity: 1, Page: 1}, {Name: "(CONT) TOP TRAY", Quantity: 1, Page: 3}},
+		},
+		{
+			name:  "serial listed again later in the form",
+			pages: [][]ParsedItem{{radio, rifle}, {{Name: "TOOL KIT", Quantity: 1}, {NSN: radioNSN, SerialNumber: "R1001", Confidence: 0.99}}},
+			want: []ParsedItem{
+				{NSN: radioNSN, Name: "RADIO SET", SerialNumber: "R1001", Quantity: 1, Confidence: 0.99, Page: 1},
+				onPage(rifle, 1),
+				{Name: "TOOL KIT", Quantity: 1, Page: 2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			numbers := tt.numbers
+			if numbers == nil {
+				for i := range tt.pages {
+					numbers = append(numbers, i+1)
+				}
+			}
+			if got := MergePageItems(tt.pages, numbers); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package ai
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ParsedItem represents a single item extracted from DA 2062
+type ParsedItem struct {
+	NSN          string     `json:"nsn"`
+	Name         string     `json:"name"`
+	SerialNumber string     `json:"serialNumber"`
+	Quantity     int        `json:"quantity"`
+	UnitOfIssue  string     `json:"unitOfIssue,omitempty"`
+	OwnerID      string     `json:"ownerId"`
+	AssignedToID string     `json:"assignedToId"`
+	Confidence   float64    `json:"confidence"`
+	Page         int        `json:"page,omitempty"` // Page of the form the item was read from
+	Row          *RowRegion `json:"row,omitempty"`  // Where the item's row is on a scanned page
+	// Where each field was read on a scanned page, keyed by the field's JSON name
+	Boxes map[string]FieldBox `json:"boxes,omitempty"`
+}
+
+// RowRegion is the band of a scanned page an item was read from, as fractions of
+// the page height measured from the top
+type RowRegion struct {
+	Top    float64 `json:"top"`
+	Bottom float64 `json:"bottom"`
+}
+
+// valid reports whether the region lies on the page
+func (r *RowRegion) valid() bool {
+	return r.Top >= 0 && r.Bottom <= 1 && r.Bottom > r.Top
+}
+
+// FieldBox is where on a scanned page a field was read, as fractions of the page
+// width and height measured from the top left corner
+type FieldBox struct {
+	Left   float64 `json:"left"`
+	Top    float64 `json:"top"`
+	Right  float64 `json:"right"`
+	Bottom float64 `json:"bottom"`
+}
+
+// valid reports whether the box lies on the page
+func (b FieldBox) valid() bool {
+	return b.Left >= 0 && b.Top >= 0 && b.Right <= 1 && b.Bottom <= 1 && b.Right > b.Left && b.Bottom > b.Top
+}
+
+// Fields of a parsed item that can have a box
+const (
+	FieldNSN          = "nsn"
+	FieldName         = "name"
+	FieldSerialNumber = "serialNumber"
+	FieldQuantity     = "quantity"
+	FieldUnitOfIssue  = "unitOfIssue"
+)
+
+// ParseMeta contains form-level metadata
+type ParseMeta struct {
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Date       string `json:"date"`
+	FormNumber string `json:"formNumber"`
+}
+
+// DA2062Response is the JSON every provider is asked to return for a page
+type DA2062Response struct {
+	Meta  ParseMeta    `json:"meta"`
+	Items []ParsedItem `json:"items"`
+}
+
+// da2062Prompt asks a provider for the items and form fields on one page of a DA 2062
+const da2062Prompt = `You are analyzing a DA Form 2062 (Hand Receipt/Annex Number). Extract all items from the form and return them as JSON.
+
+For each item, extract:
+- NSN (National Stock Number) - format: XXXX-XX-XXX-XXXX
+- Name/Description of the item
+- Serial number(s) if present
+- Quantity
+- Unit of issue (U/I column, e.g. EA, PR, SE)
+- Any other relevant details
+
+Also extract form metadata:
+- From (issuing unit/person)
+- To (receiving unit/person)
+- Date
+- Form number
+
+Return the data in this exact JSON format:
+{
+  "meta": {
+    "from": "string",
+    "to": "string",
+    "date": "YYYY-MM-DD",
+    "formNumber": "string"
+  },
+  "items": [
+    {
+      "nsn": "string or empty",
+      "name": "string",
+      "serialNumber": "string or empty",
+      "quantity": number,
+      "unitOfIssue": "string or empty",
+      "ownerId": "",
+      "assignedToId": "",
+      "confidence": 0.0-1.0,
+      "row": {"top": 0.0-1.0, "bottom": 0.0-1.0},
+      "boxes": {
+        "serialNumber": {"left": 0.0-1.0, "top": 0.0-1.0, "right": 0.0-1.0, "bottom": 0.0-1.0}
+      }
+    }
+  ]
+}
+
+For "row", give the top and bottom edges of the item's row on the page image as fractions of the image height, 0 being the top edge. For "boxes", give the bounding box of the text each field was read from, keyed by the field name ("nsn", "name", "serialNumber", "quantity", "unitOfIssue"), with edges as fractions of the image width and height from the top left corner. Include only fields you read. Leave "row" and "boxes" out when reading extracted text rather than an image.
+
+Be thorough and extract ALL items listed on the form. If you cannot read a field clearly, use an empty string but still include the item.`
+
+// UnitProfile is what reviewers' corrections of a unit's past imports say about its
+// forms. Pages carrying one are parsed with it added to the prompt.
+type UnitProfile struct {
+	Unit           string              `json:"unit"`
+	SerialPatterns []string            `json:"serialPatterns,omitempty"` // Regular expressions of common serial number formats
+	Nomenclature   []NomenclatureHint  `json:"nomenclature,omitempty"`
+	Examples       []CorrectionExample `json:"examples,omitempty"`
+}
+
+// NomenclatureHint is a name reviewers repeatedly gave an item
+type NomenclatureHint struct {
+	NSN   string `json:"nsn,omitempty"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// CorrectionExample is a value a reviewer corrected
+type CorrectionExample struct {
+	Field     string `json:"field"`
+	Read      string `json:"read"`
+	Corrected string `json:"corrected"`
+	NSN       string `json:"nsn,omitempty"`
+}
+
+// pagePrompt is da2062Prompt with the guidance learned from the page's unit profile
+func pagePrompt(page PDFPage) string {
+	if page.Profile == nil {
+		return da2062Prompt
+	}
+	mgr := NewDA2062PromptManager()
+	mgr.ApplyProfile(page.Profile)
+	return da2062Prompt + mgr.guidanceSection()
+}
+
+// pageTextPrompt introduces the text layer of a page sent in place of its image
+func pageTextPrompt(page PDFPage) string {
+	return fmt.Sprintf("Text extracted from page %d of the form, one line per row:\n\n%s", page.Number, page.Text)
+}
+
+// newExtraction records the page on each item a provider returned, with a default
+// confidence for items the provider did not score. Row regions and field boxes are
+// only kept for scanned pages, and only when they lie on the page.
+func newExtraction(parsed DA2062Response, page PDFPage, usage Usage) *Extraction {
+	for i := range parsed.Items {
+		parsed.Items[i].Page = page.Number
+		if parsed.Items[i].Confidence == 0 {
+			parsed.Items[i].Confidence = 0.85
+		}
+		if row := parsed.Items[i].Row; row != nil && (len(page.Image) == 0 || !row.valid()) {
+			parsed.Items[i].Row = nil
+		}
+		for field, box := range parsed.Items[i].Boxes {
+			if len(page.Image) == 0 || !box.valid() {
+				delete(parsed.Items[i].Boxes, field)
+			}
+		}
+		if len(parsed.Items[i].Boxes) == 0 {
+			parsed.Items[i].Boxes = nil
+		}
+	}
+	return &Extraction{Items: parsed.Items, Meta: parsed.Meta, Usage: usage}
+}
+
+// determineMediaType maps an upload content type to the image types providers accept
+func determineMediaType(contentType string) string {
+	switch {
+	case strings.Contains(contentType, "jpeg") || strings.Contains(contentType, "jpg"):
+		return "image/jpeg"
+	case strings.Contains(contentType, "png"):
+		return "image/png"
+	default:
+		// Default to JPEG if unknown
+		return "image/jpeg"
+	}
+}
+
+// extractJSON attempts to extract JSON from a text that might contain other text
+func extractJSON(text string) string {
+	// Find the first { and last }
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+
+	if start != -1 && end != -1 && end > start {
+		return text[start : end+1]
+	}
+
+	return text
+}
+
+// ValidateParsedItems validates the extracted items
+func ValidateParsedItems(items []ParsedItem) error {
+	if len(items) == 0 {
+		return fmt.Errorf("no items extracted")
+	}
+
+	for i, item := range items {
+		// Check for required fields
+		if item.Name == "" {
+			return fmt.Errorf("item %d missing name/description", i+1)
+		}
+
+		// Validate quantity
+		if item.Quantity <= 0 {
+			return fmt.Errorf("item %d has invalid quantity: %d", i+1, item.Quantity)
+		}
+
+		// Validate NSN format if provided
+		if item.NSN != "" && !isValidNSN(item.NSN) {
+			log.Printf("Warning: item %d has invalid NSN format: %s", i+1, item.NSN)
+		}
+	}
+
+	return nil
+}
+
+// isValidNSN checks if the NSN follows standard format
+func isValidNSN(nsn string) bool {
+	// Remove any spaces or dashes
+	cleaned := strings.ReplaceAll(nsn, "-", "")
+	cleaned = strings.ReplaceAll(cleaned, " ", "")
+
+	// NSN should be 13 digits
+	if len(cleaned) != 13 {
+		return false
+	}
+
+	// Check if all characters are digits
+	for _, c := range cleaned {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
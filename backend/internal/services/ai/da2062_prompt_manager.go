@@ -2,14 +2,30 @@ package ai
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
+// maxPromptCorrections caps how many past corrections are quoted in a prompt
+const maxPromptCorrections = 10
+
+// maxPromptValueLength bounds a learned value quoted in a prompt. Item names and
+// serial numbers are far shorter, so a longer value is not a reading of a form.
+const maxPromptValueLength = 80
+
 // DA2062PromptManager manages prompts specific to DA 2062 processing
 type DA2062PromptManager struct {
 	systemPrompt     string
 	parsingRules     string
 	generationPrompt string
 	examples         []DA2062Example
+
+	// Learned from reviewers' corrections of a unit's imports
+	serialPatterns []string
+	nomenclature   []NomenclatureHint
+	corrections    []CorrectionExample
 }
 
 // DA2062Example represents example items for training
@@ -213,10 +229,116 @@ func (m *DA2062PromptManager) GetGenerationPrompt() string {
 	return m.generationPrompt
 }
 
-// AddCustomSerialPatterns adds unit-specific serial number patterns
-// Currently a no-op as patterns are embedded in prompts, but kept for future extensibility
+// AddCustomSerialPatterns adds unit-specific serial number patterns, as regular
+// expressions. Patterns that do not compile or are too long are skipped.
 func (m *DA2062PromptManager) AddCustomSerialPatterns(patterns []string) {
-	// This can be extended to add unit-specific patterns
-	// For now, patterns are embedded in the prompts
-	_ = patterns // Acknowledge unused parameter
+	for _, pattern := range patterns {
+		cleaned, ok := promptValue(pattern)
+		if !ok || cleaned != pattern {
+			continue
+		}
+		if _, err := regexp.Compile(pattern); err == nil {
+			m.serialPatterns = append(m.serialPatterns, pattern)
+		}
+	}
+}
+
+// AddNomenclature adds item names a unit's forms use for stock numbers. Names come
+// from reviewers, so they are cleaned as promptValue does and overlong ones skipped.
+func (m *DA2062PromptManager) AddNomenclature(hints []NomenclatureHint) {
+	for _, hint := range hints {
+		name, ok := promptValue(hint.Name)
+		if !ok || name == "" {
+			continue
+		}
+		hint.Name = name
+		hint.NSN = NormalizeNSN(hint.NSN)
+		m.nomenclature = append(m.nomenclature, hint)
+	}
+}
+
+// AddCorrectionExamples adds values reviewers corrected on a unit's past imports,
+// to be quoted as few-shot examples. Values are cleaned as promptValue does, and
+// examples with an overlong value skipped.
+func (m *DA2062PromptManager) AddCorrectionExamples(examples []CorrectionExample) {
+	for _, example := range examples {
+		read, readOK := promptValue(example.Read)
+		corrected, correctedOK := promptValue(example.Corrected)
+		if !readOK || !correctedOK {
+			continue
+		}
+		example.Read, example.Corrected = read, corrected
+		example.NSN = NormalizeNSN(example.NSN)
+		m.corrections = append(m.corrections, example)
+	}
+}
+
+// promptValue makes a value a reviewer entered fit to quote in a prompt. Line
+// breaks and other control characters become single spaces, so the value cannot
+// start a line of its own that reads as an instruction. ok is false when the value
+// is too long to be a field of a form.
+func promptValue(value string) (cleaned string, ok bool) {
+	cleaned = strings.Join(strings.FieldsFunc(value, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	}), " ")
+	return cleaned, utf8.RuneCountInString(cleaned) <= maxPromptValueLength
+}
+
+// ApplyProfile adds everything learned about a unit's forms
+func (m *DA2062PromptManager) ApplyProfile(profile *UnitProfile) {
+	if profile == nil {
+		return
+	}
+	m.AddCustomSerialPatterns(profile.SerialPatterns)
+	m.AddNomenclature(profile.Nomenclature)
+	m.AddCorrectionExamples(profile.Examples)
+}
+
+// UnitGuidance describes what was learned about the unit's forms, or returns an
+// empty string when nothing has been
+func (m *DA2062PromptManager) UnitGuidance() string {
+	if len(m.serialPatterns) == 0 && len(m.nomenclature) == 0 && len(m.corrections) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("Reviewers have corrected earlier forms from this unit. Use what they found:\n")
+	if len(m.serialPatterns) > 0 {
+		b.WriteString("\nSerial numbers on this unit's forms usually match one of these regular expressions. Prefer a reading that matches, and check characters easily confused (0/O, 1/I, 5/S, 8/B) when one does not:\n")
+		for _, pattern := range m.serialPatterns {
+			fmt.Fprintf(&b, "- %s\n", pattern)
+		}
+	}
+	if len(m.nomenclature) > 0 {
+		b.WriteString("\nNomenclature this unit uses:\n")
+		for _, hint := range m.nomenclature {
+			if hint.NSN != "" {
+				fmt.Fprintf(&b, "- %s: %s\n", hint.NSN, hint.Name)
+			} else {
+				fmt.Fprintf(&b, "- %s\n", hint.Name)
+			}
+		}
+	}
+	if len(m.corrections) > 0 {
+		b.WriteString("\nPast misreadings and their corrections:\n")
+		for i, example := range m.corrections {
+			if i == maxPromptCorrections {
+				break
+			}
+			fmt.Fprintf(&b, "- %s read as %q, corrected to %q", example.Field, example.Read, example.Corrected)
+			if example.NSN != "" {
+				fmt.Fprintf(&b, " (NSN %s)", example.NSN)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// guidanceSection is UnitGuidance set off for appending to a prompt
+func (m *DA2062PromptManager) guidanceSection() string {
+	if guidance := m.UnitGuidance(); guidance != "" {
+		return "\n\n" + guidance
+	}
+	return ""
 }
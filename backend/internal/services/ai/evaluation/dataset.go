@@ -0,0 +1,92 @@
+// Package evaluation measures how well a DA 2062 parsing provider reads a golden
+// dataset of forms whose items are known.
+package evaluation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/toole-brendan/handreceipt-go/internal/services/ai"
+)
+
+// Case is one form of the golden dataset with the items a reader should find on it.
+// A case is a JSON file in the dataset directory naming the form file beside it:
+//
+//	{
+//	  "form": "hr-0213.png",
+//	  "description": "Scanned hand receipt, two rifles and a thermal sight",
+//	  "items": [
+//	    {"nsn": "1005-01-231-0973", "name": "RIFLE, 5.56MM, M4", "serialNumber": "W311742", "quantity": 1, "unitOfIssue": "EA"}
+//	  ]
+//	}
+//
+// The form is a PDF, a scanned or photographed image (.png, .jpg), or the text
+// layer of an exported PDF (.txt) with pages separated by form feeds.
+type Case struct {
+	Name        string          `json:"-"` // File name without the extension
+	Form        string          `json:"form"`
+	Description string          `json:"description,omitempty"`
+	Items       []ai.ParsedItem `json:"items"`
+
+	dir string
+}
+
+// LoadDataset reads every case in dir, in name order
+func LoadDataset(dir string) ([]Case, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	cases := make([]Case, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read case: %w", err)
+		}
+		var c Case
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, fmt.Errorf("failed to parse case %s: %w", path, err)
+		}
+		if c.Form == "" {
+			return nil, fmt.Errorf("case %s names no form", path)
+		}
+		c.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+		c.dir = dir
+		cases = append(cases, c)
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("no cases in %s", dir)
+	}
+	return cases, nil
+}
+
+// Pages splits the case's form into the pages a provider is given
+func (c Case) Pages() ([]ai.PDFPage, error) {
+	data, err := os.ReadFile(filepath.Join(c.dir, c.Form))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read form: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(c.Form)) {
+	case ".txt":
+		var pages []ai.PDFPage
+		for i, text := range strings.Split(string(data), "\f") {
+			pages = append(pages, ai.PDFPage{Number: i + 1, Text: text})
+		}
+		return pages, nil
+	case ".pdf":
+		return ai.SplitDA2062(data, "application/pdf")
+	case ".png":
+		return ai.SplitDA2062(data, "image/png")
+	case ".jpg", ".jpeg":
+		return ai.SplitDA2062(data, "image/jpeg")
+	default:
+		return nil, fmt.Errorf("unsupported form file %s", c.Form)
+	}
+}
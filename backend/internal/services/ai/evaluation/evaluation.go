@@ -0,0 +1,329 @@
+package evaluation
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/toole-brendan/handreceipt-go/internal/services/ai"
+)
+
+// Fields scored, in report order
+var scoredFields = []string{ai.FieldNSN, ai.FieldName, ai.FieldSerialNumber, ai.FieldQuantity, ai.FieldUnitOfIssue}
+
+// calibrationBins is how many equal ranges of confidence items are grouped into
+const calibrationBins = 10
+
+// FieldScore is how well one field was read across the dataset. A wrong value
+// counts against both precision and recall.
+type FieldScore struct {
+	Field          string  `json:"field"`
+	TruePositives  int     `json:"truePositives"`
+	FalsePositives int     `json:"falsePositives"` // Values read that were wrong or not on the form
+	FalseNegatives int     `json:"falseNegatives"` // Values on the form that were missed or misread
+	Precision      float64 `json:"precision"`
+	Recall         float64 `json:"recall"`
+	F1             float64 `json:"f1"`
+}
+
+// CalibrationBin compares the confidence a provider gave items in one range with
+// how often those items were right
+type CalibrationBin struct {
+	Low            float64 `json:"low"`
+	High           float64 `json:"high"`
+	Items          int     `json:"items"`
+	MeanConfidence float64 `json:"meanConfidence"`
+	Accuracy       float64 `json:"accuracy"` // Share of the items matched with every field right
+}
+
+// CaseResult is how one form was read
+type CaseResult struct {
+	Name     string `json:"name"`
+	Pages    int    `json:"pages"`
+	Expected int    `json:"expected"`
+	Parsed   int    `json:"parsed"`
+	Matched  int    `json:"matched"`
+	Correct  int    `json:"correct"` // Matched items with every field right
+	Error    string `json:"error,omitempty"`
+}
+
+// Report is the result of running a provider over a dataset
+type Report struct {
+	Provider string       `json:"provider"`
+	Cases    []CaseResult `json:"cases"`
+	Failed   int          `json:"failed"` // Cases the provider could not read
+	Fields   []FieldScore `json:"fields"`
+
+	SerialsExpected  int     `json:"serialsExpected"`
+	SerialsExact     int     `json:"serialsExact"`
+	SerialExactMatch float64 `json:"serialExactMatch"` // Share of expected serial numbers read exactly
+
+	Calibration []CalibrationBin `json:"calibration"`
+	// Expected calibration error: the gap between confidence and accuracy, weighted
+	// by the number of items in each bin
+	CalibrationError float64 `json:"calibrationError"`
+
+	Usage ai.Usage `json:"usage"`
+}
+
+// Run parses every case with provider, page by page and merged as an import
+// would be, and scores what was read against the expected items. A case that
+// cannot be read is reported, with its items counted as missed, and the run goes on.
+func Run(ctx context.Context, provider ai.VisionProvider, cases []Case) (*Report, error) {
+	report := &Report{Provider: provider.Name()}
+	scores := make(map[string]*FieldScore, len(scoredFields))
+	for _, field := range scoredFields {
+		scores[field] = &FieldScore{Field: field}
+	}
+	var judged []judgedItem
+
+	for _, c := range cases {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result := CaseResult{Name: c.Name, Expected: len(c.Items)}
+		parsed, err := parseCase(ctx, provider, c, &result, &report.Usage)
+		if err != nil {
+			result.Error = err.Error()
+			report.Failed++
+		}
+		result.Parsed = len(parsed)
+
+		pairs := matchItems(c.Items, parsed)
+		matched := make(map[int]int, len(pairs)) // Parsed index to expected index
+		for _, pair := range pairs {
+			matched[pair.parsed] = pair.expected
+		}
+		result.Matched = len(pairs)
+
+		// Values read, right or wrong
+		for i, item := range parsed {
+			var expected *ai.ParsedItem
+			if e, ok := matched[i]; ok {
+				expected = &c.Items[e]
+			}
+			correct := expected != nil
+			for _, field := range scoredFields {
+				got := fieldValue(item, field)
+				var want string
+				if expected != nil {
+					want = fieldValue(*expected, field)
+				}
+				switch {
+				case got != "" && got == want:
+					scores[field].TruePositives++
+				case got != "":
+					scores[field].FalsePositives++
+				}
+				if want != "" && got != want {
+					correct = false
+				}
+			}
+			if correct {
+				result.Correct++
+			}
+			judged = append(judged, judgedItem{confidence: item.Confidence, correct: correct})
+		}
+
+		// Values on the form that were not read right
+		expectedMatch := make(map[int]int, len(pairs))
+		for _, pair := range pairs {
+			expectedMatch[pair.expected] = pair.parsed
+		}
+		for e, item := range c.Items {
+			p, ok := expectedMatch[e]
+			for _, field := range scoredFields {
+				want := fieldValue(item, field)
+				if want == "" {
+					continue
+				}
+				if !ok || fieldValue(parsed[p], field) != want {
+					scores[field].FalseNegatives++
+				}
+			}
+
+			if serial := strings.TrimSpace(item.SerialNumber); serial != "" {
+				report.SerialsExpected++
+				if ok && strings.TrimSpace(parsed[p].SerialNumber) == serial {
+					report.SerialsExact++
+				}
+			}
+		}
+
+		report.Cases = append(report.Cases, result)
+	}
+
+	for _, field := range scoredFields {
+		report.Fields = append(report.Fields, scores[field].finish())
+	}
+	if report.SerialsExpected > 0 {
+		report.SerialExactMatch = float64(report.SerialsExact) / float64(report.SerialsExpected)
+	}
+	report.Calibration, report.CalibrationError = calibrate(judged)
+	return report, nil
+}
+
+// parseCase reads each page of the case's form and merges the pages' items
+func parseCase(ctx context.Context, provider ai.VisionProvider, c Case, result *CaseResult, usage *ai.Usage) ([]ai.ParsedItem, error) {
+	pages, err := c.Pages()
+	if err != nil {
+		return nil, err
+	}
+	result.Pages = len(pages)
+
+	items := make([][]ai.ParsedItem, 0, len(pages))
+	numbers := make([]int, 0, len(pages))
+	for _, page := range pages {
+		if page.Unreadable != "" {
+			return nil, fmt.Errorf("page %d cannot be read: %s", page.Number, page.Unreadable)
+		}
+		extraction, err := provider.ExtractDA2062(ctx, page)
+		if err != nil {
+			return nil, err
+		}
+		usage.InputTokens += extraction.Usage.InputTokens
+		usage.OutputTokens += extraction.Usage.OutputTokens
+		usage.CostUSD += extraction.Usage.CostUSD
+		items = append(items, extraction.Items)
+		numbers = append(numbers, page.Number)
+	}
+	return ai.MergePageItems(items, numbers), nil
+}
+
+func (s *FieldScore) finish() FieldScore {
+	if read := s.TruePositives + s.FalsePositives; read > 0 {
+		s.Precision = float64(s.TruePositives) / float64(read)
+	}
+	if present := s.TruePositives + s.FalseNegatives; present > 0 {
+		s.Recall = float64(s.TruePositives) / float64(present)
+	}
+	if s.Precision+s.Recall > 0 {
+		s.F1 = 2 * s.Precision * s.Recall / (s.Precision + s.Recall)
+	}
+	return *s
+}
+
+type itemPair struct {
+	expected, parsed int
+	score            int
+}
+
+// matchItems pairs each expected item with at most one parsed item, best matches
+// first. Items are only paired if their serial number, stock number or name agree.
+func matchItems(expected, parsed []ai.ParsedItem) []itemPair {
+	var candidates []itemPair
+	for e := range expected {
+		for p := range parsed {
+			if score := matchScore(expected[e], parsed[p]); score > 0 {
+				candidates = append(candidates, itemPair{expected: e, parsed: p, score: score})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	usedExpected := make(map[int]bool)
+	usedParsed := make(map[int]bool)
+	var pairs []itemPair
+	for _, candidate := range candidates {
+		if usedExpected[candidate.expected] || usedParsed[candidate.parsed] {
+			continue
+		}
+		usedExpected[candidate.expected] = true
+		usedParsed[candidate.parsed] = true
+		pairs = append(pairs, candidate)
+	}
+	return pairs
+}
+
+// matchScore weighs the identifying fields two items agree on, a serial number
+// most and the name least. Quantity and unit of issue only break ties.
+func matchScore(expected, parsed ai.ParsedItem) int {
+	score := 0
+	if v := fieldValue(expected, ai.FieldSerialNumber); v != "" && v == fieldValue(parsed, ai.FieldSerialNumber) {
+		score += 8
+	}
+	if v := fieldValue(expected, ai.FieldNSN); v != "" && v == fieldValue(parsed, ai.FieldNSN) {
+		score += 4
+	}
+	if v := fieldValue(expected, ai.FieldName); v != "" && v == fieldValue(parsed, ai.FieldName) {
+		score += 2
+	}
+	if score == 0 {
+		return 0
+	}
+	if v := fieldValue(expected, ai.FieldQuantity); v != "" && v == fieldValue(parsed, ai.FieldQuantity) {
+		score++
+	}
+	return score
+}
+
+// fieldValue is an item's field as it is compared: stock numbers without
+// separators, names in upper case without punctuation, serial numbers in upper
+// case. A missing value is empty.
+func fieldValue(item ai.ParsedItem, field string) string {
+	switch field {
+	case ai.FieldNSN:
+		return strings.NewReplacer("-", "", " ", "").Replace(item.NSN)
+	case ai.FieldName:
+		return strings.Join(strings.FieldsFunc(strings.ToUpper(item.Name), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.' && r != '/'
+		}), " ")
+	case ai.FieldSerialNumber:
+		return strings.ToUpper(strings.TrimSpace(item.SerialNumber))
+	case ai.FieldQuantity:
+		if item.Quantity <= 0 {
+			return ""
+		}
+		return strconv.Itoa(item.Quantity)
+	case ai.FieldUnitOfIssue:
+		return strings.ToUpper(strings.TrimSpace(item.UnitOfIssue))
+	}
+	return ""
+}
+
+type judgedItem struct {
+	confidence float64
+	correct    bool
+}
+
+// calibrate groups items into equal ranges of confidence and returns the bins
+// with items, and the expected calibration error over all of them
+func calibrate(items []judgedItem) ([]CalibrationBin, float64) {
+	if len(items) == 0 {
+		return nil, 0
+	}
+	bins := make([]CalibrationBin, calibrationBins)
+	correct := make([]int, calibrationBins)
+	for i := range bins {
+		bins[i].Low = float64(i) / calibrationBins
+		bins[i].High = float64(i+1) / calibrationBins
+	}
+	for _, item := range items {
+		i := int(item.confidence * calibrationBins)
+		i = int(math.Max(0, math.Min(float64(i), calibrationBins-1)))
+		bins[i].Items++
+		bins[i].MeanConfidence += item.confidence
+		if item.correct {
+			correct[i]++
+		}
+	}
+
+	var used []CalibrationBin
+	var gap float64
+	for i, bin := range bins {
+		if bin.Items == 0 {
+			continue
+		}
+		bin.MeanConfidence /= float64(bin.Items)
+		bin.Accuracy = float64(correct[i]) / float64(bin.Items)
+		gap += float64(bin.Items) * math.Abs(bin.Accuracy-bin.MeanConfidence)
+		used = append(used, bin)
+	}
+	return used, gap / float64(len(items))
+}
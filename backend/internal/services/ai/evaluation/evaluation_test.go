@@ -0,0 +1,160 @@
+package evaluation
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/toole-brendan/handreceipt-go/internal/services/ai"
+)
+
+// TestReplayGoldenDataset scores the recorded local provider responses offline, so
+// a change to merging or scoring that breaks the recorded run is caught
+func TestReplayGoldenDataset(t *testing.T) {
+	cases, err := LoadDataset("testdata/golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recording, err := ai.LoadRecording("testdata/recordings/local.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Run(context.Background(), ai.NewReplayProvider(recording), cases)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Provider != ai.ProviderLocal || report.Failed != 0 {
+		t.Fatalf("provider %s, %d cases failed: %+v", report.Provider, report.Failed, report.Cases)
+	}
+	if report.SerialsExpected != 6 || report.SerialExactMatch != 1 {
+		t.Errorf("serial numbers: %d of %d", report.SerialsExact, report.SerialsExpected)
+	}
+	for _, field := range report.Fields {
+		if field.F1 != 1 {
+			t.Errorf("%s: %+v", field.Field, field)
+		}
+	}
+
+	// A page without a recording fails its case, not the run
+	delete(recording.Pages, firstPageKey(t, cases[1]))
+	report, err = Run(context.Background(), ai.NewReplayProvider(recording), cases)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Failed != 1 || report.Cases[1].Error == "" {
+		t.Errorf("cases: %+v", report.Cases)
+	}
+}
+
+// scanRecording is a cloud provider's run over the scanned forms, which the local
+// provider cannot read without OCR installed. It is recorded from a live run:
+//
+//	go run ./cmd/parser-eval -provider anthropic -dataset internal/services/ai/evaluation/testdata/scans -record internal/services/ai/evaluation/testdata/recordings/anthropic-scans.json
+const scanRecording = "testdata/recordings/anthropic-scans.json"
+
+// TestReplayScannedDataset scores the recorded cloud provider responses for the
+// scanned forms, so the image path through splitting, merging and scoring is
+// checked offline
+func TestReplayScannedDataset(t *testing.T) {
+	cases, err := LoadDataset("testdata/scans")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cases {
+		pages, err := c.Pages()
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name, err)
+		}
+		if len(pages) == 0 || len(pages[0].Image) == 0 || pages[0].Text != "" {
+			t.Fatalf("%s: not read as a scanned image", c.Name)
+		}
+	}
+
+	recording, err := ai.LoadRecording(scanRecording)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Skipf("no recording at %s; record one with cmd/parser-eval", scanRecording)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Run(context.Background(), ai.NewReplayProvider(recording), cases)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Provider == ai.ProviderLocal || report.Provider == "" || report.Failed != 0 {
+		t.Fatalf("provider %q, %d cases failed: %+v", report.Provider, report.Failed, report.Cases)
+	}
+	if report.SerialsExpected != 3 || report.SerialExactMatch < 1 {
+		t.Errorf("serial numbers: %d of %d", report.SerialsExact, report.SerialsExpected)
+	}
+	for _, field := range report.Fields {
+		if field.F1 < 0.9 {
+			t.Errorf("%s: %+v", field.Field, field)
+		}
+	}
+}
+
+// TestRunScoresMisreads counts a misread serial number against precision and
+// recall, an item not on the form against precision, and a missed item against recall
+func TestRunScoresMisreads(t *testing.T) {
+	provider := ai.NewFakeProvider()
+	provider.SetPage(1, []ai.ParsedItem{
+		{NSN: "1005012310973", Name: "Rifle 5.56MM M4", SerialNumber: "W12345G", Quantity: 1, Confidence: 0.95},
+		{NSN: "8470-01-520-7373", Name: "HELMET, ADVANCED COMBAT", Quantity: 4, Confidence: 0.85},
+		{NSN: "6545-01-539-6444", Name: "IFAK", Quantity: 1, Confidence: 0.45},
+	}, ai.ParseMeta{})
+	c := Case{Name: "misreads", Items: []ai.ParsedItem{
+		{NSN: "1005-01-231-0973", Name: "RIFLE, 5.56MM, M4", SerialNumber: "W123456", Quantity: 1},
+		{NSN: "8470-01-520-7373", Name: "HELMET, ADVANCED COMBAT", Quantity: 4},
+		{NSN: "5855-01-534-5931", Name: "MONOCULAR, NIGHT VISION", SerialNumber: "104522", Quantity: 1},
+	}}
+	c.dir = t.TempDir()
+	c.Form = "misreads.txt"
+	if err := os.WriteFile(filepath.Join(c.dir, c.Form), []byte("scripted"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Run(context.Background(), provider, []Case{c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scores := make(map[string]FieldScore)
+	for _, field := range report.Fields {
+		scores[field.Field] = field
+	}
+
+	serial := scores[ai.FieldSerialNumber]
+	if serial.TruePositives != 0 || serial.FalsePositives != 1 || serial.FalseNegatives != 2 {
+		t.Errorf("serial number: %+v", serial)
+	}
+	nsn := scores[ai.FieldNSN]
+	if nsn.TruePositives != 2 || nsn.FalsePositives != 1 || nsn.FalseNegatives != 1 {
+		t.Errorf("NSN: %+v", nsn)
+	}
+	if report.SerialsExpected != 2 || report.SerialsExact != 0 {
+		t.Errorf("serial numbers: %d of %d", report.SerialsExact, report.SerialsExpected)
+	}
+
+	// The helmet alone was right
+	if len(report.Calibration) != 3 || report.Calibration[1].Accuracy != 1 || report.Calibration[2].Accuracy != 0 {
+		t.Errorf("calibration: %+v", report.Calibration)
+	}
+	if want := (0.45 + 0.15 + 0.95) / 3; math.Abs(report.CalibrationError-want) > 1e-9 {
+		t.Errorf("calibration error: got %f, want %f", report.CalibrationError, want)
+	}
+}
+
+func firstPageKey(t *testing.T, c Case) string {
+	t.Helper()
+	pages, err := c.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ai.PageKey(pages[0])
+}
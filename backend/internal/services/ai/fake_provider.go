@@ -0,0 +1,115 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// FakeProvider is a deterministic VisionProvider for tests and local development.
+// Unless a page is given a scripted result, it returns one item per page derived
+// from the page's content, so the same upload always parses the same way.
+type FakeProvider struct {
+	mu      sync.Mutex
+	pages   map[int]Extraction
+	errs    map[int]error
+	failAll error
+	delay   time.Duration
+	calls   int
+}
+
+// NewFakeProvider creates a fake provider with no scripted pages
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{
+		pages: make(map[int]Extraction),
+		errs:  make(map[int]error),
+	}
+}
+
+// SetPage scripts the result for a page number
+func (p *FakeProvider) SetPage(number int, items []ParsedItem, meta ParseMeta) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pages[number] = Extraction{Items: items, Meta: meta}
+}
+
+// FailPage makes the provider return err for a page number
+func (p *FakeProvider) FailPage(number int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.errs[number] = err
+}
+
+// FailAll makes the provider return err for every page; nil clears it
+func (p *FakeProvider) FailAll(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failAll = err
+}
+
+// SetDelay makes each call take d, or until its context ends
+func (p *FakeProvider) SetDelay(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.delay = d
+}
+
+// Calls returns how many pages the provider has been asked to parse
+func (p *FakeProvider) Calls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+// Name implements VisionProvider
+func (p *FakeProvider) Name() string {
+	return ProviderFake
+}
+
+// ExtractDA2062 implements VisionProvider
+func (p *FakeProvider) ExtractDA2062(ctx context.Context, page PDFPage) (*Extraction, error) {
+	p.mu.Lock()
+	p.calls++
+	delay, failAll, pageErr := p.delay, p.failAll, p.errs[page.Number]
+	scripted, isScripted := p.pages[page.Number]
+	p.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if failAll != nil {
+		return nil, failAll
+	}
+	if pageErr != nil {
+		return nil, pageErr
+	}
+
+	content := []byte(page.Text)
+	if page.Text == "" {
+		content = page.Image
+	}
+	usage := Usage{InputTokens: int64(len(content)/4 + 1), OutputTokens: 100}
+
+	if isScripted {
+		items := append([]ParsedItem(nil), scripted.Items...)
+		return newExtraction(DA2062Response{Items: items, Meta: scripted.Meta}, page, usage), nil
+	}
+
+	digest := sha256.Sum256(content)
+	item := ParsedItem{
+		NSN:          "1005-01-231-0973",
+		Name:         fmt.Sprintf("FAKE ITEM PAGE %d", page.Number),
+		SerialNumber: fmt.Sprintf("FAKE-%d-%s", page.Number, hex.EncodeToString(digest[:4])),
+		Quantity:     1,
+		Confidence:   0.9,
+	}
+	meta := ParseMeta{From: "FAKE UNIT", To: "FAKE RECIPIENT", Date: "2024-01-01", FormNumber: "FAKE-2062"}
+	return newExtraction(DA2062Response{Items: []ParsedItem{item}, Meta: meta}, page, usage), nil
+}
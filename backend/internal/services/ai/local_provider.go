@@ -0,0 +1,681 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/toole-brendan/handreceipt-go/internal/services/documents"
+	"github.com/toole-brendan/handreceipt-go/internal/services/ocr"
+)
+
+var (
+	nsnInTextPattern  = regexp.MustCompile(`\b\d{4}[- ]?\d{2}[- ]?\d{3}[- ]?\d{4}\b`)
+	serialPattern     = regexp.MustCompile(`(?i)\b(?:S/?N|SER(?:IAL)?(?:\s*NO\.?)?)\s*[:#.]?\s*([A-Z0-9][A-Z0-9-]{3,})`)
+	formDatePattern   = regexp.MustCompile(`(?i)\b(\d{4}-\d{2}-\d{2}|\d{1,2}/\d{1,2}/\d{2,4}|\d{1,2}\s?(?:JAN|FEB|MAR|APR|MAY|JUN|JUL|AUG|SEP|OCT|NOV|DEC)[A-Z]*\s?\d{2,4})\b`)
+	fromToLinePattern = regexp.MustCompile(`(?i)\bFROM:?\s*(.*?)\s*\bTO:\s*(.*)$`)
+)
+
+// unitsOfIssue are the U/I codes that end the description on a row read from a
+// text layer
+var unitsOfIssue = map[string]bool{
+	"EA": true, "PR": true, "SE": true, "KT": true, "BX": true, "PG": true, "CN": true,
+	"DZ": true, "RO": true, "HD": true, "LB": true, "FT": true, "YD": true, "GL": true,
+	"QT": true, "PT": true, "BT": true, "TU": true, "CO": true, "ST": true, "AY": true,
+	"SH": true, "BG": true,
+}
+
+// LocalProvider parses DA 2062 pages on the machine it runs on, with no network
+// calls, for units that cannot reach a cloud model. Scanned pages are read with a
+// local OCR engine and each word is placed in a column of the reference template;
+// exported PDFs are read from their text layer. Rows are then rebuilt with the
+// stock number and serial number rules and scored by DA2062ConfidenceScorer.
+type LocalProvider struct {
+	engine   ocr.Engine
+	template *documents.DA2062Template
+	scorer   *DA2062ConfidenceScorer
+	columns  []localColumn
+	labels   []string // Printed form text, normalized
+}
+
+type localColumn struct {
+	name  string
+	x     float64 // From the left margin, in millimetres
+	width float64
+}
+
+// localRow is one row of the table as read from the page, before it is turned
+// into an item
+type localRow struct {
+	stock       string
+	description string
+	unit        string                // Unit of issue, if it was one of unitsOfIssue
+	quantities  map[string]string     // Keyed by template column
+	confidence  float64               // Mean OCR confidence of the row's words, 0-1
+	region      *RowRegion            // Set for rows read from a scan
+	words       map[string][]ocr.Word // Words of each column, for rows read from a scan
+	width       int                   // Size of the scan in pixels
+	height      int
+}
+
+// NewLocalProvider creates the offline provider. OCRPath and OCRLanguage select the
+// Tesseract binary and language; without Tesseract only pages with a text layer
+// can be read.
+func NewLocalProvider(cfg ProviderConfig) (*LocalProvider, error) {
+	validator, err := documents.NewDA2062Validator()
+	if err != nil {
+		return nil, fmt.Errorf("local provider requires the DA 2062 reference template: %w", err)
+	}
+
+	var engine ocr.Engine
+	tesseract, err := ocr.NewTesseractEngine(cfg.OCRPath, cfg.OCRLanguage)
+	if err != nil {
+		log.Printf("WARNING: %v - the local form parser will only read PDFs with a text layer", err)
+	} else {
+		engine = tesseract
+	}
+	return newLocalProvider(engine, validator.GetTemplate()), nil
+}
+
+func newLocalProvider(engine ocr.Engine, template *documents.DA2062Template) *LocalProvider {
+	columns := make([]localColumn, 0, len(template.Sections.Table.Columns))
+	for name, col := range template.Sections.Table.Columns {
+		columns = append(columns, localColumn{name: name, x: col.X, width: col.Width})
+	}
+	sort.Slice(columns, func(i, j int) bool { return columns[i].x < columns[j].x })
+
+	var labels []string
+	for _, label := range template.Validation.RequiredText {
+		labels = append(labels, normalizeText(label))
+	}
+	for _, sig := range template.Sections.SignatureSection.Signatures {
+		labels = append(labels, normalizeText(sig.Label))
+	}
+
+	return &LocalProvider{
+		engine:   engine,
+		template: template,
+		scorer:   NewDA2062ConfidenceScorer(),
+		columns:  columns,
+		labels:   labels,
+	}
+}
+
+// Name implements VisionProvider
+func (p *LocalProvider) Name() string {
+	return ProviderLocal
+}
+
+// ExtractDA2062 implements VisionProvider. Nothing is metered, so usage is zero.
+func (p *LocalProvider) ExtractDA2062(ctx context.Context, page PDFPage) (*Extraction, error) {
+	var rows []localRow
+	var meta ParseMeta
+	if page.Text != "" {
+		rows, meta = p.readTextLayer(page.Text)
+	} else {
+		if p.engine == nil {
+			return nil, ocr.ErrEngineNotInstalled
+		}
+		scan, err := p.engine.Recognize(ctx, page.Image)
+		if err != nil {
+			return nil, fmt.Errorf("OCR failed: %w", err)
+		}
+		rows, meta = p.readScan(scan)
+	}
+	return newExtraction(DA2062Response{Items: p.buildItems(rows), Meta: meta}, page, Usage{}), nil
+}
+
+// readScan places OCR words on the template. The page is scaled from its width,
+// and shifted to line up with the STOCK NUMBER header when it can be found, since
+// scans are rarely cropped exactly to the form.
+func (p *LocalProvider) readScan(scan *ocr.Page) ([]localRow, ParseMeta) {
+	t := p.template
+	table := t.Sections.Table
+	scale := float64(scan.Width) / t.Dimensions.Width // Pixels per millimetre
+
+	var dx, dy float64
+	if anchor, ok := findStockHeader(scan.Words); ok {
+		stock := table.Columns["stock_number"]
+		center := (float64(anchor.left) + float64(anchor.right)) / 2 / scale
+		dx = center - (t.Margins.Left + stock.X + stock.Width/2)
+		// Header text sits about a millimetre below the top of its band
+		dy = float64(anchor.top)/scale - table.StartY - 1
+	}
+	bodyTop := table.StartY + table.HeaderHeight
+	bodyBottom := t.Sections.SignatureSection.Y
+
+	// Positions on the template, in millimetres from the page's top left corner
+	position := func(w ocr.Word) (float64, float64) {
+		return float64(w.CenterX())/scale - dx, float64(w.CenterY())/scale - dy
+	}
+
+	meta := p.scanMeta(scan.Words, position)
+
+	lines := make(map[ocr.LineKey][]ocr.Word)
+	for _, w := range scan.Words {
+		if _, y := position(w); y > bodyTop && y < bodyBottom {
+			lines[w.Line] = append(lines[w.Line], w)
+		}
+	}
+	ordered := make([][]ocr.Word, 0, len(lines))
+	for _, words := range lines {
+		sort.Slice(words, func(i, j int) bool { return words[i].Left < words[j].Left })
+		ordered = append(ordered, words)
+	}
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i][0].Top < ordered[j][0].Top })
+
+	var rows []localRow
+	for _, words := range ordered {
+		cells := make(map[string][]string)
+		cellWords := make(map[string][]ocr.Word)
+		total := 0.0
+		top, bottom := words[0].Top, words[0].Top+words[0].Height
+		for _, w := range words {
+			x, _ := position(w)
+			name := p.columnAt(x - t.Margins.Left)
+			cells[name] = append(cells[name], w.Text)
+			cellWords[name] = append(cellWords[name], w)
+			total += w.Confidence
+			if w.Top < top {
+				top = w.Top
+			}
+			if w.Top+w.Height > bottom {
+				bottom = w.Top + w.Height
+			}
+		}
+		row := localRow{
+			stock:       strings.Join(cells["stock_number"], " "),
+			description: strings.Join(cells["item_description"], " "),
+			quantities:  make(map[string]string),
+			confidence:  total / float64(len(words)) / 100,
+			words:       cellWords,
+			width:       scan.Width,
+			height:      scan.Height,
+		}
+		if scan.Height > 0 {
+			row.region = &RowRegion{Top: float64(top) / float64(scan.Height), Bottom: float64(bottom) / float64(scan.Height)}
+		}
+		if unit := strings.ToUpper(strings.Join(cells["ui"], "")); unitsOfIssue[unit] {
+			row.unit = unit
+		}
+		for name, texts := range cells {
+			if name == "qty_auth" || strings.HasPrefix(name, "quantity_") {
+				row.quantities[name] = strings.Join(texts, "")
+			}
+		}
+		if p.isFormText(row.stock+" "+row.description) || (row.stock == "" && row.description == "") {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, meta
+}
+
+// scanMeta reads the form fields above the table from their places on the template
+func (p *LocalProvider) scanMeta(words []ocr.Word, position func(ocr.Word) (float64, float64)) ParseMeta {
+	sections := p.template.Sections
+	fromTo := sections.FromToSection
+
+	var from, to, header []string
+	titleLines := make(map[ocr.LineKey]bool)
+	for _, w := range words {
+		x, y := position(w)
+		switch {
+		case y >= fromTo.Y && y < fromTo.Y+fromTo.Height:
+			label := strings.ToUpper(strings.TrimRight(w.Text, ":"))
+			if label == "FROM" || label == "TO" {
+				continue
+			}
+			if x >= fromTo.FromField.X && x < fromTo.ToLabel.X {
+				from = append(from, w.Text)
+			} else if x >= fromTo.ToLabel.X {
+				to = append(to, w.Text)
+			}
+		case y < fromTo.Y && x < sections.Header.FormNumber.X:
+			header = append(header, w.Text)
+			if strings.Contains(strings.ToUpper(w.Text), "ANNEX") {
+				titleLines[w.Line] = true
+			}
+		}
+	}
+
+	var title []string
+	for _, w := range words {
+		if titleLines[w.Line] {
+			if x, _ := position(w); x < sections.Header.FormNumber.X {
+				title = append(title, w.Text)
+			}
+		}
+	}
+
+	meta := ParseMeta{
+		From:       strings.Join(from, " "),
+		To:         strings.Join(to, " "),
+		FormNumber: p.formNumber(strings.Join(title, " ")),
+	}
+	if date := formDatePattern.FindString(strings.Join(header, " ")); date != "" {
+		meta.Date = date
+	}
+	return meta
+}
+
+// readTextLayer rebuilds rows from an exported PDF's text, one line per row. Lines
+// before the first stock number hold the form fields; lines without one after it
+// continue the row above.
+func (p *LocalProvider) readTextLayer(text string) ([]localRow, ParseMeta) {
+	var rows []localRow
+	var meta ParseMeta
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		loc := nsnInTextPattern.FindStringIndex(line)
+		if loc == nil && len(rows) == 0 {
+			p.readMetaLine(line, &meta)
+			continue
+		}
+		if p.isFormText(line) {
+			continue
+		}
+
+		row := localRow{confidence: 1, quantities: make(map[string]string)}
+		rest := line
+		if loc != nil {
+			row.stock = line[loc[0]:loc[1]]
+			rest = line[loc[1]:]
+		}
+
+		// Trailing counts are QTY AUTH then columns A-F, blank cells dropped
+		fields := strings.Fields(rest)
+		var counts []string
+		for len(fields) > 0 {
+			if _, ok := parseCount(fields[len(fields)-1]); !ok {
+				break
+			}
+			counts = append([]string{fields[len(fields)-1]}, counts...)
+			fields = fields[:len(fields)-1]
+		}
+		if len(counts) > 0 && len(fields) > 0 && unitsOfIssue[strings.ToUpper(fields[len(fields)-1])] {
+			row.unit = strings.ToUpper(fields[len(fields)-1])
+			fields = fields[:len(fields)-1]
+			// A one-letter security code may come before the unit of issue
+			if len(fields) > 1 && len(fields[len(fields)-1]) == 1 {
+				fields = fields[:len(fields)-1]
+			}
+		}
+		if len(counts) > 0 {
+			row.quantities["qty_auth"] = counts[0]
+		}
+		if len(counts) > 1 {
+			row.quantities["quantity_a"] = counts[1]
+		}
+		row.description = strings.Join(fields, " ")
+		rows = append(rows, row)
+	}
+	return rows, meta
+}
+
+func (p *LocalProvider) readMetaLine(line string, meta *ParseMeta) {
+	if m := fromToLinePattern.FindStringSubmatch(line); m != nil {
+		if meta.From == "" {
+			meta.From = strings.TrimSpace(m[1])
+		}
+		if meta.To == "" {
+			meta.To = strings.TrimSpace(m[2])
+		}
+		return
+	}
+	if meta.FormNumber == "" {
+		meta.FormNumber = p.formNumber(line)
+	}
+	if meta.Date == "" {
+		if !strings.Contains(strings.ToUpper(line), "DA FORM") {
+			meta.Date = formDatePattern.FindString(line)
+		}
+	}
+}
+
+// formNumber returns the hand receipt number written after the form's title
+func (p *LocalProvider) formNumber(line string) string {
+	title := strings.ToUpper(p.template.Sections.Header.Title.Text)
+	upper := strings.ToUpper(line)
+	i := strings.Index(upper, title)
+	if i < 0 {
+		return ""
+	}
+	rest := strings.TrimSpace(upper[i+len(title):])
+	if j := strings.Index(rest, "DA FORM"); j >= 0 {
+		rest = rest[:j]
+	}
+	if fields := strings.Fields(strings.TrimLeft(rest, ":# ")); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// isFormText reports whether a line is printed form text rather than an entry:
+// column headers, signature labels or the page number
+func (p *LocalProvider) isFormText(line string) bool {
+	text := normalizeText(line)
+	if text == "" {
+		return true
+	}
+	for _, label := range p.labels {
+		if strings.Contains(text, label) {
+			return true
+		}
+	}
+	if page, _, _ := strings.Cut(p.template.Sections.PageNumber.Text, "_"); page != "" && strings.HasPrefix(text, normalizeText(page)) {
+		return true
+	}
+	return strings.HasPrefix(text, "STOCK NUMBER") || text == "A. B." || strings.HasPrefix(text, "A. B. C.")
+}
+
+// columnAt names the template column at x millimetres from the left margin. Words
+// outside the table belong to the nearest edge column.
+func (p *LocalProvider) columnAt(x float64) string {
+	for _, col := range p.columns {
+		if x < col.x+col.width {
+			return col.name
+		}
+	}
+	return p.columns[len(p.columns)-1].name
+}
+
+type localItem struct {
+	item       ParsedItem
+	confidence float64
+}
+
+// buildItems turns rows into items. A row with no stock number continues the item
+// above it: a further serial number there is another item of the same line, and
+// anything else is more of its description.
+func (p *LocalProvider) buildItems(rows []localRow) []ParsedItem {
+	var built []localItem
+	for _, row := range rows {
+		nsn := NormalizeNSN(row.stock)
+		description := row.description
+		nsnText := row.stock
+		if nsn == "" {
+			if m := nsnInTextPattern.FindString(description); m != "" {
+				nsn = NormalizeNSN(m)
+				description = strings.Replace(description, m, "", 1)
+				nsnText = m
+			}
+		}
+
+		if row.stock == "" && nsn == "" && len(built) > 0 {
+			prev := &built[len(built)-1]
+			prev.confidence = math.Min(prev.confidence, row.confidence)
+			prev.item.Row = extendRegion(prev.item.Row, row.region)
+			serial, rest := splitSerial(description, prev.item.NSN, len(strings.Fields(description)) <= 2)
+			switch {
+			case serial != "" && prev.item.SerialNumber != "" && serial != prev.item.SerialNumber:
+				extra := prev.item
+				extra.SerialNumber = serial
+				extra.Quantity = 1
+				extra.Row = row.region
+				extra.Boxes = nil
+				if prev.item.Boxes != nil {
+					// The extra serial number shares the line's other fields
+					extra.Boxes = make(map[string]FieldBox)
+					for field, box := range prev.item.Boxes {
+						if field != FieldSerialNumber && field != FieldQuantity {
+							extra.Boxes[field] = box
+						}
+					}
+				}
+				setBox(extra.Boxes, FieldSerialNumber, row.box(row.words["item_description"], serial))
+				built = append(built, localItem{item: extra, confidence: row.confidence})
+			case serial != "" && prev.item.SerialNumber == "":
+				prev.item.SerialNumber = serial
+				prev.item.Name = joinDescription(prev.item.Name, rest)
+				setBox(prev.item.Boxes, FieldSerialNumber, row.box(row.words["item_description"], serial))
+			default:
+				prev.item.Name = joinDescription(prev.item.Name, description)
+				if box, ok := prev.item.Boxes[FieldName]; ok {
+					if more := row.box(row.words["item_description"], ""); more != nil {
+						prev.item.Boxes[FieldName] = unionBox(box, *more)
+					}
+				}
+			}
+			continue
+		}
+
+		serial, name := splitSerial(description, nsn, RequiresSerialNumber(nsn))
+		item := ParsedItem{
+			NSN:          nsn,
+			Name:         name,
+			SerialNumber: serial,
+			Quantity:     rowQuantity(row),
+			UnitOfIssue:  row.unit,
+			Row:          row.region,
+			Boxes:        row.fieldBoxes(nsn, nsnText, serial),
+		}
+		if item.Quantity == 0 && serial != "" {
+			item.Quantity = 1
+		}
+		built = append(built, localItem{item: item, confidence: row.confidence})
+	}
+
+	items := make([]ParsedItem, len(built))
+	for i, b := range built {
+		score := p.scorer.ScoreItem(ParsedDA2062Item{
+			NSN:          b.item.NSN,
+			Description:  b.item.Name,
+			Quantity:     b.item.Quantity,
+			SerialNumber: b.item.SerialNumber,
+		})
+		// Discount the rules' score by how sure the OCR engine was of the words
+		b.item.Confidence = math.Max(0.01, math.Round(score.Overall*b.confidence*100)/100)
+		items[i] = b.item
+	}
+	return items
+}
+
+// fieldBoxes finds where each of an item's fields was read on a scan. It returns nil
+// for rows read from a text layer.
+func (row localRow) fieldBoxes(nsn, nsnText, serial string) map[string]FieldBox {
+	if row.words == nil || row.height == 0 || row.width == 0 {
+		return nil
+	}
+	boxes := make(map[string]FieldBox)
+	description := row.words["item_description"]
+	if nsn != "" {
+		if row.stock != "" {
+			setBox(boxes, FieldNSN, row.box(row.words["stock_number"], ""))
+		} else {
+			setBox(boxes, FieldNSN, row.box(description, nsnText))
+		}
+	}
+	setBox(boxes, FieldName, row.box(description, ""))
+	if serial != "" {
+		setBox(boxes, FieldSerialNumber, row.box(description, serial))
+	}
+	for _, column := range []string{"quantity_a", "qty_auth"} {
+		if _, ok := parseCount(row.quantities[column]); ok {
+			setBox(boxes, FieldQuantity, row.box(row.words[column], ""))
+			break
+		}
+	}
+	if row.unit != "" {
+		setBox(boxes, FieldUnitOfIssue, row.box(row.words["ui"], ""))
+	}
+	if len(boxes) == 0 {
+		return nil
+	}
+	return boxes
+}
+
+// box bounds the words that spell text, or all the words when text is empty, as
+// fractions of the scan. It returns nil if no words match.
+func (row localRow) box(words []ocr.Word, text string) *FieldBox {
+	if text != "" {
+		words = wordsSpelling(words, text)
+	}
+	if len(words) == 0 || row.width == 0 || row.height == 0 {
+		return nil
+	}
+	left, top := words[0].Left, words[0].Top
+	right, bottom := left+words[0].Width, top+words[0].Height
+	for _, w := range words[1:] {
+		if w.Left < left {
+			left = w.Left
+		}
+		if w.Top < top {
+			top = w.Top
+		}
+		if w.Left+w.Width > right {
+			right = w.Left + w.Width
+		}
+		if w.Top+w.Height > bottom {
+			bottom = w.Top + w.Height
+		}
+	}
+	width, height := float64(row.width), float64(row.height)
+	return &FieldBox{Left: float64(left) / width, Top: float64(top) / height, Right: float64(right) / width, Bottom: float64(bottom) / height}
+}
+
+// wordsSpelling returns the shortest run of words whose text, run together,
+// contains text
+func wordsSpelling(words []ocr.Word, text string) []ocr.Word {
+	text = strings.ToUpper(strings.Join(strings.Fields(text), ""))
+	for i := range words {
+		joined := ""
+		for j := i; j < len(words); j++ {
+			joined += strings.ToUpper(words[j].Text)
+			if !strings.Contains(joined, text) {
+				continue
+			}
+			// Drop leading words, such as an S/N marker, the match does not need
+			for i < j && strings.Contains(strings.ToUpper(wordsText(words[i+1:j+1])), text) {
+				i++
+			}
+			return words[i : j+1]
+		}
+	}
+	return nil
+}
+
+func wordsText(words []ocr.Word) string {
+	var b strings.Builder
+	for _, w := range words {
+		b.WriteString(w.Text)
+	}
+	return b.String()
+}
+
+func setBox(boxes map[string]FieldBox, field string, box *FieldBox) {
+	if boxes != nil && box != nil {
+		boxes[field] = *box
+	}
+}
+
+func unionBox(a, b FieldBox) FieldBox {
+	return FieldBox{
+		Left:   math.Min(a.Left, b.Left),
+		Top:    math.Min(a.Top, b.Top),
+		Right:  math.Max(a.Right, b.Right),
+		Bottom: math.Max(a.Bottom, b.Bottom),
+	}
+}
+
+// extendRegion widens an item's row to take in a continuation row below it
+func extendRegion(region, more *RowRegion) *RowRegion {
+	if region == nil || more == nil {
+		return region
+	}
+	return &RowRegion{Top: math.Min(region.Top, more.Top), Bottom: math.Max(region.Bottom, more.Bottom)}
+}
+
+// rowQuantity takes column A, the quantity on hand, falling back to the quantity
+// authorized
+func rowQuantity(row localRow) int {
+	for _, column := range []string{"quantity_a", "qty_auth"} {
+		if n, ok := parseCount(row.quantities[column]); ok {
+			return n
+		}
+	}
+	return 0
+}
+
+// splitSerial finds the serial number in a description, returning it and the
+// description without it. Without an S/N marker the equipment patterns are only
+// tried when guess is set, as a bare model number is easily mistaken for one.
+func splitSerial(description, nsn string, guess bool) (string, string) {
+	if loc := serialPattern.FindStringSubmatchIndex(description); loc != nil {
+		serial := strings.ToUpper(description[loc[2]:loc[3]])
+		return serial, cleanDescription(description[:loc[0]] + description[loc[1]:])
+	}
+	if !guess {
+		return "", cleanDescription(description)
+	}
+
+	serial := extractDA2062SerialNumber(description, identifyMilitaryEquipmentType(nsn, description))
+	if serial == "" || strings.Contains(serial, "/") || !strings.ContainsAny(serial, "0123456789") {
+		return "", cleanDescription(description)
+	}
+	i := strings.Index(strings.ToUpper(description), serial)
+	if i < 0 {
+		return serial, cleanDescription(description)
+	}
+	return serial, cleanDescription(description[:i] + description[i+len(serial):])
+}
+
+func cleanDescription(text string) string {
+	return strings.Trim(strings.Join(strings.Fields(text), " "), " ,;")
+}
+
+func joinDescription(name, more string) string {
+	more = cleanDescription(stripContinuationMarker(more))
+	if more == "" {
+		return name
+	}
+	return cleanDescription(name + " " + more)
+}
+
+// parseCount reads a quantity cell, allowing for the letters OCR commonly returns
+// in place of 0 and 1
+func parseCount(text string) (int, bool) {
+	text = strings.Trim(text, " .,|")
+	if text == "" {
+		return 0, false
+	}
+	text = strings.NewReplacer("O", "0", "o", "0", "l", "1", "I", "1").Replace(text)
+	n, err := strconv.Atoi(text)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// stockHeader is the extent of the STOCK NUMBER column header on a scan, in pixels
+type stockHeader struct {
+	left, right, top int
+}
+
+// findStockHeader locates the STOCK NO. or STOCK NUMBER header, which is centred
+// over its column
+func findStockHeader(words []ocr.Word) (stockHeader, bool) {
+	for i, w := range words {
+		if strings.ToUpper(strings.Trim(w.Text, ".:")) != "STOCK" {
+			continue
+		}
+		header := stockHeader{left: w.Left, right: w.Left + w.Width, top: w.Top}
+		if i+1 < len(words) && words[i+1].Line == w.Line {
+			next := strings.ToUpper(strings.Trim(words[i+1].Text, ".:"))
+			if next == "NO" || next == "NUMBER" {
+				header.right = words[i+1].Left + words[i+1].Width
+			}
+		}
+		return header, true
+	}
+	return stockHeader{}, false
+}
@@ -0,0 +1,141 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/toole-brendan/handreceipt-go/internal/services/documents"
+	"github.com/toole-brendan/handreceipt-go/internal/services/ocr"
+)
+
+// TestLocalProviderTextLayer reads rows, serial numbers and form fields from an
+// exported PDF's text
+func TestLocalProviderTextLayer(t *testing.T) {
+	provider := newLocalProvider(nil, loadTestTemplate(t))
+	text := `HAND RECEIPT/ANNEX NUMBER HR-0042 DA FORM 2062
+FROM: CPT SMITH TO: SGT JONES
+15 MAR 2024
+STOCK NUMBER ITEM DESCRIPTION SEC UI QTY QUANTITY
+1005-01-231-0973 RIFLE, 5.56MM, M4, SN: W123456 N EA 1 1
+SER NO: W123457
+8470-01-520-7373 HELMET, ADVANCED COMBAT EA 4 4
+PAGE 1 OF 1`
+
+	extraction, err := provider.ExtractDA2062(context.Background(), PDFPage{Number: 2, Text: text})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ParseMeta{From: "CPT SMITH", To: "SGT JONES", Date: "15 MAR 2024", FormNumber: "HR-0042"}
+	if extraction.Meta != want {
+		t.Errorf("meta: got %+v, want %+v", extraction.Meta, want)
+	}
+
+	items := extraction.Items
+	if len(items) != 3 {
+		t.Fatalf("expected 3 items, got %d: %+v", len(items), items)
+	}
+	if items[0].NSN != "1005-01-231-0973" || items[0].Name != "RIFLE, 5.56MM, M4" || items[0].SerialNumber != "W123456" || items[0].Quantity != 1 {
+		t.Errorf("first rifle: %+v", items[0])
+	}
+	if items[1].NSN != items[0].NSN || items[1].SerialNumber != "W123457" || items[1].Quantity != 1 {
+		t.Errorf("second rifle from the continuation row: %+v", items[1])
+	}
+	if items[0].UnitOfIssue != "EA" || items[1].UnitOfIssue != "EA" {
+		t.Errorf("rifle unit of issue: %q, %q", items[0].UnitOfIssue, items[1].UnitOfIssue)
+	}
+	if items[2].Name != "HELMET, ADVANCED COMBAT" || items[2].Quantity != 4 || items[2].SerialNumber != "" {
+		t.Errorf("helmets: %+v", items[2])
+	}
+	for _, item := range items {
+		if item.Page != 2 || item.Confidence <= 0 || item.Confidence > 1 {
+			t.Errorf("item page or confidence: %+v", item)
+		}
+	}
+}
+
+// TestLocalProviderScan places OCR words in the template's columns, allowing for a
+// scan offset from the form
+func TestLocalProviderScan(t *testing.T) {
+	// 10 pixels per millimetre, shifted 30 pixels right and 50 down
+	const scale, offsetX, offsetY = 10, 30, 50
+	word := func(text string, xMM, yMM float64, line int) ocr.Word {
+		return ocr.Word{
+			Text:       text,
+			Left:       int(xMM*scale) + offsetX,
+			Top:        int(yMM*scale) + offsetY,
+			Width:      len(text) * 15,
+			Height:     25,
+			Confidence: 90,
+			Line:       ocr.LineKey{Block: 1, Line: line},
+		}
+	}
+	engine := &scriptedEngine{page: &ocr.Page{Width: 2159, Height: 2794, Words: []ocr.Word{
+		word("FROM:", 10, 30, 1), word("1ST", 32, 30, 1), word("PLT", 40, 30, 1), word("TO:", 100, 30, 1), word("SGT", 117, 30, 1), word("JONES", 125, 30, 1),
+		word("STOCK", 26, 61, 2), word("NUMBER", 36, 61, 2),
+		word("5855-01-534-5931", 11, 73, 3), word("NIGHT", 61, 73, 3), word("VISION", 71, 73, 3), word("GOGGLE", 82, 73, 3),
+		word("EA", 141, 73, 3), word("2", 156, 73, 3), word("2", 166.5, 73, 3),
+		word("SN:", 61, 79, 4), word("PVS001", 68, 79, 4),
+	}}}
+	provider := newLocalProvider(engine, loadTestTemplate(t))
+
+	extraction, err := provider.ExtractDA2062(context.Background(), PDFPage{Number: 1, Image: []byte("scan"), ContentType: "image/png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extraction.Meta.From != "1ST PLT" || extraction.Meta.To != "SGT JONES" {
+		t.Errorf("meta: %+v", extraction.Meta)
+	}
+	if len(extraction.Items) != 1 {
+		t.Fatalf("expected 1 item, got %+v", extraction.Items)
+	}
+	item := extraction.Items[0]
+	if item.NSN != "5855-01-534-5931" || item.Name != "NIGHT VISION GOGGLE" || item.SerialNumber != "PVS001" || item.Quantity != 2 {
+		t.Errorf("item: %+v", item)
+	}
+	// The row takes in the serial number line below it
+	if item.Row == nil || item.Row.Top != 780.0/2794 || item.Row.Bottom != 865.0/2794 {
+		t.Errorf("row region: %+v", item.Row)
+	}
+	// The serial number box covers PVS001 but not the SN: marker before it
+	if box := item.Boxes[FieldSerialNumber]; box != (FieldBox{Left: 710.0 / 2159, Top: 840.0 / 2794, Right: 800.0 / 2159, Bottom: 865.0 / 2794}) {
+		t.Errorf("serial number box: %+v", box)
+	}
+	if box, ok := item.Boxes[FieldNSN]; !ok || box.Left != 140.0/2159 || box.Top != 780.0/2794 {
+		t.Errorf("NSN box: %+v", box)
+	}
+	if _, ok := item.Boxes[FieldQuantity]; !ok {
+		t.Errorf("no quantity box: %+v", item.Boxes)
+	}
+}
+
+// TestLocalProviderWithoutOCR rejects scanned pages when no OCR engine is installed
+func TestLocalProviderWithoutOCR(t *testing.T) {
+	provider := newLocalProvider(nil, loadTestTemplate(t))
+	_, err := provider.ExtractDA2062(context.Background(), PDFPage{Number: 1, Image: []byte("scan")})
+	if err != ocr.ErrEngineNotInstalled {
+		t.Errorf("expected ErrEngineNotInstalled, got %v", err)
+	}
+}
+
+type scriptedEngine struct {
+	page *ocr.Page
+}
+
+func (e *scriptedEngine) Recognize(ctx context.Context, image []byte) (*ocr.Page, error) {
+	return e.page, nil
+}
+
+func loadTestTemplate(t *testing.T) *documents.DA2062Template {
+	t.Helper()
+	data, err := os.ReadFile("../../../assets/forms/DA_Form_2062_reference.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var template documents.DA2062Template
+	if err := json.Unmarshal(data, &template); err != nil {
+		t.Fatal(err)
+	}
+	return &template
+}
@@ -0,0 +1,225 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Form parsing back ends selectable by ProviderConfig.Name
+const (
+	ProviderAnthropic   = "anthropic"
+	ProviderAzureOpenAI = "azure_openai"
+	ProviderLocal       = "local"
+	ProviderFake        = "fake"
+)
+
+const defaultProviderTimeout = 2 * time.Minute
+
+// ErrNoProvider is returned when no form parsing back end is configured
+var ErrNoProvider = errors.New("no form parsing provider is configured")
+
+// VisionProvider reads the items and form fields from one page of a DA 2062, sent
+// as a scanned image or as the text layer of an exported PDF
+type VisionProvider interface {
+	// Name identifies the back end in logs and cost records
+	Name() string
+	// ExtractDA2062 parses one page split with SplitDA2062
+	ExtractDA2062(ctx context.Context, page PDFPage) (*Extraction, error)
+}
+
+// Extraction is what a provider read from one page, with what reading it cost
+type Extraction struct {
+	Items    []ParsedItem `json:"items"`
+	Meta     ParseMeta    `json:"meta"`
+	Provider string       `json:"provider"`
+	Usage    Usage        `json:"usage"`
+}
+
+// Usage is the metered cost of one provider call
+type Usage struct {
+	InputTokens  int64   `json:"inputTokens"`
+	OutputTokens int64   `json:"outputTokens"`
+	CostUSD      float64 `json:"costUsd"`
+}
+
+// ProviderConfig selects and configures one form parsing back end
+type ProviderConfig struct {
+	Name      string
+	Endpoint  string // Azure OpenAI resource endpoint
+	APIKey    string
+	Model     string // Model, or Azure OpenAI deployment
+	MaxTokens int
+	Timeout   time.Duration // How long one page may take before the next provider is tried
+
+	// Local OCR engine binary and language, for the local provider
+	OCRPath     string
+	OCRLanguage string
+
+	// Price in US dollars per million tokens; known models are priced by default
+	InputCostPerMTok  float64
+	OutputCostPerMTok float64
+}
+
+// cost prices a call's token counts
+func (c ProviderConfig) cost(inputTokens, outputTokens int64) float64 {
+	return (float64(inputTokens)*c.InputCostPerMTok + float64(outputTokens)*c.OutputCostPerMTok) / 1e6
+}
+
+// modelPricing is the list price, input and output per million tokens, of models
+// used when a provider's price is not configured
+var modelPricing = map[string][2]float64{
+	"claude-3-5-sonnet-20241022": {3, 15},
+	"claude-sonnet-4-20250514":   {3, 15},
+	"gpt-4o":                     {2.5, 10},
+	"gpt-4o-mini":                {0.15, 0.6},
+}
+
+// NewVisionProvider creates the back end named by cfg
+func NewVisionProvider(cfg ProviderConfig) (VisionProvider, error) {
+	if cfg.InputCostPerMTok == 0 && cfg.OutputCostPerMTok == 0 {
+		if price, ok := modelPricing[cfg.Model]; ok {
+			cfg.InputCostPerMTok, cfg.OutputCostPerMTok = price[0], price[1]
+		}
+	}
+
+	switch cfg.Name {
+	case ProviderAnthropic:
+		return NewAnthropicProvider(cfg)
+	case ProviderAzureOpenAI:
+		return NewAzureOpenAIProvider(cfg)
+	case ProviderLocal:
+		return NewLocalProvider(cfg)
+	case ProviderFake:
+		return NewFakeProvider(), nil
+	default:
+		return nil, fmt.Errorf("unsupported form parsing provider: %q", cfg.Name)
+	}
+}
+
+// ProviderStats totals one provider's calls through a chain
+type ProviderStats struct {
+	Calls        int     `json:"calls"`
+	Failures     int     `json:"failures"`
+	InputTokens  int64   `json:"inputTokens"`
+	OutputTokens int64   `json:"outputTokens"`
+	CostUSD      float64 `json:"costUsd"`
+}
+
+type chainLink struct {
+	provider VisionProvider
+	timeout  time.Duration
+}
+
+// ProviderChain tries providers in order, each under its own timeout, until one
+// parses the page. It keeps running totals of calls and cost per provider.
+type ProviderChain struct {
+	links []chainLink
+
+	mu    sync.Mutex
+	stats map[string]*ProviderStats
+}
+
+// NewProviderChain creates the providers in configs, to be tried in that order
+func NewProviderChain(configs []ProviderConfig) (*ProviderChain, error) {
+	if len(configs) == 0 {
+		return nil, ErrNoProvider
+	}
+	chain := &ProviderChain{stats: make(map[string]*ProviderStats)}
+	for _, cfg := range configs {
+		provider, err := NewVisionProvider(cfg)
+		if err != nil {
+			return nil, err
+		}
+		chain.Add(provider, cfg.Timeout)
+	}
+	return chain, nil
+}
+
+// Add appends a provider to the chain. A zero timeout uses the default.
+func (c *ProviderChain) Add(provider VisionProvider, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = defaultProviderTimeout
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stats == nil {
+		c.stats = make(map[string]*ProviderStats)
+	}
+	c.links = append(c.links, chainLink{provider: provider, timeout: timeout})
+}
+
+// Name lists the providers in the chain
+func (c *ProviderChain) Name() string {
+	names := make([]string, len(c.links))
+	for i, link := range c.links {
+		names[i] = link.provider.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+// ExtractDA2062 implements VisionProvider. The extraction names the provider that
+// read the page; if every provider fails their errors are returned together.
+func (c *ProviderChain) ExtractDA2062(ctx context.Context, page PDFPage) (*Extraction, error) {
+	if len(c.links) == 0 {
+		return nil, ErrNoProvider
+	}
+
+	var errs []error
+	for _, link := range c.links {
+		name := link.provider.Name()
+		callCtx, cancel := context.WithTimeout(ctx, link.timeout)
+		extraction, err := link.provider.ExtractDA2062(callCtx, page)
+		cancel()
+
+		if err == nil {
+			extraction.Provider = name
+			c.record(name, extraction.Usage, nil)
+			return extraction, nil
+		}
+		c.record(name, Usage{}, err)
+		errs = append(errs, fmt.Errorf("%s: %w", name, err))
+
+		// The caller gave up; the remaining providers would too
+		if ctx.Err() != nil {
+			break
+		}
+		log.Printf("WARNING: Form parsing provider %s failed on page %d: %v", name, page.Number, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Stats returns the running totals for each provider in the chain
+func (c *ProviderChain) Stats() map[string]ProviderStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := make(map[string]ProviderStats, len(c.stats))
+	for name, s := range c.stats {
+		stats[name] = *s
+	}
+	return stats
+}
+
+func (c *ProviderChain) record(name string, usage Usage, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, ok := c.stats[name]
+	if !ok {
+		s = &ProviderStats{}
+		c.stats[name] = s
+	}
+	s.Calls++
+	if err != nil {
+		s.Failures++
+		return
+	}
+	s.InputTokens += usage.InputTokens
+	s.OutputTokens += usage.OutputTokens
+	s.CostUSD += usage.CostUSD
+}
@@ -0,0 +1,136 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// ErrNoRecording is returned by a ReplayProvider for a page nothing was recorded for
+var ErrNoRecording = errors.New("no recorded response for page")
+
+// Recording is what one provider returned for each page of a set of forms, so the
+// parse can be replayed without calling the provider again
+type Recording struct {
+	Provider string                `json:"provider"`
+	Pages    map[string]Extraction `json:"pages"` // Keyed by PageKey
+}
+
+// PageKey identifies a page by its number and content, so a recording still
+// matches when forms are renamed or reordered
+func PageKey(page PDFPage) string {
+	hash := sha256.New()
+	hash.Write([]byte(strconv.Itoa(page.Number)))
+	hash.Write([]byte{0})
+	if page.Text != "" {
+		hash.Write([]byte(page.Text))
+	} else {
+		hash.Write(page.Image)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// LoadRecording reads a recording saved with Save
+func LoadRecording(path string) (*Recording, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+	var recording Recording
+	if err := json.Unmarshal(data, &recording); err != nil {
+		return nil, fmt.Errorf("failed to parse recording %s: %w", path, err)
+	}
+	if recording.Pages == nil {
+		recording.Pages = make(map[string]Extraction)
+	}
+	return &recording, nil
+}
+
+// Save writes the recording as indented JSON, to be checked in alongside the forms
+func (r *Recording) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode recording: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	return nil
+}
+
+// ReplayProvider is a VisionProvider that returns recorded responses, so parser
+// evaluations run offline and give the same result every time
+type ReplayProvider struct {
+	recording *Recording
+}
+
+// NewReplayProvider creates a provider replaying recording
+func NewReplayProvider(recording *Recording) *ReplayProvider {
+	return &ReplayProvider{recording: recording}
+}
+
+// Name implements VisionProvider, naming the provider that was recorded
+func (p *ReplayProvider) Name() string {
+	return p.recording.Provider
+}
+
+// ExtractDA2062 implements VisionProvider
+func (p *ReplayProvider) ExtractDA2062(ctx context.Context, page PDFPage) (*Extraction, error) {
+	recorded, ok := p.recording.Pages[PageKey(page)]
+	if !ok {
+		return nil, fmt.Errorf("%w %d", ErrNoRecording, page.Number)
+	}
+	extraction := recorded
+	extraction.Items = append([]ParsedItem(nil), recorded.Items...)
+	return &extraction, nil
+}
+
+// RecordingProvider passes pages to another provider and records what it returns
+type RecordingProvider struct {
+	provider VisionProvider
+
+	mu        sync.Mutex
+	recording *Recording
+}
+
+// NewRecordingProvider creates a provider recording provider's responses
+func NewRecordingProvider(provider VisionProvider) *RecordingProvider {
+	return &RecordingProvider{
+		provider:  provider,
+		recording: &Recording{Provider: provider.Name(), Pages: make(map[string]Extraction)},
+	}
+}
+
+// Name implements VisionProvider
+func (p *RecordingProvider) Name() string {
+	return p.provider.Name()
+}
+
+// ExtractDA2062 implements VisionProvider. Failed calls are not recorded.
+func (p *RecordingProvider) ExtractDA2062(ctx context.Context, page PDFPage) (*Extraction, error) {
+	extraction, err := p.provider.ExtractDA2062(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.recording.Pages[PageKey(page)] = *extraction
+	return extraction, nil
+}
+
+// Recording returns what has been recorded so far
+func (p *RecordingProvider) Recording() *Recording {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pages := make(map[string]Extraction, len(p.recording.Pages))
+	for key, extraction := range p.recording.Pages {
+		pages[key] = extraction
+	}
+	return &Recording{Provider: p.recording.Provider, Pages: pages}
+}
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidTicket  = errors.New("invalid or expired WebSocket ticket")
+	ErrSessionRevoked = errors.New("session has been revoked")
+)
+
+const (
+	// How long a WebSocket ticket can be redeemed for after it is issued
+	websocketTicketTTL = 30 * time.Second
+
+	// Revocations are kept as long as a refresh token issued to the session could
+	// still mint new access tokens, with some margin
+	revokedSessionRetention = 30 * 24 * time.Hour
+)
+
+// SessionStore issues WebSocket tickets and records which sign-in sessions have
+// been logged out
+type SessionStore struct {
+	db *gorm.DB
+}
+
+// NewSessionStore creates a session store backed by the database
+func NewSessionStore(db *gorm.DB) *SessionStore {
+	return &SessionStore{db: db}
+}
+
+// IssueWebSocketTicket creates a ticket the user can exchange once, within the next
+// few seconds, for a WebSocket connection. credentialExpiresAt is when the access
+// token used to ask for the ticket expires, or nil for cookie sessions.
+func (s *SessionStore) IssueWebSocketTicket(userID uint, sessionID string, credentialExpiresAt *time.Time) (string, time.Time, error) {
+	if sessionID != "" {
+		revoked, err := s.IsSessionRevoked(sessionID)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		if revoked {
+			return "", time.Time{}, ErrSessionRevoked
+		}
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate ticket: %w", err)
+	}
+	ticket := base64.RawURLEncoding.EncodeToString(raw)
+
+	now := time.Now().UTC()
+	record := domain.WebSocketTicket{
+		TokenHash:           hashTicket(ticket),
+		UserID:              userID,
+		SessionID:           sessionID,
+		CredentialExpiresAt: credentialExpiresAt,
+		ExpiresAt:           now.Add(websocketTicketTTL),
+	}
+	if err := s.db.Create(&record).Error; err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to store ticket: %w", err)
+	}
+
+	// Tickets are useless once expired, so clear out old ones as new ones are issued
+	s.db.Where("expires_at < ?", now.Add(-time.Hour)).Delete(&domain.WebSocketTicket{})
+
+	return ticket, record.ExpiresAt, nil
+}
+
+// RedeemWebSocketTicket marks a ticket used and returns it. A ticket that is
+// unknown, expired or already used returns ErrInvalidTicket.
+func (s *SessionStore) RedeemWebSocketTicket(ticket string) (*domain.WebSocketTicket, error) {
+	if ticket == "" {
+		return nil, ErrInvalidTicket
+	}
+
+	// A single conditional update so two connections racing with the same ticket
+	// cannot both succeed
+	now := time.Now().UTC()
+	var record domain.WebSocketTicket
+	result := s.db.Model(&record).
+		Clauses(clause.Returning{}).
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashTicket(ticket), now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to redeem ticket: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInvalidTicket
+	}
+
+	if record.SessionID != "" {
+		revoked, err := s.IsSessionRevoked(record.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrSessionRevoked
+		}
+	}
+	return &record, nil
+}
+
+// RevokeSession records that a sign-in session has ended. Open WebSocket
+// connections from it are closed the next time the hub re-validates them.
+func (s *SessionStore) RevokeSession(userID uint, sessionID string) error {
+	if sessionID == "" {
+		return nil
+	}
+	now := time.Now().UTC()
+	err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&domain.RevokedSession{
+		SessionID: sessionID,
+		UserID:    userID,
+		RevokedAt: now,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	s.db.Where("revoked_at < ?", now.Add(-revokedSessionRetention)).Delete(&domain.RevokedSession{})
+	return nil
+}
+
+// IsSessionRevoked reports whether the session has been logged out
+func (s *SessionStore) IsSessionRevoked(sessionID string) (bool, error) {
+	var count int64
+	if err := s.db.Model(&domain.RevokedSession{}).Where("session_id = ?", sessionID).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check session: %w", err)
+	}
+	return count > 0, nil
+}
+
+// RevokedSessions returns which of the given sessions have been logged out. The hub
+// uses it to check every open connection with one query.
+func (s *SessionStore) RevokedSessions(sessionIDs []string) (map[string]bool, error) {
+	revoked := make(map[string]bool)
+	if len(sessionIDs) == 0 {
+		return revoked, nil
+	}
+	var ids []string
+	err := s.db.Model(&domain.RevokedSession{}).
+		Where("session_id IN ?", sessionIDs).
+		Pluck("session_id", &ids).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to check sessions: %w", err)
+	}
+	for _, id := range ids {
+		revoked[id] = true
+	}
+	return revoked, nil
+}
+
+func hashTicket(ticket string) string {
+	sum := sha256.Sum256([]byte(ticket))
+	return hex.EncodeToString(sum[:])
+}
@@ -17,9 +17,14 @@ type ComponentService interface {
 	GetAvailableComponents(ctx context.Context, propertyID, userID uint) ([]domain.Property, error)
 	ValidateAttachment(ctx context.Context, parentID, componentID uint, position string) error
 	UpdateComponentPosition(ctx context.Context, parentID, componentID uint, position string) error
-	TransferComponents(ctx context.Context, fromPropertyID uint, fromUserID, toUserID uint) error
+	GetComponentTree(ctx context.Context, propertyID uint) ([]domain.PropertyComponent, error)
+	PlanTreeTransfer(ctx context.Context, propertyID, fromUserID uint, includeComponents bool) (*TreeTransferPlan, error)
 }
 
+// TreeTransferPlan lists the ownership and attachment changes needed to hand a
+// property to a new holder without leaving a component tree split between users.
+type TreeTransferPlan = repository.TreeTransferPlan
+
 type componentService struct {
 	repo repository.Repository
 }
@@ -176,35 +181,24 @@ func (s *componentService) UpdateComponentPosition(ctx context.Context, parentID
 	return s.repo.UpdateComponentPosition(parentID, componentID, position)
 }
 
-// TransferComponents transfers all attached components from one user to another
-func (s *componentService) TransferComponents(ctx context.Context, fromPropertyID uint, fromUserID, toUserID uint) error {
-	// Get all components attached to the property
-	components, err := s.repo.GetPropertyComponents(fromPropertyID)
+// GetComponentTree retrieves every component nested beneath a property, however deep
+func (s *componentService) GetComponentTree(ctx context.Context, propertyID uint) ([]domain.PropertyComponent, error) {
+	return s.repo.GetComponentTree(propertyID)
+}
+
+// PlanTreeTransfer works out which properties would move and which attachments
+// would break if propertyID left fromUserID now, to check a transfer before it is
+// accepted. The repository works the plan out again when it completes the transfer.
+func (s *componentService) PlanTreeTransfer(ctx context.Context, propertyID, fromUserID uint, includeComponents bool) (*TreeTransferPlan, error) {
+	parentAttachment, err := s.repo.GetComponentAttachment(propertyID)
 	if err != nil {
-		return fmt.Errorf("failed to get property components: %w", err)
+		return nil, fmt.Errorf("failed to check parent attachment: %w", err)
 	}
-
-	// Transfer ownership of each component
-	for _, component := range components {
-		// Get the component property
-		componentProperty, err := s.repo.GetPropertyByID(component.ComponentPropertyID)
-		if err != nil {
-			return fmt.Errorf("failed to get component property %d: %w", component.ComponentPropertyID, err)
-		}
-
-		// Verify current ownership
-		if componentProperty.AssignedToUserID == nil || *componentProperty.AssignedToUserID != fromUserID {
-			return fmt.Errorf("component %d is not owned by user %d", component.ComponentPropertyID, fromUserID)
-		}
-
-		// Update component ownership
-		componentProperty.AssignedToUserID = &toUserID
-		if err := s.repo.UpdateProperty(componentProperty); err != nil {
-			return fmt.Errorf("failed to transfer component %d: %w", component.ComponentPropertyID, err)
-		}
+	tree, err := s.repo.GetComponentTree(propertyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get component tree: %w", err)
 	}
-
-	return nil
+	return repository.BuildTreeTransferPlan(propertyID, fromUserID, includeComponents, parentAttachment, tree)
 }
 
 // isCompatible checks if a component is compatible with a parent property
@@ -79,6 +79,20 @@ func NewDA2062Generator(repo repository.Repository) *DA2062Generator {
 	return &DA2062Generator{repo: repo}
 }
 
+// UserInfoFromUser builds the signature block details for a user, using their unit as title
+func UserInfoFromUser(user *domain.User) UserInfo {
+	info := UserInfo{
+		Name:  user.FirstName + " " + user.LastName,
+		Rank:  user.Rank,
+		Title: user.Unit,
+		Phone: user.Phone,
+	}
+	if user.SignatureURL != nil {
+		info.SignatureURL = *user.SignatureURL
+	}
+	return info
+}
+
 func (g *DA2062Generator) GenerateDA2062(
 	properties []domain.Property,
 	fromUser UserInfo,
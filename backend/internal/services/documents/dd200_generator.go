@@ -0,0 +1,183 @@
+package documents
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// DD200HistoryEntry is one ledger event listed in the property history annex
+type DD200HistoryEntry struct {
+	Date  time.Time `json:"date"`
+	Event string    `json:"event"`
+	Hash  string    `json:"hash"`
+}
+
+// DD200Data holds everything printed on a DD Form 200
+type DD200Data struct {
+	CaseNumber        string              `json:"case_number"`
+	CaseType          string              `json:"case_type"`
+	DateInitiated     time.Time           `json:"date_initiated"`
+	IncidentDate      time.Time           `json:"incident_date"`
+	IncidentLocation  string              `json:"incident_location"`
+	Nomenclature      string              `json:"nomenclature"`
+	NSN               string              `json:"nsn"`
+	SerialNumber      string              `json:"serial_number"`
+	UnitOfIssue       string              `json:"unit_of_issue"`
+	Quantity          int                 `json:"quantity"`
+	UnitPrice         float64             `json:"unit_price"`
+	TotalLoss         float64             `json:"total_loss"`
+	Circumstances     string              `json:"circumstances"`
+	Holder            UserInfo            `json:"holder"`
+	Initiator         UserInfo            `json:"initiator"`
+	Investigator      *UserInfo           `json:"investigator,omitempty"`
+	Findings          string              `json:"findings"`
+	LiabilityDecision string              `json:"liability_decision"`
+	LiabilityAmount   float64             `json:"liability_amount"`
+	ApprovingOfficial UserInfo            `json:"approving_official"`
+	ApprovedAt        *time.Time          `json:"approved_at,omitempty"`
+	History           []DD200HistoryEntry `json:"history"`
+}
+
+// GenerateDD200 renders a DD Form 200 style Financial Liability Investigation of
+// Property Loss, followed by an annex listing the item's ledger history.
+func (g *DA2062Generator) GenerateDD200(data DD200Data, unitInfo UnitInfo) (*bytes.Buffer, error) {
+	pdf := gofpdf.New("P", "mm", "Letter", "")
+	pdf.SetMargins(10, 10, 10)
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.AddPage()
+
+	pdf.SetFont("Arial", "B", 12)
+	pdf.CellFormat(0, 6, "FINANCIAL LIABILITY INVESTIGATION OF PROPERTY LOSS", "0", 1, "C", false, 0, "")
+	pdf.SetFont("Arial", "", 8)
+	pdf.CellFormat(0, 4, fmt.Sprintf("%s  -  %s", unitInfo.UnitName, unitInfo.DODAAC), "0", 1, "C", false, 0, "")
+	pdf.Ln(3)
+
+	half := 98.0
+	g.addDD200Block(pdf, "1. DATE INITIATED", data.DateInitiated.Format("02 Jan 2006"), half, 0)
+	g.addDD200Block(pdf, "2. DOCUMENT NUMBER", data.CaseNumber, half, 1)
+	g.addDD200Block(pdf, "3. NATURE OF LOSS", strings.ToUpper(data.CaseType), half, 0)
+	g.addDD200Block(pdf, "4. DATE OF DISCOVERY / LOCATION", strings.TrimSpace(data.IncidentDate.Format("02 Jan 2006")+"  "+data.IncidentLocation), half, 1)
+
+	// Block 5 - property
+	pdf.SetFont("Arial", "B", 7)
+	pdf.CellFormat(0, 5, "5. PROPERTY LOST, DAMAGED OR DESTROYED", "LTR", 1, "L", false, 0, "")
+	pdf.CellFormat(30, 5, "NSN", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(60, 5, "NOMENCLATURE", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(36, 5, "SERIAL NUMBER", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(12, 5, "U/I", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(14, 5, "QTY", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(19, 5, "UNIT COST", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(25, 5, "TOTAL COST", "1", 1, "C", false, 0, "")
+	pdf.SetFont("Arial", "", 7)
+	pdf.CellFormat(30, 5, data.NSN, "1", 0, "L", false, 0, "")
+	pdf.CellFormat(60, 5, truncateText(data.Nomenclature, 42), "1", 0, "L", false, 0, "")
+	pdf.CellFormat(36, 5, truncateText(data.SerialNumber, 26), "1", 0, "L", false, 0, "")
+	pdf.CellFormat(12, 5, data.UnitOfIssue, "1", 0, "C", false, 0, "")
+	pdf.CellFormat(14, 5, fmt.Sprintf("%d", data.Quantity), "1", 0, "C", false, 0, "")
+	pdf.CellFormat(19, 5, fmt.Sprintf("$%.2f", data.UnitPrice), "1", 0, "R", false, 0, "")
+	pdf.CellFormat(25, 5, fmt.Sprintf("$%.2f", data.TotalLoss), "1", 1, "R", false, 0, "")
+	pdf.Ln(2)
+
+	g.addDD200TextBlock(pdf, "6. CIRCUMSTANCES UNDER WHICH PROPERTY WAS LOST, DAMAGED OR DESTROYED", data.Circumstances)
+
+	g.addDD200Block(pdf, "7. ACCOUNTABLE OFFICER / HAND RECEIPT HOLDER", fmt.Sprintf("%s %s", data.Holder.Rank, data.Holder.Name), half, 0)
+	g.addDD200Block(pdf, "8. INITIATED BY", fmt.Sprintf("%s %s", data.Initiator.Rank, data.Initiator.Name), half, 1)
+
+	investigator := ""
+	if data.Investigator != nil {
+		investigator = fmt.Sprintf("%s %s", data.Investigator.Rank, data.Investigator.Name)
+	}
+	g.addDD200Block(pdf, "9. FINANCIAL LIABILITY OFFICER", investigator, 0, 1)
+	g.addDD200TextBlock(pdf, "10. FINDINGS AND RECOMMENDATION", data.Findings)
+
+	decision := "NO FINANCIAL LIABILITY RECOMMENDED"
+	if data.LiabilityDecision == "liable" {
+		decision = fmt.Sprintf("FINANCIAL LIABILITY RECOMMENDED IN THE AMOUNT OF $%.2f", data.LiabilityAmount)
+	}
+	g.addDD200Block(pdf, "11. LIABILITY", decision, 0, 1)
+
+	// Block 12 - approving authority signature
+	if pdf.GetY() > 225 {
+		pdf.AddPage()
+	}
+	pdf.SetFont("Arial", "B", 7)
+	pdf.CellFormat(0, 5, "12. APPROVING AUTHORITY", "LTR", 1, "L", false, 0, "")
+	y := pdf.GetY()
+	pdf.CellFormat(0, 22, "", "LBR", 1, "L", false, 0, "")
+	if data.ApprovedAt != nil {
+		g.addDiagonalSignature(pdf, data.ApprovingOfficial, 12, y+1, 70, 14)
+	}
+	pdf.SetFont("Arial", "", 7)
+	pdf.SetXY(12, y+16)
+	pdf.CellFormat(90, 4, fmt.Sprintf("%s %s, %s", data.ApprovingOfficial.Rank, data.ApprovingOfficial.Name, data.ApprovingOfficial.Title), "T", 0, "L", false, 0, "")
+	approvedOn := ""
+	if data.ApprovedAt != nil {
+		approvedOn = data.ApprovedAt.Format("02 Jan 2006")
+	}
+	pdf.CellFormat(40, 4, "DATE: "+approvedOn, "0", 1, "L", false, 0, "")
+
+	if len(data.History) > 0 {
+		pdf.AddPage()
+		pdf.SetFont("Arial", "B", 10)
+		pdf.CellFormat(0, 6, fmt.Sprintf("ANNEX A - LEDGER HISTORY FOR SN %s", data.SerialNumber), "0", 1, "L", false, 0, "")
+		pdf.SetFont("Arial", "B", 7)
+		pdf.CellFormat(30, 5, "DATE", "1", 0, "C", false, 0, "")
+		pdf.CellFormat(110, 5, "EVENT", "1", 0, "C", false, 0, "")
+		pdf.CellFormat(56, 5, "LEDGER HASH", "1", 1, "C", false, 0, "")
+		pdf.SetFont("Arial", "", 7)
+		for _, entry := range data.History {
+			pdf.CellFormat(30, 5, entry.Date.Format("02 Jan 2006 1504"), "1", 0, "L", false, 0, "")
+			pdf.CellFormat(110, 5, truncateText(entry.Event, 80), "1", 0, "L", false, 0, "")
+			pdf.CellFormat(56, 5, truncateText(entry.Hash, 40), "1", 1, "L", false, 0, "")
+		}
+	}
+
+	totalPages := pdf.PageCount()
+	for i := 1; i <= totalPages; i++ {
+		pdf.SetPage(i)
+		pdf.SetXY(10, 268)
+		pdf.SetFont("Arial", "", 7)
+		pdf.CellFormat(98, 4, "DD FORM 200", "0", 0, "L", false, 0, "")
+		pdf.CellFormat(98, 4, fmt.Sprintf("%s - PAGE %d OF %d", data.CaseNumber, i, totalPages), "0", 0, "R", false, 0, "")
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to generate DD Form 200 PDF: %w", err)
+	}
+
+	return &buf, nil
+}
+
+// addDD200Block draws a labelled single-line block. A width of zero spans the page;
+// ln follows gofpdf's convention (0 to continue on the same line, 1 for a new line).
+func (g *DA2062Generator) addDD200Block(pdf *gofpdf.Fpdf, label, value string, width float64, ln int) {
+	x, y := pdf.GetXY()
+	if width == 0 {
+		width = 196
+	}
+	pdf.SetFont("Arial", "B", 6)
+	pdf.CellFormat(width, 4, label, "LTR", 2, "L", false, 0, "")
+	pdf.SetFont("Arial", "", 8)
+	pdf.CellFormat(width, 6, value, "LBR", 0, "L", false, 0, "")
+	if ln == 1 {
+		pdf.SetXY(10, y+12)
+	} else {
+		pdf.SetXY(x+width, y)
+	}
+}
+
+func (g *DA2062Generator) addDD200TextBlock(pdf *gofpdf.Fpdf, label, text string) {
+	pdf.SetFont("Arial", "B", 6)
+	pdf.CellFormat(0, 4, label, "LTR", 1, "L", false, 0, "")
+	pdf.SetFont("Arial", "", 8)
+	if text == "" {
+		text = " "
+	}
+	pdf.MultiCell(0, 4, text, "LBR", "L", false)
+	pdf.Ln(2)
+}
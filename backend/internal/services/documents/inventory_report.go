@@ -0,0 +1,122 @@
+package documents
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// InventoryReportLine is the recorded result for one item in an inventory report
+type InventoryReportLine struct {
+	Name          string `json:"name"`
+	SerialNumber  string `json:"serial_number"`
+	NSN           string `json:"nsn"`
+	Holder        string `json:"holder"`
+	Counter       string `json:"counter"`
+	Result        string `json:"result"`
+	SerialMatched *bool  `json:"serial_matched,omitempty"`
+	Notes         string `json:"notes"`
+}
+
+// InventoryReport holds everything printed on a cyclic or sensitive-item inventory report
+type InventoryReport struct {
+	Title           string                `json:"title"`
+	Reference       string                `json:"reference"`
+	DueDate         time.Time             `json:"due_date"`
+	Total           int                   `json:"total"`
+	Counted         int                   `json:"counted"`
+	Verified        int                   `json:"verified"`
+	Discrepant      int                   `json:"discrepant"`
+	NotFound        int                   `json:"not_found"`
+	CompletionRate  float64               `json:"completion_rate"`
+	DiscrepancyRate float64               `json:"discrepancy_rate"`
+	Lines           []InventoryReportLine `json:"lines"`
+}
+
+// GenerateInventoryReport renders an inventory report certified by the officer who
+// closed the inventory.
+func (g *DA2062Generator) GenerateInventoryReport(report InventoryReport, certifier UserInfo, unitInfo UnitInfo) (*bytes.Buffer, error) {
+	pdf := gofpdf.New("P", "mm", "Letter", "")
+	pdf.SetMargins(10, 10, 10)
+	pdf.SetAutoPageBreak(true, 10)
+	pdf.AddPage()
+
+	pdf.SetFont("Arial", "B", 12)
+	pdf.CellFormat(0, 7, report.Title, "0", 1, "L", false, 0, "")
+	pdf.SetFont("Arial", "", 8)
+	pdf.CellFormat(0, 4, fmt.Sprintf("Reference: %s    Unit: %s    DODAAC: %s", report.Reference, unitInfo.UnitName, unitInfo.DODAAC), "0", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 4, fmt.Sprintf("Due: %s    Generated: %s", report.DueDate.Format("02 Jan 2006"), time.Now().Format("02 Jan 2006 1504")), "0", 1, "L", false, 0, "")
+	pdf.Ln(3)
+
+	// Results summary
+	pdf.SetFont("Arial", "B", 9)
+	pdf.CellFormat(0, 5, "SUMMARY", "B", 1, "L", false, 0, "")
+	pdf.SetFont("Arial", "", 8)
+	pdf.CellFormat(0, 5, fmt.Sprintf("Items selected: %d    Counted: %d    Completion: %.1f%%", report.Total, report.Counted, report.CompletionRate*100), "0", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 5, fmt.Sprintf("Verified: %d    Discrepant: %d    Not found: %d    Discrepancy rate: %.1f%%", report.Verified, report.Discrepant, report.NotFound, report.DiscrepancyRate*100), "0", 1, "L", false, 0, "")
+	pdf.Ln(3)
+
+	g.addInventoryReportHeaders(pdf)
+	pdf.SetFont("Arial", "", 7)
+	for i, line := range report.Lines {
+		if pdf.GetY() > 240 {
+			pdf.AddPage()
+			g.addInventoryReportHeaders(pdf)
+			pdf.SetFont("Arial", "", 7)
+		}
+
+		serial := line.SerialNumber
+		if line.SerialMatched != nil && !*line.SerialMatched {
+			serial += " (MISMATCH)"
+		}
+
+		pdf.CellFormat(8, 5, fmt.Sprintf("%d", i+1), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(48, 5, truncateText(line.Name, 34), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(36, 5, truncateText(serial, 26), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(30, 5, line.NSN, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(26, 5, truncateText(line.Holder, 18), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(26, 5, truncateText(line.Counter, 18), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(22, 5, line.Result, "1", 1, "C", false, 0, "")
+	}
+
+	// Certification block
+	if pdf.GetY() > 225 {
+		pdf.AddPage()
+	}
+	y := pdf.GetY() + 8
+	pdf.SetXY(10, y)
+	pdf.SetFont("Arial", "", 8)
+	pdf.MultiCell(0, 4, "I certify that the inventory above was conducted as recorded and that all discrepancies are listed.", "0", "L", false)
+	g.addDiagonalSignature(pdf, certifier, 10, y+6, 70, 15)
+	pdf.SetXY(10, y+22)
+	pdf.CellFormat(80, 4, fmt.Sprintf("%s %s", certifier.Rank, certifier.Name), "T", 0, "L", false, 0, "")
+	pdf.CellFormat(30, 4, time.Now().Format("02 Jan 2006"), "0", 1, "L", false, 0, "")
+
+	totalPages := pdf.PageCount()
+	for i := 1; i <= totalPages; i++ {
+		pdf.SetPage(i)
+		pdf.SetXY(10, 268)
+		pdf.SetFont("Arial", "", 7)
+		pdf.CellFormat(0, 4, fmt.Sprintf("%s - PAGE %d OF %d", report.Reference, i, totalPages), "0", 0, "R", false, 0, "")
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to generate inventory report PDF: %w", err)
+	}
+
+	return &buf, nil
+}
+
+func (g *DA2062Generator) addInventoryReportHeaders(pdf *gofpdf.Fpdf) {
+	pdf.SetFont("Arial", "B", 7)
+	pdf.CellFormat(8, 5, "LINE", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(48, 5, "ITEM", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(36, 5, "SERIAL NUMBER", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(30, 5, "STOCK NUMBER", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(26, 5, "HOLDER", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(26, 5, "COUNTER", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(22, 5, "RESULT", "1", 1, "C", false, 0, "")
+}
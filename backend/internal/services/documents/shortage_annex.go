@@ -0,0 +1,112 @@
+package documents
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// ShortageItem is a single line on a shortage annex
+type ShortageItem struct {
+	NSN          string  `json:"nsn"`
+	Description  string  `json:"description"`
+	SerialNumber string  `json:"serial_number"`
+	Quantity     int     `json:"quantity"`
+	UnitPrice    float64 `json:"unit_price"`
+	Remarks      string  `json:"remarks"`
+}
+
+// GenerateShortageAnnex produces the shortage annex that accompanies a hand receipt
+// when items could not be found during a joint inventory. Both holders sign it to
+// acknowledge the shortages carried forward pending adjustment action.
+func (g *DA2062Generator) GenerateShortageAnnex(
+	items []ShortageItem,
+	fromUser UserInfo,
+	toUser UserInfo,
+	unitInfo UnitInfo,
+	reference string,
+) (*bytes.Buffer, error) {
+	pdf := gofpdf.New("P", "mm", "Letter", "")
+	pdf.SetMargins(10, 10, 10)
+	pdf.SetAutoPageBreak(true, 10)
+	pdf.AddPage()
+
+	g.addHeader(pdf, unitInfo)
+
+	pdf.SetFont("Arial", "B", 10)
+	pdf.SetY(25)
+	pdf.CellFormat(0, 6, "SHORTAGE ANNEX - "+reference, "0", 1, "L", false, 0, "")
+
+	g.addFromToSection(pdf, fromUser, toUser, unitInfo)
+	g.addShortageTableHeaders(pdf)
+
+	var total float64
+	currentY := 65.0
+	for i, item := range items {
+		if currentY > 240 {
+			pdf.AddPage()
+			g.addHeader(pdf, unitInfo)
+			g.addShortageTableHeaders(pdf)
+			currentY = 65.0
+		}
+
+		lineTotal := float64(item.Quantity) * item.UnitPrice
+		total += lineTotal
+
+		pdf.SetXY(10, currentY)
+		pdf.SetFont("Arial", "", 7)
+		pdf.CellFormat(8, 6, fmt.Sprintf("%d", i+1), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(30, 6, item.NSN, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(55, 6, truncateText(item.Description, 40), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(30, 6, item.SerialNumber, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(10, 6, fmt.Sprintf("%d", item.Quantity), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(20, 6, fmt.Sprintf("%.2f", item.UnitPrice), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(20, 6, fmt.Sprintf("%.2f", lineTotal), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(23, 6, truncateText(item.Remarks, 16), "1", 1, "L", false, 0, "")
+		currentY += 6
+	}
+
+	pdf.SetXY(10, currentY)
+	pdf.SetFont("Arial", "B", 8)
+	pdf.CellFormat(153, 6, "TOTAL VALUE OF SHORTAGES", "1", 0, "R", false, 0, "")
+	pdf.CellFormat(20, 6, fmt.Sprintf("%.2f", total), "1", 0, "R", false, 0, "")
+	pdf.CellFormat(23, 6, "", "1", 1, "L", false, 0, "")
+
+	// Keep the signature block clear of the table
+	if currentY > 225 {
+		pdf.AddPage()
+		g.addHeader(pdf, unitInfo)
+	}
+	g.addSignatureSection(pdf, fromUser, toUser)
+	g.addPageNumbers(pdf)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to generate shortage annex PDF: %w", err)
+	}
+
+	return &buf, nil
+}
+
+func (g *DA2062Generator) addShortageTableHeaders(pdf *gofpdf.Fpdf) {
+	pdf.SetY(59)
+	pdf.SetFont("Arial", "B", 7)
+	pdf.CellFormat(8, 6, "LINE", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(30, 6, "STOCK NUMBER", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(55, 6, "ITEM DESCRIPTION", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(30, 6, "SERIAL NUMBER", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(10, 6, "QTY", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(20, 6, "UNIT PRICE", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(20, 6, "TOTAL", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(23, 6, "REMARKS", "1", 1, "C", false, 0, "")
+}
+
+// truncateText shortens text to fit a fixed-width table cell
+func truncateText(text string, maxLen int) string {
+	runes := []rune(text)
+	if len(runes) <= maxLen {
+		return text
+	}
+	return string(runes[:maxLen-3]) + "..."
+}
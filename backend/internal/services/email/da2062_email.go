@@ -130,6 +130,7 @@ type EmailRequest struct {
 	To          []string
 	Subject     string
 	Body        string
+	TextBody    string // Plain-text alternative sent alongside an HTML body
 	Attachments []EmailAttachment
 	IsHTML      bool
 }
@@ -0,0 +1,160 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// SMTPEmailService sends email through an SMTP relay
+type SMTPEmailService struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+}
+
+// NewSMTPEmailService creates an SMTP sender. Username may be empty for relays that
+// do not require authentication.
+func NewSMTPEmailService(host string, port int, username, password, from string) *SMTPEmailService {
+	return &SMTPEmailService{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+// SendEmail implements EmailService
+func (s *SMTPEmailService) SendEmail(request EmailRequest) error {
+	if len(request.To) == 0 {
+		return fmt.Errorf("email has no recipients")
+	}
+
+	message, err := buildMessage(s.from, request)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.username != "" {
+		auth = smtp.PlainAuth("", s.username, s.password, s.host)
+	}
+	addr := fmt.Sprintf("%s:%d", s.host, s.port)
+	if err := smtp.SendMail(addr, auth, envelopeAddress(s.from), request.To, message); err != nil {
+		return fmt.Errorf("failed to send email to %s: %w", strings.Join(request.To, ", "), err)
+	}
+	return nil
+}
+
+// buildMessage renders the request as a MIME message. An HTML body with a text
+// alternative becomes multipart/alternative, and attachments wrap the body in
+// multipart/mixed.
+func buildMessage(from string, request EmailRequest) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(request.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", request.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	if len(request.Attachments) == 0 {
+		if err := writeBody(&buf, request); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	mixed := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mixed.Boundary())
+
+	var body bytes.Buffer
+	if err := writeBody(&body, request); err != nil {
+		return nil, err
+	}
+	header, content, _ := strings.Cut(body.String(), "\r\n\r\n")
+	part, err := mixed.CreatePart(parseHeader(header))
+	if err != nil {
+		return nil, err
+	}
+	part.Write([]byte(content))
+
+	for _, attachment := range request.Attachments {
+		part, err := mixed.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachment.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", attachment.Filename)},
+		})
+		if err != nil {
+			return nil, err
+		}
+		// Attachment content is already base64 encoded; wrap it at 76 columns
+		for content := attachment.Content; len(content) > 0; {
+			n := len(content)
+			if n > 76 {
+				n = 76
+			}
+			part.Write([]byte(content[:n] + "\r\n"))
+			content = content[n:]
+		}
+	}
+
+	if err := mixed.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBody writes the body part headers, a blank line and the body itself
+func writeBody(buf *bytes.Buffer, request EmailRequest) error {
+	if request.IsHTML && request.TextBody != "" {
+		alternative := multipart.NewWriter(buf)
+		fmt.Fprintf(buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", alternative.Boundary())
+		for _, body := range []struct{ contentType, content string }{
+			{"text/plain; charset=utf-8", request.TextBody},
+			{"text/html; charset=utf-8", request.Body},
+		} {
+			part, err := alternative.CreatePart(textproto.MIMEHeader{"Content-Type": {body.contentType}})
+			if err != nil {
+				return err
+			}
+			part.Write([]byte(body.content))
+		}
+		return alternative.Close()
+	}
+
+	contentType := "text/plain; charset=utf-8"
+	if request.IsHTML {
+		contentType = "text/html; charset=utf-8"
+	}
+	fmt.Fprintf(buf, "Content-Type: %s\r\n\r\n", contentType)
+	buf.WriteString(request.Body)
+	return nil
+}
+
+func parseHeader(raw string) textproto.MIMEHeader {
+	header := textproto.MIMEHeader{}
+	for _, line := range strings.Split(raw, "\r\n") {
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			header.Add(strings.TrimSpace(key), strings.TrimSpace(value))
+		}
+	}
+	return header
+}
+
+// envelopeAddress extracts the bare address from a "Name <address>" sender
+func envelopeAddress(from string) string {
+	if start := strings.LastIndex(from, "<"); start >= 0 {
+		if end := strings.LastIndex(from, ">"); end > start {
+			return from[start+1 : end]
+		}
+	}
+	return from
+}
@@ -0,0 +1,620 @@
+package flipl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"github.com/toole-brendan/handreceipt-go/internal/ledger"
+	"github.com/toole-brendan/handreceipt-go/internal/repository"
+	"github.com/toole-brendan/handreceipt-go/internal/services/documents"
+	"github.com/toole-brendan/handreceipt-go/internal/services/storage"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrCaseNotOpen           = errors.New("loss case is closed")
+	ErrInvalidCaseStep       = errors.New("loss case is not at the right step for this action")
+	ErrNotCaseParty          = errors.New("user is not a party to this loss case")
+	ErrOpenCaseExists        = errors.New("property already has an open loss case")
+	ErrPropertyNotOnHand     = errors.New("property is not on a hand receipt")
+	ErrInvestigatorConflict  = errors.New("the investigating officer cannot be the hand receipt holder or the approving authority")
+	ErrApproverIsHolder      = errors.New("the approving authority cannot be the hand receipt holder")
+	ErrApproverIsInitiator   = errors.New("the approving authority cannot be the person reporting the loss")
+	ErrNotHolderOrLeader     = errors.New("only the hand receipt holder or their chain of command can report this property")
+	ErrInvalidLiabilityValue = errors.New("liability amount must be greater than zero and no more than the total loss")
+)
+
+// OpenCaseInput represents input for reporting property lost, damaged or destroyed
+type OpenCaseInput struct {
+	PropertyID           uint      `json:"propertyId" binding:"required"`
+	CaseType             string    `json:"caseType" binding:"required,oneof=lost damaged destroyed"`
+	Circumstances        string    `json:"circumstances" binding:"required"`
+	IncidentDate         time.Time `json:"incidentDate" binding:"required"`
+	IncidentLocation     *string   `json:"incidentLocation"`
+	ApprovingAuthorityID uint      `json:"approvingAuthorityId" binding:"required"`
+}
+
+// FindingsInput represents the investigating officer's findings and recommendation.
+// LiabilityAmount defaults to the total loss when liability is recommended.
+type FindingsInput struct {
+	Findings          string   `json:"findings" binding:"required"`
+	LiabilityDecision string   `json:"liabilityDecision" binding:"required,oneof=liable not_liable"`
+	LiabilityAmount   *float64 `json:"liabilityAmount"`
+}
+
+// DecisionInput represents the approving authority's decision on the findings
+type DecisionInput struct {
+	Decision string  `json:"decision" binding:"required,oneof=approve return"`
+	Comments *string `json:"comments"`
+}
+
+// LossCaseService runs Financial Liability Investigation of Property Loss cases
+type LossCaseService struct {
+	db        *gorm.DB
+	repo      repository.Repository
+	ledger    ledger.LedgerService
+	generator *documents.DA2062Generator
+	storage   storage.StorageService
+}
+
+// NewLossCaseService creates a new loss case service
+func NewLossCaseService(
+	db *gorm.DB,
+	repo repository.Repository,
+	ledgerService ledger.LedgerService,
+	generator *documents.DA2062Generator,
+	storageService storage.StorageService,
+) *LossCaseService {
+	return &LossCaseService{
+		db:        db,
+		repo:      repo,
+		ledger:    ledgerService,
+		generator: generator,
+		storage:   storageService,
+	}
+}
+
+// Open reports property lost, damaged or destroyed. Only the holder or someone in
+// their chain of command can report it, and neither the holder nor the reporter can
+// approve the case. The property's status changes to the case type and any
+// transfers still in progress for it are cancelled.
+func (s *LossCaseService) Open(ctx context.Context, input OpenCaseInput, userID uint) (*domain.LossCase, error) {
+	property, err := s.repo.GetPropertyByID(input.PropertyID)
+	if err != nil {
+		return nil, err
+	}
+	if property.AssignedToUserID == nil || property.CurrentStatus == domain.PropertyStatusDisposed {
+		return nil, ErrPropertyNotOnHand
+	}
+	holderID := *property.AssignedToUserID
+	if userID != holderID {
+		leader, err := repository.InChainOfCommand(s.db.WithContext(ctx), userID, holderID)
+		if err != nil {
+			return nil, err
+		}
+		if !leader {
+			return nil, ErrNotHolderOrLeader
+		}
+	}
+	if err := checkApprover(holderID, userID, input.ApprovingAuthorityID); err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.GetUserByID(input.ApprovingAuthorityID); err != nil {
+		return nil, fmt.Errorf("approving authority not found: %w", err)
+	}
+
+	var openCases int64
+	if err := s.db.WithContext(ctx).Model(&domain.LossCase{}).
+		Where("property_id = ? AND status IN ?", property.ID, openStatuses()).
+		Count(&openCases).Error; err != nil {
+		return nil, err
+	}
+	if openCases > 0 {
+		return nil, ErrOpenCaseExists
+	}
+
+	quantity := property.Quantity
+	if quantity < 1 {
+		quantity = 1
+	}
+	lossCase := &domain.LossCase{
+		// Replaced with the dated case number once the ID is known
+		CaseNumber:           uuid.NewString(),
+		PropertyID:           property.ID,
+		HolderUserID:         property.AssignedToUserID,
+		InitiatedByUserID:    userID,
+		ApprovingAuthorityID: input.ApprovingAuthorityID,
+		CaseType:             input.CaseType,
+		Status:               domain.LossCaseStatusInitiated,
+		Circumstances:        input.Circumstances,
+		IncidentDate:         input.IncidentDate,
+		IncidentLocation:     input.IncidentLocation,
+		PreviousStatus:       property.CurrentStatus,
+		Quantity:             quantity,
+		UnitPrice:            property.UnitPrice,
+		TotalLoss:            property.UnitPrice * float64(quantity),
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(lossCase).Error; err != nil {
+			return err
+		}
+		lossCase.CaseNumber = fmt.Sprintf("FLIPL-%s-%04d", time.Now().UTC().Format("20060102"), lossCase.ID)
+		if err := tx.Model(lossCase).Update("case_number", lossCase.CaseNumber).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&domain.Property{}).Where("id = ?", property.ID).
+			Update("current_status", input.CaseType).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&domain.Transfer{}).
+			Where("property_id = ? AND status NOT IN ?", property.ID, []string{"accepted", "rejected", "cancelled"}).
+			Updates(map[string]interface{}{"status": "cancelled", "resolved_date": time.Now().UTC()}).Error; err != nil {
+			return err
+		}
+		return addAction(tx, lossCase, domain.LossCaseActionOpened, userID, "", nil)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open loss case: %w", err)
+	}
+
+	if err := s.ledger.LogStatusChange(property.ID, property.SerialNumber, property.CurrentStatus, input.CaseType, userID); err != nil {
+		log.Printf("WARNING: Failed to log status change (PropertyID: %d, SN: %s) to Ledger: %v", property.ID, property.SerialNumber, err)
+	}
+	s.logCaseEvent(lossCase, property.SerialNumber, domain.LossCaseActionOpened, userID)
+
+	return lossCase, nil
+}
+
+// checkApprover keeps the approving authority separate from the holder, who is
+// liable, and the person reporting the loss
+func checkApprover(holderID, initiatorID, approverID uint) error {
+	if approverID == holderID {
+		return ErrApproverIsHolder
+	}
+	if approverID == initiatorID {
+		return ErrApproverIsInitiator
+	}
+	return nil
+}
+
+// Get retrieves a case with its property, parties and approval steps
+func (s *LossCaseService) Get(ctx context.Context, caseID uint) (*domain.LossCase, error) {
+	var lossCase domain.LossCase
+	err := s.db.WithContext(ctx).
+		Preload("Property").
+		Preload("HolderUser").
+		Preload("InitiatedByUser").
+		Preload("InvestigatingOfficer").
+		Preload("ApprovingAuthority").
+		Preload("Actions", func(db *gorm.DB) *gorm.DB { return db.Order("created_at, id") }).
+		Preload("Actions.ActorUser").
+		First(&lossCase, caseID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &lossCase, nil
+}
+
+// ListForUser retrieves the cases a user is party to in any role
+func (s *LossCaseService) ListForUser(ctx context.Context, userID uint, status string) ([]domain.LossCase, error) {
+	var cases []domain.LossCase
+	query := s.db.WithContext(ctx).Preload("Property").
+		Where("holder_user_id = ? OR initiated_by_user_id = ? OR investigating_officer_id = ? OR approving_authority_id = ?",
+			userID, userID, userID, userID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("created_at DESC").Find(&cases).Error
+	return cases, err
+}
+
+// History returns the ledger history of the property under investigation
+func (s *LossCaseService) History(lossCase *domain.LossCase) ([]map[string]interface{}, error) {
+	return s.ledger.GetPropertyHistory(lossCase.PropertyID)
+}
+
+// IsParty reports whether the user holds any role in the case
+func IsParty(lossCase *domain.LossCase, userID uint) bool {
+	if lossCase.InitiatedByUserID == userID || lossCase.ApprovingAuthorityID == userID {
+		return true
+	}
+	if lossCase.HolderUserID != nil && *lossCase.HolderUserID == userID {
+		return true
+	}
+	return lossCase.InvestigatingOfficerID != nil && *lossCase.InvestigatingOfficerID == userID
+}
+
+// AssignInvestigator appoints or replaces the investigating officer. Only the
+// approving authority may appoint, and the officer must be disinterested.
+func (s *LossCaseService) AssignInvestigator(ctx context.Context, caseID, userID, investigatorID uint) (*domain.LossCase, error) {
+	lossCase, err := s.Get(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	if lossCase.ApprovingAuthorityID != userID {
+		return nil, ErrNotCaseParty
+	}
+	if lossCase.Status != domain.LossCaseStatusInitiated && lossCase.Status != domain.LossCaseStatusInvestigating {
+		return nil, ErrInvalidCaseStep
+	}
+	if investigatorID == lossCase.ApprovingAuthorityID || (lossCase.HolderUserID != nil && investigatorID == *lossCase.HolderUserID) {
+		return nil, ErrInvestigatorConflict
+	}
+	investigator, err := s.repo.GetUserByID(investigatorID)
+	if err != nil {
+		return nil, fmt.Errorf("investigating officer not found: %w", err)
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		fromStatus := lossCase.Status
+		lossCase.InvestigatingOfficerID = &investigatorID
+		lossCase.InvestigatingOfficer = investigator
+		lossCase.Status = domain.LossCaseStatusInvestigating
+		if err := tx.Omit(clause.Associations).Save(lossCase).Error; err != nil {
+			return err
+		}
+		return addAction(tx, lossCase, domain.LossCaseActionInvestigatorAssigned, userID, fromStatus, nil)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to assign investigating officer: %w", err)
+	}
+
+	s.logCaseEvent(lossCase, serialOf(lossCase), domain.LossCaseActionInvestigatorAssigned, userID)
+	return lossCase, nil
+}
+
+// SubmitFindings records the investigating officer's findings and sends the case
+// to the approving authority.
+func (s *LossCaseService) SubmitFindings(ctx context.Context, caseID, userID uint, input FindingsInput) (*domain.LossCase, error) {
+	lossCase, err := s.Get(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	if lossCase.InvestigatingOfficerID == nil || *lossCase.InvestigatingOfficerID != userID {
+		return nil, ErrNotCaseParty
+	}
+	if lossCase.Status != domain.LossCaseStatusInvestigating {
+		return nil, ErrInvalidCaseStep
+	}
+
+	amount := 0.0
+	if input.LiabilityDecision == domain.LiabilityDecisionLiable {
+		amount = lossCase.TotalLoss
+		if input.LiabilityAmount != nil {
+			amount = *input.LiabilityAmount
+		}
+		if amount <= 0 || amount > lossCase.TotalLoss {
+			return nil, ErrInvalidLiabilityValue
+		}
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		lossCase.Findings = &input.Findings
+		lossCase.LiabilityDecision = &input.LiabilityDecision
+		lossCase.LiabilityAmount = amount
+		lossCase.Status = domain.LossCaseStatusPendingApproval
+		if err := tx.Omit(clause.Associations).Save(lossCase).Error; err != nil {
+			return err
+		}
+		return addAction(tx, lossCase, domain.LossCaseActionFindingsSubmitted, userID, domain.LossCaseStatusInvestigating, nil)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to submit findings: %w", err)
+	}
+
+	s.logCaseEvent(lossCase, serialOf(lossCase), domain.LossCaseActionFindingsSubmitted, userID)
+	return lossCase, nil
+}
+
+// Decide applies the approving authority's decision. Returning the case sends it
+// back to the investigating officer; approving it drops the property from the hand
+// receipt and issues the signed DD Form 200 to everyone involved.
+func (s *LossCaseService) Decide(ctx context.Context, caseID, userID uint, input DecisionInput) (*domain.LossCase, error) {
+	lossCase, err := s.Get(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	if lossCase.ApprovingAuthorityID != userID {
+		return nil, ErrNotCaseParty
+	}
+	if lossCase.Status != domain.LossCaseStatusPendingApproval {
+		return nil, ErrInvalidCaseStep
+	}
+
+	if input.Decision == "return" {
+		err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			lossCase.Status = domain.LossCaseStatusInvestigating
+			if err := tx.Omit(clause.Associations).Save(lossCase).Error; err != nil {
+				return err
+			}
+			return addAction(tx, lossCase, domain.LossCaseActionReturned, userID, domain.LossCaseStatusPendingApproval, input.Comments)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to return loss case: %w", err)
+		}
+		s.logCaseEvent(lossCase, serialOf(lossCase), domain.LossCaseActionReturned, userID)
+		return lossCase, nil
+	}
+
+	var detached []domain.PropertyComponent
+	now := time.Now().UTC()
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		lossCase.Status = domain.LossCaseStatusApproved
+		lossCase.ApprovedAt = &now
+		if err := tx.Omit(clause.Associations).Save(lossCase).Error; err != nil {
+			return err
+		}
+
+		// Components stay on the hand receipt but are no longer attached to the dropped item
+		if err := tx.Where("parent_property_id = ? OR component_property_id = ?", lossCase.PropertyID, lossCase.PropertyID).
+			Find(&detached).Error; err != nil {
+			return err
+		}
+		if len(detached) > 0 {
+			if err := tx.Delete(&detached).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&domain.Property{}).Where("id = ?", lossCase.PropertyID).Updates(map[string]interface{}{
+			"assigned_to_user_id": nil,
+			"current_status":      domain.PropertyStatusDisposed,
+			"updated_at":          now,
+		}).Error; err != nil {
+			return err
+		}
+		return addAction(tx, lossCase, domain.LossCaseActionApproved, userID, domain.LossCaseStatusPendingApproval, input.Comments)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to approve loss case: %w", err)
+	}
+
+	serial := serialOf(lossCase)
+	for _, attachment := range detached {
+		if err := s.ledger.LogComponentDetached(attachment.ParentPropertyID, attachment.ComponentPropertyID, userID); err != nil {
+			log.Printf("WARNING: Failed to log component detachment (Parent: %d, Component: %d) to Ledger: %v", attachment.ParentPropertyID, attachment.ComponentPropertyID, err)
+		}
+	}
+	if err := s.ledger.LogStatusChange(lossCase.PropertyID, serial, lossCase.CaseType, domain.PropertyStatusDisposed, userID); err != nil {
+		log.Printf("WARNING: Failed to log status change (PropertyID: %d, SN: %s) to Ledger: %v", lossCase.PropertyID, serial, err)
+	}
+	s.logCaseEvent(lossCase, serial, domain.LossCaseActionApproved, userID)
+
+	// The drop has been committed; a failure to issue the form is reported but does not undo it
+	if err := s.deliverForm(ctx, lossCase); err != nil {
+		log.Printf("WARNING: Failed to deliver DD Form 200 for loss case %s: %v", lossCase.CaseNumber, err)
+	}
+
+	return lossCase, nil
+}
+
+// Withdraw closes a case that has not been approved, typically because the item was
+// found, and restores the property's previous status.
+func (s *LossCaseService) Withdraw(ctx context.Context, caseID, userID uint, comments *string) (*domain.LossCase, error) {
+	lossCase, err := s.Get(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	if lossCase.InitiatedByUserID != userID && lossCase.ApprovingAuthorityID != userID {
+		return nil, ErrNotCaseParty
+	}
+	if !isOpen(lossCase.Status) {
+		return nil, ErrCaseNotOpen
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		fromStatus := lossCase.Status
+		lossCase.Status = domain.LossCaseStatusWithdrawn
+		if err := tx.Omit(clause.Associations).Save(lossCase).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&domain.Property{}).Where("id = ?", lossCase.PropertyID).
+			Update("current_status", lossCase.PreviousStatus).Error; err != nil {
+			return err
+		}
+		return addAction(tx, lossCase, domain.LossCaseActionWithdrawn, userID, fromStatus, comments)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to withdraw loss case: %w", err)
+	}
+
+	serial := serialOf(lossCase)
+	if err := s.ledger.LogStatusChange(lossCase.PropertyID, serial, lossCase.CaseType, lossCase.PreviousStatus, userID); err != nil {
+		log.Printf("WARNING: Failed to log status change (PropertyID: %d, SN: %s) to Ledger: %v", lossCase.PropertyID, serial, err)
+	}
+	s.logCaseEvent(lossCase, serial, domain.LossCaseActionWithdrawn, userID)
+
+	return lossCase, nil
+}
+
+// Form renders the DD Form 200 for the case as it currently stands. The approving
+// authority's signature only appears once the case is approved.
+func (s *LossCaseService) Form(ctx context.Context, lossCase *domain.LossCase) (*bytes.Buffer, error) {
+	data := documents.DD200Data{
+		CaseNumber:    lossCase.CaseNumber,
+		CaseType:      lossCase.CaseType,
+		DateInitiated: lossCase.CreatedAt,
+		IncidentDate:  lossCase.IncidentDate,
+		Quantity:      lossCase.Quantity,
+		UnitPrice:     lossCase.UnitPrice,
+		TotalLoss:     lossCase.TotalLoss,
+		Circumstances: lossCase.Circumstances,
+		ApprovedAt:    lossCase.ApprovedAt,
+	}
+	if lossCase.IncidentLocation != nil {
+		data.IncidentLocation = *lossCase.IncidentLocation
+	}
+	if lossCase.Property != nil {
+		data.Nomenclature = lossCase.Property.Name
+		data.SerialNumber = lossCase.Property.SerialNumber
+		data.UnitOfIssue = lossCase.Property.UnitOfIssue
+		if lossCase.Property.NSN != nil {
+			data.NSN = *lossCase.Property.NSN
+		}
+	}
+	if lossCase.HolderUser != nil {
+		data.Holder = documents.UserInfoFromUser(lossCase.HolderUser)
+	}
+	if lossCase.InitiatedByUser != nil {
+		data.Initiator = documents.UserInfoFromUser(lossCase.InitiatedByUser)
+	}
+	if lossCase.InvestigatingOfficer != nil {
+		investigator := documents.UserInfoFromUser(lossCase.InvestigatingOfficer)
+		data.Investigator = &investigator
+	}
+	if lossCase.Findings != nil {
+		data.Findings = *lossCase.Findings
+	}
+	if lossCase.LiabilityDecision != nil {
+		data.LiabilityDecision = *lossCase.LiabilityDecision
+		data.LiabilityAmount = lossCase.LiabilityAmount
+	}
+	unitInfo := documents.UnitInfo{}
+	if lossCase.ApprovingAuthority != nil {
+		data.ApprovingOfficial = documents.UserInfoFromUser(lossCase.ApprovingAuthority)
+		unitInfo.UnitName = lossCase.ApprovingAuthority.Unit
+	}
+
+	history, err := s.History(lossCase)
+	if err != nil {
+		log.Printf("WARNING: Failed to load ledger history for loss case %s: %v", lossCase.CaseNumber, err)
+	}
+	data.History = historyEntries(history)
+
+	return s.generator.GenerateDD200(data, unitInfo)
+}
+
+func (s *LossCaseService) deliverForm(ctx context.Context, lossCase *domain.LossCase) error {
+	pdf, err := s.Form(ctx, lossCase)
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("loss_cases/%d/dd200_%s.pdf", lossCase.ID, lossCase.CaseNumber)
+	if err := s.storage.UploadFile(ctx, key, bytes.NewReader(pdf.Bytes()), int64(pdf.Len()), "application/pdf"); err != nil {
+		return fmt.Errorf("failed to upload DD Form 200: %w", err)
+	}
+	url, err := s.storage.GetPresignedURL(ctx, key, 7*24*time.Hour)
+	if err != nil {
+		log.Printf("WARNING: Failed to get presigned URL for %s: %v", key, err)
+		url = key
+	}
+	lossCase.FormURL = &url
+	if err := s.db.WithContext(ctx).Model(lossCase).Update("form_url", url).Error; err != nil {
+		return fmt.Errorf("failed to save DD Form 200 link: %w", err)
+	}
+
+	formData, _ := json.Marshal(map[string]interface{}{
+		"loss_case_id":       lossCase.ID,
+		"case_number":        lossCase.CaseNumber,
+		"liability_decision": lossCase.LiabilityDecision,
+		"liability_amount":   lossCase.LiabilityAmount,
+	})
+	subtype := "DD200"
+	for _, recipientID := range recipients(lossCase) {
+		doc := &domain.Document{
+			Type:            domain.DocumentTypeLossForm,
+			Subtype:         &subtype,
+			Title:           fmt.Sprintf("DD Form 200 %s - %s %s", lossCase.CaseNumber, strings.ToUpper(lossCase.CaseType), serialOf(lossCase)),
+			SenderUserID:    lossCase.ApprovingAuthorityID,
+			RecipientUserID: recipientID,
+			PropertyID:      &lossCase.PropertyID,
+			Status:          domain.DocumentStatusUnread,
+			SentAt:          time.Now(),
+			FormData:        string(formData),
+			Attachments:     domain.JSONStringArray([]string{url}),
+		}
+		if err := s.repo.CreateDocument(doc); err != nil {
+			log.Printf("WARNING: Failed to create DD Form 200 document for user %d: %v", recipientID, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *LossCaseService) logCaseEvent(lossCase *domain.LossCase, serialNumber, action string, userID uint) {
+	if err := s.ledger.LogLossCaseEvent(lossCase.ID, lossCase.CaseNumber, lossCase.PropertyID, serialNumber, action, userID, lossCase.LiabilityAmount); err != nil {
+		log.Printf("WARNING: Failed to log loss case %s (%s) to Ledger: %v", lossCase.CaseNumber, action, err)
+	}
+}
+
+func addAction(tx *gorm.DB, lossCase *domain.LossCase, action string, userID uint, fromStatus string, comments *string) error {
+	return tx.Create(&domain.LossCaseAction{
+		CaseID:      lossCase.ID,
+		Action:      action,
+		ActorUserID: userID,
+		FromStatus:  fromStatus,
+		ToStatus:    lossCase.Status,
+		Comments:    comments,
+	}).Error
+}
+
+// recipients lists everyone who receives the approved form, without duplicates
+func recipients(lossCase *domain.LossCase) []uint {
+	seen := make(map[uint]bool)
+	var ids []uint
+	candidates := []*uint{lossCase.HolderUserID, &lossCase.InitiatedByUserID, lossCase.InvestigatingOfficerID}
+	for _, id := range candidates {
+		if id != nil && !seen[*id] {
+			seen[*id] = true
+			ids = append(ids, *id)
+		}
+	}
+	return ids
+}
+
+// historyEntries converts raw ledger history into rows for the DD Form 200 annex
+func historyEntries(history []map[string]interface{}) []documents.DD200HistoryEntry {
+	entries := make([]documents.DD200HistoryEntry, 0, len(history))
+	for _, event := range history {
+		entry := documents.DD200HistoryEntry{}
+		if createdAt, ok := event["ledger_created_at"].(time.Time); ok {
+			entry.Date = createdAt
+		}
+		if hash, ok := event["ledger_hash"].(string); ok {
+			entry.Hash = hash
+		}
+
+		description, _ := event["event_type"].(string)
+		for _, key := range []string{"action", "old_status", "new_status", "verification_type"} {
+			if value, ok := event[key].(string); ok && value != "" {
+				description += " " + key + "=" + value
+			}
+		}
+		entry.Event = description
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Date.Before(entries[j].Date) })
+	return entries
+}
+
+func serialOf(lossCase *domain.LossCase) string {
+	if lossCase.Property != nil {
+		return lossCase.Property.SerialNumber
+	}
+	return ""
+}
+
+func openStatuses() []string {
+	return []string{domain.LossCaseStatusInitiated, domain.LossCaseStatusInvestigating, domain.LossCaseStatusPendingApproval}
+}
+
+func isOpen(status string) bool {
+	for _, open := range openStatuses() {
+		if status == open {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package flipl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"github.com/toole-brendan/handreceipt-go/internal/ledger"
+	"github.com/toole-brendan/handreceipt-go/internal/repository"
+	"github.com/toole-brendan/handreceipt-go/internal/services/documents"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestCheckApprover(t *testing.T) {
+	tests := []struct {
+		name                        string
+		holder, initiator, approver uint
+		want                        error
+	}{
+		{"commander approves a report by the platoon leader", 1, 2, 3, nil},
+		{"holder reports and the commander approves", 1, 1, 3, nil},
+		{"holder cannot approve their own liability", 1, 2, 1, ErrApproverIsHolder},
+		{"reporter cannot approve their own report", 1, 2, 2, ErrApproverIsInitiator},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkApprover(tt.holder, tt.initiator, tt.approver); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestLossCaseFlow reports a lost item, investigates it and approves the findings
+// against a real database, checking who may open the case on the way. Set
+// HANDRECEIPT_TEST_DATABASE_URL to run it.
+func TestLossCaseFlow(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	suffix := time.Now().UnixNano()
+
+	newUser := func(name, rank string, supervisorID *uint) uint {
+		user := domain.User{Email: fmt.Sprintf("%s-%d@test.mil", name, suffix), PasswordHash: "x", FirstName: name, LastName: name, Rank: rank, SupervisorID: supervisorID}
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Delete(&user) })
+		return user.ID
+	}
+	commander := newUser("commander", "CPT", nil)
+	leader := newUser("leader", "1LT", &commander)
+	holder := newUser("holder", "SPC", &leader)
+	investigator := newUser("investigator", "1LT", nil)
+	outsider := newUser("outsider", "SGT", nil)
+
+	property := domain.Property{Name: "GOGGLES NIGHT VISION", SerialNumber: fmt.Sprintf("N-%d", suffix), CurrentStatus: "active", AssignedToUserID: &holder, Quantity: 1, UnitPrice: 3500}
+	if err := db.Create(&property).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM loss_case_actions WHERE case_id IN (SELECT id FROM loss_cases WHERE property_id = ?)", property.ID)
+		db.Where("property_id = ?", property.ID).Delete(&domain.Document{})
+		db.Where("property_id = ?", property.ID).Delete(&domain.LossCase{})
+		db.Delete(&property)
+	})
+
+	repo := repository.NewPostgresRepository(db)
+	ledgerService, err := ledger.NewPostgresLedgerService(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewLossCaseService(db, repo, ledgerService, documents.NewDA2062Generator(repo), memoryStorage{})
+	input := OpenCaseInput{
+		PropertyID:           property.ID,
+		CaseType:             domain.LossCaseLost,
+		Circumstances:        "Left at the range",
+		IncidentDate:         time.Now().Add(-24 * time.Hour),
+		ApprovingAuthorityID: commander,
+	}
+
+	// Someone outside the holder's chain of command cannot report the goggles
+	if _, err := service.Open(ctx, input, outsider); !errors.Is(err, ErrNotHolderOrLeader) {
+		t.Fatalf("expected ErrNotHolderOrLeader, got %v", err)
+	}
+	// The platoon leader cannot name themselves to approve their own report
+	input.ApprovingAuthorityID = leader
+	if _, err := service.Open(ctx, input, leader); !errors.Is(err, ErrApproverIsInitiator) {
+		t.Fatalf("expected ErrApproverIsInitiator, got %v", err)
+	}
+
+	input.ApprovingAuthorityID = commander
+	lossCase, err := service.Open(ctx, input, leader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.AssignInvestigator(ctx, lossCase.ID, commander, investigator); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.SubmitFindings(ctx, lossCase.ID, investigator, FindingsInput{Findings: "Negligent", LiabilityDecision: domain.LiabilityDecisionLiable}); err != nil {
+		t.Fatal(err)
+	}
+	// Only the approving authority decides
+	if _, err := service.Decide(ctx, lossCase.ID, leader, DecisionInput{Decision: "approve"}); !errors.Is(err, ErrNotCaseParty) {
+		t.Fatalf("expected ErrNotCaseParty, got %v", err)
+	}
+	approved, err := service.Decide(ctx, lossCase.ID, commander, DecisionInput{Decision: "approve"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if approved.Status != domain.LossCaseStatusApproved || approved.LiabilityAmount != 3500 {
+		t.Errorf("approved case: status %s, liability %.2f", approved.Status, approved.LiabilityAmount)
+	}
+
+	var dropped domain.Property
+	if err := db.First(&dropped, property.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if dropped.AssignedToUserID != nil || dropped.CurrentStatus != domain.PropertyStatusDisposed {
+		t.Errorf("property not dropped: holder %v, status %s", dropped.AssignedToUserID, dropped.CurrentStatus)
+	}
+}
+
+// memoryStorage accepts uploads and discards them
+type memoryStorage struct{}
+
+func (memoryStorage) UploadFile(ctx context.Context, objectName string, reader io.Reader, objectSize int64, contentType string) error {
+	_, err := io.Copy(io.Discard, reader)
+	return err
+}
+
+func (memoryStorage) DownloadFile(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	return nil, os.ErrNotExist
+}
+
+func (memoryStorage) DeleteFile(ctx context.Context, objectName string) error { return nil }
+
+func (memoryStorage) GetPresignedURL(ctx context.Context, objectName string, expiry time.Duration) (string, error) {
+	return "memory://" + objectName, nil
+}
+
+func (memoryStorage) ListFiles(ctx context.Context, prefix string) ([]string, error) { return nil, nil }
+
+// openTestDB connects to the database named by HANDRECEIPT_TEST_DATABASE_URL, or
+// skips the test
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("HANDRECEIPT_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("Skipping database integration test - HANDRECEIPT_TEST_DATABASE_URL not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&domain.User{}, &domain.Property{}, &domain.PropertyComponent{}, &domain.Transfer{},
+		&domain.LossCase{}, &domain.LossCaseAction{}, &domain.Document{}); err != nil {
+		t.Fatalf("Failed to migrate tables: %v", err)
+	}
+	return db
+}
@@ -0,0 +1,859 @@
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"github.com/toole-brendan/handreceipt-go/internal/ledger"
+	"github.com/toole-brendan/handreceipt-go/internal/repository"
+	"github.com/toole-brendan/handreceipt-go/internal/services/documents"
+	"github.com/toole-brendan/handreceipt-go/internal/services/notification"
+	"github.com/toole-brendan/handreceipt-go/internal/services/storage"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrCampaignNotActive     = errors.New("inventory campaign is not active")
+	ErrNoPropertySelected    = errors.New("selection matched no property")
+	ErrNotCampaignOwner      = errors.New("only the campaign creator can do this")
+	ErrNotAssignedCounter    = errors.New("item is assigned to a different counter")
+	ErrCounterIsHolder       = errors.New("sensitive items must be counted by someone other than their holder")
+	ErrInvalidSamplePercent  = errors.New("sample percent must be between 1 and 100")
+	ErrNotResponsible        = errors.New("selection includes property you are not responsible for")
+	ErrNotCampaignParty      = errors.New("not the creator or a counter of this inventory campaign")
+	ErrCreatorIsCounter      = errors.New("the campaign creator certifies the report and cannot count items")
+	ErrInvalidRepeat         = errors.New("repeat interval must be between 1 and 365 days")
+	ErrCounterNotSubordinate = errors.New("counters must be in the campaign creator's chain of command")
+)
+
+// reminderInterval is the minimum gap between overdue reminders for the same item
+const reminderInterval = 24 * time.Hour
+
+// CampaignSelection describes which property a campaign covers. Filters combine;
+// SamplePercent then draws a random sample from whatever matched.
+type CampaignSelection struct {
+	PropertyIDs   []uint   `json:"propertyIds,omitempty"`
+	Categories    []string `json:"categories,omitempty"`
+	HolderUserIDs []uint   `json:"holderUserIds,omitempty"`
+	Unit          string   `json:"unit,omitempty"`
+	SamplePercent int      `json:"samplePercent,omitempty"`
+}
+
+// CreateCampaignInput represents input for creating an inventory campaign
+type CreateCampaignInput struct {
+	Name         string            `json:"name" binding:"required"`
+	CampaignType string            `json:"campaignType" binding:"required,oneof=cyclic sensitive_item"`
+	DueDate      time.Time         `json:"dueDate" binding:"required"`
+	Selection    CampaignSelection `json:"selection"`
+	// Open the next campaign over the same selection this many days after this one
+	RepeatEveryDays *int `json:"repeatEveryDays,omitempty"`
+}
+
+// AssignCountersInput assigns a counter to specific items, to every item held by
+// one holder, or, when neither is given, to every unassigned item.
+type AssignCountersInput struct {
+	CounterUserID uint   `json:"counterUserId" binding:"required"`
+	ItemIDs       []uint `json:"itemIds"`
+	HolderUserID  *uint  `json:"holderUserId"`
+}
+
+// RecordCountInput represents a counter's result for one item
+type RecordCountInput struct {
+	Result        string  `json:"result" form:"result" binding:"required,oneof=verified discrepant not_found"`
+	ScannedSerial *string `json:"scannedSerial" form:"scannedSerial"`
+	Condition     *string `json:"condition" form:"condition"`
+	Notes         *string `json:"notes" form:"notes"`
+}
+
+// CountPhoto is an optional photo taken while counting an item
+type CountPhoto struct {
+	Reader      io.Reader
+	Size        int64
+	ContentType string
+	Filename    string
+}
+
+// CampaignStats gives completion and discrepancy rates for a campaign
+type CampaignStats struct {
+	Total           int     `json:"total"`
+	Counted         int     `json:"counted"`
+	Pending         int     `json:"pending"`
+	Verified        int     `json:"verified"`
+	Discrepant      int     `json:"discrepant"`
+	NotFound        int     `json:"notFound"`
+	Unassigned      int     `json:"unassigned"`
+	CompletionRate  float64 `json:"completionRate"`
+	DiscrepancyRate float64 `json:"discrepancyRate"` // Share of counted items that were discrepant or not found
+}
+
+// CampaignService manages cyclic and sensitive-item inventory campaigns
+type CampaignService struct {
+	db        *gorm.DB
+	repo      repository.Repository
+	ledger    ledger.LedgerService
+	generator *documents.DA2062Generator
+	storage   storage.StorageService
+}
+
+// NewCampaignService creates a new inventory campaign service
+func NewCampaignService(
+	db *gorm.DB,
+	repo repository.Repository,
+	ledgerService ledger.LedgerService,
+	generator *documents.DA2062Generator,
+	storageService storage.StorageService,
+) *CampaignService {
+	return &CampaignService{
+		db:        db,
+		repo:      repo,
+		ledger:    ledgerService,
+		generator: generator,
+		storage:   storageService,
+	}
+}
+
+// Create selects property according to the input and opens a campaign over it.
+// Only property held by the creator or someone below them in the chain of command
+// can be selected.
+func (s *CampaignService) Create(ctx context.Context, input CreateCampaignInput, userID uint) (*domain.InventoryCampaign, error) {
+	selection := input.Selection
+	if selection.SamplePercent < 0 || selection.SamplePercent > 100 {
+		return nil, ErrInvalidSamplePercent
+	}
+	if input.RepeatEveryDays != nil && (*input.RepeatEveryDays < 1 || *input.RepeatEveryDays > 365) {
+		return nil, ErrInvalidRepeat
+	}
+
+	now := time.Now().UTC()
+	campaign := &domain.InventoryCampaign{
+		Name:            input.Name,
+		CampaignType:    input.CampaignType,
+		CreatedByUserID: userID,
+		DueDate:         input.DueDate,
+		RepeatEveryDays: input.RepeatEveryDays,
+	}
+	if input.RepeatEveryDays != nil {
+		nextRun := now.AddDate(0, 0, *input.RepeatEveryDays)
+		campaign.NextRunAt = &nextRun
+	}
+
+	if err := openCampaign(ctx, s.db, campaign, selection); err != nil {
+		return nil, err
+	}
+
+	s.logCampaignEvent(ctx, "InventoryCampaignCreated", userID, campaign, map[string]interface{}{
+		"item_count": len(campaign.Items),
+		"selection":  selection,
+	})
+
+	return campaign, nil
+}
+
+// openCampaign selects the property for a new campaign within its creator's
+// responsibility and saves the campaign with its items
+func openCampaign(ctx context.Context, db *gorm.DB, campaign *domain.InventoryCampaign, selection CampaignSelection) error {
+	holders, err := responsibleHolders(db.WithContext(ctx), campaign.CreatedByUserID)
+	if err != nil {
+		return err
+	}
+	if err := checkResponsible(ctx, db, selection, holders); err != nil {
+		return err
+	}
+
+	properties, err := selectProperty(ctx, db, campaign.CampaignType, selection, holders)
+	if err != nil {
+		return err
+	}
+	if len(properties) == 0 {
+		return ErrNoPropertySelected
+	}
+
+	criteria, err := json.Marshal(selection)
+	if err != nil {
+		return fmt.Errorf("failed to encode selection: %w", err)
+	}
+	campaign.Status = domain.InventoryCampaignStatusActive
+	campaign.SelectionCriteria = string(criteria)
+
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(campaign).Error; err != nil {
+			return err
+		}
+
+		items := make([]domain.InventoryCampaignItem, 0, len(properties))
+		for _, property := range properties {
+			items = append(items, domain.InventoryCampaignItem{
+				CampaignID:   campaign.ID,
+				PropertyID:   property.ID,
+				HolderUserID: property.AssignedToUserID,
+				SerialNumber: property.SerialNumber,
+				Status:       domain.InventoryCountPending,
+			})
+		}
+		if err := tx.CreateInBatches(items, 100).Error; err != nil {
+			return err
+		}
+		campaign.Items = items
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create inventory campaign: %w", err)
+	}
+	return nil
+}
+
+// responsibleHolders returns the user and everyone below them in the chain of
+// command, whose property the user can inventory
+func responsibleHolders(db *gorm.DB, userID uint) ([]uint, error) {
+	subordinates, err := repository.SubordinateIDs(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	return append([]uint{userID}, subordinates...), nil
+}
+
+// checkResponsible rejects a selection naming holders or property outside holders
+// rather than quietly leaving them out
+func checkResponsible(ctx context.Context, db *gorm.DB, selection CampaignSelection, holders []uint) error {
+	if outside := notIn(selection.HolderUserIDs, holders); len(outside) > 0 {
+		return fmt.Errorf("%w: holder %v", ErrNotResponsible, outside)
+	}
+	if len(selection.PropertyIDs) == 0 {
+		return nil
+	}
+
+	var held []uint
+	err := db.WithContext(ctx).Model(&domain.Property{}).
+		Where("id IN ? AND assigned_to_user_id IN ?", selection.PropertyIDs, holders).
+		Pluck("id", &held).Error
+	if err != nil {
+		return fmt.Errorf("failed to check selected property: %w", err)
+	}
+	if outside := notIn(selection.PropertyIDs, held); len(outside) > 0 {
+		return fmt.Errorf("%w: property %v", ErrNotResponsible, outside)
+	}
+	return nil
+}
+
+// notIn returns the IDs that are not in allowed, once each
+func notIn(ids, allowed []uint) []uint {
+	ok := make(map[uint]bool, len(allowed))
+	for _, id := range allowed {
+		ok[id] = true
+	}
+	var outside []uint
+	for _, id := range ids {
+		if !ok[id] {
+			outside = append(outside, id)
+			ok[id] = true
+		}
+	}
+	return outside
+}
+
+// selectProperty finds the property held by holders matching a selection, narrowed
+// to sensitive categories for sensitive-item campaigns and sampled if requested.
+func selectProperty(ctx context.Context, db *gorm.DB, campaignType string, selection CampaignSelection, holders []uint) ([]domain.Property, error) {
+	query := db.WithContext(ctx).Model(&domain.Property{}).Where("properties.assigned_to_user_id IN ?", holders)
+
+	if campaignType == domain.InventoryCampaignSensitiveItem {
+		query = query.Where("properties.category IN (SELECT code FROM property_categories WHERE is_sensitive = TRUE)")
+	}
+	if len(selection.PropertyIDs) > 0 {
+		query = query.Where("properties.id IN ?", selection.PropertyIDs)
+	}
+	if len(selection.Categories) > 0 {
+		query = query.Where("properties.category IN ?", selection.Categories)
+	}
+	if len(selection.HolderUserIDs) > 0 {
+		query = query.Where("properties.assigned_to_user_id IN ?", selection.HolderUserIDs)
+	}
+	if selection.Unit != "" {
+		query = query.Joins("JOIN users ON users.id = properties.assigned_to_user_id").
+			Where("users.unit = ?", selection.Unit)
+	}
+
+	var properties []domain.Property
+	if err := query.Order("properties.id").Find(&properties).Error; err != nil {
+		return nil, fmt.Errorf("failed to select property: %w", err)
+	}
+
+	if selection.SamplePercent > 0 && selection.SamplePercent < 100 && len(properties) > 0 {
+		sampleSize := int(math.Ceil(float64(len(properties)) * float64(selection.SamplePercent) / 100))
+		rand.Shuffle(len(properties), func(i, j int) {
+			properties[i], properties[j] = properties[j], properties[i]
+		})
+		properties = properties[:sampleSize]
+	}
+
+	return properties, nil
+}
+
+// Get retrieves a campaign with its items for its creator or one of its counters
+func (s *CampaignService) Get(ctx context.Context, campaignID, userID uint) (*domain.InventoryCampaign, error) {
+	campaign, err := s.load(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if !IsCampaignParty(campaign, userID) {
+		return nil, ErrNotCampaignParty
+	}
+	return campaign, nil
+}
+
+// IsCampaignParty reports whether the user created the campaign or counts any of
+// its items
+func IsCampaignParty(campaign *domain.InventoryCampaign, userID uint) bool {
+	if campaign.CreatedByUserID == userID {
+		return true
+	}
+	for _, item := range campaign.Items {
+		if item.CounterUserID != nil && *item.CounterUserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *CampaignService) load(ctx context.Context, campaignID uint) (*domain.InventoryCampaign, error) {
+	var campaign domain.InventoryCampaign
+	err := s.db.WithContext(ctx).
+		Preload("CreatedByUser").
+		Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Items.Property").
+		Preload("Items.CounterUser").
+		First(&campaign, campaignID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+// ListForUser retrieves campaigns the user created or is counting for
+func (s *CampaignService) ListForUser(ctx context.Context, userID uint, status string) ([]domain.InventoryCampaign, error) {
+	var campaigns []domain.InventoryCampaign
+	query := s.db.WithContext(ctx).
+		Where("created_by_user_id = ? OR id IN (SELECT campaign_id FROM inventory_campaign_items WHERE counter_user_id = ?)", userID, userID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("due_date ASC").Find(&campaigns).Error
+	return campaigns, err
+}
+
+// AssignCounters assigns a counter to campaign items and returns how many were
+// assigned. The counter must be in the campaign creator's chain of command.
+func (s *CampaignService) AssignCounters(ctx context.Context, campaignID, userID uint, input AssignCountersInput) (int64, error) {
+	campaign, err := s.activeCampaign(ctx, campaignID)
+	if err != nil {
+		return 0, err
+	}
+	if campaign.CreatedByUserID != userID {
+		return 0, ErrNotCampaignOwner
+	}
+	if input.CounterUserID == campaign.CreatedByUserID {
+		return 0, ErrCreatorIsCounter
+	}
+	if _, err := s.repo.GetUserByID(input.CounterUserID); err != nil {
+		return 0, fmt.Errorf("counter not found: %w", err)
+	}
+	// Only the creator's own soldiers can be sent to count property
+	subordinate, err := repository.InChainOfCommand(s.db.WithContext(ctx), campaign.CreatedByUserID, input.CounterUserID)
+	if err != nil {
+		return 0, err
+	}
+	if !subordinate {
+		return 0, ErrCounterNotSubordinate
+	}
+
+	query := s.db.WithContext(ctx).Model(&domain.InventoryCampaignItem{}).
+		Where("campaign_id = ? AND status = ?", campaignID, domain.InventoryCountPending)
+	switch {
+	case len(input.ItemIDs) > 0:
+		query = query.Where("id IN ?", input.ItemIDs)
+	case input.HolderUserID != nil:
+		query = query.Where("holder_user_id = ?", *input.HolderUserID)
+	default:
+		query = query.Where("counter_user_id IS NULL")
+	}
+
+	// Sensitive items are never counted by the soldier who signed for them
+	if campaign.CampaignType == domain.InventoryCampaignSensitiveItem {
+		var conflicts int64
+		if err := query.Session(&gorm.Session{}).Where("holder_user_id = ?", input.CounterUserID).Count(&conflicts).Error; err != nil {
+			return 0, err
+		}
+		if conflicts > 0 {
+			return 0, ErrCounterIsHolder
+		}
+	}
+
+	result := query.Updates(map[string]interface{}{
+		"counter_user_id": input.CounterUserID,
+		"updated_at":      time.Now().UTC(),
+	})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to assign counter: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// RecordCount stores a counter's result for one item. A scanned serial that does
+// not match the item turns a verified count into a discrepancy.
+func (s *CampaignService) RecordCount(ctx context.Context, campaignID, itemID, userID uint, input RecordCountInput, photo *CountPhoto) (*domain.InventoryCampaignItem, error) {
+	campaign, err := s.activeCampaign(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	var item domain.InventoryCampaignItem
+	if err := s.db.WithContext(ctx).Where("id = ? AND campaign_id = ?", itemID, campaignID).First(&item).Error; err != nil {
+		return nil, err
+	}
+	if item.CounterUserID == nil || *item.CounterUserID != userID {
+		return nil, ErrNotAssignedCounter
+	}
+
+	status := input.Result
+	if input.ScannedSerial != nil && *input.ScannedSerial != "" {
+		matched := strings.EqualFold(strings.TrimSpace(*input.ScannedSerial), strings.TrimSpace(item.SerialNumber))
+		item.ScannedSerial = input.ScannedSerial
+		item.SerialMatched = &matched
+		if !matched && status == domain.InventoryCountVerified {
+			status = domain.InventoryCountDiscrepant
+		}
+	}
+
+	if photo != nil {
+		key := fmt.Sprintf("inventory_campaigns/%d/items/%d_%d%s", campaignID, item.ID, time.Now().Unix(), filepath.Ext(photo.Filename))
+		if err := s.storage.UploadFile(ctx, key, photo.Reader, photo.Size, photo.ContentType); err != nil {
+			return nil, fmt.Errorf("failed to upload photo: %w", err)
+		}
+		photoURL, err := s.storage.GetPresignedURL(ctx, key, 24*time.Hour*365)
+		if err != nil {
+			log.Printf("WARNING: Failed to get presigned URL for inventory photo %s: %v", key, err)
+			photoURL = key
+		}
+		item.PhotoURL = &photoURL
+	}
+
+	now := time.Now().UTC()
+	item.Status = status
+	item.Condition = input.Condition
+	item.Notes = input.Notes
+	item.CountedAt = &now
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(&item).Error; err != nil {
+			return err
+		}
+		if status != domain.InventoryCountVerified {
+			return nil
+		}
+		return tx.Model(&domain.Property{}).Where("id = ?", item.PropertyID).Updates(map[string]interface{}{
+			"verified":         true,
+			"verified_at":      now,
+			"verified_by":      userID,
+			"last_verified_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record count: %w", err)
+	}
+
+	count := ledger.InventoryCountEvent{
+		CampaignID:    campaign.ID,
+		CampaignType:  campaign.CampaignType,
+		PropertyID:    item.PropertyID,
+		SerialNumber:  item.SerialNumber,
+		UserID:        userID,
+		Result:        status,
+		ScannedSerial: item.ScannedSerial,
+		SerialMatched: item.SerialMatched,
+	}
+	if err := s.ledger.LogInventoryCount(count); err != nil {
+		log.Printf("WARNING: Failed to log inventory count (PropertyID: %d, SN: %s) to ledger: %v", item.PropertyID, item.SerialNumber, err)
+	}
+
+	return &item, nil
+}
+
+// ComputeStats works out completion and discrepancy rates from a campaign's items
+func ComputeStats(items []domain.InventoryCampaignItem) CampaignStats {
+	stats := CampaignStats{Total: len(items)}
+	for _, item := range items {
+		if item.CounterUserID == nil {
+			stats.Unassigned++
+		}
+		switch item.Status {
+		case domain.InventoryCountPending:
+			stats.Pending++
+		case domain.InventoryCountVerified:
+			stats.Verified++
+		case domain.InventoryCountDiscrepant:
+			stats.Discrepant++
+		case domain.InventoryCountNotFound:
+			stats.NotFound++
+		}
+	}
+	stats.Counted = stats.Total - stats.Pending
+	if stats.Total > 0 {
+		stats.CompletionRate = float64(stats.Counted) / float64(stats.Total)
+	}
+	if stats.Counted > 0 {
+		stats.DiscrepancyRate = float64(stats.Discrepant+stats.NotFound) / float64(stats.Counted)
+	}
+	return stats
+}
+
+// Close ends a campaign and issues the inventory report signed by the closing user.
+// The report's SHA-256 digest is recorded in the ledger so it can be verified later.
+func (s *CampaignService) Close(ctx context.Context, campaignID, userID uint) (*domain.InventoryCampaign, error) {
+	campaign, err := s.load(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if campaign.Status != domain.InventoryCampaignStatusActive {
+		return nil, ErrCampaignNotActive
+	}
+	if campaign.CreatedByUserID != userID {
+		return nil, ErrNotCampaignOwner
+	}
+
+	certifier, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get certifying officer: %w", err)
+	}
+
+	stats := ComputeStats(campaign.Items)
+	pdf, err := s.generator.GenerateInventoryReport(
+		s.buildReport(ctx, campaign, stats),
+		documents.UserInfoFromUser(certifier),
+		documents.UnitInfo{UnitName: certifier.Unit},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256(pdf.Bytes())
+	reportHash := hex.EncodeToString(digest[:])
+	key := fmt.Sprintf("inventory_campaigns/%d/report_%s.pdf", campaign.ID, reportHash[:12])
+	if err := s.storage.UploadFile(ctx, key, bytes.NewReader(pdf.Bytes()), int64(pdf.Len()), "application/pdf"); err != nil {
+		return nil, fmt.Errorf("failed to upload inventory report: %w", err)
+	}
+	reportURL, err := s.storage.GetPresignedURL(ctx, key, 7*24*time.Hour)
+	if err != nil {
+		log.Printf("WARNING: Failed to get presigned URL for inventory report %s: %v", key, err)
+		reportURL = key
+	}
+
+	now := time.Now().UTC()
+	campaign.Status = domain.InventoryCampaignStatusClosed
+	campaign.ClosedAt = &now
+	campaign.ClosedByUserID = &userID
+	campaign.ReportURL = &reportURL
+	campaign.ReportSHA256 = &reportHash
+	if err := s.db.WithContext(ctx).Omit(clause.Associations).Save(campaign).Error; err != nil {
+		return nil, fmt.Errorf("failed to close inventory campaign: %w", err)
+	}
+
+	s.logCampaignEvent(ctx, "InventoryCampaignClosed", userID, campaign, map[string]interface{}{
+		"report_sha256":    reportHash,
+		"total":            stats.Total,
+		"counted":          stats.Counted,
+		"discrepant":       stats.Discrepant,
+		"not_found":        stats.NotFound,
+		"completion_rate":  stats.CompletionRate,
+		"discrepancy_rate": stats.DiscrepancyRate,
+	})
+
+	return campaign, nil
+}
+
+// Cancel abandons an active campaign, and stops a recurring one from repeating
+func (s *CampaignService) Cancel(ctx context.Context, campaignID, userID uint) (*domain.InventoryCampaign, error) {
+	campaign, err := s.activeCampaign(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if campaign.CreatedByUserID != userID {
+		return nil, ErrNotCampaignOwner
+	}
+
+	campaign.Status = domain.InventoryCampaignStatusCancelled
+	campaign.NextRunAt = nil
+	if err := s.db.WithContext(ctx).Omit(clause.Associations).Save(campaign).Error; err != nil {
+		return nil, fmt.Errorf("failed to cancel inventory campaign: %w", err)
+	}
+
+	s.logCampaignEvent(ctx, "InventoryCampaignCancelled", userID, campaign, nil)
+	return campaign, nil
+}
+
+func (s *CampaignService) buildReport(ctx context.Context, campaign *domain.InventoryCampaign, stats CampaignStats) documents.InventoryReport {
+	title := "CYCLIC INVENTORY REPORT"
+	if campaign.CampaignType == domain.InventoryCampaignSensitiveItem {
+		title = "SENSITIVE ITEM INVENTORY REPORT"
+	}
+
+	// Resolve holder names once rather than per line
+	holderNames := make(map[uint]string)
+	var holderIDs []uint
+	for _, item := range campaign.Items {
+		if item.HolderUserID != nil {
+			holderIDs = append(holderIDs, *item.HolderUserID)
+		}
+	}
+	if len(holderIDs) > 0 {
+		var holders []domain.User
+		if err := s.db.WithContext(ctx).Where("id IN ?", holderIDs).Find(&holders).Error; err != nil {
+			log.Printf("WARNING: Failed to load holders for inventory report %d: %v", campaign.ID, err)
+		}
+		for _, holder := range holders {
+			holderNames[holder.ID] = holder.Rank + " " + holder.LastName
+		}
+	}
+
+	report := documents.InventoryReport{
+		Title:           title + " - " + strings.ToUpper(campaign.Name),
+		Reference:       fmt.Sprintf("INV-%s-%d", campaign.CreatedAt.Format("20060102"), campaign.ID),
+		DueDate:         campaign.DueDate,
+		Total:           stats.Total,
+		Counted:         stats.Counted,
+		Verified:        stats.Verified,
+		Discrepant:      stats.Discrepant,
+		NotFound:        stats.NotFound,
+		CompletionRate:  stats.CompletionRate,
+		DiscrepancyRate: stats.DiscrepancyRate,
+	}
+
+	for _, item := range campaign.Items {
+		line := documents.InventoryReportLine{
+			SerialNumber:  item.SerialNumber,
+			Result:        strings.ToUpper(strings.ReplaceAll(item.Status, "_", " ")),
+			SerialMatched: item.SerialMatched,
+		}
+		if item.Property != nil {
+			line.Name = item.Property.Name
+			if item.Property.NSN != nil {
+				line.NSN = *item.Property.NSN
+			}
+		}
+		if item.HolderUserID != nil {
+			line.Holder = holderNames[*item.HolderUserID]
+		}
+		if item.CounterUser != nil {
+			line.Counter = item.CounterUser.Rank + " " + item.CounterUser.LastName
+		}
+		if item.Notes != nil {
+			line.Notes = *item.Notes
+		}
+		report.Lines = append(report.Lines, line)
+	}
+
+	return report
+}
+
+func (s *CampaignService) activeCampaign(ctx context.Context, campaignID uint) (*domain.InventoryCampaign, error) {
+	var campaign domain.InventoryCampaign
+	if err := s.db.WithContext(ctx).First(&campaign, campaignID).Error; err != nil {
+		return nil, err
+	}
+	if campaign.Status != domain.InventoryCampaignStatusActive {
+		return nil, ErrCampaignNotActive
+	}
+	return &campaign, nil
+}
+
+func (s *CampaignService) logCampaignEvent(ctx context.Context, eventType string, userID uint, campaign *domain.InventoryCampaign, extra map[string]interface{}) {
+	metadata := map[string]interface{}{
+		"campaign_id":   campaign.ID,
+		"campaign_type": campaign.CampaignType,
+		"status":        campaign.Status,
+		"due_date":      campaign.DueDate,
+	}
+	for k, v := range extra {
+		metadata[k] = v
+	}
+
+	if err := s.ledger.LogEvent(ctx, ledger.Event{
+		Type:     eventType,
+		UserID:   strconv.FormatUint(uint64(userID), 10),
+		Metadata: metadata,
+	}); err != nil {
+		log.Printf("WARNING: Failed to log %s for campaign %d to ledger: %v", eventType, campaign.ID, err)
+	}
+}
+
+// ScheduleRecurring opens the next campaign of every recurring series that has
+// fallen due, over the same selection and with the same time to count. A series
+// whose selection no longer matches property its creator is responsible for
+// skips this run and is tried again after the next interval. It returns the
+// number of campaigns opened.
+func (s *CampaignService) ScheduleRecurring(ctx context.Context, now time.Time) (int, error) {
+	var due []uint
+	err := s.db.WithContext(ctx).Model(&domain.InventoryCampaign{}).
+		Where("next_run_at <= ? AND status <> ?", now, domain.InventoryCampaignStatusCancelled).
+		Order("next_run_at").
+		Pluck("id", &due).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to find recurring inventory campaigns: %w", err)
+	}
+
+	opened := 0
+	for _, id := range due {
+		next, err := s.openNext(ctx, id, now)
+		if err != nil {
+			return opened, fmt.Errorf("failed to schedule campaign after %d: %w", id, err)
+		}
+		if next == nil {
+			continue
+		}
+		opened++
+		s.logCampaignEvent(ctx, "InventoryCampaignCreated", next.CreatedByUserID, next, map[string]interface{}{
+			"item_count":           len(next.Items),
+			"previous_campaign_id": id,
+		})
+	}
+	return opened, nil
+}
+
+// openNext opens the campaign following previousID if it is still due. The
+// previous campaign is locked so two workers cannot both open it.
+func (s *CampaignService) openNext(ctx context.Context, previousID uint, now time.Time) (*domain.InventoryCampaign, error) {
+	var next *domain.InventoryCampaign
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var previous domain.InventoryCampaign
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("id = ? AND next_run_at <= ? AND status <> ?", previousID, now, domain.InventoryCampaignStatusCancelled).
+			First(&previous).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // Opened by another worker, or cancelled since
+		}
+		if err != nil {
+			return err
+		}
+		if previous.RepeatEveryDays == nil {
+			return tx.Model(&previous).Update("next_run_at", nil).Error
+		}
+
+		var selection CampaignSelection
+		if err := json.Unmarshal([]byte(previous.SelectionCriteria), &selection); err != nil {
+			return fmt.Errorf("failed to decode selection: %w", err)
+		}
+		days := *previous.RepeatEveryDays
+		nextRun := now.AddDate(0, 0, days)
+
+		campaign := NextCampaign(&previous, now)
+		err = openCampaign(ctx, tx, campaign, selection)
+		if errors.Is(err, ErrNoPropertySelected) || errors.Is(err, ErrNotResponsible) {
+			log.Printf("WARNING: Skipping recurring inventory campaign after %d until %s: %v", previous.ID, nextRun.Format(time.RFC3339), err)
+			return tx.Model(&previous).Update("next_run_at", nextRun).Error
+		}
+		if err != nil {
+			return err
+		}
+		next = campaign
+		return tx.Model(&previous).Update("next_run_at", nil).Error
+	})
+	return next, err
+}
+
+// NextCampaign returns the campaign following previous in its recurring series,
+// opening at now with as long to count as previous had
+func NextCampaign(previous *domain.InventoryCampaign, now time.Time) *domain.InventoryCampaign {
+	days := *previous.RepeatEveryDays
+	window := previous.DueDate.Sub(previous.CreatedAt)
+	if window <= 0 {
+		window = time.Duration(days) * 24 * time.Hour
+	}
+	nextRun := now.AddDate(0, 0, days)
+	return &domain.InventoryCampaign{
+		Name:               previous.Name,
+		CampaignType:       previous.CampaignType,
+		CreatedByUserID:    previous.CreatedByUserID,
+		DueDate:            now.Add(window),
+		RepeatEveryDays:    previous.RepeatEveryDays,
+		NextRunAt:          &nextRun,
+		PreviousCampaignID: &previous.ID,
+	}
+}
+
+// RemindOverdueCounters sends each counter one notification per overdue campaign
+// listing how many of their items are still uncounted. Items are reminded at most
+// once per reminderInterval. It returns the number of notifications created.
+func RemindOverdueCounters(ctx context.Context, db *gorm.DB, now time.Time) (int, error) {
+	type overdueGroup struct {
+		CampaignID    uint
+		CampaignName  string
+		CampaignType  string
+		DueDate       time.Time
+		CounterUserID uint
+		ItemCount     int
+	}
+
+	var groups []overdueGroup
+	err := db.WithContext(ctx).
+		Table("inventory_campaign_items AS i").
+		Select("c.id AS campaign_id, c.name AS campaign_name, c.campaign_type, c.due_date, i.counter_user_id, COUNT(*) AS item_count").
+		Joins("JOIN inventory_campaigns c ON c.id = i.campaign_id").
+		Where("c.status = ? AND c.due_date < ?", domain.InventoryCampaignStatusActive, now).
+		Where("i.status = ? AND i.counter_user_id IS NOT NULL", domain.InventoryCountPending).
+		Where("i.reminder_sent_at IS NULL OR i.reminder_sent_at < ?", now.Add(-reminderInterval)).
+		Group("c.id, c.name, c.campaign_type, c.due_date, i.counter_user_id").
+		Scan(&groups).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to find overdue inventory items: %w", err)
+	}
+
+	sent := 0
+	for _, group := range groups {
+		priority := domain.NotificationPriorityHigh
+		if group.CampaignType == domain.InventoryCampaignSensitiveItem {
+			priority = domain.NotificationPriorityUrgent
+		}
+		data, _ := json.Marshal(map[string]interface{}{
+			"campaignId": group.CampaignID,
+			"itemCount":  group.ItemCount,
+			"dueDate":    group.DueDate,
+		})
+
+		err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			reminder := &domain.Notification{
+				UserID:   group.CounterUserID,
+				Type:     domain.NotificationTypeInventoryReminder,
+				Title:    "Inventory overdue",
+				Message:  fmt.Sprintf("%d item(s) in %s were due %s and have not been counted", group.ItemCount, group.CampaignName, group.DueDate.Format("02 Jan 2006")),
+				Data:     data,
+				Priority: priority,
+			}
+			if err := notification.Persist(tx, reminder, nil); err != nil {
+				return err
+			}
+			return tx.Model(&domain.InventoryCampaignItem{}).
+				Where("campaign_id = ? AND counter_user_id = ? AND status = ?", group.CampaignID, group.CounterUserID, domain.InventoryCountPending).
+				Update("reminder_sent_at", now).Error
+		})
+		if err != nil {
+			return sent, fmt.Errorf("failed to remind counter %d for campaign %d: %w", group.CounterUserID, group.CampaignID, err)
+		}
+		sent++
+	}
+
+	return sent, nil
+}
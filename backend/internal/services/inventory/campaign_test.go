@@ -0,0 +1,168 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"github.com/toole-brendan/handreceipt-go/internal/ledger"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestNotIn(t *testing.T) {
+	if got := notIn([]uint{1, 4, 2, 4, 5}, []uint{1, 2, 3}); len(got) != 2 || got[0] != 4 || got[1] != 5 {
+		t.Errorf("notIn: %v", got)
+	}
+	if got := notIn(nil, []uint{1}); got != nil {
+		t.Errorf("notIn of nothing: %v", got)
+	}
+}
+
+func TestIsCampaignParty(t *testing.T) {
+	counter := uint(3)
+	campaign := &domain.InventoryCampaign{
+		CreatedByUserID: 1,
+		Items: []domain.InventoryCampaignItem{
+			{PropertyID: 10},
+			{PropertyID: 11, CounterUserID: &counter},
+		},
+	}
+	for userID, want := range map[uint]bool{1: true, 3: true, 2: false} {
+		if got := IsCampaignParty(campaign, userID); got != want {
+			t.Errorf("user %d: got %v, want %v", userID, got, want)
+		}
+	}
+}
+
+func TestNextCampaign(t *testing.T) {
+	days := 30
+	created := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	previous := &domain.InventoryCampaign{
+		ID:              7,
+		Name:            "Monthly sensitive items",
+		CampaignType:    domain.InventoryCampaignSensitiveItem,
+		CreatedByUserID: 1,
+		CreatedAt:       created,
+		DueDate:         created.Add(72 * time.Hour),
+		RepeatEveryDays: &days,
+	}
+
+	now := created.AddDate(0, 0, days)
+	next := NextCampaign(previous, now)
+	if next.Name != previous.Name || next.CampaignType != previous.CampaignType || next.CreatedByUserID != 1 {
+		t.Errorf("next campaign: %+v", next)
+	}
+	if !next.DueDate.Equal(now.Add(72 * time.Hour)) {
+		t.Errorf("due %s, want three days after %s", next.DueDate, now)
+	}
+	if next.NextRunAt == nil || !next.NextRunAt.Equal(now.AddDate(0, 0, days)) {
+		t.Errorf("next run: %v", next.NextRunAt)
+	}
+	if next.PreviousCampaignID == nil || *next.PreviousCampaignID != 7 {
+		t.Errorf("previous campaign: %v", next.PreviousCampaignID)
+	}
+}
+
+// TestCampaignScope checks campaigns only cover property in the creator's chain of
+// command, are only shown to their parties, and repeat. Set
+// HANDRECEIPT_TEST_DATABASE_URL to run it.
+func TestCampaignScope(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	suffix := time.Now().UnixNano()
+
+	leader := domain.User{Email: fmt.Sprintf("leader-%d@test.mil", suffix), PasswordHash: "x", FirstName: "A", LastName: "A", Rank: "1LT"}
+	if err := db.Create(&leader).Error; err != nil {
+		t.Fatal(err)
+	}
+	users := []domain.User{
+		{Email: fmt.Sprintf("soldier-%d@test.mil", suffix), PasswordHash: "x", FirstName: "B", LastName: "B", Rank: "SPC", SupervisorID: &leader.ID},
+		{Email: fmt.Sprintf("outsider-%d@test.mil", suffix), PasswordHash: "x", FirstName: "C", LastName: "C", Rank: "SGT"},
+	}
+	if err := db.Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	soldier, outsider := users[0].ID, users[1].ID
+	properties := []domain.Property{
+		{Name: "RIFLE", SerialNumber: fmt.Sprintf("R-%d", suffix), CurrentStatus: "active", AssignedToUserID: &soldier},
+		{Name: "RADIO", SerialNumber: fmt.Sprintf("X-%d", suffix), CurrentStatus: "active", AssignedToUserID: &outsider},
+	}
+	if err := db.Create(&properties).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM inventory_campaign_items WHERE campaign_id IN (SELECT id FROM inventory_campaigns WHERE created_by_user_id = ?)", leader.ID)
+		db.Where("created_by_user_id = ?", leader.ID).Delete(&domain.InventoryCampaign{})
+		db.Delete(&properties)
+		db.Delete(&users)
+		db.Delete(&leader)
+	})
+
+	ledgerService, err := ledger.NewPostgresLedgerService(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewCampaignService(db, nil, ledgerService, nil, nil)
+	input := CreateCampaignInput{Name: "Cyclic", CampaignType: domain.InventoryCampaignCyclic, DueDate: time.Now().Add(48 * time.Hour)}
+
+	// Another section's radio cannot be selected
+	input.Selection = CampaignSelection{PropertyIDs: []uint{properties[0].ID, properties[1].ID}}
+	if _, err := service.Create(ctx, input, leader.ID); !errors.Is(err, ErrNotResponsible) {
+		t.Fatalf("expected ErrNotResponsible, got %v", err)
+	}
+
+	// An empty selection covers only the leader's soldiers
+	days := 7
+	input.Selection = CampaignSelection{}
+	input.RepeatEveryDays = &days
+	campaign, err := service.Create(ctx, input, leader.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(campaign.Items) != 1 || campaign.Items[0].PropertyID != properties[0].ID {
+		t.Errorf("items: %+v", campaign.Items)
+	}
+
+	if _, err := service.Get(ctx, campaign.ID, outsider); !errors.Is(err, ErrNotCampaignParty) {
+		t.Errorf("expected ErrNotCampaignParty, got %v", err)
+	}
+	if _, err := service.Get(ctx, campaign.ID, leader.ID); err != nil {
+		t.Errorf("creator cannot view campaign: %v", err)
+	}
+
+	// Once due, the next campaign in the series opens exactly once
+	now := campaign.NextRunAt.Add(time.Minute)
+	for run := 0; run < 2; run++ {
+		opened, err := service.ScheduleRecurring(ctx, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 1 - run; opened != want {
+			t.Errorf("run %d opened %d campaigns, want %d", run, opened, want)
+		}
+	}
+}
+
+// openTestDB connects to the database named by HANDRECEIPT_TEST_DATABASE_URL, or
+// skips the test
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("HANDRECEIPT_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("Skipping database integration test - HANDRECEIPT_TEST_DATABASE_URL not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&domain.User{}, &domain.Property{}, &domain.InventoryCampaign{}, &domain.InventoryCampaignItem{}); err != nil {
+		t.Fatalf("Failed to migrate tables: %v", err)
+	}
+	return db
+}
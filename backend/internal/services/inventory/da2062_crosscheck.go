@@ -0,0 +1,300 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"github.com/toole-brendan/handreceipt-go/internal/models"
+	publogModels "github.com/toole-brendan/handreceipt-go/internal/publog/models"
+	publogStore "github.com/toole-brendan/handreceipt-go/internal/publog/store"
+	"github.com/toole-brendan/handreceipt-go/internal/services/ai"
+	"gorm.io/gorm"
+)
+
+// NSNCatalog looks up stock numbers in PUB LOG data. *publog.Service implements it.
+// A stock number that is not listed is reported with an error wrapping
+// publogStore.ErrNSNNotFound.
+type NSNCatalog interface {
+	SearchNSN(nsn string) (*publogModels.SearchResult, error)
+}
+
+// nsnDigitsColumn is a property's stock number without separators
+const nsnDigitsColumn = "REGEXP_REPLACE(nsn, '[^0-9]', '', 'g')"
+
+// DA2062ItemChecker cross-checks parsed DA 2062 lines against PUB LOG, the property
+// already in the system and the importing user's hand receipt
+type DA2062ItemChecker struct {
+	db      *gorm.DB
+	catalog NSNCatalog
+}
+
+// NewDA2062ItemChecker creates a checker. catalog may be nil when no PUB LOG data
+// is loaded, in which case stock numbers are not checked.
+func NewDA2062ItemChecker(db *gorm.DB, catalog NSNCatalog) *DA2062ItemChecker {
+	return &DA2062ItemChecker{db: db, catalog: catalog}
+}
+
+// Check returns the findings for each item, in the order the items were given.
+// userID is the user importing the form.
+func (c *DA2062ItemChecker) Check(ctx context.Context, userID uint, items []ai.ParsedItem) ([][]models.VerificationFinding, error) {
+	findings := make([][]models.VerificationFinding, len(items))
+
+	serials := make([]string, 0, len(items))
+	nsns := make([]string, 0, len(items))
+	firstLine := make(map[string]int, len(items))
+	for i, item := range items {
+		if c.catalog != nil && item.NSN != "" {
+			findings[i] = append(findings[i], c.checkCatalog(item)...)
+		}
+
+		serial := normalizeSerial(item.SerialNumber)
+		if serial == "" {
+			if nsn := ai.NormalizeNSN(item.NSN); nsn != "" {
+				nsns = append(nsns, nsn)
+			}
+			continue
+		}
+		if line, ok := firstLine[serial]; ok {
+			findings[i] = append(findings[i], models.VerificationFinding{
+				Reason:  models.VerificationDuplicateSerial,
+				Field:   "serial_number",
+				Message: fmt.Sprintf("Serial number %s is also on line %d of this form", serial, line),
+				Found:   serial,
+			})
+			continue
+		}
+		firstLine[serial] = i + 1
+		serials = append(serials, serial)
+	}
+
+	held, err := c.propertiesBySerial(ctx, serials)
+	if err != nil {
+		return nil, err
+	}
+	onHand, err := c.handReceiptQuantities(ctx, userID, nsns)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, item := range items {
+		serial := normalizeSerial(item.SerialNumber)
+		if serial == "" {
+			if quantity, ok := onHand[ai.NormalizeNSN(item.NSN)]; ok && quantity != item.Quantity {
+				findings[i] = append(findings[i], models.VerificationFinding{
+					Reason:   models.VerificationQuantityDiffers,
+					Field:    "quantity",
+					Message:  fmt.Sprintf("Your hand receipt holds %d of %s", quantity, item.NSN),
+					Found:    fmt.Sprint(item.Quantity),
+					Expected: fmt.Sprint(quantity),
+				})
+			}
+			continue
+		}
+		property, ok := held[serial]
+		if !ok || firstLine[serial] != i+1 {
+			continue
+		}
+		findings[i] = append(findings[i], serialFindings(item, property, userID)...)
+	}
+	return findings, nil
+}
+
+// checkCatalog compares an item with its PUB LOG record. A failed lookup is
+// reported as such rather than as a stock number PUB LOG does not list.
+func (c *DA2062ItemChecker) checkCatalog(item ai.ParsedItem) []models.VerificationFinding {
+	result, err := c.catalog.SearchNSN(item.NSN)
+	if err != nil && !errors.Is(err, publogStore.ErrNSNNotFound) {
+		log.Printf("WARNING: Failed to look up NSN %s in PUB LOG: %v", item.NSN, err)
+		return []models.VerificationFinding{{
+			Reason:  models.VerificationNSNNotChecked,
+			Field:   "nsn",
+			Message: fmt.Sprintf("NSN %s could not be checked against PUB LOG", item.NSN),
+			Found:   item.NSN,
+		}}
+	}
+	if err != nil || result == nil || result.NSNItem == nil {
+		return []models.VerificationFinding{{
+			Reason:  models.VerificationNSNNotFound,
+			Field:   "nsn",
+			Message: fmt.Sprintf("NSN %s is not in PUB LOG", item.NSN),
+			Found:   item.NSN,
+		}}
+	}
+
+	var findings []models.VerificationFinding
+	record := result.NSNItem
+	if record.ItemName != "" && !nomenclatureMatches(item.Name, record.ItemName) {
+		findings = append(findings, models.VerificationFinding{
+			Reason:   models.VerificationNomenclatureMismatch,
+			Field:    "name",
+			Message:  fmt.Sprintf("PUB LOG lists %s as %s", item.NSN, record.ItemName),
+			Found:    item.Name,
+			Expected: record.ItemName,
+		})
+	}
+	unit := strings.ToUpper(strings.TrimSpace(item.UnitOfIssue))
+	expected := strings.ToUpper(strings.TrimSpace(record.UnitOfIssue))
+	if unit != "" && expected != "" && unit != expected {
+		findings = append(findings, models.VerificationFinding{
+			Reason:   models.VerificationUnitOfIssueMismatch,
+			Field:    "unit",
+			Message:  fmt.Sprintf("PUB LOG issues %s by %s", item.NSN, expected),
+			Found:    unit,
+			Expected: expected,
+		})
+	}
+	return findings
+}
+
+// propertiesBySerial returns the existing property with each of the serial numbers,
+// keyed by normalized serial
+func (c *DA2062ItemChecker) propertiesBySerial(ctx context.Context, serials []string) (map[string]domain.Property, error) {
+	held := make(map[string]domain.Property, len(serials))
+	if len(serials) == 0 {
+		return held, nil
+	}
+	var properties []domain.Property
+	if err := c.db.WithContext(ctx).Where("UPPER(serial_number) IN ?", serials).Find(&properties).Error; err != nil {
+		return nil, fmt.Errorf("failed to check serial numbers: %w", err)
+	}
+	for _, property := range properties {
+		held[normalizeSerial(property.SerialNumber)] = property
+	}
+	return held, nil
+}
+
+// handReceiptQuantities totals the quantity of each stock number on the user's hand
+// receipt, keyed by stock number as ai.NormalizeNSN gives it. nsns must already be
+// normalized. Property records are compared by their digits, since they are not
+// all formatted the same way.
+func (c *DA2062ItemChecker) handReceiptQuantities(ctx context.Context, userID uint, nsns []string) (map[string]int, error) {
+	onHand := make(map[string]int)
+	if len(nsns) == 0 {
+		return onHand, nil
+	}
+	digits := make([]string, 0, len(nsns))
+	for _, nsn := range nsns {
+		digits = append(digits, strings.ReplaceAll(nsn, "-", ""))
+	}
+	var rows []struct {
+		NSN      string
+		Quantity int
+	}
+	err := c.db.WithContext(ctx).Model(&domain.Property{}).
+		Select(nsnDigitsColumn+" AS nsn, COALESCE(SUM(quantity), 0) AS quantity").
+		Where("assigned_to_user_id = ? AND "+nsnDigitsColumn+" IN ?", userID, digits).
+		Group(nsnDigitsColumn).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to check hand receipt: %w", err)
+	}
+	for _, row := range rows {
+		onHand[ai.NormalizeNSN(row.NSN)] = row.Quantity
+	}
+	return onHand, nil
+}
+
+// serialFindings describes an item whose serial number already belongs to property
+func serialFindings(item ai.ParsedItem, property domain.Property, userID uint) []models.VerificationFinding {
+	id := property.ID
+	var findings []models.VerificationFinding
+	if property.AssignedToUserID != nil && *property.AssignedToUserID == userID {
+		findings = append(findings, models.VerificationFinding{
+			Reason:     models.VerificationSerialOnHandReceipt,
+			Field:      "serial_number",
+			Message:    fmt.Sprintf("Serial number %s is already on your hand receipt as %s", property.SerialNumber, property.Name),
+			Found:      item.SerialNumber,
+			PropertyID: &id,
+		})
+		// Compared normalized, so the same NSN written with or without dashes matches
+		if property.NSN != nil && differentNSNs(*property.NSN, item.NSN) {
+			findings = append(findings, models.VerificationFinding{
+				Reason:     models.VerificationNSNDiffersFromRecord,
+				Field:      "nsn",
+				Message:    fmt.Sprintf("Serial number %s is recorded under NSN %s", property.SerialNumber, *property.NSN),
+				Found:      item.NSN,
+				Expected:   *property.NSN,
+				PropertyID: &id,
+			})
+		}
+		return findings
+	}
+
+	message := fmt.Sprintf("Serial number %s belongs to property that is not assigned to anyone", property.SerialNumber)
+	if property.AssignedToUserID != nil {
+		message = fmt.Sprintf("Serial number %s is signed out to another user", property.SerialNumber)
+	}
+	return append(findings, models.VerificationFinding{
+		Reason:     models.VerificationSerialHeldByOther,
+		Field:      "serial_number",
+		Message:    message,
+		Found:      item.SerialNumber,
+		PropertyID: &id,
+	})
+}
+
+// nomenclatureMatches reports whether a description read from a form names the
+// same item as a PUB LOG item name. PUB LOG names put the basic noun first
+// ("RIFLE,5.56 MILLIMETER"), so the description matches if it contains every word
+// of the noun or at least half the words of the whole name.
+func nomenclatureMatches(description, itemName string) bool {
+	words := make(map[string]bool)
+	for _, word := range nomenclatureWords(description) {
+		words[word] = true
+	}
+
+	noun, _, _ := strings.Cut(itemName, ",")
+	nounWords := nomenclatureWords(noun)
+	if len(nounWords) > 0 && containsAll(words, nounWords) {
+		return true
+	}
+
+	nameWords := nomenclatureWords(itemName)
+	if len(nameWords) == 0 {
+		return true
+	}
+	matched := 0
+	for _, word := range nameWords {
+		if words[word] {
+			matched++
+		}
+	}
+	return matched*2 >= len(nameWords)
+}
+
+func nomenclatureWords(text string) []string {
+	return strings.FieldsFunc(strings.ToUpper(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.'
+	})
+}
+
+func containsAll(words map[string]bool, want []string) bool {
+	for _, word := range want {
+		if !words[word] {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeSerial is a serial number as compared with others. N/A and NA, written on
+// forms for items without one, give the empty string, which is never a match or a
+// duplicate.
+func normalizeSerial(serial string) string {
+	serial = strings.ToUpper(strings.TrimSpace(serial))
+	if serial == "N/A" || serial == "NA" {
+		return ""
+	}
+	return serial
+}
+
+// differentNSNs reports whether two NSNs that are both readable name different items
+func differentNSNs(recorded, found string) bool {
+	recorded, found = ai.NormalizeNSN(recorded), ai.NormalizeNSN(found)
+	return recorded != "" && found != "" && recorded != found
+}
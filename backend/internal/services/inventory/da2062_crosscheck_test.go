@@ -0,0 +1,118 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"github.com/toole-brendan/handreceipt-go/internal/models"
+	publogModels "github.com/toole-brendan/handreceipt-go/internal/publog/models"
+	publogStore "github.com/toole-brendan/handreceipt-go/internal/publog/store"
+	"github.com/toole-brendan/handreceipt-go/internal/services/ai"
+)
+
+type fakeCatalog map[string]*publogModels.NSNItem
+
+func (c fakeCatalog) SearchNSN(nsn string) (*publogModels.SearchResult, error) {
+	item, ok := c[nsn]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", publogStore.ErrNSNNotFound, nsn)
+	}
+	return &publogModels.SearchResult{NSNItem: item}, nil
+}
+
+func TestNomenclatureMatches(t *testing.T) {
+	tests := []struct {
+		description, itemName string
+		want                  bool
+	}{
+		{"RIFLE, 5.56MM, M4", "RIFLE,5.56 MILLIMETER", true},
+		{"HELMET, ADVANCED COMBAT", "HELMET,COMBAT", true},
+		{"Night vision goggles", "GOGGLES,NIGHT VISION", true},
+		{"BINOCULAR M22", "RIFLE,5.56 MILLIMETER", false},
+		{"", "HELMET,COMBAT", false},
+	}
+	for _, tt := range tests {
+		if got := nomenclatureMatches(tt.description, tt.itemName); got != tt.want {
+			t.Errorf("nomenclatureMatches(%q, %q) = %v, want %v", tt.description, tt.itemName, got, tt.want)
+		}
+	}
+}
+
+func TestCheckCatalog(t *testing.T) {
+	checker := NewDA2062ItemChecker(nil, fakeCatalog{
+		"1005-01-231-0973": {NSN: "1005-01-231-0973", ItemName: "RIFLE,5.56 MILLIMETER", UnitOfIssue: "EA"},
+	})
+
+	reasons := func(item ai.ParsedItem) []models.VerificationReason {
+		var got []models.VerificationReason
+		for _, finding := range checker.checkCatalog(item) {
+			got = append(got, finding.Reason)
+		}
+		return got
+	}
+
+	if got := reasons(ai.ParsedItem{NSN: "1005-01-231-0973", Name: "RIFLE, 5.56MM, M4", UnitOfIssue: "EA"}); len(got) != 0 {
+		t.Errorf("matching item: unexpected findings %v", got)
+	}
+	if got := reasons(ai.ParsedItem{NSN: "9999-99-999-9999", Name: "RIFLE"}); len(got) != 1 || got[0] != models.VerificationNSNNotFound {
+		t.Errorf("unknown NSN: got %v", got)
+	}
+	got := reasons(ai.ParsedItem{NSN: "1005-01-231-0973", Name: "BINOCULAR", UnitOfIssue: "PR"})
+	if len(got) != 2 || got[0] != models.VerificationNomenclatureMismatch || got[1] != models.VerificationUnitOfIssueMismatch {
+		t.Errorf("mismatched item: got %v", got)
+	}
+}
+
+type unavailableCatalog struct{}
+
+func (unavailableCatalog) SearchNSN(string) (*publogModels.SearchResult, error) {
+	return nil, errors.New("PUB LOG data is not loaded")
+}
+
+func TestCheckCatalogLookupError(t *testing.T) {
+	checker := NewDA2062ItemChecker(nil, unavailableCatalog{})
+	findings := checker.checkCatalog(ai.ParsedItem{NSN: "1005-01-231-0973", Name: "RIFLE"})
+	if len(findings) != 1 || findings[0].Reason != models.VerificationNSNNotChecked {
+		t.Errorf("got %+v, want one %s finding", findings, models.VerificationNSNNotChecked)
+	}
+}
+
+func TestCheckNotApplicableSerials(t *testing.T) {
+	checker := NewDA2062ItemChecker(nil, nil)
+	findings, err := checker.Check(context.Background(), 1, []ai.ParsedItem{
+		{Name: "TOOL KIT", SerialNumber: "N/A", Quantity: 1},
+		{Name: "TOOL KIT", SerialNumber: "n/a", Quantity: 1},
+		{Name: "CAMOUFLAGE NET", SerialNumber: "NA", Quantity: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, lineFindings := range findings {
+		for _, finding := range lineFindings {
+			if finding.Reason == models.VerificationDuplicateSerial {
+				t.Errorf("line %d: N/A serial counted as a duplicate: %s", i+1, finding.Message)
+			}
+		}
+	}
+}
+
+func TestSerialFindingsNSNFormats(t *testing.T) {
+	userID := uint(7)
+	nsn := "1005012310973"
+	property := domain.Property{ID: 3, SerialNumber: "W123", Name: "RIFLE", NSN: &nsn, AssignedToUserID: &userID}
+
+	findings := serialFindings(ai.ParsedItem{NSN: "1005-01-231-0973", SerialNumber: "W123"}, property, userID)
+	for _, finding := range findings {
+		if finding.Reason == models.VerificationNSNDiffersFromRecord {
+			t.Errorf("same NSN in another format reported as different: %s", finding.Message)
+		}
+	}
+
+	findings = serialFindings(ai.ParsedItem{NSN: "1005-01-565-7445", SerialNumber: "W123"}, property, userID)
+	if len(findings) != 2 || findings[1].Reason != models.VerificationNSNDiffersFromRecord {
+		t.Errorf("got %+v, want a %s finding", findings, models.VerificationNSNDiffersFromRecord)
+	}
+}
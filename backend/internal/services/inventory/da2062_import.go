@@ -0,0 +1,601 @@
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"github.com/toole-brendan/handreceipt-go/internal/models"
+	"github.com/toole-brendan/handreceipt-go/internal/services/ai"
+	"github.com/toole-brendan/handreceipt-go/internal/services/jobs"
+	"github.com/toole-brendan/handreceipt-go/internal/services/notification"
+	"github.com/toole-brendan/handreceipt-go/internal/services/storage"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// JobProcessDA2062Import parses an uploaded DA 2062 into import items
+const JobProcessDA2062Import = "da2062.process_import"
+
+// A long form can take several minutes to parse. If a run is cut off, the pages it
+// finished are kept and the retry carries on from there.
+const da2062ImportTimeout = 15 * time.Minute
+
+// How often Wait checks on an import the worker is parsing
+const importPollInterval = time.Second
+
+var (
+	ErrImportUnreadable = errors.New("uploaded DA 2062 could not be read")
+	ErrImportNoItems    = errors.New("no items were found on the DA 2062")
+)
+
+// DA2062ImportJob is the payload of a JobProcessDA2062Import job
+type DA2062ImportJob struct {
+	ImportID int64 `json:"importId"`
+}
+
+// DA2062ImportService takes DA 2062 uploads from the API and parses them in the
+// worker, page by page, publishing progress to clients watching the import
+type DA2062ImportService struct {
+	db       *gorm.DB
+	storage  storage.StorageService
+	queue    *jobs.Queue
+	parser   ai.VisionProvider
+	checker  *DA2062ItemChecker
+	learning *DA2062LearningService
+	hub      *notification.Hub
+}
+
+// NewDA2062ImportService creates a new import service. queue is only needed to
+// start imports, and parser, checker and learning only to process them; without a
+// checker items are not cross-checked, and without learning pages are parsed
+// without what was learned from the unit's past corrections. hub may be nil, in
+// which case progress is not published.
+func NewDA2062ImportService(
+	db *gorm.DB,
+	storageService storage.StorageService,
+	queue *jobs.Queue,
+	parser ai.VisionProvider,
+	checker *DA2062ItemChecker,
+	learning *DA2062LearningService,
+	hub *notification.Hub,
+) *DA2062ImportService {
+	return &DA2062ImportService{
+		db:       db,
+		storage:  storageService,
+		queue:    queue,
+		parser:   parser,
+		checker:  checker,
+		learning: learning,
+		hub:      hub,
+	}
+}
+
+// Start stores an uploaded form and queues it for parsing. The import is returned
+// straight away; its items appear as the worker reads them. mode is
+// domain.DA2062ImportModeReview or domain.DA2062ImportModeReconcile.
+func (s *DA2062ImportService) Start(ctx context.Context, userID uint, fileName, contentType, mode string, data []byte) (*domain.DA2062Import, error) {
+	if s.storage == nil {
+		return nil, ErrStorageUnavailable
+	}
+
+	objectName := fmt.Sprintf("da2062-scans/%d/%d-%s", userID, time.Now().Unix(), fileName)
+	if err := s.storage.UploadFile(ctx, objectName, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return nil, fmt.Errorf("failed to store upload: %w", err)
+	}
+	fileURL := "/storage/" + objectName
+
+	record := &domain.DA2062Import{
+		FileName:       fileName,
+		FileURL:        &fileURL,
+		ImportedByUser: int64(userID),
+		Status:         domain.DA2062ImportPending,
+		Mode:           mode,
+		StorageKey:     &objectName,
+		ContentType:    &contentType,
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(record).Error; err != nil {
+			return fmt.Errorf("failed to create import: %w", err)
+		}
+		job, err := s.queue.EnqueueTx(tx, JobProcessDA2062Import, DA2062ImportJob{ImportID: record.ID}, jobs.EnqueueOptions{
+			Timeout:   da2062ImportTimeout,
+			CreatedBy: userID,
+		})
+		if err != nil {
+			return err
+		}
+		record.JobID = &job.ID
+		return tx.Model(record).Update("job_id", job.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Process parses an import's upload. Each page is saved once parsed and each item
+// once written, so running it again after a crash or timeout skips the work already
+// done. A completed import is left alone.
+func (s *DA2062ImportService) Process(ctx context.Context, importID int64) error {
+	var record domain.DA2062Import
+	if err := s.db.WithContext(ctx).First(&record, importID).Error; err != nil {
+		return fmt.Errorf("failed to get import %d: %w", importID, err)
+	}
+	if record.Status == domain.DA2062ImportCompleted {
+		return nil
+	}
+	if record.StorageKey == nil {
+		return fmt.Errorf("%w: import %d has no stored upload", ErrImportUnreadable, importID)
+	}
+	if s.storage == nil {
+		return ErrStorageUnavailable
+	}
+	if s.parser == nil {
+		return ai.ErrNoProvider
+	}
+
+	if err := s.db.Model(&record).Updates(map[string]interface{}{"status": domain.DA2062ImportProcessing, "error_log": nil}).Error; err != nil {
+		return fmt.Errorf("failed to update import %d: %w", importID, err)
+	}
+	s.publishProgress(importID)
+
+	data, err := s.download(ctx, *record.StorageKey)
+	if err != nil {
+		return err
+	}
+	contentType := ""
+	if record.ContentType != nil {
+		contentType = *record.ContentType
+	}
+	pages, err := ai.SplitDA2062(data, contentType)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrImportUnreadable, err)
+	}
+	if err := s.db.Model(&record).Update("page_count", len(pages)).Error; err != nil {
+		return fmt.Errorf("failed to update import %d: %w", importID, err)
+	}
+	profile := s.unitProfile(ctx, &record)
+	for i := range pages {
+		pages[i].Profile = profile
+	}
+
+	parsed, err := s.parsePages(ctx, &record, pages)
+	if err != nil {
+		return err
+	}
+
+	pageItems := make([][]ai.ParsedItem, 0, len(parsed))
+	pageNumbers := make([]int, 0, len(parsed))
+	metas := make([]ai.ParseMeta, 0, len(parsed))
+	providers := make(map[int]string, len(parsed))
+	for _, page := range parsed {
+		var items []ai.ParsedItem
+		var meta ai.ParseMeta
+		if err := json.Unmarshal(page.Items, &items); err != nil {
+			return fmt.Errorf("failed to decode page %d of import %d: %w", page.PageNumber, importID, err)
+		}
+		if len(page.Meta) > 0 {
+			if err := json.Unmarshal(page.Meta, &meta); err != nil {
+				return fmt.Errorf("failed to decode page %d of import %d: %w", page.PageNumber, importID, err)
+			}
+		}
+		pageItems = append(pageItems, items)
+		pageNumbers = append(pageNumbers, page.PageNumber)
+		metas = append(metas, meta)
+		providers[page.PageNumber] = page.Provider
+	}
+
+	items := ai.MergePageItems(pageItems, pageNumbers)
+	meta := ai.MergePageMeta(metas)
+	if len(items) == 0 {
+		return ErrImportNoItems
+	}
+
+	formInfo, _ := json.Marshal(meta)
+	if err := s.db.Model(&record).Updates(map[string]interface{}{"total_items": len(items), "form_info": formInfo}).Error; err != nil {
+		return fmt.Errorf("failed to update import %d: %w", importID, err)
+	}
+
+	var findings [][]models.VerificationFinding
+	if s.checker != nil {
+		findings, err = s.checker.Check(ctx, uint(record.ImportedByUser), items)
+		if err != nil {
+			return fmt.Errorf("failed to cross-check items of import %d: %w", importID, err)
+		}
+	}
+
+	if err := s.writeItems(ctx, &record, items, meta, providers, findings); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := s.db.Model(&record).Updates(map[string]interface{}{"status": domain.DA2062ImportCompleted, "completed_at": now}).Error; err != nil {
+		return fmt.Errorf("failed to complete import %d: %w", importID, err)
+	}
+	s.publishProgress(importID)
+
+	log.Printf("DA 2062 import %d: %d items read from %d page(s), confidence avg: %.2f", importID, len(items), len(pages), averageConfidence(items))
+	return nil
+}
+
+// Fail marks an import as failed once its job has given up on it
+func (s *DA2062ImportService) Fail(importID int64, cause error) error {
+	errorLog, _ := json.Marshal(map[string]interface{}{
+		"error":    cause.Error(),
+		"failedAt": time.Now(),
+	})
+	err := s.db.Model(&domain.DA2062Import{}).
+		Where("id = ? AND status <> ?", importID, domain.DA2062ImportCompleted).
+		Updates(map[string]interface{}{"status": domain.DA2062ImportFailed, "error_log": errorLog}).Error
+	if err != nil {
+		return fmt.Errorf("failed to mark import %d failed: %w", importID, err)
+	}
+	s.publishProgress(importID)
+	return nil
+}
+
+// Wait blocks until the worker has completed or failed an import, or ctx is done,
+// and returns the import as the worker left it. It is for clients that cannot
+// follow an import's progress.
+func (s *DA2062ImportService) Wait(ctx context.Context, importID int64) (*domain.DA2062Import, error) {
+	ticker := time.NewTicker(importPollInterval)
+	defer ticker.Stop()
+	for {
+		var record domain.DA2062Import
+		if err := s.db.WithContext(ctx).First(&record, importID).Error; err != nil {
+			return nil, fmt.Errorf("failed to get import %d: %w", importID, err)
+		}
+		if record.Status == domain.DA2062ImportCompleted || record.Status == domain.DA2062ImportFailed {
+			return &record, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Items returns the lines read from an import as they were parsed, in form order
+func (s *DA2062ImportService) Items(ctx context.Context, importID int64) ([]models.DA2062ImportItem, error) {
+	var rows []domain.DA2062ImportItem
+	err := s.db.WithContext(ctx).
+		Where("import_id = ? AND merged_into_item_id IS NULL", importID).
+		Order("line_number ASC").
+		Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get items of import %d: %w", importID, err)
+	}
+
+	items := make([]models.DA2062ImportItem, 0, len(rows))
+	for _, row := range rows {
+		var item models.DA2062ImportItem
+		if err := json.Unmarshal(row.RawData, &item); err != nil {
+			return nil, fmt.Errorf("failed to decode line %d of import %d: %w", row.LineNumber, importID, err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (s *DA2062ImportService) download(ctx context.Context, key string) ([]byte, error) {
+	return downloadUpload(ctx, s.storage, key)
+}
+
+// downloadUpload reads a stored DA 2062 upload
+func downloadUpload(ctx context.Context, store storage.StorageService, key string) ([]byte, error) {
+	reader, err := store.DownloadFile(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve upload: %w", err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	return data, nil
+}
+
+// unitProfile returns what was learned from corrections of the importing unit's
+// earlier forms. Parsing goes ahead without it if it cannot be loaded.
+func (s *DA2062ImportService) unitProfile(ctx context.Context, record *domain.DA2062Import) *ai.UnitProfile {
+	if s.learning == nil {
+		return nil
+	}
+	var importer domain.User
+	if err := s.db.WithContext(ctx).Select("id", "unit").First(&importer, record.ImportedByUser).Error; err != nil {
+		log.Printf("WARNING: Failed to find importer of import %d: %v", record.ID, err)
+		return nil
+	}
+	profile, err := s.learning.Profile(ctx, importer.Unit)
+	if err != nil {
+		log.Printf("WARNING: Failed to load unit profile for import %d: %v", record.ID, err)
+		return nil
+	}
+	return profile
+}
+
+// parsePages parses every page not already saved for the import and returns all
+// of the import's pages in order
+func (s *DA2062ImportService) parsePages(ctx context.Context, record *domain.DA2062Import, pages []ai.PDFPage) ([]domain.DA2062ImportPage, error) {
+	importID := record.ID
+	var saved []domain.DA2062ImportPage
+	if err := s.db.WithContext(ctx).Where("import_id = ?", importID).Find(&saved).Error; err != nil {
+		return nil, fmt.Errorf("failed to get parsed pages of import %d: %w", importID, err)
+	}
+	done := make(map[int]domain.DA2062ImportPage, len(saved))
+	for _, page := range saved {
+		done[page.PageNumber] = page
+	}
+
+	parsed := make([]domain.DA2062ImportPage, 0, len(pages))
+	for _, page := range pages {
+		if existing, ok := done[page.Number]; ok {
+			parsed = append(parsed, existing)
+			continue
+		}
+
+		row, items, err := s.parsePage(ctx, record, page)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+			return nil, fmt.Errorf("failed to save page %d of import %d: %w", page.Number, importID, err)
+		}
+		parsed = append(parsed, row)
+
+		done[page.Number] = row
+		err = s.db.Model(&domain.DA2062Import{}).Where("id = ?", importID).Updates(map[string]interface{}{
+			"pages_parsed":   len(done),
+			"pages_failed":   gorm.Expr("(SELECT COUNT(*) FROM da2062_import_pages WHERE import_id = ? AND error IS NOT NULL)", importID),
+			"parse_cost_usd": gorm.Expr("(SELECT COALESCE(SUM(cost_usd), 0) FROM da2062_import_pages WHERE import_id = ?)", importID),
+		}).Error
+		if err != nil {
+			log.Printf("WARNING: Failed to update page progress of import %d: %v", importID, err)
+		}
+		if row.Error != nil {
+			log.Printf("WARNING: DA 2062 import %d: page %d cannot be read: %s", importID, page.Number, *row.Error)
+		} else {
+			log.Printf("DA 2062 import %d: page %d parsed by %s (%d items, %d/%d tokens, $%.4f)",
+				importID, page.Number, row.Provider, len(items), row.InputTokens, row.OutputTokens, row.CostUSD)
+		}
+		s.publishProgress(importID)
+	}
+	return parsed, nil
+}
+
+// parsePage reads one page's items. A page that could not be prepared for parsing
+// is recorded as failed, with no items, so the rest of the form is still imported
+// and the reviewer can see which page is missing. A scanned page's image is stored
+// alongside the upload, for the review to crop lines from.
+func (s *DA2062ImportService) parsePage(ctx context.Context, record *domain.DA2062Import, page ai.PDFPage) (domain.DA2062ImportPage, []ai.ParsedItem, error) {
+	importID := record.ID
+	row := domain.DA2062ImportPage{ImportID: importID, PageNumber: page.Number}
+	if page.Unreadable != "" {
+		reason := page.Unreadable
+		row.Items = json.RawMessage("[]")
+		row.Error = &reason
+		return row, nil, nil
+	}
+
+	// An empty key records that the page has no image, only a text layer
+	imageKey := ""
+	if len(page.Image) > 0 {
+		imageKey = fmt.Sprintf("%s.page-%d", *record.StorageKey, page.Number)
+		if err := s.storage.UploadFile(ctx, imageKey, bytes.NewReader(page.Image), int64(len(page.Image)), page.ContentType); err != nil {
+			return row, nil, fmt.Errorf("failed to store page %d of import %d: %w", page.Number, importID, err)
+		}
+		contentType := page.ContentType
+		row.ImageType = &contentType
+	}
+	row.ImageKey = &imageKey
+
+	extraction, err := s.parser.ExtractDA2062(ctx, page)
+	if err != nil {
+		return row, nil, fmt.Errorf("failed to parse page %d of import %d: %w", page.Number, importID, err)
+	}
+	items := extraction.Items
+	if items == nil {
+		items = []ai.ParsedItem{}
+	}
+	row.Items, _ = json.Marshal(items)
+	row.Meta, _ = json.Marshal(extraction.Meta)
+	row.Provider = extraction.Provider
+	if row.Provider == "" {
+		row.Provider = s.parser.Name()
+	}
+	row.InputTokens = extraction.Usage.InputTokens
+	row.OutputTokens = extraction.Usage.OutputTokens
+	row.CostUSD = extraction.Usage.CostUSD
+	return row, items, nil
+}
+
+// writeItems stores the merged items in line order, starting after the last line
+// written by an earlier run. providers names the provider that read each page, and
+// findings holds each item's cross-check findings, if it was checked.
+func (s *DA2062ImportService) writeItems(ctx context.Context, record *domain.DA2062Import, items []ai.ParsedItem, meta ai.ParseMeta, providers map[int]string, findings [][]models.VerificationFinding) error {
+	var written int
+	err := s.db.WithContext(ctx).Model(&domain.DA2062ImportItem{}).
+		Where("import_id = ?", record.ID).
+		Select("COALESCE(MAX(line_number), 0)").
+		Scan(&written).Error
+	if err != nil {
+		return fmt.Errorf("failed to get items of import %d: %w", record.ID, err)
+	}
+
+	documentURL := ""
+	if record.FileURL != nil {
+		documentURL = *record.FileURL
+	}
+
+	for i, item := range items {
+		line := i + 1
+		if line <= written {
+			continue
+		}
+
+		var itemFindings []models.VerificationFinding
+		if i < len(findings) {
+			itemFindings = findings[i]
+		}
+		rawData, _ := json.Marshal(importItemFromParsed(item, meta, documentURL, providers[item.Page], itemFindings))
+		row := domain.DA2062ImportItem{
+			ImportID:   record.ID,
+			LineNumber: line,
+			RawData:    rawData,
+			Status:     "pending",
+		}
+		if item.Page > 0 {
+			page := item.Page
+			row.PageNumber = &page
+		}
+		if item.Row != nil {
+			row.RowRegion, _ = json.Marshal(item.Row)
+		}
+		if len(item.Boxes) > 0 {
+			row.FieldBoxes, _ = json.Marshal(item.Boxes)
+		}
+		if problem := unusableItemReason(item); problem != "" {
+			row.Status = "failed"
+			row.ErrorMessage = &problem
+		}
+		if err := s.db.WithContext(ctx).Create(&row).Error; err != nil {
+			return fmt.Errorf("failed to save line %d of import %d: %w", line, record.ID, err)
+		}
+
+		if err := s.countItems(record.ID); err != nil {
+			log.Printf("WARNING: Failed to update item progress of import %d: %v", record.ID, err)
+		}
+		s.publishProgress(record.ID)
+	}
+	return s.countItems(record.ID)
+}
+
+func (s *DA2062ImportService) countItems(importID int64) error {
+	return countImportItems(s.db, importID)
+}
+
+// countImportItems sets an import's counters from its item rows, so that a run
+// resumed part way through never counts an item twice
+func countImportItems(db *gorm.DB, importID int64) error {
+	return db.Exec(`
+		UPDATE da2062_imports SET
+			processed_items = (SELECT COUNT(*) FROM da2062_import_items WHERE import_id = ? AND status <> 'failed'),
+			failed_items = (SELECT COUNT(*) FROM da2062_import_items WHERE import_id = ? AND status = 'failed')
+		WHERE id = ?`, importID, importID, importID).Error
+}
+
+func (s *DA2062ImportService) publishProgress(importID int64) {
+	PublishImportProgress(s.db, s.hub, importID)
+}
+
+// PublishImportProgress sends an import's current counts to clients watching it. It
+// does nothing when hub is nil.
+func PublishImportProgress(db *gorm.DB, hub *notification.Hub, importID int64) {
+	if hub == nil {
+		return
+	}
+	var record domain.DA2062Import
+	if err := db.First(&record, importID).Error; err != nil {
+		return
+	}
+	hub.PublishTopic(notification.ImportTopic(importID), notification.Event{
+		Type: notification.EventTypeImportUpdate,
+		Data: notification.ImportUpdateData{
+			ImportID:       record.ID,
+			Status:         record.Status,
+			TotalItems:     record.TotalItems,
+			ProcessedItems: record.ProcessedItems,
+			FailedItems:    record.FailedItems,
+			PageCount:      record.PageCount,
+			PagesParsed:    record.PagesParsed,
+			PagesFailed:    record.PagesFailed,
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+// importItemFromParsed converts a parsed line into the item the client reviews and
+// submits for creation, with the findings from cross-checking it
+func importItemFromParsed(item ai.ParsedItem, meta ai.ParseMeta, documentURL, provider string, findings []models.VerificationFinding) models.DA2062ImportItem {
+	importMetadata := models.ImportMetadata{
+		Source:               provider,
+		ImportDate:           time.Now(),
+		FormNumber:           meta.FormNumber,
+		ScanConfidence:       item.Confidence,
+		ItemConfidence:       item.Confidence,
+		SerialSource:         "ai_extracted",
+		OriginalQuantity:     item.Quantity,
+		RequiresVerification: item.Confidence < 0.8,
+		VerificationReasons:  []string{},
+		SourceDocumentURL:    documentURL,
+	}
+	if item.Confidence < 0.8 {
+		importMetadata.VerificationReasons = append(importMetadata.VerificationReasons, string(models.VerificationLowConfidence))
+	}
+	if item.SerialNumber == "" || item.SerialNumber == "N/A" {
+		importMetadata.VerificationReasons = append(importMetadata.VerificationReasons, string(models.VerificationMissingSerial))
+		importMetadata.RequiresVerification = true
+	}
+	for _, finding := range findings {
+		reason := string(finding.Reason)
+		if !containsString(importMetadata.VerificationReasons, reason) {
+			importMetadata.VerificationReasons = append(importMetadata.VerificationReasons, reason)
+		}
+		importMetadata.RequiresVerification = true
+	}
+	importMetadata.Findings = findings
+
+	return models.DA2062ImportItem{
+		Name:           item.Name,
+		Description:    item.Name,
+		SerialNumber:   item.SerialNumber,
+		NSN:            item.NSN,
+		Quantity:       item.Quantity,
+		Unit:           item.UnitOfIssue,
+		SourceRef:      meta.FormNumber,
+		PageNumber:     item.Page,
+		ImportMetadata: &importMetadata,
+	}
+}
+
+// unusableItemReason explains why a parsed line cannot become property, or returns
+// "" if it can
+func unusableItemReason(item ai.ParsedItem) string {
+	if strings.TrimSpace(item.Name) == "" {
+		return "Item name or description is missing"
+	}
+	if item.Quantity <= 0 {
+		return fmt.Sprintf("Invalid quantity: %d", item.Quantity)
+	}
+	return ""
+}
+
+func averageConfidence(items []ai.ParsedItem) float64 {
+	if len(items) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, item := range items {
+		total += item.Confidence
+	}
+	return total / float64(len(items))
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,296 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"github.com/toole-brendan/handreceipt-go/internal/models"
+	"github.com/toole-brendan/handreceipt-go/internal/services/ai"
+	"gorm.io/gorm"
+)
+
+// Limits on what a unit profile is built from and how much of it goes in a prompt
+const (
+	profileExampleWindow   = 500 // Most recent corrections considered
+	profileMinOccurrences  = 2   // Times a format or name must recur to be learned
+	profileMaxPatterns     = 5
+	profileMaxNomenclature = 15
+	profileMaxExamples     = 10
+)
+
+// ErrInvalidAccuracyPeriod is returned for an accuracy period other than day, week or month
+var ErrInvalidAccuracyPeriod = errors.New("period must be day, week or month")
+
+// correctionFields maps the fields a reviewer can correct to the names the parser
+// gives them
+var correctionFields = map[string]string{
+	"name":          ai.FieldName,
+	"description":   ai.FieldName,
+	"serial_number": ai.FieldSerialNumber,
+	"nsn":           ai.FieldNSN,
+	"quantity":      ai.FieldQuantity,
+	"unit":          ai.FieldUnitOfIssue,
+}
+
+// AccuracyQuery selects the imports an accuracy report covers. An empty Unit
+// reports every unit.
+type AccuracyQuery struct {
+	Unit   string
+	Period string // day, week or month
+	Since  time.Time
+}
+
+// UnitAccuracy is how much of a unit's imports reviewers had to correct in one period
+type UnitAccuracy struct {
+	Unit             string         `json:"unit"`
+	Period           time.Time      `json:"period"`
+	Imports          int            `json:"imports"`
+	Lines            int            `json:"lines"`          // Reviewed lines, not counting ignored ones
+	CorrectedLines   int            `json:"correctedLines"` // Lines with any correction, including merges
+	IgnoredLines     int            `json:"ignoredLines"`
+	LineAccuracy     float64        `json:"lineAccuracy"` // Share of lines taken as read
+	FieldCorrections map[string]int `json:"fieldCorrections"`
+}
+
+// DA2062LearningService learns from reviewers' corrections of imported forms. Each
+// correction is kept as a labeled example under the importing user's unit; the
+// examples are summed up into a profile of the unit's forms that guides parsing,
+// and into a report of how accurate parsing has been.
+type DA2062LearningService struct {
+	db *gorm.DB
+}
+
+// NewDA2062LearningService creates a new learning service
+func NewDA2062LearningService(db *gorm.DB) *DA2062LearningService {
+	return &DA2062LearningService{db: db}
+}
+
+// Profile sums up the corrections made to a unit's recent imports. It returns nil
+// when the unit has none.
+func (s *DA2062LearningService) Profile(ctx context.Context, unit string) (*ai.UnitProfile, error) {
+	var examples []domain.DA2062CorrectionExample
+	if err := s.db.WithContext(ctx).Where("unit = ?", unit).
+		Order("created_at DESC").Limit(profileExampleWindow).
+		Find(&examples).Error; err != nil {
+		return nil, fmt.Errorf("failed to load corrections for unit %q: %w", unit, err)
+	}
+	return buildUnitProfile(unit, examples), nil
+}
+
+// Accuracy reports, for each unit and period, the share of reviewed lines that were
+// taken as read and which fields were corrected
+func (s *DA2062LearningService) Accuracy(ctx context.Context, query AccuracyQuery) ([]UnitAccuracy, error) {
+	switch query.Period {
+	case "day", "week", "month":
+	default:
+		return nil, ErrInvalidAccuracyPeriod
+	}
+	// The period is one of the names above, so it can be written into the query
+	period := fmt.Sprintf("date_trunc('%s', i.created_at)", query.Period)
+
+	lines := s.db.WithContext(ctx).Table("da2062_import_items AS it").
+		Select(`u.unit AS unit, `+period+` AS period,
+			COUNT(DISTINCT i.id) AS imports,
+			COUNT(it.id) FILTER (WHERE it.status <> ?) AS lines,
+			COUNT(it.id) FILTER (WHERE it.status <> ? AND jsonb_array_length(COALESCE(it.corrections, '[]'::jsonb)) > 0) AS corrected_lines,
+			COUNT(it.id) FILTER (WHERE it.status = ?) AS ignored_lines`,
+			domain.DA2062ItemIgnored, domain.DA2062ItemIgnored, domain.DA2062ItemIgnored).
+		Joins("JOIN da2062_imports i ON i.id = it.import_id").
+		Joins("JOIN users u ON u.id = i.imported_by_user_id").
+		Where("it.reviewed_at IS NOT NULL AND i.created_at >= ?", query.Since)
+	fields := s.db.WithContext(ctx).Table("da2062_correction_examples AS e").
+		Select(`u.unit AS unit, `+period+` AS period, e.field AS field, COUNT(*) AS count`).
+		Joins("JOIN da2062_imports i ON i.id = e.import_id").
+		Joins("JOIN users u ON u.id = i.imported_by_user_id").
+		Where("i.created_at >= ?", query.Since)
+	if query.Unit != "" {
+		lines = lines.Where("u.unit = ?", query.Unit)
+		fields = fields.Where("u.unit = ?", query.Unit)
+	}
+
+	var report []UnitAccuracy
+	if err := lines.Group("1, 2").Order("1, 2").Scan(&report).Error; err != nil {
+		return nil, fmt.Errorf("failed to count reviewed lines: %w", err)
+	}
+	var counts []struct {
+		Unit   string
+		Period time.Time
+		Field  string
+		Count  int
+	}
+	if err := fields.Group("1, 2, 3").Scan(&counts).Error; err != nil {
+		return nil, fmt.Errorf("failed to count corrections: %w", err)
+	}
+
+	for i := range report {
+		row := &report[i]
+		row.FieldCorrections = make(map[string]int)
+		if row.Lines > 0 {
+			row.LineAccuracy = float64(row.Lines-row.CorrectedLines) / float64(row.Lines)
+		}
+		for _, count := range counts {
+			if count.Unit == row.Unit && count.Period.Equal(row.Period) {
+				row.FieldCorrections[count.Field] += count.Count
+			}
+		}
+	}
+	return report, nil
+}
+
+// recordCorrectionExamples keeps a reviewer's corrections of a line as labeled
+// examples under the importing user's unit
+func recordCorrectionExamples(tx *gorm.DB, record *domain.DA2062Import, item domain.DA2062ImportItem, data models.DA2062ImportItem, corrections []domain.DA2062ItemCorrection) error {
+	if len(corrections) == 0 {
+		return nil
+	}
+	var importer domain.User
+	if err := tx.Select("id", "unit").First(&importer, record.ImportedByUser).Error; err != nil {
+		return fmt.Errorf("failed to find importer of import %d: %w", record.ID, err)
+	}
+
+	var nsn, source *string
+	if data.NSN != "" {
+		nsn = &data.NSN
+	}
+	if data.ImportMetadata != nil && data.ImportMetadata.Source != "" {
+		source = &data.ImportMetadata.Source
+	}
+	itemID := item.ID
+	examples := make([]domain.DA2062CorrectionExample, 0, len(corrections))
+	for _, correction := range corrections {
+		examples = append(examples, domain.DA2062CorrectionExample{
+			Unit:           importer.Unit,
+			ImportID:       record.ID,
+			ItemID:         &itemID,
+			UserID:         correction.UserID,
+			Field:          correction.Field,
+			ReadValue:      correction.From,
+			CorrectedValue: correction.To,
+			NSN:            nsn,
+			Source:         source,
+		})
+	}
+	if err := tx.Create(&examples).Error; err != nil {
+		return fmt.Errorf("failed to record corrections of line %d: %w", item.LineNumber, err)
+	}
+	return nil
+}
+
+// buildUnitProfile learns the serial number formats and item names that recur in a
+// unit's corrections, most recent first, and picks recent corrections to quote
+func buildUnitProfile(unit string, examples []domain.DA2062CorrectionExample) *ai.UnitProfile {
+	if len(examples) == 0 {
+		return nil
+	}
+	profile := &ai.UnitProfile{Unit: unit}
+
+	shapes := make(map[string]int)
+	names := make(map[string]int) // NSN and name, separated by a tab
+	seen := make(map[ai.CorrectionExample]bool)
+	for _, example := range examples {
+		field, ok := correctionFields[example.Field]
+		if !ok {
+			continue
+		}
+		corrected := strings.TrimSpace(example.CorrectedValue)
+		nsn := ""
+		if example.NSN != nil {
+			nsn = *example.NSN
+		}
+
+		switch field {
+		case ai.FieldSerialNumber:
+			if shape := serialShape(corrected); shape != "" {
+				shapes[shape]++
+			}
+		case ai.FieldName:
+			if corrected != "" && example.Field == "name" {
+				names[nsn+"\t"+strings.ToUpper(corrected)]++
+			}
+		}
+
+		quoted := ai.CorrectionExample{Field: field, Read: example.ReadValue, Corrected: corrected, NSN: nsn}
+		if !seen[quoted] && len(profile.Examples) < profileMaxExamples {
+			seen[quoted] = true
+			profile.Examples = append(profile.Examples, quoted)
+		}
+	}
+
+	profile.SerialPatterns = recurring(shapes, profileMaxPatterns)
+	for _, key := range recurring(names, profileMaxNomenclature) {
+		nsn, name, _ := strings.Cut(key, "\t")
+		profile.Nomenclature = append(profile.Nomenclature, ai.NomenclatureHint{NSN: nsn, Name: name, Count: names[key]})
+	}
+	return profile
+}
+
+// recurring returns the keys counted at least profileMinOccurrences times, most
+// frequent first, up to limit
+func recurring(counts map[string]int, limit int) []string {
+	var keys []string
+	for key, count := range counts {
+		if count >= profileMinOccurrences {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys
+}
+
+// serialShape reduces a serial number to a regular expression of its format, with
+// runs of letters and of digits as counted classes and other characters kept:
+// W123456 becomes ^[A-Z][0-9]{6}$. Serial numbers too short to have a format give
+// an empty string.
+func serialShape(serial string) string {
+	serial = strings.ToUpper(strings.TrimSpace(serial))
+	if len(serial) < 4 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	runes := []rune(serial)
+	for i := 0; i < len(runes); {
+		class := ""
+		switch {
+		case unicode.IsDigit(runes[i]):
+			class = "[0-9]"
+		case unicode.IsLetter(runes[i]):
+			class = "[A-Z]"
+		default:
+			b.WriteString(regexp.QuoteMeta(string(runes[i])))
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(runes) && sameClass(runes[i], runes[j]) {
+			j++
+		}
+		b.WriteString(class)
+		if j-i > 1 {
+			fmt.Fprintf(&b, "{%d}", j-i)
+		}
+		i = j
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+func sameClass(a, b rune) bool {
+	return (unicode.IsDigit(a) && unicode.IsDigit(b)) || (unicode.IsLetter(a) && unicode.IsLetter(b))
+}
@@ -0,0 +1,51 @@
+package inventory
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"github.com/toole-brendan/handreceipt-go/internal/services/ai"
+)
+
+func TestSerialShape(t *testing.T) {
+	for serial, want := range map[string]string{
+		"W123456":    "^[A-Z][0-9]{6}$",
+		" 12-ab345 ": "^[0-9]{2}-[A-Z]{2}[0-9]{3}$",
+		"A1":         "",
+	} {
+		if got := serialShape(serial); got != want {
+			t.Errorf("serialShape(%q) = %q, want %q", serial, got, want)
+		}
+	}
+}
+
+func TestBuildUnitProfile(t *testing.T) {
+	if buildUnitProfile("A CO", nil) != nil {
+		t.Error("unit without corrections got a profile")
+	}
+
+	nsn := "1005-01-231-0973"
+	examples := []domain.DA2062CorrectionExample{
+		{Field: "serial_number", ReadValue: "W12345G", CorrectedValue: "W123456", NSN: &nsn},
+		{Field: "serial_number", ReadValue: "W65432I", CorrectedValue: "W654321", NSN: &nsn},
+		{Field: "serial_number", ReadValue: "W12345G", CorrectedValue: "W123456", NSN: &nsn},
+		{Field: "serial_number", ReadValue: "1234", CorrectedValue: "12345"},
+		{Field: "name", ReadValue: "RIFLE 5.56 M4", CorrectedValue: "Rifle 5.56MM M4", NSN: &nsn},
+		{Field: "name", ReadValue: "RIFEL 5.56MM M4", CorrectedValue: "RIFLE 5.56MM M4", NSN: &nsn},
+		{Field: "status", ReadValue: "pending", CorrectedValue: "accepted"},
+	}
+
+	profile := buildUnitProfile("A CO", examples)
+	// Only the recurring format and name are learned
+	if want := []string{"^[A-Z][0-9]{6}$"}; !reflect.DeepEqual(profile.SerialPatterns, want) {
+		t.Errorf("serial patterns: %v", profile.SerialPatterns)
+	}
+	if want := []ai.NomenclatureHint{{NSN: nsn, Name: "RIFLE 5.56MM M4", Count: 2}}; !reflect.DeepEqual(profile.Nomenclature, want) {
+		t.Errorf("nomenclature: %+v", profile.Nomenclature)
+	}
+	// Repeated corrections are quoted once, and fields the parser does not read not at all
+	if len(profile.Examples) != 5 {
+		t.Errorf("examples: %+v", profile.Examples)
+	}
+}
@@ -0,0 +1,574 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"github.com/toole-brendan/handreceipt-go/internal/ledger"
+	"github.com/toole-brendan/handreceipt-go/internal/repository"
+	"github.com/toole-brendan/handreceipt-go/internal/services/ai"
+	"github.com/toole-brendan/handreceipt-go/internal/services/flipl"
+	"github.com/toole-brendan/handreceipt-go/internal/services/notification"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// reconcileInventoryDue is how long a requested inventory of reconciled property
+// has when no due date is given
+const reconcileInventoryDue = 7 * 24 * time.Hour
+
+var (
+	ErrNotReconciliation   = errors.New("import was not uploaded for reconciliation")
+	ErrReconciliationMode  = errors.New("reconciliation imports are not committed; act on the reconciliation report instead")
+	ErrNotADifference      = errors.New("the action does not apply to that line or property in the reconciliation report")
+	ErrInvalidReconcileAct = errors.New("action needs line IDs for create, or property IDs and, for a loss case, an approving authority")
+)
+
+// Kinds of difference between a form and a hand receipt
+const (
+	ReconcileNotInSystem = "not_in_system"
+	ReconcileNotOnPaper  = "not_on_paper"
+	ReconcileQuantity    = "quantity_mismatch"
+	ReconcileSerial      = "serial_mismatch"
+)
+
+// ReconciliationLine is a line of the form as it appears in a reconciliation report
+type ReconciliationLine struct {
+	ItemID       int64  `json:"itemId"`
+	LineNumber   int    `json:"lineNumber"`
+	PageNumber   *int   `json:"pageNumber,omitempty"`
+	Name         string `json:"name"`
+	NSN          string `json:"nsn,omitempty"`
+	SerialNumber string `json:"serialNumber,omitempty"`
+	Quantity     int    `json:"quantity"`
+	Problem      string `json:"problem,omitempty"` // Why the line cannot be created as property yet
+}
+
+// ReconciliationDifference is one way the form and the hand receipt disagree.
+// Quantity mismatches total every line and every property of the stock number.
+type ReconciliationDifference struct {
+	Kind           string                              `json:"kind"`
+	Lines          []ReconciliationLine                `json:"lines,omitempty"`
+	Properties     []domain.Property                   `json:"properties,omitempty"`
+	PaperQuantity  int                                 `json:"paperQuantity"`
+	SystemQuantity int                                 `json:"systemQuantity"`
+	Actions        []string                            `json:"actions"`
+	Taken          []domain.DA2062ReconciliationAction `json:"taken,omitempty"`
+}
+
+// ReconciliationReport compares an imported property book printout with the
+// importer's current hand receipt
+type ReconciliationReport struct {
+	Import      *domain.DA2062Import       `json:"import"`
+	Matched     int                        `json:"matched"`
+	Summary     map[string]int             `json:"summary"`
+	Differences []ReconciliationDifference `json:"differences"`
+}
+
+// ReconcileActionInput acts on differences in a reconciliation report. create takes
+// ItemIDs of lines not in the system; inventory and loss_case take PropertyIDs.
+// All the property flagged for inventory goes into one campaign.
+type ReconcileActionInput struct {
+	Action               string     `json:"action" binding:"required,oneof=create inventory loss_case"`
+	ItemIDs              []int64    `json:"itemIds"`
+	PropertyIDs          []uint     `json:"propertyIds"`
+	DueDate              *time.Time `json:"dueDate"`
+	ApprovingAuthorityID uint       `json:"approvingAuthorityId"`
+	Circumstances        string     `json:"circumstances"`
+}
+
+// DA2062ReconcileService compares an imported DA 2062 with the importer's hand
+// receipt and acts on the differences: creating property that is only on paper,
+// requesting an inventory of property that disagrees, or opening a loss case for
+// property the form no longer shows
+type DA2062ReconcileService struct {
+	db        *gorm.DB
+	repo      repository.Repository
+	ledger    ledger.LedgerService
+	campaigns *CampaignService
+	lossCases *flipl.LossCaseService
+	hub       *notification.Hub
+}
+
+// NewDA2062ReconcileService creates a new reconciliation service. hub may be nil, in
+// which case import progress is not published.
+func NewDA2062ReconcileService(
+	db *gorm.DB,
+	repo repository.Repository,
+	ledgerService ledger.LedgerService,
+	campaigns *CampaignService,
+	lossCases *flipl.LossCaseService,
+	hub *notification.Hub,
+) *DA2062ReconcileService {
+	return &DA2062ReconcileService{
+		db:        db,
+		repo:      repo,
+		ledger:    ledgerService,
+		campaigns: campaigns,
+		lossCases: lossCases,
+		hub:       hub,
+	}
+}
+
+// Report diffs a parsed reconciliation import against the importer's hand receipt.
+// Ignored, merged and duplicate lines are left out; lines corrected in review are
+// compared as corrected.
+func (s *DA2062ReconcileService) Report(ctx context.Context, importID int64, userID uint) (*ReconciliationReport, error) {
+	var record domain.DA2062Import
+	if err := s.db.WithContext(ctx).First(&record, importID).Error; err != nil {
+		return nil, err
+	}
+	if uint(record.ImportedByUser) != userID {
+		return nil, ErrNotImporter
+	}
+	if record.Mode != domain.DA2062ImportModeReconcile {
+		return nil, ErrNotReconciliation
+	}
+	if record.Status != domain.DA2062ImportCompleted {
+		return nil, ErrImportNotReady
+	}
+
+	var items []domain.DA2062ImportItem
+	if err := s.db.WithContext(ctx).
+		Where("import_id = ? AND status NOT IN ?", importID, []string{domain.DA2062ItemIgnored, domain.DA2062ItemMerged, domain.DA2062ItemDuplicate}).
+		Order("line_number ASC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	lines := make([]ReconciliationLine, len(items))
+	for i, item := range items {
+		data, err := decodeLine(item)
+		if err != nil {
+			return nil, err
+		}
+		name := data.Name
+		if strings.TrimSpace(name) == "" {
+			name = data.Description
+		}
+		lines[i] = ReconciliationLine{
+			ItemID:       item.ID,
+			LineNumber:   item.LineNumber,
+			PageNumber:   item.PageNumber,
+			Name:         name,
+			NSN:          data.NSN,
+			SerialNumber: data.SerialNumber,
+			Quantity:     data.Quantity,
+			Problem:      reviewProblem(data),
+		}
+	}
+
+	properties, err := s.repo.ListProperties(&userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hand receipt: %w", err)
+	}
+
+	var taken []domain.DA2062ReconciliationAction
+	if err := s.db.WithContext(ctx).Where("import_id = ?", importID).Order("id ASC").Find(&taken).Error; err != nil {
+		return nil, err
+	}
+
+	report := &ReconciliationReport{Import: &record, Summary: make(map[string]int)}
+	report.Matched, report.Differences = reconcile(lines, properties)
+	for i := range report.Differences {
+		difference := &report.Differences[i]
+		difference.Taken = actionsTaken(*difference, taken)
+		report.Summary[difference.Kind]++
+	}
+	return report, nil
+}
+
+// Act carries out an action on differences in the import's reconciliation report.
+// Loss cases are opened one property at a time; if one cannot be opened, those
+// already opened stand and show as taken in the report.
+func (s *DA2062ReconcileService) Act(ctx context.Context, importID int64, input ReconcileActionInput, userID uint) ([]domain.DA2062ReconciliationAction, error) {
+	report, err := s.Report(ctx, importID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch input.Action {
+	case domain.DA2062ReconcileCreate:
+		if len(input.ItemIDs) == 0 {
+			return nil, ErrInvalidReconcileAct
+		}
+		lines := make(map[int64]ReconciliationLine)
+		for _, difference := range report.Differences {
+			if difference.Kind == ReconcileNotInSystem {
+				for _, line := range difference.Lines {
+					lines[line.ItemID] = line
+				}
+			}
+		}
+		for _, itemID := range input.ItemIDs {
+			line, ok := lines[itemID]
+			if !ok {
+				return nil, fmt.Errorf("%w: line %d", ErrNotADifference, itemID)
+			}
+			if line.Problem != "" {
+				return nil, fmt.Errorf("%w: line %d: %s", ErrLineInvalid, line.LineNumber, line.Problem)
+			}
+		}
+		return s.create(ctx, report.Import, input.ItemIDs, userID)
+
+	case domain.DA2062ReconcileInventory, domain.DA2062ReconcileLossCase:
+		if len(input.PropertyIDs) == 0 || (input.Action == domain.DA2062ReconcileLossCase && input.ApprovingAuthorityID == 0) {
+			return nil, ErrInvalidReconcileAct
+		}
+		allowed := make(map[uint]bool)
+		for _, difference := range report.Differences {
+			if !containsString(difference.Actions, input.Action) {
+				continue
+			}
+			for _, property := range difference.Properties {
+				allowed[property.ID] = true
+			}
+		}
+		for _, propertyID := range input.PropertyIDs {
+			if !allowed[propertyID] {
+				return nil, fmt.Errorf("%w: property %d", ErrNotADifference, propertyID)
+			}
+		}
+		if input.Action == domain.DA2062ReconcileInventory {
+			return s.requestInventory(ctx, report.Import, input, userID)
+		}
+		return s.openLossCases(ctx, report.Import, input, userID)
+	}
+	return nil, ErrInvalidReconcileAct
+}
+
+// create makes property of lines that are on the form but not on the hand receipt.
+// The import is locked first, so two requests for the same lines cannot both
+// create them; the second finds them created and is refused.
+func (s *DA2062ReconcileService) create(ctx context.Context, record *domain.DA2062Import, itemIDs []int64, userID uint) ([]domain.DA2062ReconciliationAction, error) {
+	var actions []domain.DA2062ReconciliationAction
+	var propertyIDs []uint
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&domain.DA2062Import{}, record.ID).Error; err != nil {
+			return err
+		}
+
+		var items []domain.DA2062ImportItem
+		if err := tx.Where("import_id = ? AND id IN ?", record.ID, itemIDs).Order("line_number ASC").Find(&items).Error; err != nil {
+			return err
+		}
+		for _, item := range items {
+			if item.PropertyID != nil {
+				return fmt.Errorf("%w: line %d", ErrNotADifference, item.LineNumber)
+			}
+		}
+
+		now := time.Now()
+		for _, item := range items {
+			data, err := decodeLine(item)
+			if err != nil {
+				return err
+			}
+			var taken int64
+			if err := tx.Model(&domain.Property{}).Where("UPPER(serial_number) = ?", normalizeSerial(data.SerialNumber)).Count(&taken).Error; err != nil {
+				return err
+			}
+			if taken > 0 {
+				return fmt.Errorf("%w: %s (line %d)", ErrSerialTaken, data.SerialNumber, item.LineNumber)
+			}
+
+			property := propertyFromLine(data, userID, now)
+			if err := tx.Create(&property).Error; err != nil {
+				return fmt.Errorf("failed to create property from line %d: %w", item.LineNumber, err)
+			}
+			if err := tx.Model(&item).Updates(map[string]interface{}{
+				"property_id": int64(property.ID),
+				"status":      domain.DA2062ItemProcessed,
+			}).Error; err != nil {
+				return err
+			}
+
+			itemID, propertyID := item.ID, property.ID
+			action := domain.DA2062ReconciliationAction{
+				ImportID:   record.ID,
+				Action:     domain.DA2062ReconcileCreate,
+				ItemID:     &itemID,
+				PropertyID: &propertyID,
+				UserID:     userID,
+			}
+			if err := tx.Create(&action).Error; err != nil {
+				return err
+			}
+			actions = append(actions, action)
+			propertyIDs = append(propertyIDs, property.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := countImportItems(s.db, record.ID); err != nil {
+		log.Printf("WARNING: Failed to update item counts of import %d: %v", record.ID, err)
+	}
+	PublishImportProgress(s.db, s.hub, record.ID)
+
+	if s.ledger != nil {
+		event := ledger.DA2062Event{
+			UserID:       strconv.FormatUint(uint64(userID), 10),
+			ItemCount:    len(propertyIDs),
+			ImportMethod: "reconcile",
+			ImportID:     record.ID,
+			PropertyIDs:  propertyIDs,
+		}
+		if err := s.ledger.LogDA2062Import(ctx, event); err != nil {
+			log.Printf("WARNING: Failed to log property created from reconciliation of import %d to ledger: %v", record.ID, err)
+		}
+	}
+	return actions, nil
+}
+
+// requestInventory opens one cyclic inventory campaign over the property
+func (s *DA2062ReconcileService) requestInventory(ctx context.Context, record *domain.DA2062Import, input ReconcileActionInput, userID uint) ([]domain.DA2062ReconciliationAction, error) {
+	dueDate := time.Now().Add(reconcileInventoryDue)
+	if input.DueDate != nil {
+		dueDate = *input.DueDate
+	}
+	campaign, err := s.campaigns.Create(ctx, CreateCampaignInput{
+		Name:         fmt.Sprintf("Reconciliation of %s", record.FileName),
+		CampaignType: domain.InventoryCampaignCyclic,
+		DueDate:      dueDate,
+		Selection:    CampaignSelection{PropertyIDs: input.PropertyIDs},
+	}, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := make([]domain.DA2062ReconciliationAction, len(input.PropertyIDs))
+	for i := range input.PropertyIDs {
+		actions[i] = domain.DA2062ReconciliationAction{
+			ImportID:   record.ID,
+			Action:     domain.DA2062ReconcileInventory,
+			PropertyID: &input.PropertyIDs[i],
+			CampaignID: &campaign.ID,
+			UserID:     userID,
+		}
+	}
+	if err := s.db.WithContext(ctx).Create(&actions).Error; err != nil {
+		return nil, fmt.Errorf("failed to record inventory campaign %d: %w", campaign.ID, err)
+	}
+	return actions, nil
+}
+
+// openLossCases reports each property lost
+func (s *DA2062ReconcileService) openLossCases(ctx context.Context, record *domain.DA2062Import, input ReconcileActionInput, userID uint) ([]domain.DA2062ReconciliationAction, error) {
+	circumstances := strings.TrimSpace(input.Circumstances)
+	if circumstances == "" {
+		circumstances = fmt.Sprintf("Not on the property book printout %s reconciled on %s", record.FileName, time.Now().UTC().Format("2006-01-02"))
+	}
+
+	var actions []domain.DA2062ReconciliationAction
+	for i := range input.PropertyIDs {
+		lossCase, err := s.lossCases.Open(ctx, flipl.OpenCaseInput{
+			PropertyID:           input.PropertyIDs[i],
+			CaseType:             "lost",
+			Circumstances:        circumstances,
+			IncidentDate:         time.Now().UTC(),
+			ApprovingAuthorityID: input.ApprovingAuthorityID,
+		}, userID)
+		if err != nil {
+			return actions, fmt.Errorf("failed to open loss case for property %d: %w", input.PropertyIDs[i], err)
+		}
+		action := domain.DA2062ReconciliationAction{
+			ImportID:   record.ID,
+			Action:     domain.DA2062ReconcileLossCase,
+			PropertyID: &input.PropertyIDs[i],
+			LossCaseID: &lossCase.ID,
+			UserID:     userID,
+		}
+		if err := s.db.WithContext(ctx).Create(&action).Error; err != nil {
+			return actions, fmt.Errorf("failed to record loss case %s: %w", lossCase.CaseNumber, err)
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+// reconcile pairs the form's lines with the hand receipt. Lines match property by
+// serial number first. Lines without a serial number are totalled by stock number
+// against the remaining property of that stock number. Serialized lines left over
+// are then paired with remaining property of the same stock number as serial
+// mismatches. Whatever is still unpaired is on only one side.
+func reconcile(lines []ReconciliationLine, properties []domain.Property) (int, []ReconciliationDifference) {
+	sort.Slice(properties, func(i, j int) bool { return properties[i].ID < properties[j].ID })
+
+	matched := 0
+	var differences []ReconciliationDifference
+	usedLine := make([]bool, len(lines))
+	usedProperty := make([]bool, len(properties))
+
+	bySerial := make(map[string]int, len(properties))
+	for i, property := range properties {
+		if serial := normalizeSerial(property.SerialNumber); serial != "" {
+			bySerial[serial] = i
+		}
+	}
+	for i, line := range lines {
+		serial := normalizeSerial(line.SerialNumber)
+		if serial == "" {
+			continue
+		}
+		j, ok := bySerial[serial]
+		if !ok || usedProperty[j] {
+			continue
+		}
+		usedLine[i], usedProperty[j] = true, true
+		if line.Quantity > 0 && properties[j].Quantity > 0 && line.Quantity != properties[j].Quantity {
+			differences = append(differences, ReconciliationDifference{
+				Kind:           ReconcileQuantity,
+				Lines:          []ReconciliationLine{line},
+				Properties:     []domain.Property{properties[j]},
+				PaperQuantity:  line.Quantity,
+				SystemQuantity: properties[j].Quantity,
+				Actions:        []string{domain.DA2062ReconcileInventory},
+			})
+			continue
+		}
+		matched++
+	}
+
+	// Lines without serial numbers, totalled by stock number
+	var keys []string
+	unserialized := make(map[string][]int)
+	for i, line := range lines {
+		if usedLine[i] || normalizeSerial(line.SerialNumber) != "" {
+			continue
+		}
+		key := stockKey(line.NSN, line.Name)
+		if _, ok := unserialized[key]; !ok {
+			keys = append(keys, key)
+		}
+		unserialized[key] = append(unserialized[key], i)
+	}
+	for _, key := range keys {
+		var held []int
+		for j, property := range properties {
+			if !usedProperty[j] && stockKey(propertyNSN(property), property.Name) == key {
+				held = append(held, j)
+			}
+		}
+		if len(held) == 0 {
+			continue
+		}
+		difference := ReconciliationDifference{Kind: ReconcileQuantity, Actions: []string{domain.DA2062ReconcileInventory}}
+		for _, i := range unserialized[key] {
+			usedLine[i] = true
+			difference.Lines = append(difference.Lines, lines[i])
+			difference.PaperQuantity += lines[i].Quantity
+		}
+		for _, j := range held {
+			usedProperty[j] = true
+			difference.Properties = append(difference.Properties, properties[j])
+			difference.SystemQuantity += propertyQuantity(properties[j])
+		}
+		if difference.PaperQuantity == difference.SystemQuantity {
+			matched += len(difference.Lines)
+			continue
+		}
+		differences = append(differences, difference)
+	}
+
+	// Serialized lines whose serial number is not on the hand receipt
+	for i, line := range lines {
+		if usedLine[i] {
+			continue
+		}
+		key := stockKey(line.NSN, line.Name)
+		for j, property := range properties {
+			if usedProperty[j] || stockKey(propertyNSN(property), property.Name) != key {
+				continue
+			}
+			usedLine[i], usedProperty[j] = true, true
+			differences = append(differences, ReconciliationDifference{
+				Kind:           ReconcileSerial,
+				Lines:          []ReconciliationLine{line},
+				Properties:     []domain.Property{property},
+				PaperQuantity:  line.Quantity,
+				SystemQuantity: propertyQuantity(property),
+				Actions:        []string{domain.DA2062ReconcileInventory},
+			})
+			break
+		}
+	}
+
+	for i, line := range lines {
+		if usedLine[i] {
+			continue
+		}
+		difference := ReconciliationDifference{
+			Kind:          ReconcileNotInSystem,
+			Lines:         []ReconciliationLine{line},
+			PaperQuantity: line.Quantity,
+			Actions:       []string{},
+		}
+		if line.Problem == "" {
+			difference.Actions = append(difference.Actions, domain.DA2062ReconcileCreate)
+		}
+		differences = append(differences, difference)
+	}
+	for j, property := range properties {
+		if usedProperty[j] {
+			continue
+		}
+		differences = append(differences, ReconciliationDifference{
+			Kind:           ReconcileNotOnPaper,
+			Properties:     []domain.Property{property},
+			SystemQuantity: propertyQuantity(property),
+			Actions:        []string{domain.DA2062ReconcileInventory, domain.DA2062ReconcileLossCase},
+		})
+	}
+	return matched, differences
+}
+
+// actionsTaken returns the recorded actions on a difference's lines or property
+func actionsTaken(difference ReconciliationDifference, taken []domain.DA2062ReconciliationAction) []domain.DA2062ReconciliationAction {
+	var found []domain.DA2062ReconciliationAction
+	for _, action := range taken {
+		for _, line := range difference.Lines {
+			if action.ItemID != nil && *action.ItemID == line.ItemID {
+				found = append(found, action)
+			}
+		}
+		if action.ItemID != nil {
+			continue
+		}
+		for _, property := range difference.Properties {
+			if action.PropertyID != nil && *action.PropertyID == property.ID {
+				found = append(found, action)
+			}
+		}
+	}
+	return found
+}
+
+// stockKey identifies an item by stock number, or by name when it has none
+func stockKey(nsn, name string) string {
+	if normalized := ai.NormalizeNSN(nsn); normalized != "" {
+		return normalized
+	}
+	return strings.ToUpper(strings.TrimSpace(name))
+}
+
+func propertyNSN(property domain.Property) string {
+	if property.NSN == nil {
+		return ""
+	}
+	return *property.NSN
+}
+
+func propertyQuantity(property domain.Property) int {
+	if property.Quantity < 1 {
+		return 1
+	}
+	return property.Quantity
+}
@@ -0,0 +1,81 @@
+package inventory
+
+import (
+	"testing"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+)
+
+func TestReconcile(t *testing.T) {
+	rifleNSN := "1005-01-231-0973"
+	magazineNSN := "1005-01-561-7200"
+	goggleNSN := "5855-01-534-5931"
+	properties := []domain.Property{
+		{ID: 1, Name: "RIFLE 5.56MM M4", SerialNumber: "W100", NSN: &rifleNSN, Quantity: 1},
+		{ID: 2, Name: "RIFLE 5.56MM M4", SerialNumber: "W200", NSN: &rifleNSN, Quantity: 1},
+		{ID: 3, Name: "MAGAZINE", SerialNumber: "MAG-1", NSN: &magazineNSN, Quantity: 20},
+		{ID: 4, Name: "GOGGLES NIGHT VISION", SerialNumber: "N1", NSN: &goggleNSN, Quantity: 1},
+		{ID: 5, Name: "COMPASS", SerialNumber: "C1", Quantity: 1},
+	}
+	lines := []ReconciliationLine{
+		{ItemID: 11, LineNumber: 1, Name: "RIFLE 5.56MM M4", NSN: "1005012310973", SerialNumber: "w100", Quantity: 1},
+		{ItemID: 12, LineNumber: 2, Name: "RIFLE 5.56MM M4", NSN: rifleNSN, SerialNumber: "W299", Quantity: 1},
+		{ItemID: 13, LineNumber: 3, Name: "MAGAZINE", NSN: magazineNSN, Quantity: 30},
+		{ItemID: 14, LineNumber: 4, Name: "HELMET", NSN: "8470-01-520-7373", SerialNumber: "H1", Quantity: 1},
+		{ItemID: 15, LineNumber: 5, Name: "COMPASS", Quantity: 1},
+		{ItemID: 16, LineNumber: 6, Name: "BAYONET", Quantity: 1, Problem: "Serial number is required"},
+	}
+
+	matched, differences := reconcile(lines, properties)
+
+	// The first rifle matches by serial number and the compass by name
+	if matched != 2 {
+		t.Errorf("matched %d, want 2", matched)
+	}
+	kinds := make(map[string][]ReconciliationDifference)
+	for _, difference := range differences {
+		kinds[difference.Kind] = append(kinds[difference.Kind], difference)
+	}
+
+	if got := kinds[ReconcileSerial]; len(got) != 1 || got[0].Lines[0].ItemID != 12 || got[0].Properties[0].ID != 2 {
+		t.Errorf("serial mismatches: %+v", got)
+	}
+	if got := kinds[ReconcileQuantity]; len(got) != 1 || got[0].PaperQuantity != 30 || got[0].SystemQuantity != 20 {
+		t.Errorf("quantity mismatches: %+v", got)
+	}
+	notInSystem := kinds[ReconcileNotInSystem]
+	if len(notInSystem) != 2 || notInSystem[0].Lines[0].ItemID != 14 || notInSystem[1].Lines[0].ItemID != 16 {
+		t.Fatalf("not in system: %+v", notInSystem)
+	}
+	if actions := notInSystem[0].Actions; len(actions) != 1 || actions[0] != domain.DA2062ReconcileCreate {
+		t.Errorf("actions for a valid line: %v", actions)
+	}
+	if actions := notInSystem[1].Actions; len(actions) != 0 {
+		t.Errorf("actions for a line that cannot be created: %v", actions)
+	}
+	if got := kinds[ReconcileNotOnPaper]; len(got) != 1 || got[0].Properties[0].ID != 4 {
+		t.Errorf("not on paper: %+v", got)
+	}
+}
+
+func TestActionsTaken(t *testing.T) {
+	itemID, propertyID, other := int64(14), uint(4), uint(9)
+	taken := []domain.DA2062ReconciliationAction{
+		{ID: 1, Action: domain.DA2062ReconcileCreate, ItemID: &itemID, PropertyID: &other},
+		{ID: 2, Action: domain.DA2062ReconcileLossCase, PropertyID: &propertyID},
+	}
+
+	line := ReconciliationDifference{Lines: []ReconciliationLine{{ItemID: 14}}}
+	if got := actionsTaken(line, taken); len(got) != 1 || got[0].ID != 1 {
+		t.Errorf("line actions: %+v", got)
+	}
+	// The property created from a line is not itself a difference
+	created := ReconciliationDifference{Properties: []domain.Property{{ID: 9}}}
+	if got := actionsTaken(created, taken); len(got) != 0 {
+		t.Errorf("created property actions: %+v", got)
+	}
+	held := ReconciliationDifference{Properties: []domain.Property{{ID: 4}}}
+	if got := actionsTaken(held, taken); len(got) != 1 || got[0].ID != 2 {
+		t.Errorf("property actions: %+v", got)
+	}
+}
@@ -0,0 +1,806 @@
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/disintegration/imaging"
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"github.com/toole-brendan/handreceipt-go/internal/ledger"
+	"github.com/toole-brendan/handreceipt-go/internal/models"
+	"github.com/toole-brendan/handreceipt-go/internal/services/ai"
+	"github.com/toole-brendan/handreceipt-go/internal/services/notification"
+	"github.com/toole-brendan/handreceipt-go/internal/services/storage"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNotImporter     = errors.New("only the user who imported the form can review it")
+	ErrImportNotReady  = errors.New("import has not finished parsing")
+	ErrImportCommitted = errors.New("import has already been committed")
+	ErrLineLocked      = errors.New("line has been merged or committed and can no longer be changed")
+	ErrInvalidReview   = errors.New("status must be accepted, ignored or pending")
+	ErrLineInvalid     = errors.New("line cannot be accepted")
+	ErrMergeMismatch   = errors.New("only lines with the same stock number and serial number can be merged")
+	ErrLinesUnreviewed = errors.New("every line must be accepted, ignored or merged before the import is committed")
+	ErrNothingAccepted = errors.New("no lines were accepted")
+	ErrDuplicateSerial = errors.New("serial number is on more than one accepted line")
+	ErrSerialTaken     = errors.New("serial number already belongs to existing property")
+	ErrNoRowImage      = errors.New("no scanned image of this line is available")
+	ErrNoFieldBox      = errors.New("where this field was read on the scan is not known")
+)
+
+// ReviewItemInput changes a staged line. Fields left nil keep the value read from
+// the form. Status, if set, accepts or ignores the line or returns it to pending.
+type ReviewItemInput struct {
+	Name         *string `json:"name"`
+	Description  *string `json:"description"`
+	SerialNumber *string `json:"serialNumber"`
+	NSN          *string `json:"nsn"`
+	Quantity     *int    `json:"quantity"`
+	Unit         *string `json:"unit"`
+	Category     *string `json:"category"`
+	Status       string  `json:"status"`
+}
+
+// MergeItemsInput folds the quantities of ItemIDs into TargetItemID, for an item
+// whose quantity the form split across several lines
+type MergeItemsInput struct {
+	TargetItemID int64   `json:"targetItemId" binding:"required"`
+	ItemIDs      []int64 `json:"itemIds" binding:"required,min=1"`
+}
+
+// CommitResult reports what committing an import created
+type CommitResult struct {
+	Import      *domain.DA2062Import `json:"import"`
+	Properties  []domain.Property    `json:"properties"`
+	Ignored     int                  `json:"ignored"`
+	Merged      int                  `json:"merged"`
+	Corrections int                  `json:"corrections"`
+}
+
+// DA2062ReviewService runs the review of a parsed DA 2062. The importing user
+// checks each staged line against a crop of the scan, corrects it, merges lines
+// that split one item's quantity, accepts or ignores it, and then commits the
+// accepted lines as property in one transaction.
+type DA2062ReviewService struct {
+	db      *gorm.DB
+	storage storage.StorageService
+	ledger  ledger.LedgerService
+	hub     *notification.Hub
+}
+
+// NewDA2062ReviewService creates a new review service. hub may be nil, in which
+// case review progress is not published.
+func NewDA2062ReviewService(
+	db *gorm.DB,
+	storageService storage.StorageService,
+	ledgerService ledger.LedgerService,
+	hub *notification.Hub,
+) *DA2062ReviewService {
+	return &DA2062ReviewService{
+		db:      db,
+		storage: storageService,
+		ledger:  ledgerService,
+		hub:     hub,
+	}
+}
+
+// ReviewItem applies a reviewer's corrections and decision to one line. Every
+// changed field is recorded as a correction on the line, and kept as an example
+// for learning the unit's forms.
+func (s *DA2062ReviewService) ReviewItem(ctx context.Context, importID, itemID int64, input ReviewItemInput, userID uint) (*domain.DA2062ImportItem, error) {
+	switch input.Status {
+	case "", domain.DA2062ItemAccepted, domain.DA2062ItemIgnored, domain.DA2062ItemPending:
+	default:
+		return nil, ErrInvalidReview
+	}
+
+	var item domain.DA2062ImportItem
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		record, err := lockReviewableImport(tx, importID, userID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("id = ? AND import_id = ?", itemID, importID).First(&item).Error; err != nil {
+			return err
+		}
+		if item.Status == domain.DA2062ItemMerged || item.Status == domain.DA2062ItemProcessed {
+			return ErrLineLocked
+		}
+		data, err := decodeLine(item)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		corrections := applyEdits(&data, input, item.LineNumber, userID, now)
+
+		status := item.Status
+		if input.Status != "" {
+			status = input.Status
+		} else if status == domain.DA2062ItemFailed && unusableLineReason(data) == "" {
+			// Corrected enough to be considered
+			status = domain.DA2062ItemPending
+		}
+		if status == domain.DA2062ItemAccepted {
+			if problem := reviewProblem(data); problem != "" {
+				return fmt.Errorf("%w: %s", ErrLineInvalid, problem)
+			}
+		}
+
+		updates, err := lineUpdates(item, data, corrections, userID, now)
+		if err != nil {
+			return err
+		}
+		updates["status"] = status
+		if status != domain.DA2062ItemFailed {
+			updates["error_message"] = nil
+		}
+		if err := tx.Model(&item).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := recordCorrectionExamples(tx, record, item, data, corrections); err != nil {
+			return err
+		}
+		return tx.First(&item, item.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.updateProgress(importID)
+	return &item, nil
+}
+
+// MergeItems adds the quantities of the given lines to the target line and marks
+// them merged. The lines must be for the same stock number, and carry no serial
+// number other than the target's.
+func (s *DA2062ReviewService) MergeItems(ctx context.Context, importID int64, input MergeItemsInput, userID uint) (*domain.DA2062ImportItem, error) {
+	ids := []int64{input.TargetItemID}
+	seen := map[int64]bool{input.TargetItemID: true}
+	for _, id := range input.ItemIDs {
+		if id == input.TargetItemID {
+			return nil, fmt.Errorf("%w: a line cannot be merged into itself", ErrMergeMismatch)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	var target domain.DA2062ImportItem
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := lockReviewableImport(tx, importID, userID); err != nil {
+			return err
+		}
+		var items []domain.DA2062ImportItem
+		if err := tx.Where("import_id = ? AND id IN ?", importID, ids).Order("line_number ASC").Find(&items).Error; err != nil {
+			return err
+		}
+		if len(items) != len(ids) {
+			return gorm.ErrRecordNotFound
+		}
+
+		lines := make(map[int64]models.DA2062ImportItem, len(items))
+		for _, item := range items {
+			if item.Status == domain.DA2062ItemMerged || item.Status == domain.DA2062ItemProcessed {
+				return fmt.Errorf("%w: line %d", ErrLineLocked, item.LineNumber)
+			}
+			data, err := decodeLine(item)
+			if err != nil {
+				return err
+			}
+			lines[item.ID] = data
+			if item.ID == input.TargetItemID {
+				target = item
+			}
+		}
+
+		targetData := lines[target.ID]
+		now := time.Now()
+		total := targetData.Quantity
+		var merged []string
+		for _, item := range items {
+			if item.ID == target.ID {
+				continue
+			}
+			data := lines[item.ID]
+			if !sameStockNumber(data.NSN, targetData.NSN) {
+				return fmt.Errorf("%w: line %d has NSN %q, line %d has %q", ErrMergeMismatch, item.LineNumber, data.NSN, target.LineNumber, targetData.NSN)
+			}
+			if serial := normalizeSerial(data.SerialNumber); serial != "" && serial != normalizeSerial(targetData.SerialNumber) {
+				return fmt.Errorf("%w: line %d has serial number %s", ErrMergeMismatch, item.LineNumber, data.SerialNumber)
+			}
+			total += data.Quantity
+			merged = append(merged, strconv.Itoa(item.LineNumber))
+
+			correction := domain.DA2062ItemCorrection{
+				Line:   item.LineNumber,
+				Field:  "status",
+				From:   item.Status,
+				To:     domain.DA2062ItemMerged,
+				Note:   fmt.Sprintf("Quantity of %d merged into line %d", data.Quantity, target.LineNumber),
+				UserID: userID,
+				At:     now,
+			}
+			updates, err := lineUpdates(item, data, []domain.DA2062ItemCorrection{correction}, userID, now)
+			if err != nil {
+				return err
+			}
+			updates["status"] = domain.DA2062ItemMerged
+			updates["merged_into_item_id"] = target.ID
+			updates["error_message"] = nil
+			if err := tx.Model(&item).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+
+		correction := domain.DA2062ItemCorrection{
+			Line:   target.LineNumber,
+			Field:  "quantity",
+			From:   strconv.Itoa(targetData.Quantity),
+			To:     strconv.Itoa(total),
+			Note:   fmt.Sprintf("Merged line(s) %s", strings.Join(merged, ", ")),
+			UserID: userID,
+			At:     now,
+		}
+		targetData.Quantity = total
+		updates, err := lineUpdates(target, targetData, []domain.DA2062ItemCorrection{correction}, userID, now)
+		if err != nil {
+			return err
+		}
+		if target.Status == domain.DA2062ItemFailed && unusableLineReason(targetData) == "" {
+			updates["status"] = domain.DA2062ItemPending
+			updates["error_message"] = nil
+		}
+		if err := tx.Model(&target).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.First(&target, target.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.updateProgress(importID)
+	return &target, nil
+}
+
+// Commit creates property from every accepted line in one transaction, then logs a
+// single DA2062Import ledger event carrying every correction made in review. Each
+// line must have been accepted, ignored or merged first.
+func (s *DA2062ReviewService) Commit(ctx context.Context, importID int64, userID uint) (*CommitResult, error) {
+	result := &CommitResult{}
+	var corrections []domain.DA2062ItemCorrection
+	var committed []models.DA2062ImportItem
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		record, err := lockReviewableImport(tx, importID, userID)
+		if err != nil {
+			return err
+		}
+		if record.Mode == domain.DA2062ImportModeReconcile {
+			return ErrReconciliationMode
+		}
+		result.Import = record
+
+		var items []domain.DA2062ImportItem
+		if err := tx.Where("import_id = ?", importID).Order("line_number ASC").Find(&items).Error; err != nil {
+			return err
+		}
+
+		var accepted []domain.DA2062ImportItem
+		unreviewed := 0
+		for _, item := range items {
+			if len(item.Corrections) > 0 {
+				var lineCorrections []domain.DA2062ItemCorrection
+				if err := json.Unmarshal(item.Corrections, &lineCorrections); err != nil {
+					return fmt.Errorf("failed to read corrections of line %d: %w", item.LineNumber, err)
+				}
+				corrections = append(corrections, lineCorrections...)
+			}
+			switch item.Status {
+			case domain.DA2062ItemAccepted:
+				accepted = append(accepted, item)
+			case domain.DA2062ItemIgnored:
+				result.Ignored++
+			case domain.DA2062ItemMerged:
+				result.Merged++
+			case domain.DA2062ItemPending, domain.DA2062ItemFailed:
+				unreviewed++
+			}
+		}
+		if unreviewed > 0 {
+			return fmt.Errorf("%w: %d line(s) still to review", ErrLinesUnreviewed, unreviewed)
+		}
+		if len(accepted) == 0 {
+			return ErrNothingAccepted
+		}
+
+		lines := make([]models.DA2062ImportItem, len(accepted))
+		serials := make([]string, 0, len(accepted))
+		bySerial := make(map[string]int, len(accepted))
+		for i, item := range accepted {
+			data, err := decodeLine(item)
+			if err != nil {
+				return err
+			}
+			// Lines accepted before a serial was required, such as N/A, would all
+			// share the empty serial
+			if problem := reviewProblem(data); problem != "" {
+				return fmt.Errorf("%w: line %d: %s", ErrLineInvalid, item.LineNumber, problem)
+			}
+			serial := normalizeSerial(data.SerialNumber)
+			if line, ok := bySerial[serial]; ok {
+				return fmt.Errorf("%w: %s is on lines %d and %d", ErrDuplicateSerial, data.SerialNumber, line, item.LineNumber)
+			}
+			bySerial[serial] = item.LineNumber
+			serials = append(serials, serial)
+			lines[i] = data
+		}
+
+		var taken []string
+		if err := tx.Model(&domain.Property{}).Where("UPPER(serial_number) IN ?", serials).Pluck("serial_number", &taken).Error; err != nil {
+			return err
+		}
+		if len(taken) > 0 {
+			return fmt.Errorf("%w: %s (line %d)", ErrSerialTaken, taken[0], bySerial[normalizeSerial(taken[0])])
+		}
+
+		now := time.Now()
+		for i, item := range accepted {
+			property := propertyFromLine(lines[i], userID, now)
+			if err := tx.Create(&property).Error; err != nil {
+				return fmt.Errorf("failed to create property from line %d: %w", item.LineNumber, err)
+			}
+			propertyID := int64(property.ID)
+			if err := tx.Model(&item).Updates(map[string]interface{}{
+				"property_id": propertyID,
+				"status":      domain.DA2062ItemProcessed,
+			}).Error; err != nil {
+				return err
+			}
+			result.Properties = append(result.Properties, property)
+		}
+		committed = lines
+
+		record.CommittedAt = &now
+		record.CommittedBy = &userID
+		return tx.Model(record).Updates(map[string]interface{}{
+			"committed_at":         now,
+			"committed_by_user_id": userID,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Corrections = len(corrections)
+
+	s.updateProgress(importID)
+	s.logCommit(ctx, result, committed, corrections, userID)
+	return result, nil
+}
+
+// RowImage returns a crop of the scanned page around a line, so the reviewer can
+// compare what was read with the form. The whole page is returned when the line's
+// position is not known.
+func (s *DA2062ReviewService) RowImage(ctx context.Context, importID, itemID int64, userID uint) ([]byte, string, error) {
+	item, page, err := s.linePage(ctx, importID, itemID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	return cropRow(page.Image, page.ContentType, item.RowRegion)
+}
+
+// FieldImage returns a crop of the scanned page around the text one field of a
+// line was read from, such as its serial number. field is the field's name in
+// ai.ParsedItem's JSON. Besides the importer, the holder of property created from
+// the line can see it, so the source of a record can be checked after commit.
+func (s *DA2062ReviewService) FieldImage(ctx context.Context, importID, itemID int64, field string, userID uint) ([]byte, string, error) {
+	item, page, err := s.linePage(ctx, importID, itemID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	var boxes map[string]ai.FieldBox
+	if len(item.FieldBoxes) > 0 {
+		if err := json.Unmarshal(item.FieldBoxes, &boxes); err != nil {
+			return nil, "", fmt.Errorf("failed to read field boxes of line %d: %w", item.LineNumber, err)
+		}
+	}
+	box, ok := boxes[field]
+	if !ok {
+		return nil, "", ErrNoFieldBox
+	}
+	return cropField(page.Image, box)
+}
+
+// linePage loads a line and the scanned page it was read from. The page image
+// stored when the import was parsed is used; pages parsed before page images were
+// kept have their upload split again.
+func (s *DA2062ReviewService) linePage(ctx context.Context, importID, itemID int64, userID uint) (*domain.DA2062ImportItem, *ai.PDFPage, error) {
+	var record domain.DA2062Import
+	if err := s.db.WithContext(ctx).First(&record, importID).Error; err != nil {
+		return nil, nil, err
+	}
+	var item domain.DA2062ImportItem
+	if err := s.db.WithContext(ctx).Where("id = ? AND import_id = ?", itemID, importID).First(&item).Error; err != nil {
+		return nil, nil, err
+	}
+	if uint(record.ImportedByUser) != userID {
+		var held int64
+		if item.PropertyID != nil {
+			if err := s.db.WithContext(ctx).Model(&domain.Property{}).
+				Where("id = ? AND assigned_to_user_id = ?", *item.PropertyID, userID).
+				Count(&held).Error; err != nil {
+				return nil, nil, err
+			}
+		}
+		if held == 0 {
+			return nil, nil, ErrNotImporter
+		}
+	}
+	if record.StorageKey == nil {
+		return nil, nil, ErrNoRowImage
+	}
+	if s.storage == nil {
+		return nil, nil, ErrStorageUnavailable
+	}
+
+	pageNumber := 1
+	if item.PageNumber != nil {
+		pageNumber = *item.PageNumber
+	}
+	var parsed domain.DA2062ImportPage
+	err := s.db.WithContext(ctx).Where("import_id = ? AND page_number = ?", importID, pageNumber).First(&parsed).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, err
+	}
+	if err == nil && parsed.ImageKey != nil {
+		if *parsed.ImageKey == "" {
+			// Exported forms have a text layer and no scan
+			return nil, nil, ErrNoRowImage
+		}
+		image, err := downloadUpload(ctx, s.storage, *parsed.ImageKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		page := &ai.PDFPage{Number: pageNumber, Image: image}
+		if parsed.ImageType != nil {
+			page.ContentType = *parsed.ImageType
+		}
+		return &item, page, nil
+	}
+
+	data, err := downloadUpload(ctx, s.storage, *record.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	contentType := ""
+	if record.ContentType != nil {
+		contentType = *record.ContentType
+	}
+	pages, err := ai.SplitDA2062(data, contentType)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrImportUnreadable, err)
+	}
+	for i := range pages {
+		if pages[i].Number != pageNumber {
+			continue
+		}
+		if len(pages[i].Image) == 0 {
+			// Exported forms have a text layer and no scan
+			return nil, nil, ErrNoRowImage
+		}
+		return &item, &pages[i], nil
+	}
+	return nil, nil, ErrNoRowImage
+}
+
+// logCommit records the commit in the ledger. The property already exists, so a
+// ledger failure is logged rather than returned.
+func (s *DA2062ReviewService) logCommit(ctx context.Context, result *CommitResult, lines []models.DA2062ImportItem, corrections []domain.DA2062ItemCorrection, userID uint) {
+	if s.ledger == nil {
+		return
+	}
+
+	var meta ai.ParseMeta
+	if len(result.Import.FormInfo) > 0 {
+		_ = json.Unmarshal(result.Import.FormInfo, &meta)
+	}
+
+	propertyIDs := make([]uint, len(result.Properties))
+	for i, property := range result.Properties {
+		propertyIDs[i] = property.ID
+	}
+	sources := make(map[string]bool)
+	confidence := 0.0
+	for _, line := range lines {
+		if line.ImportMetadata != nil {
+			sources[line.ImportMetadata.Source] = true
+			confidence += line.ImportMetadata.ItemConfidence
+		}
+	}
+	methods := make([]string, 0, len(sources))
+	for source := range sources {
+		if source != "" {
+			methods = append(methods, source)
+		}
+	}
+	sort.Strings(methods)
+
+	event := ledger.DA2062Event{
+		FormNumber:    meta.FormNumber,
+		UserID:        strconv.FormatUint(uint64(userID), 10),
+		UnitName:      meta.From,
+		ItemCount:     len(result.Properties),
+		ImportMethod:  strings.Join(methods, ","),
+		Confidence:    confidence / float64(len(lines)),
+		Corrections:   len(corrections),
+		ProcessingMs:  result.Import.CommittedAt.Sub(result.Import.CreatedAt).Milliseconds(),
+		ImportID:      result.Import.ID,
+		PropertyIDs:   propertyIDs,
+		CorrectionLog: corrections,
+	}
+	if err := s.ledger.LogDA2062Import(ctx, event); err != nil {
+		log.Printf("WARNING: Failed to log commit of DA 2062 import %d to ledger: %v", result.Import.ID, err)
+	}
+}
+
+func (s *DA2062ReviewService) updateProgress(importID int64) {
+	if err := countImportItems(s.db, importID); err != nil {
+		log.Printf("WARNING: Failed to update item counts of import %d: %v", importID, err)
+	}
+	PublishImportProgress(s.db, s.hub, importID)
+}
+
+// lockReviewableImport locks an import for a review change, checking that it is
+// the user's, has finished parsing and has not been committed
+func lockReviewableImport(tx *gorm.DB, importID int64, userID uint) (*domain.DA2062Import, error) {
+	var record domain.DA2062Import
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&record, importID).Error; err != nil {
+		return nil, err
+	}
+	if uint(record.ImportedByUser) != userID {
+		return nil, ErrNotImporter
+	}
+	if record.CommittedAt != nil {
+		return nil, ErrImportCommitted
+	}
+	if record.Status != domain.DA2062ImportCompleted {
+		return nil, ErrImportNotReady
+	}
+	return &record, nil
+}
+
+func decodeLine(item domain.DA2062ImportItem) (models.DA2062ImportItem, error) {
+	var data models.DA2062ImportItem
+	if err := json.Unmarshal(item.RawData, &data); err != nil {
+		return data, fmt.Errorf("failed to read line %d: %w", item.LineNumber, err)
+	}
+	return data, nil
+}
+
+// lineUpdates returns the column updates saving a reviewed line's data, with its
+// new corrections appended to those already made
+func lineUpdates(item domain.DA2062ImportItem, data models.DA2062ImportItem, corrections []domain.DA2062ItemCorrection, userID uint, now time.Time) (map[string]interface{}, error) {
+	rawData, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	updates := map[string]interface{}{
+		"raw_data":            rawData,
+		"reviewed_by_user_id": userID,
+		"reviewed_at":         now,
+	}
+	if len(corrections) > 0 {
+		var all []domain.DA2062ItemCorrection
+		if len(item.Corrections) > 0 {
+			if err := json.Unmarshal(item.Corrections, &all); err != nil {
+				return nil, fmt.Errorf("failed to read corrections of line %d: %w", item.LineNumber, err)
+			}
+		}
+		all = append(all, corrections...)
+		encoded, err := json.Marshal(all)
+		if err != nil {
+			return nil, err
+		}
+		updates["corrections"] = encoded
+	}
+	return updates, nil
+}
+
+// applyEdits sets the fields a reviewer changed and returns a correction for each
+func applyEdits(data *models.DA2062ImportItem, input ReviewItemInput, line int, userID uint, now time.Time) []domain.DA2062ItemCorrection {
+	var corrections []domain.DA2062ItemCorrection
+	edit := func(field string, value *string, current *string) {
+		if value == nil {
+			return
+		}
+		next := strings.TrimSpace(*value)
+		if next == *current {
+			return
+		}
+		corrections = append(corrections, domain.DA2062ItemCorrection{
+			Line: line, Field: field, From: *current, To: next, UserID: userID, At: now,
+		})
+		*current = next
+	}
+
+	edit("name", input.Name, &data.Name)
+	edit("description", input.Description, &data.Description)
+	edit("serial_number", input.SerialNumber, &data.SerialNumber)
+	if input.NSN != nil {
+		nsn := strings.TrimSpace(*input.NSN)
+		if normalized := ai.NormalizeNSN(nsn); normalized != "" {
+			nsn = normalized
+		}
+		edit("nsn", &nsn, &data.NSN)
+	}
+	edit("unit", input.Unit, &data.Unit)
+	edit("category", input.Category, &data.Category)
+	if input.Quantity != nil && *input.Quantity != data.Quantity {
+		corrections = append(corrections, domain.DA2062ItemCorrection{
+			Line: line, Field: "quantity", From: strconv.Itoa(data.Quantity), To: strconv.Itoa(*input.Quantity), UserID: userID, At: now,
+		})
+		data.Quantity = *input.Quantity
+	}
+
+	if input.SerialNumber != nil && data.ImportMetadata != nil {
+		for _, c := range corrections {
+			if c.Field == "serial_number" {
+				data.ImportMetadata.SerialSource = "manual"
+				break
+			}
+		}
+	}
+	return corrections
+}
+
+// reviewProblem explains why a line cannot be accepted as property, or returns ""
+// if it can. The rules match those for batch created property.
+func reviewProblem(data models.DA2062ImportItem) string {
+	if problem := unusableLineReason(data); problem != "" {
+		return problem
+	}
+	if normalizeSerial(data.SerialNumber) == "" {
+		return "Serial number is required"
+	}
+	if data.NSN != "" && ai.NormalizeNSN(data.NSN) == "" {
+		return "Invalid NSN format (should be XXXX-XX-XXX-XXXX)"
+	}
+	return ""
+}
+
+// unusableLineReason is unusableItemReason for a line already staged
+func unusableLineReason(data models.DA2062ImportItem) string {
+	if strings.TrimSpace(data.Name) == "" && strings.TrimSpace(data.Description) == "" {
+		return "Item name or description is missing"
+	}
+	if data.Quantity <= 0 {
+		return fmt.Sprintf("Invalid quantity: %d", data.Quantity)
+	}
+	return ""
+}
+
+// propertyFromLine builds the property for an accepted line. The reviewer has
+// checked the line against the form, so it is created verified.
+func propertyFromLine(data models.DA2062ImportItem, userID uint, now time.Time) domain.Property {
+	name := data.Name
+	if strings.TrimSpace(name) == "" {
+		name = data.Description
+	}
+	sourceType := "da2062_scan"
+	property := domain.Property{
+		Name:             name,
+		SerialNumber:     data.SerialNumber,
+		Quantity:         data.Quantity,
+		CurrentStatus:    "Active",
+		SourceType:       &sourceType,
+		AssignedToUserID: &userID,
+		Verified:         true,
+		VerifiedAt:       &now,
+		VerifiedBy:       &userID,
+	}
+	if data.Description != "" {
+		description := data.Description
+		property.Description = &description
+	}
+	if data.NSN != "" {
+		nsn := data.NSN
+		property.NSN = &nsn
+	}
+	if data.SourceRef != "" {
+		sourceRef := data.SourceRef
+		property.SourceRef = &sourceRef
+	}
+	if data.Unit != "" {
+		property.UnitOfIssue = data.Unit
+	}
+	if data.Category != "" {
+		category := data.Category
+		property.Category = &category
+	}
+	if data.ImportMetadata != nil {
+		if url := data.ImportMetadata.SourceDocumentURL; url != "" {
+			property.SourceDocumentURL = &url
+		}
+		if metadata, err := json.Marshal(data.ImportMetadata); err == nil {
+			encoded := string(metadata)
+			property.ImportMetadata = &encoded
+		}
+	}
+	return property
+}
+
+// sameStockNumber compares stock numbers however they were written
+func sameStockNumber(a, b string) bool {
+	if na, nb := ai.NormalizeNSN(a), ai.NormalizeNSN(b); na != "" || nb != "" {
+		return na == nb
+	}
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
+
+// cropRow cuts a line's band out of a page scan, with half a row's margin above and
+// below so the rows either side can be seen
+func cropRow(scan []byte, contentType string, region json.RawMessage) ([]byte, string, error) {
+	if len(region) == 0 {
+		return scan, contentType, nil
+	}
+	var row ai.RowRegion
+	if err := json.Unmarshal(region, &row); err != nil || row.Bottom <= row.Top {
+		return scan, contentType, nil
+	}
+
+	decoded, err := imaging.Decode(bytes.NewReader(scan), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read page scan: %w", err)
+	}
+	bounds := decoded.Bounds()
+	height := float64(bounds.Dy())
+	margin := math.Max((row.Bottom-row.Top)/2, 0.01)
+	top := bounds.Min.Y + int(math.Max(0, row.Top-margin)*height)
+	bottom := bounds.Min.Y + int(math.Ceil(math.Min(1, row.Bottom+margin)*height))
+
+	cropped := imaging.Crop(decoded, image.Rect(bounds.Min.X, top, bounds.Max.X, bottom))
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, cropped, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, "", fmt.Errorf("failed to encode line image: %w", err)
+	}
+	return buf.Bytes(), "image/jpeg", nil
+}
+
+// cropField cuts the text a field was read from out of a page scan, with a margin
+// of half the text's height all round
+func cropField(scan []byte, box ai.FieldBox) ([]byte, string, error) {
+	decoded, err := imaging.Decode(bytes.NewReader(scan), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read page scan: %w", err)
+	}
+	bounds := decoded.Bounds()
+	width, height := float64(bounds.Dx()), float64(bounds.Dy())
+	margin := math.Max((box.Bottom-box.Top)/2, 0.005)
+	left := bounds.Min.X + int(math.Max(0, box.Left-margin*height/width)*width)
+	right := bounds.Min.X + int(math.Ceil(math.Min(1, box.Right+margin*height/width)*width))
+	top := bounds.Min.Y + int(math.Max(0, box.Top-margin)*height)
+	bottom := bounds.Min.Y + int(math.Ceil(math.Min(1, box.Bottom+margin)*height))
+
+	cropped := imaging.Crop(decoded, image.Rect(left, top, right, bottom))
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, cropped, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, "", fmt.Errorf("failed to encode field image: %w", err)
+	}
+	return buf.Bytes(), "image/jpeg", nil
+}
@@ -0,0 +1,115 @@
+package inventory
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/toole-brendan/handreceipt-go/internal/models"
+	"github.com/toole-brendan/handreceipt-go/internal/services/ai"
+)
+
+func TestApplyEdits(t *testing.T) {
+	data := models.DA2062ImportItem{
+		Name:           "RIFLE 5.56MM M4",
+		SerialNumber:   "W12345G",
+		NSN:            "1005012310973",
+		Quantity:       1,
+		ImportMetadata: &models.ImportMetadata{SerialSource: "ai_extracted"},
+	}
+	serial := " W123456 "
+	name := "RIFLE 5.56MM M4"
+	nsn := "1005-01-231-0973"
+	quantity := 1
+	now := time.Now()
+
+	corrections := applyEdits(&data, ReviewItemInput{SerialNumber: &serial, Name: &name, NSN: &nsn, Quantity: &quantity}, 7, 3, now)
+
+	// Only the serial number and the stock number's formatting changed
+	if len(corrections) != 2 {
+		t.Fatalf("expected 2 corrections, got %+v", corrections)
+	}
+	if c := corrections[0]; c.Field != "serial_number" || c.From != "W12345G" || c.To != "W123456" || c.Line != 7 || c.UserID != 3 {
+		t.Errorf("serial correction: %+v", c)
+	}
+	if c := corrections[1]; c.Field != "nsn" || c.To != "1005-01-231-0973" {
+		t.Errorf("NSN correction: %+v", c)
+	}
+	if data.SerialNumber != "W123456" || data.ImportMetadata.SerialSource != "manual" {
+		t.Errorf("edited line: %+v, serial source %q", data, data.ImportMetadata.SerialSource)
+	}
+}
+
+func TestReviewProblem(t *testing.T) {
+	line := models.DA2062ImportItem{Name: "HELMET", SerialNumber: "H1", Quantity: 1, NSN: "8470-01-520-7373"}
+	if problem := reviewProblem(line); problem != "" {
+		t.Errorf("valid line: %s", problem)
+	}
+	line.SerialNumber = ""
+	if reviewProblem(line) == "" {
+		t.Error("line without a serial number was accepted")
+	}
+	line.SerialNumber = "N/A"
+	if reviewProblem(line) == "" {
+		t.Error("line with N/A for a serial number was accepted")
+	}
+	line.SerialNumber = "H1"
+	line.NSN = "8470-01"
+	if reviewProblem(line) == "" {
+		t.Error("line with a malformed NSN was accepted")
+	}
+}
+
+func TestCropRow(t *testing.T) {
+	var scan bytes.Buffer
+	if err := png.Encode(&scan, image.NewGray(image.Rect(0, 0, 200, 1000))); err != nil {
+		t.Fatal(err)
+	}
+
+	// A row from 40% to 50% of the page, with half a row either side
+	cropped, contentType, err := cropRow(scan.Bytes(), "image/png", []byte(`{"top":0.4,"bottom":0.5}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "image/jpeg" {
+		t.Errorf("content type: %s", contentType)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(cropped))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Width != 200 || config.Height != 200 {
+		t.Errorf("crop size: %dx%d", config.Width, config.Height)
+	}
+
+	// Without a region the page is returned as it is
+	whole, contentType, err := cropRow(scan.Bytes(), "image/png", nil)
+	if err != nil || contentType != "image/png" || !bytes.Equal(whole, scan.Bytes()) {
+		t.Errorf("whole page: %s, %v", contentType, err)
+	}
+}
+
+func TestCropField(t *testing.T) {
+	var scan bytes.Buffer
+	if err := png.Encode(&scan, image.NewGray(image.Rect(0, 0, 1000, 1000))); err != nil {
+		t.Fatal(err)
+	}
+
+	// A serial number 100 by 20 pixels, with 10 pixels' margin all round
+	cropped, contentType, err := cropField(scan.Bytes(), ai.FieldBox{Left: 0.5, Top: 0.4, Right: 0.6, Bottom: 0.42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "image/jpeg" {
+		t.Errorf("content type: %s", contentType)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(cropped))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Width != 120 || config.Height != 40 {
+		t.Errorf("crop size: %dx%d", config.Width, config.Height)
+	}
+}
@@ -0,0 +1,641 @@
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"github.com/toole-brendan/handreceipt-go/internal/ledger"
+	"github.com/toole-brendan/handreceipt-go/internal/repository"
+	"github.com/toole-brendan/handreceipt-go/internal/services/documents"
+	"github.com/toole-brendan/handreceipt-go/internal/services/storage"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrChangeNotOpen     = errors.New("hand receipt change is not open")
+	ErrNotChangeParty    = errors.New("user is not a party to this hand receipt change")
+	ErrCannotStartChange = errors.New("only the outgoing holder or someone in their chain of command can start a hand receipt change")
+	ErrOpenChangeExists  = errors.New("outgoing holder already has an open hand receipt change")
+	ErrItemsUnresolved   = errors.New("every item must be marked the same by both holders before completion")
+	ErrInvalidMark       = errors.New("mark must be found, missing or discrepant")
+	ErrSameHolder        = errors.New("outgoing and incoming holders must be different users")
+	ErrItemsGone         = errors.New("items on the hand receipt no longer exist")
+)
+
+// StartChangeInput represents input for starting a change of hand receipt holder
+type StartChangeInput struct {
+	OutgoingUserID uint       `json:"outgoingUserId" binding:"required"`
+	IncomingUserID uint       `json:"incomingUserId" binding:"required"`
+	DueDate        *time.Time `json:"dueDate"`
+	Notes          *string    `json:"notes"`
+}
+
+// ChangeSummary gives the running totals of a joint inventory
+type ChangeSummary struct {
+	Total         int     `json:"total"`
+	Pending       int     `json:"pending"`
+	Disputed      int     `json:"disputed"`
+	Found         int     `json:"found"`
+	Missing       int     `json:"missing"`
+	Discrepant    int     `json:"discrepant"`
+	ShortageValue float64 `json:"shortageValue"`
+}
+
+// HandReceiptChangeService runs the change of primary hand receipt holder workflow
+type HandReceiptChangeService struct {
+	db        *gorm.DB
+	repo      repository.Repository
+	ledger    ledger.LedgerService
+	generator *documents.DA2062Generator
+	storage   storage.StorageService
+}
+
+// NewHandReceiptChangeService creates a new hand receipt change service
+func NewHandReceiptChangeService(
+	db *gorm.DB,
+	repo repository.Repository,
+	ledgerService ledger.LedgerService,
+	generator *documents.DA2062Generator,
+	storageService storage.StorageService,
+) *HandReceiptChangeService {
+	return &HandReceiptChangeService{
+		db:        db,
+		repo:      repo,
+		ledger:    ledgerService,
+		generator: generator,
+		storage:   storageService,
+	}
+}
+
+// Start opens a change of hand receipt holder and snapshots every item, components
+// included, currently signed to the outgoing holder. Only the outgoing holder or
+// someone in their chain of command can start one.
+func (s *HandReceiptChangeService) Start(ctx context.Context, input StartChangeInput, initiatorID uint) (*domain.HandReceiptChange, error) {
+	if input.OutgoingUserID == input.IncomingUserID {
+		return nil, ErrSameHolder
+	}
+	if initiatorID != input.OutgoingUserID {
+		leader, err := repository.InChainOfCommand(s.db.WithContext(ctx), initiatorID, input.OutgoingUserID)
+		if err != nil {
+			return nil, err
+		}
+		if !leader {
+			return nil, ErrCannotStartChange
+		}
+	}
+	if _, err := s.repo.GetUserByID(input.IncomingUserID); err != nil {
+		return nil, fmt.Errorf("incoming holder not found: %w", err)
+	}
+
+	var openCount int64
+	if err := s.db.WithContext(ctx).Model(&domain.HandReceiptChange{}).
+		Where("outgoing_user_id = ? AND status = ?", input.OutgoingUserID, domain.HandReceiptChangeStatusOpen).
+		Count(&openCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to check open changes: %w", err)
+	}
+	if openCount > 0 {
+		return nil, ErrOpenChangeExists
+	}
+
+	properties, err := s.repo.ListProperties(&input.OutgoingUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list outgoing holder's property: %w", err)
+	}
+
+	// Record which items are components so the inventory can be walked as a tree
+	propertyIDs := make([]uint, 0, len(properties))
+	for _, property := range properties {
+		propertyIDs = append(propertyIDs, property.ID)
+	}
+	parents := make(map[uint]uint)
+	if len(propertyIDs) > 0 {
+		var attachments []domain.PropertyComponent
+		if err := s.db.WithContext(ctx).Where("component_property_id IN ?", propertyIDs).Find(&attachments).Error; err != nil {
+			return nil, fmt.Errorf("failed to load component attachments: %w", err)
+		}
+		for _, attachment := range attachments {
+			parents[attachment.ComponentPropertyID] = attachment.ParentPropertyID
+		}
+	}
+
+	change := &domain.HandReceiptChange{
+		OutgoingUserID:    input.OutgoingUserID,
+		IncomingUserID:    input.IncomingUserID,
+		InitiatedByUserID: initiatorID,
+		Status:            domain.HandReceiptChangeStatusOpen,
+		DueDate:           input.DueDate,
+		Notes:             input.Notes,
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(change).Error; err != nil {
+			return err
+		}
+
+		items := make([]domain.HandReceiptChangeItem, 0, len(properties))
+		for _, property := range properties {
+			item := domain.HandReceiptChangeItem{
+				ChangeID:     change.ID,
+				PropertyID:   property.ID,
+				Name:         property.Name,
+				SerialNumber: property.SerialNumber,
+				NSN:          property.NSN,
+				Quantity:     property.Quantity,
+				UnitPrice:    property.UnitPrice,
+				Status:       domain.InventoryItemPending,
+			}
+			if parentID, ok := parents[property.ID]; ok {
+				item.ParentPropertyID = &parentID
+			}
+			items = append(items, item)
+		}
+		if len(items) > 0 {
+			if err := tx.CreateInBatches(items, 100).Error; err != nil {
+				return err
+			}
+		}
+		change.Items = items
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start hand receipt change: %w", err)
+	}
+
+	s.logEvent(ctx, "HandReceiptChangeStarted", initiatorID, change, map[string]interface{}{
+		"item_count": len(change.Items),
+	})
+
+	return change, nil
+}
+
+// Get retrieves a hand receipt change with its items
+func (s *HandReceiptChangeService) Get(ctx context.Context, changeID uint) (*domain.HandReceiptChange, error) {
+	var change domain.HandReceiptChange
+	err := s.db.WithContext(ctx).
+		Preload("OutgoingUser").
+		Preload("IncomingUser").
+		Preload("Items", func(db *gorm.DB) *gorm.DB {
+			return db.Order("COALESCE(parent_property_id, property_id), parent_property_id NULLS FIRST, id")
+		}).
+		First(&change, changeID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+// ListForUser retrieves the changes a user is party to, optionally filtered by status
+func (s *HandReceiptChangeService) ListForUser(ctx context.Context, userID uint, status string) ([]domain.HandReceiptChange, error) {
+	var changes []domain.HandReceiptChange
+	query := s.db.WithContext(ctx).
+		Preload("OutgoingUser").
+		Preload("IncomingUser").
+		Where("outgoing_user_id = ? OR incoming_user_id = ?", userID, userID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("created_at DESC").Find(&changes).Error
+	return changes, err
+}
+
+// MarkItem records one holder's count of an item. The item settles on a status once
+// both holders have recorded the same mark.
+func (s *HandReceiptChangeService) MarkItem(ctx context.Context, changeID, itemID, userID uint, mark string, remarks *string) (*domain.HandReceiptChangeItem, error) {
+	if mark != domain.InventoryMarkFound && mark != domain.InventoryMarkMissing && mark != domain.InventoryMarkDiscrepant {
+		return nil, ErrInvalidMark
+	}
+
+	var change domain.HandReceiptChange
+	if err := s.db.WithContext(ctx).First(&change, changeID).Error; err != nil {
+		return nil, err
+	}
+	if change.Status != domain.HandReceiptChangeStatusOpen {
+		return nil, ErrChangeNotOpen
+	}
+
+	var item domain.HandReceiptChangeItem
+	if err := s.db.WithContext(ctx).Where("id = ? AND change_id = ?", itemID, changeID).First(&item).Error; err != nil {
+		return nil, err
+	}
+
+	switch userID {
+	case change.OutgoingUserID:
+		item.OutgoingMark = &mark
+	case change.IncomingUserID:
+		item.IncomingMark = &mark
+	default:
+		return nil, ErrNotChangeParty
+	}
+
+	if remarks != nil && *remarks != "" {
+		item.Remarks = remarks
+	}
+	now := time.Now().UTC()
+	item.LastMarkedAt = &now
+	item.LastMarkedBy = &userID
+	item.Status = resolveItemStatus(item.OutgoingMark, item.IncomingMark)
+
+	if err := s.db.WithContext(ctx).Omit(clause.Associations).Save(&item).Error; err != nil {
+		return nil, fmt.Errorf("failed to save item mark: %w", err)
+	}
+
+	return &item, nil
+}
+
+// Summarize counts the items of a change by status
+func Summarize(change *domain.HandReceiptChange) ChangeSummary {
+	summary := ChangeSummary{Total: len(change.Items)}
+	for _, item := range change.Items {
+		switch item.Status {
+		case domain.InventoryItemPending:
+			summary.Pending++
+		case domain.InventoryItemDisputed:
+			summary.Disputed++
+		case domain.InventoryMarkFound:
+			summary.Found++
+		case domain.InventoryMarkMissing:
+			summary.Missing++
+			summary.ShortageValue += float64(item.Quantity) * item.UnitPrice
+		case domain.InventoryMarkDiscrepant:
+			summary.Discrepant++
+		}
+	}
+	return summary
+}
+
+// ShortageAnnex renders the shortage annex for the items both holders marked missing
+func (s *HandReceiptChangeService) ShortageAnnex(ctx context.Context, change *domain.HandReceiptChange) (*bytes.Buffer, error) {
+	outgoing, incoming, err := s.loadHolders(change)
+	if err != nil {
+		return nil, err
+	}
+
+	var shortages []documents.ShortageItem
+	for _, item := range change.Items {
+		if item.Status != domain.InventoryMarkMissing {
+			continue
+		}
+		shortage := documents.ShortageItem{
+			Description:  item.Name,
+			SerialNumber: item.SerialNumber,
+			Quantity:     item.Quantity,
+			UnitPrice:    item.UnitPrice,
+		}
+		if item.NSN != nil {
+			shortage.NSN = *item.NSN
+		}
+		if item.Remarks != nil {
+			shortage.Remarks = *item.Remarks
+		}
+		shortages = append(shortages, shortage)
+	}
+
+	return s.generator.GenerateShortageAnnex(
+		shortages,
+		documents.UserInfoFromUser(outgoing),
+		documents.UserInfoFromUser(incoming),
+		documents.UnitInfo{UnitName: outgoing.Unit},
+		changeReference(change),
+	)
+}
+
+// missingProperties returns the ids that were not among the properties found
+func missingProperties(ids []uint, found []domain.Property) []uint {
+	present := make(map[uint]bool, len(found))
+	for _, property := range found {
+		present[property.ID] = true
+	}
+	var missing []uint
+	for _, id := range ids {
+		if !present[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
+
+// Complete closes the inventory: every item found (including those found with a
+// discrepancy) moves to the incoming holder in one transaction, missing items stay
+// on the outgoing holder's books, and the signed DA 2062 and shortage annex are
+// generated and delivered to both holders. Completion fails if an item found has
+// since been deleted or reassigned.
+func (s *HandReceiptChangeService) Complete(ctx context.Context, changeID, userID uint) (*domain.HandReceiptChange, error) {
+	change, err := s.Get(ctx, changeID)
+	if err != nil {
+		return nil, err
+	}
+	if change.Status != domain.HandReceiptChangeStatusOpen {
+		return nil, ErrChangeNotOpen
+	}
+	if userID != change.OutgoingUserID && userID != change.IncomingUserID {
+		return nil, ErrNotChangeParty
+	}
+
+	summary := Summarize(change)
+	if summary.Pending > 0 || summary.Disputed > 0 {
+		return nil, fmt.Errorf("%w: %d pending, %d disputed", ErrItemsUnresolved, summary.Pending, summary.Disputed)
+	}
+
+	moving := make(map[uint]bool)
+	var movingIDs []uint
+	for _, item := range change.Items {
+		if item.Status == domain.InventoryMarkFound || item.Status == domain.InventoryMarkDiscrepant {
+			moving[item.PropertyID] = true
+			movingIDs = append(movingIDs, item.PropertyID)
+		}
+	}
+
+	var transfers []domain.Transfer
+	var detached []domain.PropertyComponent
+	now := time.Now().UTC()
+	notes := fmt.Sprintf("Change of hand receipt holder %s", changeReference(change))
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Another party may have completed the change since it was read
+		var current domain.HandReceiptChange
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "status").First(&current, change.ID).Error; err != nil {
+			return err
+		}
+		if current.Status != domain.HandReceiptChangeStatusOpen {
+			return ErrChangeNotOpen
+		}
+
+		if len(movingIDs) > 0 {
+			var properties []domain.Property
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Select("id", "assigned_to_user_id").
+				Where("id IN ?", movingIDs).
+				Find(&properties).Error; err != nil {
+				return err
+			}
+			if len(properties) != len(movingIDs) {
+				return fmt.Errorf("%w: property %v", ErrItemsGone, missingProperties(movingIDs, properties))
+			}
+			for _, property := range properties {
+				if property.AssignedToUserID == nil || *property.AssignedToUserID != change.OutgoingUserID {
+					return fmt.Errorf("%w: property %d", repository.ErrPropertyHeldByOtherUser, property.ID)
+				}
+			}
+
+			if err := tx.Model(&domain.Property{}).
+				Where("id IN ?", movingIDs).
+				Updates(map[string]interface{}{
+					"assigned_to_user_id": change.IncomingUserID,
+					"updated_at":          now,
+				}).Error; err != nil {
+				return err
+			}
+
+			// Break attachments that would leave a tree split between the two holders
+			var attachments []domain.PropertyComponent
+			if err := tx.Where("parent_property_id IN ? OR component_property_id IN ?", movingIDs, movingIDs).
+				Find(&attachments).Error; err != nil {
+				return err
+			}
+			for _, attachment := range attachments {
+				if moving[attachment.ParentPropertyID] != moving[attachment.ComponentPropertyID] {
+					if err := tx.Delete(&domain.PropertyComponent{}, attachment.ID).Error; err != nil {
+						return err
+					}
+					detached = append(detached, attachment)
+				}
+			}
+
+			for _, propertyID := range movingIDs {
+				transfers = append(transfers, domain.Transfer{
+					PropertyID:   propertyID,
+					FromUserID:   change.OutgoingUserID,
+					ToUserID:     change.IncomingUserID,
+					Status:       "accepted",
+					TransferType: domain.TransferTypeOffer,
+					InitiatorID:  &userID,
+					RequestDate:  now,
+					ResolvedDate: &now,
+					Notes:        &notes,
+				})
+			}
+			if err := tx.CreateInBatches(transfers, 100).Error; err != nil {
+				return err
+			}
+		}
+
+		change.Status = domain.HandReceiptChangeStatusCompleted
+		change.CompletedAt = &now
+		return tx.Omit(clause.Associations).Save(change).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete hand receipt change: %w", err)
+	}
+
+	for _, transfer := range transfers {
+		serialNumber := ""
+		for _, item := range change.Items {
+			if item.PropertyID == transfer.PropertyID {
+				serialNumber = item.SerialNumber
+				break
+			}
+		}
+		if err := s.ledger.LogTransferEvent(transfer, serialNumber); err != nil {
+			log.Printf("WARNING: Failed to log hand receipt change transfer for property %d to ledger: %v", transfer.PropertyID, err)
+		}
+	}
+	for _, attachment := range detached {
+		if err := s.ledger.LogComponentDetached(attachment.ParentPropertyID, attachment.ComponentPropertyID, userID); err != nil {
+			log.Printf("WARNING: Failed to log component detachment (parent %d, component %d) to ledger: %v", attachment.ParentPropertyID, attachment.ComponentPropertyID, err)
+		}
+	}
+
+	// The transfer has already happened; document failures are logged, not returned
+	if err := s.deliverDocuments(ctx, change, movingIDs, summary); err != nil {
+		log.Printf("WARNING: Failed to generate documents for hand receipt change %d: %v", change.ID, err)
+	}
+
+	s.logEvent(ctx, "HandReceiptChangeCompleted", userID, change, map[string]interface{}{
+		"transferred": len(movingIDs),
+		"found":       summary.Found,
+		"discrepant":  summary.Discrepant,
+		"missing":     summary.Missing,
+		"shortage":    summary.ShortageValue,
+	})
+
+	return change, nil
+}
+
+// Cancel abandons an open change without moving any property
+func (s *HandReceiptChangeService) Cancel(ctx context.Context, changeID, userID uint) (*domain.HandReceiptChange, error) {
+	change, err := s.Get(ctx, changeID)
+	if err != nil {
+		return nil, err
+	}
+	if change.Status != domain.HandReceiptChangeStatusOpen {
+		return nil, ErrChangeNotOpen
+	}
+	if userID != change.OutgoingUserID && userID != change.IncomingUserID {
+		return nil, ErrNotChangeParty
+	}
+
+	change.Status = domain.HandReceiptChangeStatusCancelled
+	if err := s.db.WithContext(ctx).Omit(clause.Associations).Save(change).Error; err != nil {
+		return nil, fmt.Errorf("failed to cancel hand receipt change: %w", err)
+	}
+
+	s.logEvent(ctx, "HandReceiptChangeCancelled", userID, change, nil)
+	return change, nil
+}
+
+// deliverDocuments generates the signed DA 2062 for the items transferred and, when
+// anything is short, the shortage annex, then files both in each holder's inbox.
+func (s *HandReceiptChangeService) deliverDocuments(ctx context.Context, change *domain.HandReceiptChange, movingIDs []uint, summary ChangeSummary) error {
+	outgoing, incoming, err := s.loadHolders(change)
+	if err != nil {
+		return err
+	}
+	fromInfo := documents.UserInfoFromUser(outgoing)
+	toInfo := documents.UserInfoFromUser(incoming)
+	unitInfo := documents.UnitInfo{UnitName: outgoing.Unit}
+	var attachments []string
+
+	if len(movingIDs) > 0 {
+		var properties []domain.Property
+		if err := s.db.WithContext(ctx).Where("id IN ?", movingIDs).Order("category, name").Find(&properties).Error; err != nil {
+			return fmt.Errorf("failed to load transferred property: %w", err)
+		}
+
+		pdfBuffer, err := s.generator.GenerateDA2062(properties, fromInfo, toInfo, unitInfo, documents.GenerateOptions{
+			GroupByCategory:   true,
+			IncludeSignatures: true,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to generate DA 2062: %w", err)
+		}
+		url, err := s.upload(ctx, fmt.Sprintf("hand_receipt_changes/%d/da2062.pdf", change.ID), pdfBuffer)
+		if err != nil {
+			return err
+		}
+		change.HandReceiptURL = &url
+		attachments = append(attachments, url)
+	}
+
+	if summary.Missing > 0 {
+		annex, err := s.ShortageAnnex(ctx, change)
+		if err != nil {
+			return fmt.Errorf("failed to generate shortage annex: %w", err)
+		}
+		url, err := s.upload(ctx, fmt.Sprintf("hand_receipt_changes/%d/shortage_annex.pdf", change.ID), annex)
+		if err != nil {
+			return err
+		}
+		change.ShortageAnnexURL = &url
+		attachments = append(attachments, url)
+	}
+
+	if err := s.db.WithContext(ctx).Model(change).Updates(map[string]interface{}{
+		"hand_receipt_url":   change.HandReceiptURL,
+		"shortage_annex_url": change.ShortageAnnexURL,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to save document links: %w", err)
+	}
+
+	formData, _ := json.Marshal(map[string]interface{}{
+		"hand_receipt_change_id": change.ID,
+		"summary":                summary,
+	})
+	subtype := "DA2062"
+	for _, recipientID := range []uint{change.IncomingUserID, change.OutgoingUserID} {
+		doc := &domain.Document{
+			Type:            domain.DocumentTypeTransferForm,
+			Subtype:         &subtype,
+			Title:           fmt.Sprintf("Change of Hand Receipt Holder %s - %d items, %d short", changeReference(change), len(movingIDs), summary.Missing),
+			SenderUserID:    change.OutgoingUserID,
+			RecipientUserID: recipientID,
+			Status:          domain.DocumentStatusUnread,
+			SentAt:          time.Now(),
+			FormData:        string(formData),
+			Attachments:     domain.JSONStringArray(attachments),
+		}
+		if err := s.repo.CreateDocument(doc); err != nil {
+			log.Printf("WARNING: Failed to create hand receipt change document for user %d: %v", recipientID, err)
+		}
+	}
+
+	if err := s.ledger.LogDA2062Export(change.IncomingUserID, len(movingIDs), "hand_receipt_change", incoming.Email); err != nil {
+		log.Printf("WARNING: Failed to log DA 2062 export to ledger: %v", err)
+	}
+
+	return nil
+}
+
+func (s *HandReceiptChangeService) upload(ctx context.Context, key string, buf *bytes.Buffer) (string, error) {
+	if err := s.storage.UploadFile(ctx, key, bytes.NewReader(buf.Bytes()), int64(buf.Len()), "application/pdf"); err != nil {
+		return "", fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	url, err := s.storage.GetPresignedURL(ctx, key, 7*24*time.Hour)
+	if err != nil {
+		log.Printf("WARNING: Failed to get presigned URL for %s: %v", key, err)
+		return key, nil
+	}
+	return url, nil
+}
+
+func (s *HandReceiptChangeService) loadHolders(change *domain.HandReceiptChange) (*domain.User, *domain.User, error) {
+	outgoing := change.OutgoingUser
+	if outgoing == nil {
+		user, err := s.repo.GetUserByID(change.OutgoingUserID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get outgoing holder: %w", err)
+		}
+		outgoing = user
+	}
+	incoming := change.IncomingUser
+	if incoming == nil {
+		user, err := s.repo.GetUserByID(change.IncomingUserID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get incoming holder: %w", err)
+		}
+		incoming = user
+	}
+	return outgoing, incoming, nil
+}
+
+func (s *HandReceiptChangeService) logEvent(ctx context.Context, eventType string, userID uint, change *domain.HandReceiptChange, extra map[string]interface{}) {
+	metadata := map[string]interface{}{
+		"hand_receipt_change_id": change.ID,
+		"outgoing_user_id":       change.OutgoingUserID,
+		"incoming_user_id":       change.IncomingUserID,
+		"status":                 change.Status,
+	}
+	for k, v := range extra {
+		metadata[k] = v
+	}
+
+	if err := s.ledger.LogEvent(ctx, ledger.Event{
+		Type:     eventType,
+		UserID:   strconv.FormatUint(uint64(userID), 10),
+		Metadata: metadata,
+	}); err != nil {
+		log.Printf("WARNING: Failed to log %s for hand receipt change %d to ledger: %v", eventType, change.ID, err)
+	}
+}
+
+// resolveItemStatus settles an item once both holders have marked it the same way
+func resolveItemStatus(outgoingMark, incomingMark *string) string {
+	if outgoingMark == nil || incomingMark == nil {
+		return domain.InventoryItemPending
+	}
+	if *outgoingMark != *incomingMark {
+		return domain.InventoryItemDisputed
+	}
+	return *outgoingMark
+}
+
+func changeReference(change *domain.HandReceiptChange) string {
+	return fmt.Sprintf("HRC-%s-%d", change.CreatedAt.Format("20060102"), change.ID)
+}
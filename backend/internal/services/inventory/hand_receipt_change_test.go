@@ -0,0 +1,18 @@
+package inventory
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+)
+
+func TestMissingProperties(t *testing.T) {
+	found := []domain.Property{{ID: 3}, {ID: 1}}
+	if missing := missingProperties([]uint{1, 2, 3, 4}, found); !reflect.DeepEqual(missing, []uint{2, 4}) {
+		t.Errorf("missing: %v", missing)
+	}
+	if missing := missingProperties([]uint{1, 3}, found); len(missing) != 0 {
+		t.Errorf("nothing should be missing: %v", missing)
+	}
+}
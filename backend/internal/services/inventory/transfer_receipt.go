@@ -0,0 +1,254 @@
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"github.com/toole-brendan/handreceipt-go/internal/ledger"
+	"github.com/toole-brendan/handreceipt-go/internal/repository"
+	"github.com/toole-brendan/handreceipt-go/internal/services/documents"
+	"github.com/toole-brendan/handreceipt-go/internal/services/email"
+	"github.com/toole-brendan/handreceipt-go/internal/services/storage"
+)
+
+// Background job types for issuing and mailing DA 2062 hand receipts
+const (
+	JobIssueTransferReceipt = "transfer.issue_da2062"
+	JobEmailDocument        = "document.email_da2062"
+)
+
+var (
+	ErrTransferNotAccepted = errors.New("transfer has not been accepted")
+	ErrStorageUnavailable  = errors.New("document storage is not configured")
+)
+
+// TransferReceiptJob is the payload of a JobIssueTransferReceipt job
+type TransferReceiptJob struct {
+	TransferID uint `json:"transferId"`
+}
+
+// DocumentEmailJob is the payload of a JobEmailDocument job. Exported forms set
+// Recipients, FileKey and FormNumber; stored transfer receipts only need Email.
+type DocumentEmailJob struct {
+	DocumentID uint     `json:"documentId"`
+	UserID     uint     `json:"userId"` // User sending the document
+	Email      string   `json:"email,omitempty"`
+	Recipients []string `json:"recipients,omitempty"`
+	FileKey    string   `json:"fileKey,omitempty"`
+	FormNumber string   `json:"formNumber,omitempty"`
+}
+
+// TransferReceiptService issues the DA 2062 for accepted transfers and mails stored
+// hand receipts. Both run in the worker, off the request path.
+type TransferReceiptService struct {
+	repo      repository.Repository
+	ledger    ledger.LedgerService
+	generator *documents.DA2062Generator
+	storage   storage.StorageService
+	mailer    *email.DA2062EmailService
+}
+
+// NewTransferReceiptService creates a new transfer receipt service. mailer may be
+// nil when email is not configured.
+func NewTransferReceiptService(
+	repo repository.Repository,
+	ledgerService ledger.LedgerService,
+	generator *documents.DA2062Generator,
+	storageService storage.StorageService,
+	mailer *email.DA2062EmailService,
+) *TransferReceiptService {
+	return &TransferReceiptService{
+		repo:      repo,
+		ledger:    ledgerService,
+		generator: generator,
+		storage:   storageService,
+		mailer:    mailer,
+	}
+}
+
+// Issue generates the DA 2062 for an accepted transfer, stores it and puts a copy in
+// the Documents inbox of both the sender and the recipient
+func (s *TransferReceiptService) Issue(ctx context.Context, transferID uint) error {
+	if s.storage == nil {
+		return ErrStorageUnavailable
+	}
+
+	transfer, err := s.repo.GetTransferByID(transferID)
+	if err != nil {
+		return fmt.Errorf("failed to get transfer %d: %w", transferID, err)
+	}
+	if transfer.Status != "accepted" {
+		return ErrTransferNotAccepted
+	}
+	property, err := s.repo.GetPropertyByID(transfer.PropertyID)
+	if err != nil {
+		return fmt.Errorf("failed to get property %d: %w", transfer.PropertyID, err)
+	}
+
+	fromUser, err := s.repo.GetUserByID(transfer.FromUserID)
+	if err != nil {
+		return fmt.Errorf("failed to get FROM user: %w", err)
+	}
+	toUser, err := s.repo.GetUserByID(transfer.ToUserID)
+	if err != nil {
+		return fmt.Errorf("failed to get TO user: %w", err)
+	}
+
+	// The main item, plus the whole component tree when components moved with it
+	properties := []domain.Property{*property}
+	if transfer.IncludeComponents {
+		components, err := s.repo.GetComponentTree(transfer.PropertyID)
+		if err != nil {
+			// Continue without components rather than failing
+			log.Printf("WARNING: Failed to get components for property %d: %v", transfer.PropertyID, err)
+		} else {
+			for _, comp := range components {
+				if comp.ComponentProperty != nil {
+					properties = append(properties, *comp.ComponentProperty)
+				}
+			}
+		}
+	}
+
+	pdfBuffer, err := s.generator.GenerateDA2062(
+		properties,
+		receiptUserInfo(fromUser),
+		receiptUserInfo(toUser),
+		documents.UnitInfo{UnitName: fromUser.Unit},
+		documents.GenerateOptions{IncludeSignatures: true},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to generate DA 2062 PDF: %w", err)
+	}
+
+	formNumber := fmt.Sprintf("HR-%s-%d", time.Now().Format("20060102"), transfer.ID)
+	fileKey := fmt.Sprintf("da2062/transfer_%d.pdf", transfer.ID)
+	if err := s.storage.UploadFile(ctx, fileKey, bytes.NewReader(pdfBuffer.Bytes()), int64(pdfBuffer.Len()), "application/pdf"); err != nil {
+		return fmt.Errorf("failed to upload DA 2062 PDF: %w", err)
+	}
+	fileURL, err := s.storage.GetPresignedURL(ctx, fileKey, 7*24*time.Hour)
+	if err != nil {
+		log.Printf("WARNING: Failed to get presigned URL for DA 2062: %v", err)
+		fileURL = ""
+	}
+
+	if err := s.createReceiptDocument(transfer, property, fileURL, transfer.ToUserID, "received"); err != nil {
+		log.Printf("WARNING: Failed to create in-app document for recipient: %v", err)
+	}
+	if err := s.createReceiptDocument(transfer, property, fileURL, transfer.FromUserID, "sent"); err != nil {
+		log.Printf("WARNING: Failed to create in-app document for sender: %v", err)
+	}
+
+	if err := s.ledger.LogDA2062Export(transfer.ToUserID, len(properties), "email_and_app", toUser.Email); err != nil {
+		log.Printf("WARNING: Failed to log DA 2062 export to ledger: %v", err)
+	}
+
+	log.Printf("Issued DA 2062 %s for transfer %d (%d items)", formNumber, transfer.ID, len(properties))
+	return nil
+}
+
+func (s *TransferReceiptService) createReceiptDocument(transfer *domain.Transfer, property *domain.Property, fileURL string, recipientUserID uint, documentType string) error {
+	title := fmt.Sprintf("Hand Receipt Sent - %s (SN:%s)", property.Name, property.SerialNumber)
+	if documentType == "received" {
+		title = fmt.Sprintf("Hand Receipt Received - %s (SN:%s)", property.Name, property.SerialNumber)
+	}
+
+	subtype := "DA2062"
+	doc := &domain.Document{
+		Type:            domain.DocumentTypeTransferForm,
+		Subtype:         &subtype,
+		Title:           title,
+		SenderUserID:    transfer.FromUserID,
+		RecipientUserID: recipientUserID,
+		PropertyID:      &property.ID,
+		Status:          domain.DocumentStatusUnread,
+		SentAt:          time.Now(),
+		FormData:        "{}",
+		Attachments:     domain.JSONStringArray{},
+	}
+	if fileURL != "" {
+		doc.Attachments = domain.JSONStringArray{fileURL}
+	}
+
+	if err := s.repo.CreateDocument(doc); err != nil {
+		return fmt.Errorf("failed to create document record: %w", err)
+	}
+	log.Printf("Created in-app document %d (%s) for transfer %d, recipient %d", doc.ID, documentType, transfer.ID, recipientUserID)
+	return nil
+}
+
+// EmailDocument mails a stored DA 2062 document as an attachment
+func (s *TransferReceiptService) EmailDocument(ctx context.Context, job DocumentEmailJob) error {
+	if s.storage == nil {
+		return ErrStorageUnavailable
+	}
+	if s.mailer == nil {
+		return errors.New("email is not configured")
+	}
+
+	document, err := s.repo.GetDocumentByID(job.DocumentID)
+	if err != nil {
+		return fmt.Errorf("failed to get document %d: %w", job.DocumentID, err)
+	}
+	user, err := s.repo.GetUserByID(job.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get user %d: %w", job.UserID, err)
+	}
+
+	fileKey := job.FileKey
+	if fileKey == "" {
+		fileKey = fmt.Sprintf("da2062/transfer_%d.pdf", document.ID)
+	}
+	reader, err := s.storage.DownloadFile(ctx, fileKey)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve document: %w", err)
+	}
+	defer reader.Close()
+
+	pdfData := &bytes.Buffer{}
+	if _, err := io.Copy(pdfData, reader); err != nil {
+		return fmt.Errorf("failed to read document: %w", err)
+	}
+
+	senderInfo := email.UserInfo{
+		Name:  user.FirstName + " " + user.LastName,
+		Rank:  user.Rank,
+		Title: user.Unit,
+		Phone: user.Phone,
+	}
+	formNumber := job.FormNumber
+	if formNumber == "" {
+		formNumber = fmt.Sprintf("DOC-%d", document.ID)
+	}
+	recipients := job.Recipients
+	if len(recipients) == 0 {
+		recipients = []string{job.Email}
+	}
+	if err := s.mailer.SendDA2062Email(recipients, pdfData, formNumber, senderInfo); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	if err := s.ledger.LogDocumentEvent(document.ID, "DOCUMENT_EMAILED", job.UserID, 0); err != nil {
+		log.Printf("WARNING: Failed to log document email to ledger: %v", err)
+	}
+	return nil
+}
+
+func receiptUserInfo(user *domain.User) documents.UserInfo {
+	info := documents.UserInfo{
+		Name:  user.FirstName + " " + user.LastName,
+		Rank:  user.Rank,
+		Title: user.Unit, // Use unit as title
+		Phone: user.Phone,
+	}
+	if user.SignatureURL != nil {
+		info.SignatureURL = *user.SignatureURL
+	}
+	return info
+}
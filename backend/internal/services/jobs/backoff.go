@@ -0,0 +1,29 @@
+package jobs
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff is an exponential retry schedule: the wait starts at Base, doubles with
+// each failed attempt and stops growing at Max
+type Backoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// DefaultBackoff is used for job types registered without their own
+var DefaultBackoff = Backoff{Base: 15 * time.Second, Max: time.Hour}
+
+// Delay returns how long to wait after the given number of failed attempts. Half of
+// it is jitter, so work that failed together does not all retry at the same moment.
+func (b Backoff) Delay(attempts int) time.Duration {
+	delay := b.Base
+	for i := 1; i < attempts && delay < b.Max; i++ {
+		delay *= 2
+	}
+	if delay > b.Max {
+		delay = b.Max
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
@@ -0,0 +1,26 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	backoff := Backoff{Base: 30 * time.Second, Max: 6 * time.Hour}
+	tests := []struct {
+		attempts int
+		ceiling  time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{5, 8 * time.Minute},
+		{20, 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 50; i++ {
+			if got := backoff.Delay(tt.attempts); got < tt.ceiling/2 || got > tt.ceiling {
+				t.Fatalf("Delay(%d) = %s, want between %s and %s", tt.attempts, got, tt.ceiling/2, tt.ceiling)
+			}
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidJobType = errors.New("job type is required")
+	ErrNotRetryable   = errors.New("only dead or cancelled jobs can be retried")
+	ErrNotCancellable = errors.New("only queued jobs can be cancelled")
+)
+
+const (
+	DefaultMaxAttempts = 5
+	DefaultTimeout     = 5 * time.Minute
+)
+
+// EnqueueOptions adjusts how a job is run. Zero values take the defaults.
+type EnqueueOptions struct {
+	RunAt       time.Time     // Run no earlier than this; defaults to now
+	MaxAttempts int           // Runs before the job is dead-lettered
+	Timeout     time.Duration // How long one run may take before it is abandoned
+	CreatedBy   uint          // User whose request queued the job, if any
+}
+
+// TypeStats counts a job type's rows by status
+type TypeStats struct {
+	Type      string `json:"type"`
+	Queued    int64  `json:"queued"`
+	Running   int64  `json:"running"`
+	Succeeded int64  `json:"succeeded"`
+	Dead      int64  `json:"dead"`
+	Cancelled int64  `json:"cancelled"`
+}
+
+// RetryHook runs in the same transaction as an operator retry of a job, so state
+// the job's handler checks before doing its work can be reset along with the job.
+// Returning an error rolls the retry back.
+type RetryHook func(tx *gorm.DB, job *domain.Job) error
+
+// Queue adds jobs for the worker and lets operators inspect and manage them
+type Queue struct {
+	db         *gorm.DB
+	mu         sync.RWMutex
+	retryHooks map[string]RetryHook
+}
+
+// NewQueue creates a new job queue
+func NewQueue(db *gorm.DB) *Queue {
+	return &Queue{db: db, retryHooks: make(map[string]RetryHook)}
+}
+
+// OnRetry sets the hook run when an operator retries a job of jobType
+func (q *Queue) OnRetry(jobType string, hook RetryHook) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.retryHooks[jobType] = hook
+}
+
+// Enqueue stores a job for the worker to run. The payload is encoded as JSON and
+// decoded into the handler's payload type when the job runs.
+func (q *Queue) Enqueue(ctx context.Context, jobType string, payload interface{}, opts EnqueueOptions) (*domain.Job, error) {
+	return enqueue(q.db.WithContext(ctx), jobType, payload, opts)
+}
+
+// EnqueueTx stores a job as part of tx, so it is only queued if tx commits
+func (q *Queue) EnqueueTx(tx *gorm.DB, jobType string, payload interface{}, opts EnqueueOptions) (*domain.Job, error) {
+	return enqueue(tx, jobType, payload, opts)
+}
+
+func enqueue(db *gorm.DB, jobType string, payload interface{}, opts EnqueueOptions) (*domain.Job, error) {
+	if jobType == "" {
+		return nil, ErrInvalidJobType
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s payload: %w", jobType, err)
+	}
+
+	job := &domain.Job{
+		Type:           jobType,
+		Payload:        body,
+		Status:         domain.JobQueued,
+		MaxAttempts:    opts.MaxAttempts,
+		TimeoutSeconds: int(opts.Timeout / time.Second),
+		RunAt:          opts.RunAt,
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = DefaultMaxAttempts
+	}
+	if job.TimeoutSeconds <= 0 {
+		job.TimeoutSeconds = int(DefaultTimeout / time.Second)
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now().UTC()
+	}
+	if opts.CreatedBy != 0 {
+		createdBy := opts.CreatedBy
+		job.CreatedByUserID = &createdBy
+	}
+
+	if err := db.Create(job).Error; err != nil {
+		return nil, fmt.Errorf("failed to queue %s job: %w", jobType, err)
+	}
+	return job, nil
+}
+
+// List returns jobs newest first, optionally filtered by status and type
+func (q *Queue) List(status, jobType string, limit, offset int) ([]domain.Job, error) {
+	query := q.db.Order("created_at DESC").Limit(limit).Offset(offset)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if jobType != "" {
+		query = query.Where("type = ?", jobType)
+	}
+
+	var jobs []domain.Job
+	if err := query.Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// Get returns a job by ID
+func (q *Queue) Get(jobID uint) (*domain.Job, error) {
+	var job domain.Job
+	if err := q.db.First(&job, jobID).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Stats counts jobs of each type by status
+func (q *Queue) Stats() ([]TypeStats, error) {
+	var rows []struct {
+		Type   string
+		Status string
+		Count  int64
+	}
+	err := q.db.Model(&domain.Job{}).
+		Select("type, status, COUNT(*) AS count").
+		Group("type, status").
+		Order("type").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	stats := []TypeStats{}
+	index := make(map[string]int)
+	for _, row := range rows {
+		i, ok := index[row.Type]
+		if !ok {
+			i = len(stats)
+			index[row.Type] = i
+			stats = append(stats, TypeStats{Type: row.Type})
+		}
+		switch row.Status {
+		case domain.JobQueued:
+			stats[i].Queued = row.Count
+		case domain.JobRunning:
+			stats[i].Running = row.Count
+		case domain.JobSucceeded:
+			stats[i].Succeeded = row.Count
+		case domain.JobDead:
+			stats[i].Dead = row.Count
+		case domain.JobCancelled:
+			stats[i].Cancelled = row.Count
+		}
+	}
+	return stats, nil
+}
+
+// Retry queues a dead-lettered or cancelled job again with a fresh set of attempts
+func (q *Queue) Retry(jobID uint) (*domain.Job, error) {
+	var job *domain.Job
+	err := q.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		job, err = transition(tx, jobID, []string{domain.JobDead, domain.JobCancelled}, ErrNotRetryable, map[string]interface{}{
+			"status":      domain.JobQueued,
+			"attempts":    0,
+			"run_at":      time.Now().UTC(),
+			"finished_at": nil,
+		})
+		if err != nil {
+			return err
+		}
+
+		q.mu.RLock()
+		hook := q.retryHooks[job.Type]
+		q.mu.RUnlock()
+		if hook != nil {
+			return hook(tx, job)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Cancel stops a queued job from running. A job already running cannot be stopped.
+func (q *Queue) Cancel(jobID uint) (*domain.Job, error) {
+	return transition(q.db, jobID, []string{domain.JobQueued}, ErrNotCancellable, map[string]interface{}{
+		"status":      domain.JobCancelled,
+		"finished_at": time.Now().UTC(),
+	})
+}
+
+// transition applies updates only while the job is in one of the from states, so
+// it cannot race a worker claiming the job
+func transition(db *gorm.DB, jobID uint, from []string, notAllowed error, updates map[string]interface{}) (*domain.Job, error) {
+	updates["updated_at"] = time.Now().UTC()
+	result := db.Model(&domain.Job{}).
+		Where("id = ? AND status IN ?", jobID, from).
+		Updates(updates)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	var job domain.Job
+	if err := db.First(&job, jobID).Error; err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, notAllowed
+	}
+	return &job, nil
+}
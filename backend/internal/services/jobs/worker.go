@@ -0,0 +1,284 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"gorm.io/gorm"
+)
+
+const (
+	// Extra time a running job stays hidden past its timeout, so the worker can
+	// record the outcome before another worker picks the job up again
+	lockGrace = 30 * time.Second
+)
+
+// Handler runs one job. Returning an error retries the job with backoff until it
+// runs out of attempts; wrap the error with Permanent to dead-letter it at once.
+type Handler func(ctx context.Context, job *domain.Job) error
+
+// Handle registers a handler that receives the job payload decoded as T. A payload
+// that does not decode is dead-lettered without running the handler.
+func Handle[T any](w *Worker, jobType string, fn func(ctx context.Context, payload T) error) {
+	w.Register(jobType, func(ctx context.Context, job *domain.Job) error {
+		var payload T
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return Permanent(fmt.Errorf("invalid %s payload: %w", jobType, err))
+		}
+		return fn(ctx, payload)
+	})
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks a failure that retrying cannot fix, such as a missing record
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Worker claims queued jobs and runs them. Several workers, in one process or many,
+// may run against the same database; each claims different rows.
+type Worker struct {
+	db       *gorm.DB
+	name     string
+	mu       sync.RWMutex
+	handlers map[string]Handler
+	backoffs map[string]Backoff
+}
+
+// NewWorker creates a worker with no handlers registered
+func NewWorker(db *gorm.DB) *Worker {
+	hostname, _ := os.Hostname()
+	return &Worker{
+		db:       db,
+		name:     fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+		handlers: make(map[string]Handler),
+		backoffs: make(map[string]Backoff),
+	}
+}
+
+// Register sets the handler for a job type. The worker only claims job types it has
+// a handler for, so jobs wait in the queue until some worker can run them.
+func (w *Worker) Register(jobType string, handler Handler) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handlers[jobType] = handler
+}
+
+// SetBackoff changes how long failed jobs of a type wait before they are retried.
+// Types without one use DefaultBackoff.
+func (w *Worker) SetBackoff(jobType string, backoff Backoff) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.backoffs[jobType] = backoff
+}
+
+func (w *Worker) backoff(jobType string) Backoff {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if backoff, ok := w.backoffs[jobType]; ok {
+		return backoff
+	}
+	return DefaultBackoff
+}
+
+func (w *Worker) types() []string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	types := make([]string, 0, len(w.handlers))
+	for jobType := range w.handlers {
+		types = append(types, jobType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Run processes jobs with the given number of concurrent runners, polling for new
+// work every interval, until ctx is cancelled. Jobs already running are allowed to
+// finish before Run returns.
+func (w *Worker) Run(ctx context.Context, concurrency int, interval time.Duration) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	log.Printf("Job worker %s running %v with %d runner(s)", w.name, w.types(), concurrency)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.runLoop(ctx, interval)
+		}()
+	}
+	wg.Wait()
+}
+
+func (w *Worker) runLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// Keep going while jobs are waiting
+		for ctx.Err() == nil {
+			ran, err := w.RunNext(ctx)
+			if err != nil {
+				log.Printf("WARNING: Job worker pass failed: %v", err)
+				break
+			}
+			if !ran {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunNext claims and runs one due job, reporting whether there was one
+func (w *Worker) RunNext(ctx context.Context) (bool, error) {
+	job, err := w.claim(ctx)
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	if job.Attempts > job.MaxAttempts {
+		// Its last run timed out without the worker reporting back
+		w.finish(job, Permanent(errors.New(derefString(job.LastError, "job timed out"))))
+		return true, nil
+	}
+
+	w.finish(job, w.run(job))
+	return true, nil
+}
+
+// claim locks the oldest due job this worker can run. A running job whose lock has
+// lapsed counts as due, so work held by a worker that died is picked up again.
+func (w *Worker) claim(ctx context.Context) (*domain.Job, error) {
+	types := w.types()
+	if len(types) == 0 {
+		return nil, nil
+	}
+
+	var jobs []domain.Job
+	now := time.Now().UTC()
+	err := w.db.WithContext(ctx).Raw(`
+		UPDATE jobs SET
+			status = ?,
+			attempts = attempts + 1,
+			locked_by = ?,
+			locked_until = ?::timestamptz + timeout_seconds * INTERVAL '1 second',
+			last_error = CASE WHEN status = ? THEN 'timed out on ' || COALESCE(locked_by, 'unknown worker') ELSE last_error END,
+			started_at = ?,
+			updated_at = ?
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE type IN ? AND (
+				(status = ? AND run_at <= ?) OR
+				(status = ? AND locked_until <= ?)
+			)
+			ORDER BY run_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		domain.JobRunning, w.name, now.Add(lockGrace), domain.JobRunning, now, now,
+		types, domain.JobQueued, now, domain.JobRunning, now,
+	).Scan(&jobs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim job: %w", err)
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+	return &jobs[0], nil
+}
+
+// run calls the job's handler with a deadline of the job's timeout. The handler
+// does not share the worker's context, so shutting down lets it finish.
+func (w *Worker) run(job *domain.Job) (err error) {
+	w.mu.RLock()
+	handler, ok := w.handlers[job.Type]
+	w.mu.RUnlock()
+	if !ok {
+		return Permanent(fmt.Errorf("no handler for job type %s", job.Type))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(job.TimeoutSeconds)*time.Second)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// finish records a run's outcome. The update only applies while this worker still
+// holds the claim it ran under; if the lock lapsed and another worker took the job
+// over, that worker's outcome stands.
+func (w *Worker) finish(job *domain.Job, runErr error) {
+	now := time.Now().UTC()
+	updates := map[string]interface{}{
+		"locked_by":    nil,
+		"locked_until": nil,
+		"updated_at":   now,
+	}
+
+	switch {
+	case runErr == nil:
+		updates["status"] = domain.JobSucceeded
+		updates["finished_at"] = now
+		updates["last_error"] = nil
+	case IsPermanent(runErr) || job.Attempts >= job.MaxAttempts:
+		updates["status"] = domain.JobDead
+		updates["finished_at"] = now
+		updates["last_error"] = runErr.Error()
+		log.Printf("WARNING: Dead-lettered %s job %d after %d attempt(s): %v", job.Type, job.ID, job.Attempts, runErr)
+	default:
+		updates["status"] = domain.JobQueued
+		updates["run_at"] = now.Add(w.backoff(job.Type).Delay(job.Attempts))
+		updates["last_error"] = runErr.Error()
+		log.Printf("WARNING: %s job %d failed on attempt %d, will retry: %v", job.Type, job.ID, job.Attempts, runErr)
+	}
+
+	result := w.db.Model(&domain.Job{}).
+		Where("id = ? AND status = ? AND attempts = ? AND locked_by = ?", job.ID, domain.JobRunning, job.Attempts, w.name).
+		Updates(updates)
+	if result.Error != nil {
+		log.Printf("WARNING: Failed to record outcome of %s job %d: %v", job.Type, job.ID, result.Error)
+	} else if result.RowsAffected == 0 {
+		log.Printf("WARNING: %s job %d was taken over by another worker before it finished", job.Type, job.ID)
+	}
+}
+
+func derefString(value *string, fallback string) string {
+	if value == nil {
+		return fallback
+	}
+	return *value
+}
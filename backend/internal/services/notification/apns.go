@@ -0,0 +1,193 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	APNsProductionURL  = "https://api.push.apple.com"
+	APNsDevelopmentURL = "https://api.sandbox.push.apple.com"
+
+	// Apple rejects provider tokens older than an hour and throttles ones
+	// refreshed more often than every 20 minutes
+	apnsTokenLifetime = 45 * time.Minute
+)
+
+// APNsConfig identifies the app and signing key used for Apple push notifications
+type APNsConfig struct {
+	BaseURL string // APNsProductionURL, APNsDevelopmentURL or a local stub
+	Topic   string // The app's bundle id
+	KeyID   string
+	TeamID  string
+	Key     *ecdsa.PrivateKey // The .p8 key downloaded from the developer portal
+}
+
+// APNsProvider sends notifications over the APNs HTTP/2 API using token-based
+// authentication
+type APNsProvider struct {
+	config APNsConfig
+	client *http.Client
+
+	mu       sync.Mutex
+	token    string
+	issuedAt time.Time
+}
+
+// NewAPNsProvider creates an APNs provider. A nil client uses one that negotiates
+// HTTP/2, which APNs requires.
+func NewAPNsProvider(config APNsConfig, client *http.Client) (*APNsProvider, error) {
+	if config.Key == nil || config.KeyID == "" || config.TeamID == "" || config.Topic == "" {
+		return nil, fmt.Errorf("APNs requires a signing key, key id, team id and topic")
+	}
+	if config.BaseURL == "" {
+		config.BaseURL = APNsProductionURL
+	}
+	if client == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.ForceAttemptHTTP2 = true
+		client = &http.Client{Transport: transport, Timeout: 30 * time.Second}
+	}
+	return &APNsProvider{config: config, client: client}, nil
+}
+
+// LoadAPNsKey reads a .p8 signing key
+func LoadAPNsKey(path string) (*ecdsa.PrivateKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse APNs key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("APNs key in %s is not an ECDSA key", path)
+	}
+	return key, nil
+}
+
+type apnsPayload struct {
+	APS  apnsAPS           `json:"aps"`
+	Data map[string]string `json:"data,omitempty"`
+}
+
+type apnsAPS struct {
+	Alert    apnsAlert `json:"alert"`
+	Sound    string    `json:"sound,omitempty"`
+	Category string    `json:"category,omitempty"`
+}
+
+type apnsAlert struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// Send implements PushProvider
+func (p *APNsProvider) Send(ctx context.Context, token string, message PushMessage) error {
+	body, err := json.Marshal(apnsPayload{
+		APS: apnsAPS{
+			Alert:    apnsAlert{Title: message.Title, Body: message.Body},
+			Sound:    "default",
+			Category: message.Category,
+		},
+		Data: message.Data,
+	})
+	if err != nil {
+		return err
+	}
+
+	bearer, err := p.providerToken()
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/3/device/%s", strings.TrimRight(p.config.BaseURL, "/"), token)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "bearer "+bearer)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("apns-topic", p.config.Topic)
+	req.Header.Set("apns-push-type", "alert")
+	if message.Urgent {
+		req.Header.Set("apns-priority", "10")
+	} else {
+		req.Header.Set("apns-priority", "5")
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var failure struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(resp.Body).Decode(&failure)
+
+	switch {
+	case resp.StatusCode == http.StatusGone,
+		failure.Reason == "BadDeviceToken",
+		failure.Reason == "DeviceTokenNotForTopic",
+		failure.Reason == "Unregistered":
+		return fmt.Errorf("%w: APNs %s", ErrInvalidDeviceToken, failure.Reason)
+	case failure.Reason == "ExpiredProviderToken":
+		// Sign a fresh token on the next attempt
+		p.mu.Lock()
+		p.token = ""
+		p.mu.Unlock()
+		pushErr := newPushError(resp, failure.Reason)
+		pushErr.Retryable = true
+		return pushErr
+	}
+	return newPushError(resp, failure.Reason)
+}
+
+// providerToken returns the signed JWT sent as the bearer token, reusing it until
+// it is due for renewal
+func (p *APNsProvider) providerToken() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token != "" && time.Since(p.issuedAt) < apnsTokenLifetime {
+		return p.token, nil
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": p.config.TeamID,
+		"iat": now.Unix(),
+	})
+	token.Header["kid"] = p.config.KeyID
+	signed, err := token.SignedString(p.config.Key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign APNs provider token: %w", err)
+	}
+	p.token = signed
+	p.issuedAt = now
+	return signed, nil
+}
@@ -0,0 +1,159 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// DefaultBackplaneChannel is the channel hubs publish to unless configured otherwise
+const DefaultBackplaneChannel = "handreceipt_notifications"
+
+const (
+	// Postgres rejects NOTIFY payloads of 8000 bytes or more
+	maxNotifyPayload = 7900
+
+	// How long a subscriber waits before reconnecting after losing its connection
+	backplaneRetryInterval = 5 * time.Second
+)
+
+// ErrPayloadTooLarge is returned when a message does not fit the backplane's limit
+var ErrPayloadTooLarge = errors.New("backplane message too large")
+
+// Backplane carries events between hub instances so that a notification raised on
+// one API node reaches users whose WebSocket is connected to another
+type Backplane interface {
+	// Publish sends a message to every subscribed node, including this one
+	Publish(ctx context.Context, payload []byte) error
+	// Subscribe calls handle for each message until ctx is cancelled, reconnecting
+	// as needed
+	Subscribe(ctx context.Context, handle func(payload []byte)) error
+}
+
+// backplaneMessage is what hubs exchange. Events too large for the backplane are
+// sent by reference and loaded from the event store by the receiving node.
+type backplaneMessage struct {
+	Origin     string          `json:"origin"`
+	Recipients []int           `json:"recipients"`
+	Topic      string          `json:"topic,omitempty"` // Set instead of recipients for topic events
+	Event      json.RawMessage `json:"event,omitempty"`
+	Seq        int64           `json:"seq,omitempty"`
+}
+
+// PostgresBackplane fans events out with LISTEN/NOTIFY on the application database
+type PostgresBackplane struct {
+	db      *gorm.DB
+	dsn     string
+	channel string
+}
+
+// NewPostgresBackplane creates a backplane that publishes through db and listens on
+// a dedicated connection opened from dsn
+func NewPostgresBackplane(db *gorm.DB, dsn, channel string) *PostgresBackplane {
+	if channel == "" {
+		channel = DefaultBackplaneChannel
+	}
+	return &PostgresBackplane{
+		db:      db,
+		dsn:     dsn,
+		channel: channel,
+	}
+}
+
+// Publish sends the payload with pg_notify
+func (b *PostgresBackplane) Publish(ctx context.Context, payload []byte) error {
+	if len(payload) > maxNotifyPayload {
+		return ErrPayloadTooLarge
+	}
+	return b.db.WithContext(ctx).Exec("SELECT pg_notify(?, ?)", b.channel, string(payload)).Error
+}
+
+// Subscribe listens on the channel until ctx is cancelled
+func (b *PostgresBackplane) Subscribe(ctx context.Context, handle func(payload []byte)) error {
+	for {
+		err := b.listen(ctx, handle)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Printf("WARNING: Notification backplane lost its Postgres listener: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backplaneRetryInterval):
+		}
+	}
+}
+
+func (b *PostgresBackplane) listen(ctx context.Context, handle func(payload []byte)) error {
+	conn, err := pgx.Connect(ctx, b.dsn)
+	if err != nil {
+		return fmt.Errorf("failed to connect listener: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{b.channel}.Sanitize()); err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", b.channel, err)
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		handle([]byte(n.Payload))
+	}
+}
+
+// RedisBackplane fans events out with Redis pub/sub
+type RedisBackplane struct {
+	client  *redis.Client
+	channel string
+}
+
+// NewRedisBackplane creates a backplane on the given Redis server
+func NewRedisBackplane(addr, password string, db int, channel string) *RedisBackplane {
+	if channel == "" {
+		channel = DefaultBackplaneChannel
+	}
+	return &RedisBackplane{
+		client: redis.NewClient(&redis.Options{
+			Addr:     addr,
+			Password: password,
+			DB:       db,
+		}),
+		channel: channel,
+	}
+}
+
+// Publish sends the payload to the channel
+func (b *RedisBackplane) Publish(ctx context.Context, payload []byte) error {
+	return b.client.Publish(ctx, b.channel, payload).Err()
+}
+
+// Subscribe reads from the channel until ctx is cancelled. The Redis client
+// re-establishes the subscription itself if the connection drops.
+func (b *RedisBackplane) Subscribe(ctx context.Context, handle func(payload []byte)) error {
+	sub := b.client.Subscribe(ctx, b.channel)
+	defer sub.Close()
+
+	messages := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-messages:
+			if !ok {
+				return errors.New("redis subscription closed")
+			}
+			handle([]byte(msg.Payload))
+		}
+	}
+}
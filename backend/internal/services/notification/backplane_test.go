@@ -0,0 +1,91 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestPostgresBackplaneAcrossHubs runs two hubs against one database, as two API
+// instances would, and checks a notification raised on one reaches a client
+// connected to the other. Set HANDRECEIPT_TEST_DATABASE_URL to run it.
+func TestPostgresBackplaneAcrossHubs(t *testing.T) {
+	dsn := os.Getenv("HANDRECEIPT_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("Skipping backplane integration test - HANDRECEIPT_TEST_DATABASE_URL not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&domain.Notification{}, &domain.NotificationSequence{}); err != nil {
+		t.Fatalf("Failed to migrate notification tables: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	channel := fmt.Sprintf("handreceipt_test_%d", time.Now().UnixNano())
+	hubA := NewHub()
+	hubB := NewHub()
+	go hubA.Run()
+	go hubB.Run()
+	hubA.AttachBackplane(ctx, NewPostgresBackplane(db, dsn, channel))
+	hubB.AttachBackplane(ctx, NewPostgresBackplane(db, dsn, channel))
+
+	// A user id no real account will have, so leftover rows cannot collide
+	userID := int(time.Now().Unix()%1000000) + 900000000
+	defer db.Where("user_id = ?", userID).Delete(&domain.Notification{})
+	defer db.Where("user_id = ?", userID).Delete(&domain.NotificationSequence{})
+
+	client := &Client{UserID: userID, DeviceID: "ios", Send: make(chan []byte, 16)}
+	hubB.RegisterClient(client)
+
+	// The listeners connect asynchronously; ping through hub A until hub B hears it
+	ready := false
+	for attempt := 0; attempt < 50 && !ready; attempt++ {
+		hubA.SendToUser(userID, Event{Type: EventTypeNotification, Data: "ping", Timestamp: time.Now()})
+		select {
+		case <-client.Send:
+			ready = true
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+	if !ready {
+		t.Fatal("Hub B never received an event published by hub A")
+	}
+
+	service := NewDBService(hubA, db)
+	if err := service.SendGeneralNotification(userID, "Transfer accepted", "M4 carbine transferred"); err != nil {
+		t.Fatalf("Failed to send notification: %v", err)
+	}
+
+	select {
+	case message := <-client.Send:
+		var event Event
+		if err := json.Unmarshal(message, &event); err != nil {
+			t.Fatalf("Client received malformed event: %v", err)
+		}
+		if event.Type != EventTypeNotification {
+			t.Errorf("Expected event type %s, got %s", EventTypeNotification, event.Type)
+		}
+		if event.Seq <= 0 {
+			t.Errorf("Expected a sequenced event, got seq %d", event.Seq)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Notification raised on hub A did not reach the client on hub B")
+	}
+
+	if hubA.IsUserConnected(userID) {
+		t.Error("Hub A should not report a connection held by hub B")
+	}
+}
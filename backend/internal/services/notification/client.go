@@ -3,6 +3,7 @@ package notification
 import (
 	"bytes"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -18,16 +19,198 @@ const (
 	// Send pings to peer with this period. Must be less than pongWait
 	pingPeriod = (pongWait * 9) / 10
 
-	// Maximum message size allowed from peer
-	maxMessageSize = 512
+	// Maximum message size allowed from peer; enough for a subscribe command
+	// listing a few dozen topics
+	maxMessageSize = 4096
+
+	// How long replay waits before retrying when the send buffer is full
+	replayRetryInterval = 10 * time.Millisecond
 )
 
-// Client represents a WebSocket client connection
+// Client represents a WebSocket client connection. A user has one Client per
+// connected device or browser session.
 type Client struct {
-	UserID int
-	Conn   *websocket.Conn
-	Hub    *Hub
-	Send   chan []byte
+	UserID      int
+	DeviceID    string // Stable identifier supplied by the app; reconnects from the same device replace the old connection
+	SessionID   string // Unique per connection
+	ConnectedAt time.Time
+	AuthSession string    // Sign-in session the connection was opened under; empty if it cannot be revoked
+	AuthExpiry  time.Time // When the credential the connection was opened with expires; zero if it does not
+	Conn        *websocket.Conn
+	Hub         *Hub
+	Send        chan []byte
+
+	mu      sync.Mutex
+	closed  bool
+	lastSeq int64   // Highest sequence number delivered to this connection
+	syncing bool    // Replay in progress; live sequenced events are held until it finishes
+	held    []Event // Live events that arrived during replay
+	topics  map[string]struct{}
+
+	closeCode   int    // Close frame sent when the hub ends the connection
+	closeReason string
+}
+
+// closeSend closes the send channel exactly once, however the connection ends
+func (c *Client) closeSend() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.Send)
+	}
+}
+
+// enqueue queues a raw message without blocking. It reports false when the
+// connection is closed or cannot keep up.
+func (c *Client) enqueue(message []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return false
+	}
+	select {
+	case c.Send <- message:
+		return true
+	default:
+		return false
+	}
+}
+
+// delivery is the outcome of handing a live event to a connection
+type delivery int
+
+const (
+	delivered delivery = iota // Queued, held, or already seen
+	tooSlow                   // Send buffer full; the connection must be dropped
+	gapFound                  // Held because earlier events have not arrived; the connection must catch up from the store
+)
+
+// deliver queues a live event. Sequenced events the connection has already seen are
+// skipped, and those arriving mid-replay are held so the client receives them in
+// order. Events are persisted and sent by different goroutines, so a later event can
+// arrive before an earlier one; when canFill is set such an event is held and the
+// caller must fill the gap from the store, otherwise it is sent and the earlier one
+// skipped when it arrives. A client that cannot keep up must be dropped; it
+// recovers what it missed by reconnecting with its last sequence.
+func (c *Client) deliver(event Event, canFill bool) delivery {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return delivered
+	}
+	if event.Seq > 0 {
+		if event.Seq <= c.lastSeq {
+			return delivered
+		}
+		if c.syncing {
+			c.held = append(c.held, event)
+			return delivered
+		}
+		// Until a connection has seen a sequenced event there is nothing to measure a gap from
+		if canFill && c.lastSeq > 0 && event.Seq > c.lastSeq+1 {
+			c.syncing = true
+			c.held = append(c.held, event)
+			return gapFound
+		}
+	}
+	select {
+	case c.Send <- event.ToJSON():
+		if event.Seq > 0 {
+			c.lastSeq = event.Seq
+		}
+		return delivered
+	default:
+		return tooSlow
+	}
+}
+
+// seen returns the highest sequence number delivered to the connection
+func (c *Client) seen() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastSeq
+}
+
+// beginSync marks the connection as replaying everything after lastSeq
+func (c *Client) beginSync(lastSeq int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.syncing = true
+	c.lastSeq = lastSeq
+}
+
+// replay writes a missed event, waiting for the write pump to make room if the
+// buffer is full. The lock is released while waiting so live delivery to the
+// hub's other clients is not held up.
+func (c *Client) replay(event Event) error {
+	message := event.ToJSON()
+	deadline := time.Now().Add(writeWait)
+	for {
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			return ErrClientClosed
+		}
+		if event.Seq <= c.lastSeq {
+			c.mu.Unlock()
+			return nil
+		}
+		select {
+		case c.Send <- message:
+			c.lastSeq = event.Seq
+			c.mu.Unlock()
+			return nil
+		default:
+		}
+		c.mu.Unlock()
+
+		if time.Now().After(deadline) {
+			return ErrClientTooSlow
+		}
+		time.Sleep(replayRetryInterval)
+	}
+}
+
+// finishSync flushes events held during replay and, if announce is set, tells the
+// client it is live
+func (c *Client) finishSync(announce bool) error {
+	for {
+		c.mu.Lock()
+		held := c.held
+		c.held = nil
+		if len(held) == 0 {
+			break
+		}
+		c.mu.Unlock()
+
+		for _, event := range held {
+			if err := c.replay(event); err != nil {
+				return err
+			}
+		}
+	}
+	defer c.mu.Unlock()
+
+	c.syncing = false
+	if c.closed {
+		return ErrClientClosed
+	}
+	if !announce {
+		return nil
+	}
+	synced := Event{
+		Type:      EventTypeSyncComplete,
+		Data:      SyncCompleteData{LastSeq: c.lastSeq},
+		Timestamp: time.Now(),
+		UserID:    c.UserID,
+	}
+	select {
+	case c.Send <- synced.ToJSON():
+		return nil
+	default:
+		return ErrClientTooSlow
+	}
 }
 
 // ReadPump pumps messages from the websocket connection to the hub
@@ -53,15 +236,9 @@ func (c *Client) ReadPump() {
 			break
 		}
 
-		// Handle incoming messages (e.g., ping/pong or client commands)
+		// Handle incoming commands (subscribe, unsubscribe, heartbeat)
 		message = bytes.TrimSpace(bytes.Replace(message, []byte{'\n'}, []byte{' '}, -1))
-		
-		// For now, we're only handling server->client communication
-		// Add client->server message handling here if needed
-		if string(message) == "ping" {
-			// Client sent a ping, respond with pong
-			c.Send <- []byte("pong")
-		}
+		c.handleMessage(message)
 	}
 }
 
@@ -79,7 +256,7 @@ func (c *Client) WritePump() {
 			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
 				// The hub closed the channel
-				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+				c.Conn.WriteMessage(websocket.CloseMessage, c.closeMessage())
 				return
 			}
 
@@ -8,26 +8,45 @@ import (
 
 	"gorm.io/gorm"
 	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"github.com/toole-brendan/handreceipt-go/internal/services/email"
 )
 
 // DBService extends the notification service with database persistence
 type DBService struct {
 	*Service
-	db *gorm.DB
+	db       *gorm.DB
+	push     *PushSender // nil when push delivery is not configured
+	mailer   email.EmailService // nil when email is not configured
+	listener func(Event) // Called with each transfer and property event, for webhooks
 }
 
 // NewDBService creates a new notification service with database support
 func NewDBService(hub *Hub, db *gorm.DB) *DBService {
-	return &DBService{
+	s := &DBService{
 		Service: NewService(hub),
 		db:      db,
 	}
+	hub.SetEventStore(s)
+	hub.SetTopicAuthorizer(s)
+	return s
+}
+
+// SetEventListener registers a function called with every transfer and property
+// event the service publishes, once per event rather than once per recipient
+func (s *DBService) SetEventListener(listener func(Event)) {
+	s.listener = listener
+}
+
+func (s *DBService) notifyListener(event Event) {
+	if s.listener != nil {
+		s.listener(event)
+	}
 }
 
 // NotifyTransferUpdate sends a real-time notification and saves to database
 func (s *DBService) NotifyTransferUpdate(transfer *domain.Transfer) error {
-	// Send real-time notification
-	s.Service.NotifyTransferUpdate(
+	event := transferEvent(
+		EventTypeTransferUpdate,
 		int(transfer.ID),
 		int(transfer.FromUserID),
 		int(transfer.ToUserID),
@@ -35,8 +54,13 @@ func (s *DBService) NotifyTransferUpdate(transfer *domain.Transfer) error {
 		transfer.Property.SerialNumber,
 		transfer.Property.Name,
 	)
+	s.publishTransferTopics(transfer, event)
+	s.notifyListener(event)
+
+	// Sensitive items are delivered whatever either party's preferences say
+	sensitive := s.isSensitiveProperty(transfer.Property)
 
-	// Create persistent notifications
+	// Create persistent notifications, pushing each in real time once stored
 	data := map[string]interface{}{
 		"transferId":   transfer.ID,
 		"propertyId":   transfer.PropertyID,
@@ -54,7 +78,7 @@ func (s *DBService) NotifyTransferUpdate(transfer *domain.Transfer) error {
 		Data:     dataJSON,
 		Priority: domain.NotificationPriorityNormal,
 	}
-	if err := s.CreateNotification(senderNotif); err != nil {
+	if err := s.deliver(senderNotif, event, sensitive); err != nil {
 		return err
 	}
 
@@ -67,19 +91,23 @@ func (s *DBService) NotifyTransferUpdate(transfer *domain.Transfer) error {
 		Data:     dataJSON,
 		Priority: domain.NotificationPriorityNormal,
 	}
-	return s.CreateNotification(receiverNotif)
+	return s.deliver(receiverNotif, event, sensitive)
 }
 
 // NotifyTransferCreated sends a real-time notification and saves to database
 func (s *DBService) NotifyTransferCreated(transfer *domain.Transfer) error {
-	// Send real-time notification
-	s.Service.NotifyTransferCreated(
+	event := transferEvent(
+		EventTypeTransferCreated,
 		int(transfer.ID),
 		int(transfer.FromUserID),
 		int(transfer.ToUserID),
+		"pending",
 		transfer.Property.SerialNumber,
 		transfer.Property.Name,
 	)
+	s.echo(int(transfer.FromUserID), event)
+	s.publishTransferTopics(transfer, event)
+	s.notifyListener(event)
 
 	// Create persistent notification for receiver
 	data := map[string]interface{}{
@@ -98,7 +126,60 @@ func (s *DBService) NotifyTransferCreated(transfer *domain.Transfer) error {
 		Data:     dataJSON,
 		Priority: domain.NotificationPriorityHigh,
 	}
-	return s.CreateNotification(notification)
+	return s.deliver(notification, event, s.isSensitiveProperty(transfer.Property))
+}
+
+// NotifyTransferComment pushes a new transfer comment and saves a notification for
+// every recipient other than the author
+func (s *DBService) NotifyTransferComment(comment *domain.TransferComment, recipientIDs []uint) error {
+	authorName := ""
+	if comment.Author != nil {
+		authorName = fmt.Sprintf("%s %s", comment.Author.FirstName, comment.Author.LastName)
+	}
+
+	data := TransferCommentData{
+		TransferID: int(comment.TransferID),
+		CommentID:  int(comment.ID),
+		AuthorID:   int(comment.AuthorUserID),
+		AuthorName: authorName,
+		Body:       comment.Body,
+	}
+	if comment.ParentCommentID != nil {
+		parentID := int(*comment.ParentCommentID)
+		data.ParentCommentID = &parentID
+	}
+	for _, id := range recipientIDs {
+		data.RecipientIDs = append(data.RecipientIDs, int(id))
+	}
+	event := Event{
+		Type:      EventTypeTransferComment,
+		Data:      data,
+		Timestamp: time.Now(),
+	}
+	s.echo(int(comment.AuthorUserID), event)
+	s.notifyListener(event)
+
+	dataJSON, _ := json.Marshal(map[string]interface{}{
+		"transferId": comment.TransferID,
+		"commentId":  comment.ID,
+	})
+	for _, recipientID := range recipientIDs {
+		if recipientID == comment.AuthorUserID {
+			continue
+		}
+		notification := &domain.Notification{
+			UserID:   recipientID,
+			Type:     domain.NotificationTypeTransferComment,
+			Title:    "New Transfer Comment",
+			Message:  fmt.Sprintf("%s commented on transfer #%d", authorName, comment.TransferID),
+			Data:     dataJSON,
+			Priority: domain.NotificationPriorityNormal,
+		}
+		if err := s.deliver(notification, event, false); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // NotifyPropertyUpdate sends a real-time notification and saves to database
@@ -107,14 +188,19 @@ func (s *DBService) NotifyPropertyUpdate(property *domain.Property) error {
 		return nil // No owner to notify
 	}
 
-	// Send real-time notification
-	s.Service.NotifyPropertyUpdate(
-		int(property.ID),
-		int(*property.AssignedToUserID),
-		property.SerialNumber,
-		property.CurrentStatus,
-		"updated",
-	)
+	event := Event{
+		Type: EventTypePropertyUpdate,
+		Data: PropertyUpdateData{
+			PropertyID:   int(property.ID),
+			OwnerID:      int(*property.AssignedToUserID),
+			SerialNumber: property.SerialNumber,
+			Status:       property.CurrentStatus,
+			Action:       "updated",
+		},
+		Timestamp: time.Now(),
+	}
+	s.hub.PublishTopic(PropertyTopic(property.ID), event)
+	s.notifyListener(event)
 
 	// Create persistent notification
 	data := map[string]interface{}{
@@ -132,7 +218,7 @@ func (s *DBService) NotifyPropertyUpdate(property *domain.Property) error {
 		Data:     dataJSON,
 		Priority: domain.NotificationPriorityNormal,
 	}
-	return s.CreateNotification(notification)
+	return s.deliver(notification, event, false)
 }
 
 // NotifyConnectionRequest sends a real-time notification and saves to database
@@ -143,9 +229,9 @@ func (s *DBService) NotifyConnectionRequest(requesterID, targetUserID int) error
 		return err
 	}
 
-	// Send real-time notification
 	requesterName := fmt.Sprintf("%s %s", requester.FirstName, requester.LastName)
-	s.Service.NotifyConnectionRequest(0, requesterID, requesterName, targetUserID)
+	event := connectionEvent(EventTypeConnectionRequest, 0, requesterID, requesterName, targetUserID, "pending")
+	s.echo(requesterID, event)
 
 	// Create persistent notification
 	data := map[string]interface{}{
@@ -162,7 +248,7 @@ func (s *DBService) NotifyConnectionRequest(requesterID, targetUserID int) error
 		Data:     dataJSON,
 		Priority: domain.NotificationPriorityNormal,
 	}
-	return s.CreateNotification(notification)
+	return s.deliver(notification, event, false)
 }
 
 // NotifyConnectionAccepted sends a real-time notification and saves to database
@@ -173,9 +259,9 @@ func (s *DBService) NotifyConnectionAccepted(acceptorID, requesterID int) error
 		return err
 	}
 
-	// Send real-time notification
 	acceptorName := fmt.Sprintf("%s %s", acceptor.FirstName, acceptor.LastName)
-	s.Service.NotifyConnectionAccepted(0, acceptorID, acceptorName, requesterID)
+	event := connectionEvent(EventTypeConnectionAccepted, 0, acceptorID, acceptorName, requesterID, "accepted")
+	s.echo(acceptorID, event)
 
 	// Create persistent notification
 	data := map[string]interface{}{
@@ -192,7 +278,7 @@ func (s *DBService) NotifyConnectionAccepted(acceptorID, requesterID int) error
 		Data:     dataJSON,
 		Priority: domain.NotificationPriorityNormal,
 	}
-	return s.CreateNotification(notification)
+	return s.deliver(notification, event, false)
 }
 
 // NotifyDocumentReceived sends a real-time notification and saves to database
@@ -203,14 +289,17 @@ func (s *DBService) NotifyDocumentReceived(document *domain.Document) error {
 		return err
 	}
 
-	// Send real-time notification
-	s.Service.NotifyDocumentReceived(
-		int(document.ID),
-		int(document.RecipientUserID),
-		int(document.SenderUserID),
-		document.Type,
-		document.Title,
-	)
+	event := Event{
+		Type: EventTypeDocumentReceived,
+		Data: DocumentReceivedData{
+			DocumentID:   int(document.ID),
+			RecipientID:  int(document.RecipientUserID),
+			SenderID:     int(document.SenderUserID),
+			DocumentType: document.Type,
+			Title:        document.Title,
+		},
+		Timestamp: time.Now(),
+	}
 
 	// Create persistent notification
 	data := map[string]interface{}{
@@ -229,15 +318,11 @@ func (s *DBService) NotifyDocumentReceived(document *domain.Document) error {
 		Data:     dataJSON,
 		Priority: domain.NotificationPriorityNormal,
 	}
-	return s.CreateNotification(notification)
+	return s.deliver(notification, event, false)
 }
 
 // SendGeneralNotification sends a general notification with persistence
 func (s *DBService) SendGeneralNotification(userID int, title, message string) error {
-	// Send real-time notification
-	s.Service.SendGeneralNotification(userID, message, nil)
-
-	// Create persistent notification
 	notification := &domain.Notification{
 		UserID:   uint(userID),
 		Type:     domain.NotificationTypeGeneral,
@@ -248,9 +333,32 @@ func (s *DBService) SendGeneralNotification(userID int, title, message string) e
 	return s.CreateNotification(notification)
 }
 
-// CreateNotification creates a new notification in the database
+// CreateNotification saves a notification and pushes it as a general notification
+// event, routed by the user's preferences the same way as the service's own
+// notifications: it is only kept in their list, pushed live, sent to their mobile
+// devices or emailed if they allow it.
 func (s *DBService) CreateNotification(notification *domain.Notification) error {
-	return s.db.Create(notification).Error
+	r := s.routeFor(notification.UserID, notification.Type, false, time.Now())
+	switch {
+	case r.store && r.live:
+		if err := Persist(s.db, notification, nil); err != nil {
+			return err
+		}
+		s.hub.SendToUser(int(notification.UserID), eventFromNotification(*notification))
+	case r.store:
+		if err := s.db.Create(notification).Error; err != nil {
+			return err
+		}
+	case r.live:
+		s.echo(int(notification.UserID), eventFromNotification(*notification))
+	}
+	if r.push {
+		s.sendPush(notification)
+	}
+	if r.email {
+		s.sendEmail(notification)
+	}
+	return nil
 }
 
 // GetUserNotifications retrieves notifications for a user
@@ -0,0 +1,125 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// pushTimeout bounds one notification's delivery to all of a user's devices,
+// including retries
+const pushTimeout = 2 * time.Minute
+
+var ErrDeviceTokenNotFound = errors.New("device token not found")
+
+// SetPushSender enables push delivery to registered mobile devices
+func (s *DBService) SetPushSender(sender *PushSender) {
+	s.push = sender
+}
+
+// RegisterDeviceToken records a device token for the user, taking it over if it
+// was registered to someone else. A new token for a device id the user already
+// registered replaces the old one, which the app will no longer receive on.
+func (s *DBService) RegisterDeviceToken(userID uint, device *domain.DeviceToken) error {
+	if device.Platform != domain.DevicePlatformIOS && device.Platform != domain.DevicePlatformAndroid {
+		return ErrUnsupportedPlatform
+	}
+	device.ID = 0
+	device.UserID = userID
+	device.LastSeenAt = time.Now()
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if device.DeviceID != "" {
+			err := tx.Where("user_id = ? AND device_id = ? AND token <> ?", userID, device.DeviceID, device.Token).
+				Delete(&domain.DeviceToken{}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "token"}},
+			DoUpdates: clause.AssignmentColumns([]string{"user_id", "platform", "device_id", "app_version", "last_seen_at", "updated_at"}),
+		}).Create(device).Error
+	})
+}
+
+// UnregisterDeviceToken removes one of the user's device tokens, typically on sign out
+func (s *DBService) UnregisterDeviceToken(userID uint, token string) error {
+	result := s.db.Where("user_id = ? AND token = ?", userID, token).Delete(&domain.DeviceToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDeviceTokenNotFound
+	}
+	return nil
+}
+
+// GetDeviceTokens lists the devices registered for the user's push notifications
+func (s *DBService) GetDeviceTokens(userID uint) ([]domain.DeviceToken, error) {
+	var devices []domain.DeviceToken
+	err := s.db.Where("user_id = ?", userID).Order("last_seen_at DESC").Find(&devices).Error
+	return devices, err
+}
+
+// sendPush delivers a notification to the recipient's registered devices in the
+// background. Devices with an open WebSocket connection to this instance already
+// have the event and are skipped. Tokens the push services reject are removed.
+func (s *DBService) sendPush(notification *domain.Notification) {
+	if s.push == nil {
+		return
+	}
+
+	message := PushMessage{
+		Title:    notification.Title,
+		Body:     notification.Message,
+		Category: notification.Type,
+		Urgent:   notification.Priority == domain.NotificationPriorityHigh || notification.Priority == domain.NotificationPriorityUrgent,
+		Data: map[string]string{
+			"type": notification.Type,
+		},
+	}
+	if notification.ID != 0 {
+		message.Data["notificationId"] = strconv.FormatUint(uint64(notification.ID), 10)
+	}
+	if notification.Sequence != 0 {
+		message.Data["seq"] = strconv.FormatInt(notification.Sequence, 10)
+	}
+	if len(notification.Data) > 0 {
+		message.Data["payload"] = string(notification.Data)
+	}
+	userID := notification.UserID
+
+	go func() {
+		devices, err := s.GetDeviceTokens(userID)
+		if err != nil {
+			log.Printf("WARNING: Failed to load device tokens for user %d: %v", userID, err)
+			return
+		}
+		offline := devices[:0]
+		for _, device := range devices {
+			if device.DeviceID != "" && s.IsDeviceOnline(int(userID), device.DeviceID) {
+				continue
+			}
+			offline = append(offline, device)
+		}
+		if len(offline) == 0 {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+		defer cancel()
+		invalid := s.push.Send(ctx, offline, message)
+		if len(invalid) > 0 {
+			if err := s.db.Where("token IN ?", invalid).Delete(&domain.DeviceToken{}).Error; err != nil {
+				log.Printf("WARNING: Failed to prune %d invalid device tokens: %v", len(invalid), err)
+			}
+		}
+	}()
+}
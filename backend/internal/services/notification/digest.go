@@ -0,0 +1,324 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"github.com/toole-brendan/handreceipt-go/internal/services/email"
+	"gorm.io/gorm"
+)
+
+// Digest is one user's roll-up of unread notifications
+type Digest struct {
+	User             domain.User
+	Frequency        string
+	GeneratedAt      time.Time
+	PendingTransfers []domain.Notification // Transfer requests still awaiting the user's action
+	OverdueInventory []domain.Notification
+	ExpiringOffers   []DigestOffer
+	Documents        []domain.Notification
+	Other            []domain.Notification
+}
+
+// DigestOffer is an open transfer offer to the user that expires before the next digest
+type DigestOffer struct {
+	OfferID      uint
+	ItemName     string
+	SerialNumber string
+	ExpiresAt    time.Time
+}
+
+// Total is the number of entries in the digest
+func (d *Digest) Total() int {
+	return len(d.PendingTransfers) + len(d.OverdueInventory) + len(d.ExpiringOffers) + len(d.Documents) + len(d.Other)
+}
+
+// digestOptIn matches notifications whose type the recipient chose to receive by
+// email or in the digest. Types without a stored preference keep the defaults, which
+// send neither.
+const digestOptIn = `EXISTS (SELECT 1 FROM notification_preferences p
+	WHERE p.user_id = n.user_id AND p.notification_type = n.type AND (p.email OR p.digest_only))`
+
+// SendDigests emails a digest to every user on the given frequency who has unread
+// notifications not yet included in a digest, of the types they chose for email or
+// the digest, then marks those notifications as digested. A failure for one user is
+// logged and does not stop the others.
+func SendDigests(ctx context.Context, db *gorm.DB, sender email.EmailService, frequency string, now time.Time) (int, error) {
+	var userIDs []uint
+	err := db.WithContext(ctx).
+		Table("notifications AS n").
+		Distinct("n.user_id").
+		Joins("LEFT JOIN notification_settings s ON s.user_id = n.user_id").
+		Where("n.read = ? AND n.digested_at IS NULL", false).
+		Where("n.expires_at IS NULL OR n.expires_at > ?", now).
+		Where(digestOptIn).
+		Where("COALESCE(s.digest_frequency, ?) = ?", domain.DigestFrequencyDaily, frequency).
+		Pluck("n.user_id", &userIDs).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to find users due a digest: %w", err)
+	}
+
+	sent := 0
+	for _, userID := range userIDs {
+		if ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+
+		digest, notificationIDs, err := buildDigest(ctx, db, userID, frequency, now)
+		if err != nil {
+			log.Printf("WARNING: Failed to build %s digest for user %d: %v", frequency, userID, err)
+			continue
+		}
+		if digest.Total() == 0 || digest.User.Email == "" {
+			continue
+		}
+
+		html, text, err := renderDigest(digest)
+		if err != nil {
+			log.Printf("WARNING: Failed to render %s digest for user %d: %v", frequency, userID, err)
+			continue
+		}
+		err = sender.SendEmail(email.EmailRequest{
+			To:       []string{digest.User.Email},
+			Subject:  fmt.Sprintf("HandReceipt %s digest - %d item(s) need your attention", frequency, digest.Total()),
+			Body:     html,
+			TextBody: text,
+			IsHTML:   true,
+		})
+		if err != nil {
+			log.Printf("WARNING: Failed to email %s digest to user %d: %v", frequency, userID, err)
+			continue
+		}
+
+		if len(notificationIDs) > 0 {
+			err = db.WithContext(ctx).Model(&domain.Notification{}).
+				Where("id IN ?", notificationIDs).
+				Update("digested_at", now).Error
+			if err != nil {
+				return sent, fmt.Errorf("failed to mark digest notifications for user %d: %w", userID, err)
+			}
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// buildDigest gathers a user's undigested unread notifications into sections,
+// keeping only the types the user chose for email or the digest. It returns the ids
+// of the notifications rolled up.
+func buildDigest(ctx context.Context, db *gorm.DB, userID uint, frequency string, now time.Time) (*Digest, []uint, error) {
+	digest := &Digest{Frequency: frequency, GeneratedAt: now}
+	if err := db.WithContext(ctx).First(&digest.User, userID).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var preferences []domain.NotificationPreference
+	if err := db.WithContext(ctx).Where("user_id = ?", userID).Find(&preferences).Error; err != nil {
+		return nil, nil, err
+	}
+	types := digestTypes(preferences)
+	if len(types) == 0 {
+		return digest, nil, nil
+	}
+
+	var notifications []domain.Notification
+	err := db.WithContext(ctx).
+		Where("user_id = ? AND read = ? AND digested_at IS NULL", userID, false).
+		Where("type IN ?", types).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Order("created_at ASC").
+		Find(&notifications).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Transfer requests only count as awaiting action while the transfer is still pending
+	var transferIDs []uint
+	for _, notification := range notifications {
+		if notification.Type == domain.NotificationTypeTransferCreated {
+			if id := notificationTransferID(notification); id != 0 {
+				transferIDs = append(transferIDs, id)
+			}
+		}
+	}
+	pending := make(map[uint]bool)
+	if len(transferIDs) > 0 {
+		var pendingIDs []uint
+		err := db.WithContext(ctx).Model(&domain.Transfer{}).
+			Where("id IN ? AND status = ?", transferIDs, "pending").
+			Pluck("id", &pendingIDs).Error
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, id := range pendingIDs {
+			pending[id] = true
+		}
+	}
+
+	ids := make([]uint, 0, len(notifications))
+	for _, notification := range notifications {
+		ids = append(ids, notification.ID)
+		switch notification.Type {
+		case domain.NotificationTypeTransferCreated:
+			if pending[notificationTransferID(notification)] {
+				digest.PendingTransfers = append(digest.PendingTransfers, notification)
+			} else {
+				digest.Other = append(digest.Other, notification)
+			}
+		case domain.NotificationTypeInventoryReminder:
+			digest.OverdueInventory = append(digest.OverdueInventory, notification)
+		case domain.NotificationTypeDocumentReceived:
+			digest.Documents = append(digest.Documents, notification)
+		default:
+			digest.Other = append(digest.Other, notification)
+		}
+	}
+
+	// Offers are transfer requests, so they follow the transfer request preference
+	if !containsType(types, domain.NotificationTypeTransferCreated) {
+		return digest, ids, nil
+	}
+	window := 24 * time.Hour
+	if frequency == domain.DigestFrequencyWeekly {
+		window = 7 * 24 * time.Hour
+	}
+	var offers []domain.TransferOffer
+	err = db.WithContext(ctx).
+		Preload("Property").
+		Joins("JOIN transfer_offer_recipients r ON r.transfer_offer_id = transfer_offers.id").
+		Where("r.recipient_user_id = ? AND transfer_offers.offer_status = ?", userID, "active").
+		Where("transfer_offers.expires_at BETWEEN ? AND ?", now, now.Add(window)).
+		Order("transfer_offers.expires_at ASC").
+		Find(&offers).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, offer := range offers {
+		entry := DigestOffer{OfferID: offer.ID, ExpiresAt: *offer.ExpiresAt}
+		if offer.Property != nil {
+			entry.ItemName = offer.Property.Name
+			entry.SerialNumber = offer.Property.SerialNumber
+		}
+		digest.ExpiringOffers = append(digest.ExpiringOffers, entry)
+	}
+
+	return digest, ids, nil
+}
+
+// digestTypes lists the notification types the user chose to receive by email or in
+// the digest
+func digestTypes(preferences []domain.NotificationPreference) []string {
+	var types []string
+	for _, preference := range preferences {
+		if preference.Email || preference.DigestOnly {
+			types = append(types, preference.NotificationType)
+		}
+	}
+	return types
+}
+
+func containsType(types []string, notificationType string) bool {
+	for _, t := range types {
+		if t == notificationType {
+			return true
+		}
+	}
+	return false
+}
+
+func notificationTransferID(notification domain.Notification) uint {
+	var data struct {
+		TransferID uint `json:"transferId"`
+	}
+	if len(notification.Data) == 0 || json.Unmarshal(notification.Data, &data) != nil {
+		return 0
+	}
+	return data.TransferID
+}
+
+// renderDigest produces the HTML and plain-text bodies of a digest email
+func renderDigest(digest *Digest) (string, string, error) {
+	var html, text bytes.Buffer
+	if err := digestHTMLTemplate.Execute(&html, digest); err != nil {
+		return "", "", err
+	}
+	if err := digestTextTemplate.Execute(&text, digest); err != nil {
+		return "", "", err
+	}
+	return html.String(), text.String(), nil
+}
+
+var digestFuncs = map[string]interface{}{
+	"date": func(t time.Time) string { return t.Format("02 Jan 2006 1504") },
+}
+
+var digestHTMLTemplate = htmltemplate.Must(htmltemplate.New("digest").Funcs(digestFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>HandReceipt Digest</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h2 style="color: #2c3e50; border-bottom: 2px solid #3498db; padding-bottom: 10px;">
+            HandReceipt {{.Frequency}} digest
+        </h2>
+        <p>{{.User.Rank}} {{.User.LastName}}, you have {{.Total}} item(s) since your last digest.</p>
+        {{with .PendingTransfers}}
+        <h3 style="color: #495057;">Transfers awaiting your action</h3>
+        <ul>{{range .}}<li>{{.Message}} <span style="color: #6c757d;">({{date .CreatedAt}})</span></li>{{end}}</ul>
+        {{end}}
+        {{with .OverdueInventory}}
+        <h3 style="color: #495057;">Overdue inventories</h3>
+        <ul>{{range .}}<li>{{.Message}}</li>{{end}}</ul>
+        {{end}}
+        {{with .ExpiringOffers}}
+        <h3 style="color: #495057;">Offers expiring soon</h3>
+        <ul>{{range .}}<li>{{.ItemName}} (SN {{.SerialNumber}}) expires {{date .ExpiresAt}}</li>{{end}}</ul>
+        {{end}}
+        {{with .Documents}}
+        <h3 style="color: #495057;">Documents received</h3>
+        <ul>{{range .}}<li>{{.Message}} <span style="color: #6c757d;">({{date .CreatedAt}})</span></li>{{end}}</ul>
+        {{end}}
+        {{with .Other}}
+        <h3 style="color: #495057;">Other activity</h3>
+        <ul>{{range .}}<li>{{.Title}}: {{.Message}}</li>{{end}}</ul>
+        {{end}}
+        <hr style="border: none; border-top: 1px solid #dee2e6; margin: 20px 0;">
+        <p style="font-size: 0.9em; color: #6c757d;">
+            This message was automatically generated by the HandReceipt system.<br>
+            Change how often you receive it in your notification preferences.
+        </p>
+    </div>
+</body>
+</html>`))
+
+var digestTextTemplate = texttemplate.Must(texttemplate.New("digest").Funcs(digestFuncs).Parse(`HandReceipt {{.Frequency}} digest
+
+{{.User.Rank}} {{.User.LastName}}, you have {{.Total}} item(s) since your last digest.
+{{with .PendingTransfers}}
+TRANSFERS AWAITING YOUR ACTION
+{{range .}}- {{.Message}} ({{date .CreatedAt}})
+{{end}}{{end}}{{with .OverdueInventory}}
+OVERDUE INVENTORIES
+{{range .}}- {{.Message}}
+{{end}}{{end}}{{with .ExpiringOffers}}
+OFFERS EXPIRING SOON
+{{range .}}- {{.ItemName}} (SN {{.SerialNumber}}) expires {{date .ExpiresAt}}
+{{end}}{{end}}{{with .Documents}}
+DOCUMENTS RECEIVED
+{{range .}}- {{.Message}} ({{date .CreatedAt}})
+{{end}}{{end}}{{with .Other}}
+OTHER ACTIVITY
+{{range .}}- {{.Title}}: {{.Message}}
+{{end}}{{end}}
+Change how often you receive this digest in your notification preferences.
+`))
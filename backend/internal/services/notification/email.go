@@ -0,0 +1,43 @@
+package notification
+
+import (
+	"log"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"github.com/toole-brendan/handreceipt-go/internal/services/email"
+)
+
+// SetEmailSender enables emailing notifications of the types users chose to
+// receive by email
+func (s *DBService) SetEmailSender(sender email.EmailService) {
+	s.mailer = sender
+}
+
+// sendEmail emails a notification to its recipient in the background. Failures are
+// logged; the notification is still in the user's list.
+func (s *DBService) sendEmail(notification *domain.Notification) {
+	if s.mailer == nil {
+		return
+	}
+	userID, notificationType := notification.UserID, notification.Type
+	request := email.EmailRequest{
+		Subject:  "HandReceipt: " + notification.Title,
+		Body:     notification.Message + "\n\nChange which notifications you receive by email in your notification preferences.\n",
+		TextBody: notification.Message,
+	}
+
+	go func() {
+		var user domain.User
+		if err := s.db.Select("id", "email").First(&user, userID).Error; err != nil {
+			log.Printf("WARNING: Failed to load email address for user %d: %v", userID, err)
+			return
+		}
+		if user.Email == "" {
+			return
+		}
+		request.To = []string{user.Email}
+		if err := s.mailer.SendEmail(request); err != nil {
+			log.Printf("WARNING: Failed to email %s notification to user %d: %v", notificationType, userID, err)
+		}
+	}()
+}
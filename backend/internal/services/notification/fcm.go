@@ -0,0 +1,253 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	FCMProductionURL = "https://fcm.googleapis.com"
+
+	fcmScope           = "https://www.googleapis.com/auth/firebase.messaging"
+	defaultGoogleToken = "https://oauth2.googleapis.com/token"
+)
+
+// FCMCredentials is the part of a Firebase service account key file needed to send
+// messages
+type FCMCredentials struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// LoadFCMCredentials reads a service account key file
+func LoadFCMCredentials(path string) (*FCMCredentials, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var credentials FCMCredentials
+	if err := json.Unmarshal(raw, &credentials); err != nil {
+		return nil, fmt.Errorf("failed to parse FCM credentials: %w", err)
+	}
+	return &credentials, nil
+}
+
+// FCMProvider sends notifications through the FCM HTTP v1 API, authenticating with
+// OAuth access tokens minted from a service account
+type FCMProvider struct {
+	baseURL     string
+	credentials *FCMCredentials
+	key         *rsa.PrivateKey
+	client      *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewFCMProvider creates an FCM provider. An empty baseURL uses FCMProductionURL and
+// a nil client uses a default one.
+func NewFCMProvider(baseURL string, credentials *FCMCredentials, client *http.Client) (*FCMProvider, error) {
+	if credentials == nil || credentials.ProjectID == "" || credentials.ClientEmail == "" {
+		return nil, fmt.Errorf("FCM requires a service account with a project id and client email")
+	}
+	block, _ := pem.Decode([]byte(credentials.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("FCM service account has no private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse FCM private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("FCM private key is not an RSA key")
+	}
+
+	if baseURL == "" {
+		baseURL = FCMProductionURL
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &FCMProvider{
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		credentials: credentials,
+		key:         key,
+		client:      client,
+	}, nil
+}
+
+type fcmRequest struct {
+	Message fcmMessage `json:"message"`
+}
+
+type fcmMessage struct {
+	Token        string            `json:"token"`
+	Notification fcmNotification   `json:"notification"`
+	Data         map[string]string `json:"data,omitempty"`
+	Android      fcmAndroid        `json:"android"`
+}
+
+type fcmNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type fcmAndroid struct {
+	Priority     string                 `json:"priority"`
+	Notification fcmAndroidNotification `json:"notification"`
+}
+
+type fcmAndroidNotification struct {
+	ClickAction string `json:"click_action,omitempty"`
+}
+
+// Send implements PushProvider
+func (p *FCMProvider) Send(ctx context.Context, token string, message PushMessage) error {
+	priority := "NORMAL"
+	if message.Urgent {
+		priority = "HIGH"
+	}
+	body, err := json.Marshal(fcmRequest{Message: fcmMessage{
+		Token:        token,
+		Notification: fcmNotification{Title: message.Title, Body: message.Body},
+		Data:         message.Data,
+		Android: fcmAndroid{
+			Priority:     priority,
+			Notification: fcmAndroidNotification{ClickAction: message.Category},
+		},
+	}})
+	if err != nil {
+		return err
+	}
+
+	accessToken, err := p.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/projects/%s/messages:send", p.baseURL, p.credentials.ProjectID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var failure struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&failure)
+
+	errorCode := failure.Error.Status
+	for _, detail := range failure.Error.Details {
+		if detail.ErrorCode != "" {
+			errorCode = detail.ErrorCode
+		}
+	}
+
+	switch {
+	case errorCode == "UNREGISTERED",
+		errorCode == "INVALID_ARGUMENT" && strings.Contains(failure.Error.Message, "registration token"):
+		return fmt.Errorf("%w: FCM %s", ErrInvalidDeviceToken, errorCode)
+	case resp.StatusCode == http.StatusUnauthorized:
+		// The access token was revoked or expired early; mint a new one next attempt
+		p.mu.Lock()
+		p.accessToken = ""
+		p.mu.Unlock()
+		pushErr := newPushError(resp, errorCode)
+		pushErr.Retryable = true
+		return pushErr
+	}
+	return newPushError(resp, errorCode)
+}
+
+// token returns an OAuth access token for the service account, exchanging a
+// signed assertion for a new one shortly before the current one expires
+func (p *FCMProvider) token(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.accessToken != "" && time.Now().Before(p.expiresAt.Add(-time.Minute)) {
+		return p.accessToken, nil
+	}
+
+	tokenURI := p.credentials.TokenURI
+	if tokenURI == "" {
+		tokenURI = defaultGoogleToken
+	}
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   p.credentials.ClientEmail,
+		"scope": fcmScope,
+		"aud":   tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(p.key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign FCM token request: %w", err)
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", newPushError(resp, "access token request failed")
+	}
+	var grant struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&grant); err != nil {
+		return "", fmt.Errorf("failed to decode FCM access token: %w", err)
+	}
+
+	p.accessToken = grant.AccessToken
+	p.expiresAt = now.Add(time.Duration(grant.ExpiresIn) * time.Second)
+	return p.accessToken, nil
+}
@@ -1,26 +1,50 @@
 package notification
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"sort"
 	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
 )
 
-// Hub maintains the set of active clients and broadcasts messages to the clients
+// Hub maintains the set of active clients and broadcasts messages to the clients.
+// A user may be connected from several devices at once; every connection receives
+// the events addressed to that user.
 type Hub struct {
-	clients    map[int]*Client
-	broadcast  chan Event
+	clients    map[int]map[*Client]struct{}
 	register   chan *Client
 	unregister chan *Client
+	store      EventStore
+	authorizer TopicAuthorizer
+	sessions   SessionValidator
+	maxPerUser int // Connections allowed per user on this node; 0 means no limit
+	backplane  Backplane
+	nodeID     string // Identifies this hub's own messages on the backplane
 	mu         sync.RWMutex
 }
 
+// DevicePresence describes one live connection for a user
+type DevicePresence struct {
+	DeviceID    string    `json:"deviceId"`
+	SessionID   string    `json:"sessionId"`
+	ConnectedAt time.Time `json:"connectedAt"`
+	Topics      []string  `json:"topics,omitempty"`
+}
+
 // NewHub creates a new Hub instance
 func NewHub() *Hub {
 	return &Hub{
-		broadcast:  make(chan Event),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
-		clients:    make(map[int]*Client),
+		clients:    make(map[int]map[*Client]struct{}),
+		nodeID:     uuid.New().String(),
 	}
 }
 
@@ -29,100 +53,192 @@ func (h *Hub) Run() {
 	for {
 		select {
 		case client := <-h.register:
-			h.mu.Lock()
-			h.clients[client.UserID] = client
-			h.mu.Unlock()
-			log.Printf("Client registered: UserID %d", client.UserID)
+			h.addClient(client)
 
 		case client := <-h.unregister:
 			h.mu.Lock()
-			if _, ok := h.clients[client.UserID]; ok {
-				delete(h.clients, client.UserID)
-				close(client.Send)
-				h.mu.Unlock()
-				log.Printf("Client unregistered: UserID %d", client.UserID)
-			} else {
-				h.mu.Unlock()
-			}
+			h.removeClientLocked(client)
+			h.mu.Unlock()
+			log.Printf("Client unregistered: UserID %d, Device %s", client.UserID, client.DeviceID)
+		}
+	}
+}
+
+// addClient registers a connection. A new connection from a device the user is already
+// connected from replaces the stale one rather than running alongside it.
+func (h *Hub) addClient(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
-		case event := <-h.broadcast:
-			h.mu.RLock()
-			for userID, client := range h.clients {
-				if h.shouldSendEvent(userID, event) {
-					select {
-					case client.Send <- event.ToJSON():
-					default:
-						// Client's send channel is full
-						close(client.Send)
-						delete(h.clients, userID)
-					}
-				}
+	connections, ok := h.clients[client.UserID]
+	if !ok {
+		connections = make(map[*Client]struct{})
+		h.clients[client.UserID] = connections
+	}
+	if client.DeviceID != "" {
+		for existing := range connections {
+			if existing.DeviceID == client.DeviceID {
+				h.removeClientLocked(existing)
 			}
-			h.mu.RUnlock()
 		}
 	}
+	// The handler turns away connections over the limit, but two can race past it
+	if h.maxPerUser > 0 {
+		for len(connections) >= h.maxPerUser {
+			oldest := oldestClient(connections)
+			oldest.setCloseReason(websocket.ClosePolicyViolation, closeReasonConnectionLimit)
+			h.removeClientLocked(oldest)
+		}
+	}
+	if client.ConnectedAt.IsZero() {
+		client.ConnectedAt = time.Now()
+	}
+	// removeClientLocked may have dropped the user's set when it emptied
+	if _, ok := h.clients[client.UserID]; !ok {
+		h.clients[client.UserID] = connections
+	}
+	connections[client] = struct{}{}
+	log.Printf("Client registered: UserID %d, Device %s (%d connection(s))", client.UserID, client.DeviceID, len(connections))
+}
+
+// removeClientLocked drops one connection and closes its send channel. The caller
+// must hold h.mu for writing.
+func (h *Hub) removeClientLocked(client *Client) {
+	connections, ok := h.clients[client.UserID]
+	if !ok {
+		return
+	}
+	if _, ok := connections[client]; !ok {
+		return
+	}
+	delete(connections, client)
+	client.closeSend()
+	if len(connections) == 0 {
+		delete(h.clients, client.UserID)
+	}
 }
 
-// shouldSendEvent determines if an event should be sent to a specific user
-func (h *Hub) shouldSendEvent(userID int, event Event) bool {
+// eventRecipients lists the users an event is addressed to
+func eventRecipients(event Event) []int {
 	// If UserID is specified in the event, only send to that user
 	if event.UserID > 0 {
-		return userID == event.UserID
+		return []int{event.UserID}
 	}
 
 	// Otherwise, use event type-specific logic
 	switch event.Type {
 	case EventTypeTransferUpdate, EventTypeTransferCreated:
 		if data, ok := event.Data.(TransferUpdateData); ok {
-			return userID == data.FromUserID || userID == data.ToUserID
+			return []int{data.FromUserID, data.ToUserID}
+		}
+	case EventTypeTransferComment:
+		if data, ok := event.Data.(TransferCommentData); ok {
+			return data.RecipientIDs
 		}
 	case EventTypePropertyUpdate:
 		if data, ok := event.Data.(PropertyUpdateData); ok {
-			return userID == data.OwnerID
+			return []int{data.OwnerID}
 		}
 	case EventTypeConnectionRequest, EventTypeConnectionAccepted:
 		if data, ok := event.Data.(ConnectionRequestData); ok {
-			return userID == data.TargetUserID || userID == data.FromUserID
+			return []int{data.TargetUserID, data.FromUserID}
 		}
 	case EventTypeDocumentReceived:
 		if data, ok := event.Data.(DocumentReceivedData); ok {
-			return userID == data.RecipientID
+			return []int{data.RecipientID}
 		}
 	}
 
-	return false
+	return nil
 }
 
-// BroadcastEvent sends an event to all relevant clients
+// BroadcastEvent sends an event to all relevant clients, on this node and, through
+// the backplane, on every other node. Delivery is the same as SendToUser's for each
+// recipient.
 func (h *Hub) BroadcastEvent(event Event) {
-	select {
-	case h.broadcast <- event:
-	default:
-		log.Printf("Broadcast channel full, event dropped: %v", event.Type)
+	recipients := uniqueRecipients(eventRecipients(event))
+	for _, userID := range recipients {
+		h.sendLocal(userID, event)
 	}
+	h.publish(recipients, event)
 }
 
-// SendToUser sends an event to a specific user
+// uniqueRecipients drops repeated user IDs, such as a transfer between a user and themselves
+func uniqueRecipients(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	unique := ids[:0:0]
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// SendToUser sends an event to every connection a specific user has open, on any
+// node. A connection that cannot keep up is closed so the client reconnects and
+// replays what it missed rather than silently skipping it. A connection that has
+// not yet received the events before this one catches up from the store first.
 func (h *Hub) SendToUser(userID int, event Event) {
-	h.mu.RLock()
-	client, ok := h.clients[userID]
-	h.mu.RUnlock()
+	h.sendLocal(userID, event)
+	h.publish([]int{userID}, event)
+}
 
-	if ok {
-		select {
-		case client.Send <- event.ToJSON():
-		default:
-			log.Printf("Failed to send event to user %d: channel full", userID)
-		}
+// sendLocal delivers an event to the user's connections on this node only
+func (h *Hub) sendLocal(userID int, event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for client := range h.clients[userID] {
+		h.deliverLocked(client, event)
+	}
+}
+
+// deliverLocked hands a live event to one connection, dropping it if it cannot keep
+// up and filling any gap before the event from the store. The caller must hold h.mu
+// for writing.
+func (h *Hub) deliverLocked(client *Client, event Event) {
+	switch client.deliver(event, h.store != nil) {
+	case tooSlow:
+		log.Printf("Dropping connection for user %d on device %s: channel full", client.UserID, client.DeviceID)
+		h.removeClientLocked(client)
+	case gapFound:
+		go h.fillGap(client, h.store)
 	}
 }
 
-// IsUserConnected checks if a user is currently connected
+// fillGap replays the persisted events a live connection has not received, then
+// delivers the events held while it ran. A connection that cannot be caught up is
+// dropped so the client reconnects and replays instead.
+func (h *Hub) fillGap(client *Client, store EventStore) {
+	err := catchUp(client, store)
+	if err == nil {
+		err = client.finishSync(false)
+	}
+	if err != nil && !errors.Is(err, ErrClientClosed) {
+		log.Printf("Dropping connection for user %d on device %s: failed to fill missed events: %v", client.UserID, client.DeviceID, err)
+		h.UnregisterClient(client)
+	}
+}
+
+// IsUserConnected checks if a user has at least one open connection
 func (h *Hub) IsUserConnected(userID int) bool {
 	h.mu.RLock()
-	_, ok := h.clients[userID]
-	h.mu.RUnlock()
-	return ok
+	defer h.mu.RUnlock()
+	return len(h.clients[userID]) > 0
+}
+
+// IsDeviceConnected checks if a user is connected from a particular device
+func (h *Hub) IsDeviceConnected(userID int, deviceID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for client := range h.clients[userID] {
+		if client.DeviceID == deviceID {
+			return true
+		}
+	}
+	return false
 }
 
 // GetConnectedUsers returns a list of all connected user IDs
@@ -137,6 +253,24 @@ func (h *Hub) GetConnectedUsers() []int {
 	return users
 }
 
+// GetUserDevices returns the live connections for a user, oldest first
+func (h *Hub) GetUserDevices(userID int) []DevicePresence {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	devices := make([]DevicePresence, 0, len(h.clients[userID]))
+	for client := range h.clients[userID] {
+		devices = append(devices, DevicePresence{
+			DeviceID:    client.DeviceID,
+			SessionID:   client.SessionID,
+			ConnectedAt: client.ConnectedAt,
+			Topics:      client.Topics(),
+		})
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].ConnectedAt.Before(devices[j].ConnectedAt) })
+	return devices
+}
+
 // RegisterClient registers a new client with the hub
 func (h *Hub) RegisterClient(client *Client) {
 	h.register <- client
@@ -145,4 +279,169 @@ func (h *Hub) RegisterClient(client *Client) {
 // UnregisterClient unregisters a client from the hub
 func (h *Hub) UnregisterClient(client *Client) {
 	h.unregister <- client
-}
\ No newline at end of file
+}
+
+// SetEventStore sets where the hub reads persisted events from when a client
+// reconnects and asks for the ones it missed
+func (h *Hub) SetEventStore(store EventStore) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.store = store
+}
+
+// Resume registers a reconnecting client and replays every persisted event after
+// lastSeq before the client goes live. Events published while the replay runs are
+// held and delivered in sequence once it finishes. The client's write pump must
+// already be running.
+func (h *Hub) Resume(client *Client, lastSeq int64) error {
+	client.beginSync(lastSeq)
+	h.addClient(client)
+
+	h.mu.RLock()
+	store := h.store
+	h.mu.RUnlock()
+
+	if store != nil {
+		if err := catchUp(client, store); err != nil {
+			return err
+		}
+	}
+
+	return client.finishSync(true)
+}
+
+// catchUp replays every persisted event after the last one the connection received
+func catchUp(client *Client, store EventStore) error {
+	after := client.seen()
+	for {
+		events, err := store.EventsSince(client.UserID, after, replayBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to load missed events for user %d: %w", client.UserID, err)
+		}
+		for _, event := range events {
+			if err := client.replay(event); err != nil {
+				return err
+			}
+			after = event.Seq
+		}
+		if len(events) < replayBatchSize {
+			return nil
+		}
+	}
+}
+
+// AttachBackplane connects the hub to other nodes. Events sent on this hub are
+// published to the backplane and events published by other hubs are delivered to
+// the connections held here, until ctx is cancelled.
+func (h *Hub) AttachBackplane(ctx context.Context, backplane Backplane) {
+	h.mu.Lock()
+	h.backplane = backplane
+	h.mu.Unlock()
+
+	go func() {
+		if err := backplane.Subscribe(ctx, h.receive); err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("WARNING: Notification backplane subscription ended: %v", err)
+		}
+	}()
+}
+
+// publish forwards an event for the given recipients, or for its topic, to the
+// other nodes. Sequenced events too large for the backplane go by reference; the
+// receiving node reads them back from the store.
+func (h *Hub) publish(recipients []int, event Event) {
+	h.mu.RLock()
+	backplane := h.backplane
+	h.mu.RUnlock()
+	if backplane == nil || (len(recipients) == 0 && event.Topic == "") {
+		return
+	}
+
+	message := backplaneMessage{
+		Origin:     h.nodeID,
+		Recipients: recipients,
+		Topic:      event.Topic,
+		Event:      event.ToJSON(),
+	}
+	payload, _ := json.Marshal(message)
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeWait)
+	defer cancel()
+
+	err := backplane.Publish(ctx, payload)
+	if errors.Is(err, ErrPayloadTooLarge) && event.Seq > 0 {
+		message.Event = nil
+		message.Seq = event.Seq
+		payload, _ = json.Marshal(message)
+		err = backplane.Publish(ctx, payload)
+	}
+	if err != nil {
+		log.Printf("WARNING: Failed to publish %s event to notification backplane: %v", event.Type, err)
+	}
+}
+
+// receive delivers an event published by another node to local connections
+func (h *Hub) receive(payload []byte) {
+	var message backplaneMessage
+	if err := json.Unmarshal(payload, &message); err != nil {
+		log.Printf("WARNING: Ignoring malformed notification backplane message: %v", err)
+		return
+	}
+	if message.Origin == h.nodeID {
+		return
+	}
+
+	if message.Topic != "" {
+		event, err := h.resolveEvent(0, message)
+		if err != nil {
+			log.Printf("WARNING: Failed to decode backplane event for topic %s: %v", message.Topic, err)
+			return
+		}
+		event.Topic = message.Topic
+		h.sendTopicLocal(event)
+		return
+	}
+
+	for _, userID := range message.Recipients {
+		if !h.IsUserConnected(userID) {
+			continue
+		}
+		event, err := h.resolveEvent(userID, message)
+		if err != nil {
+			log.Printf("WARNING: Failed to load backplane event for user %d: %v", userID, err)
+			continue
+		}
+		h.sendLocal(userID, event)
+	}
+}
+
+// resolveEvent decodes the event carried by a backplane message, loading it from
+// the store when it was sent by reference
+func (h *Hub) resolveEvent(userID int, message backplaneMessage) (Event, error) {
+	if len(message.Event) == 0 {
+		h.mu.RLock()
+		store := h.store
+		h.mu.RUnlock()
+		if store == nil {
+			return Event{}, errors.New("no event store to resolve event reference")
+		}
+		events, err := store.EventsSince(userID, message.Seq-1, 1)
+		if err != nil {
+			return Event{}, err
+		}
+		if len(events) == 0 || events[0].Seq != message.Seq {
+			return Event{}, fmt.Errorf("event %d not found", message.Seq)
+		}
+		return events[0], nil
+	}
+
+	// Data stays raw; it is only re-encoded for the client, so its concrete type is not needed
+	var event struct {
+		Event
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(message.Event, &event); err != nil {
+		return Event{}, err
+	}
+	event.Event.Data = event.Data
+	return event.Event, nil
+}
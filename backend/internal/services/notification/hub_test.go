@@ -0,0 +1,98 @@
+package notification
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStore is an EventStore over a fixed set of events
+type memoryStore struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (s *memoryStore) EventsSince(userID int, afterSeq int64, limit int) ([]Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var events []Event
+	for _, event := range s.events {
+		if event.UserID == userID && event.Seq > afterSeq && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// receivedSeqs reads sequence numbers off a client's send channel until want have
+// arrived or a second passes
+func receivedSeqs(t *testing.T, client *Client, want int) []int64 {
+	t.Helper()
+	var seqs []int64
+	timeout := time.After(time.Second)
+	for len(seqs) < want {
+		select {
+		case message := <-client.Send:
+			var event Event
+			if err := json.Unmarshal(message, &event); err != nil {
+				t.Fatal(err)
+			}
+			seqs = append(seqs, event.Seq)
+		case <-timeout:
+			t.Fatalf("received %v, expected %d events", seqs, want)
+		}
+	}
+	return seqs
+}
+
+func TestSendToUserFillsGapFromStore(t *testing.T) {
+	const userID = 7
+	event := func(seq int64) Event {
+		return Event{Type: EventTypeNotification, UserID: userID, Seq: seq, Timestamp: time.Now()}
+	}
+	store := &memoryStore{events: []Event{event(1), event(2), event(3)}}
+	hub := NewHub()
+	hub.SetEventStore(store)
+	client := &Client{UserID: userID, Send: make(chan []byte, 16), Hub: hub}
+	hub.addClient(client)
+
+	// Event 3 is sent before event 2, whose sender has not caught up yet
+	hub.SendToUser(userID, event(1))
+	hub.SendToUser(userID, event(3))
+	if got := receivedSeqs(t, client, 3); got[0] != 1 || got[1] != 2 || got[2] != 3 {
+		t.Fatalf("received %v, want [1 2 3]", got)
+	}
+
+	// The late event 2 was already replayed
+	hub.SendToUser(userID, event(2))
+	hub.SendToUser(userID, event(4))
+	if got := receivedSeqs(t, client, 1); got[0] != 4 {
+		t.Errorf("received %v after the gap, want [4]", got)
+	}
+}
+
+func TestBroadcastEventDeliversWithoutHubLoop(t *testing.T) {
+	hub := NewHub()
+	from := &Client{UserID: 1, Send: make(chan []byte, 1), Hub: hub}
+	to := &Client{UserID: 2, Send: make(chan []byte, 1), Hub: hub}
+	hub.addClient(from)
+	hub.addClient(to)
+
+	// Run is not started, so nothing is reading a broadcast channel
+	hub.BroadcastEvent(Event{
+		Type: EventTypeTransferUpdate,
+		Data: TransferUpdateData{TransferID: 9, FromUserID: 1, ToUserID: 2, Status: "accepted"},
+	})
+	for _, client := range []*Client{from, to} {
+		select {
+		case <-client.Send:
+		default:
+			t.Errorf("user %d did not receive the transfer update", client.UserID)
+		}
+	}
+
+	if got := uniqueRecipients([]int{3, 3, 4}); len(got) != 2 {
+		t.Errorf("uniqueRecipients: %v", got)
+	}
+}
@@ -0,0 +1,225 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidTimezone         = errors.New("unknown timezone")
+	ErrInvalidQuietHours       = errors.New("quiet hours must be given as HH:MM")
+	ErrUnknownNotificationType = errors.New("unknown notification type")
+	ErrInvalidDigestFrequency  = errors.New("digest frequency must be none, daily or weekly")
+)
+
+// route is how one notification reaches its recipient once their preferences and
+// quiet hours have been applied
+type route struct {
+	store bool // Save to the recipient's notification list
+	live  bool // Push over WebSocket
+	push  bool // Send to registered mobile devices
+	email bool // Email now; digest-only types wait for the digest instead
+}
+
+// routeFor applies the recipient's preferences. Quiet hours hold back everything
+// that would interrupt the user; the notification is still stored, and reaches them
+// in their digest if they take one. Mandatory notifications, such as sensitive-item
+// transfers, ignore preferences and quiet hours entirely.
+func (s *DBService) routeFor(userID uint, notificationType string, mandatory bool, now time.Time) route {
+	if mandatory {
+		return route{store: true, live: true, push: true, email: true}
+	}
+
+	preference, settings, err := s.loadPreference(userID, notificationType)
+	if err != nil {
+		log.Printf("WARNING: Failed to load notification preferences for user %d, using defaults: %v", userID, err)
+		return route{store: true, live: true, push: true}
+	}
+	return routeFromPreference(preference, settings, now)
+}
+
+// routeFromPreference applies one type's preference and the user's quiet hours
+func routeFromPreference(preference domain.NotificationPreference, settings domain.NotificationSettings, now time.Time) route {
+	r := route{
+		store: preference.InApp || preference.DigestOnly,
+		live:  preference.WebSocket && !preference.DigestOnly,
+		push:  preference.Push && !preference.DigestOnly,
+		email: preference.Email && !preference.DigestOnly,
+	}
+	if inQuietHours(settings, now) {
+		r.live = false
+		r.push = false
+		r.email = false
+	}
+	return r
+}
+
+func (s *DBService) loadPreference(userID uint, notificationType string) (domain.NotificationPreference, domain.NotificationSettings, error) {
+	preference := domain.DefaultNotificationPreference(userID, notificationType)
+	err := s.db.Where("user_id = ? AND notification_type = ?", userID, notificationType).First(&preference).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return preference, domain.NotificationSettings{}, err
+	}
+
+	settings, err := s.loadSettings(userID)
+	return preference, settings, err
+}
+
+func (s *DBService) loadSettings(userID uint) (domain.NotificationSettings, error) {
+	settings := domain.DefaultNotificationSettings(userID)
+	err := s.db.Where("user_id = ?", userID).First(&settings).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return settings, err
+	}
+	return settings, nil
+}
+
+// GetNotificationPreferences returns the user's settings and a preference for every
+// configurable notification type, filling in defaults for types never changed
+func (s *DBService) GetNotificationPreferences(userID uint) (*domain.NotificationSettings, []domain.NotificationPreference, error) {
+	settings, err := s.loadSettings(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var stored []domain.NotificationPreference
+	if err := s.db.Where("user_id = ?", userID).Find(&stored).Error; err != nil {
+		return nil, nil, err
+	}
+	byType := make(map[string]domain.NotificationPreference, len(stored))
+	for _, preference := range stored {
+		byType[preference.NotificationType] = preference
+	}
+
+	preferences := make([]domain.NotificationPreference, 0, len(domain.ConfigurableNotificationTypes))
+	for _, notificationType := range domain.ConfigurableNotificationTypes {
+		preference, ok := byType[notificationType]
+		if !ok {
+			preference = domain.DefaultNotificationPreference(userID, notificationType)
+		}
+		preferences = append(preferences, preference)
+	}
+	return &settings, preferences, nil
+}
+
+// UpdateNotificationPreferences saves the user's settings, when given, and any
+// per-type preferences
+func (s *DBService) UpdateNotificationPreferences(userID uint, settings *domain.NotificationSettings, preferences []domain.NotificationPreference) error {
+	if settings != nil {
+		if _, err := time.LoadLocation(settings.Timezone); err != nil || settings.Timezone == "" {
+			return fmt.Errorf("%w: %q", ErrInvalidTimezone, settings.Timezone)
+		}
+		if _, err := parseClock(settings.QuietHoursStart); err != nil {
+			return err
+		}
+		if _, err := parseClock(settings.QuietHoursEnd); err != nil {
+			return err
+		}
+		switch settings.DigestFrequency {
+		case "":
+			settings.DigestFrequency = domain.DigestFrequencyDaily
+		case domain.DigestFrequencyNone, domain.DigestFrequencyDaily, domain.DigestFrequencyWeekly:
+		default:
+			return ErrInvalidDigestFrequency
+		}
+		settings.UserID = userID
+	}
+	for i := range preferences {
+		if !isConfigurableType(preferences[i].NotificationType) {
+			return fmt.Errorf("%w: %q", ErrUnknownNotificationType, preferences[i].NotificationType)
+		}
+		preferences[i].ID = 0
+		preferences[i].UserID = userID
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if settings != nil {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"timezone", "quiet_hours_enabled", "quiet_hours_start", "quiet_hours_end", "digest_frequency", "updated_at"}),
+			}).Create(settings).Error
+			if err != nil {
+				return err
+			}
+		}
+		for i := range preferences {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "notification_type"}},
+				DoUpdates: clause.AssignmentColumns([]string{"in_app", "web_socket", "email", "push", "digest_only", "updated_at"}),
+			}).Create(&preferences[i]).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// isSensitiveProperty reports whether the property is in a sensitive item category
+func (s *DBService) isSensitiveProperty(property *domain.Property) bool {
+	if property == nil || property.Category == nil {
+		return false
+	}
+	var count int64
+	err := s.db.Model(&domain.PropertyCategory{}).
+		Where("code = ? AND is_sensitive = ?", *property.Category, true).
+		Count(&count).Error
+	if err != nil {
+		// Err on the side of delivering
+		log.Printf("WARNING: Failed to check whether property %d is sensitive: %v", property.ID, err)
+		return true
+	}
+	return count > 0
+}
+
+// inQuietHours reports whether now falls within the user's quiet hours in their own
+// timezone. A window whose end is before its start runs over midnight.
+func inQuietHours(settings domain.NotificationSettings, now time.Time) bool {
+	if !settings.QuietHoursEnabled {
+		return false
+	}
+	start, err := parseClock(settings.QuietHoursStart)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(settings.QuietHoursEnd)
+	if err != nil || start == end {
+		return false
+	}
+
+	location, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	local := now.In(location)
+	minute := local.Hour()*60 + local.Minute()
+
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// parseClock converts HH:MM to minutes after midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidQuietHours, value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func isConfigurableType(notificationType string) bool {
+	for _, configurable := range domain.ConfigurableNotificationTypes {
+		if configurable == notificationType {
+			return true
+		}
+	}
+	return false
+}
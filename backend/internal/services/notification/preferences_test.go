@@ -0,0 +1,61 @@
+package notification
+
+import (
+	"testing"
+	"time"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+)
+
+func TestRouteFromPreference(t *testing.T) {
+	settings := domain.DefaultNotificationSettings(1)
+	settings.QuietHoursEnabled = true
+	settings.QuietHoursStart = "22:00"
+	settings.QuietHoursEnd = "06:00"
+	day := time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC)
+	night := time.Date(2026, 3, 2, 23, 0, 0, 0, time.UTC)
+
+	emailed := domain.DefaultNotificationPreference(1, domain.NotificationTypeTransferUpdate)
+	emailed.Email = true
+	digestOnly := emailed
+	digestOnly.DigestOnly = true
+	listOnly := domain.DefaultNotificationPreference(1, domain.NotificationTypeTransferUpdate)
+	listOnly.WebSocket = false
+	listOnly.Push = false
+
+	tests := []struct {
+		name       string
+		preference domain.NotificationPreference
+		now        time.Time
+		want       route
+	}{
+		{"defaults do not email", domain.DefaultNotificationPreference(1, domain.NotificationTypeTransferUpdate), day, route{store: true, live: true, push: true}},
+		{"email chosen", emailed, day, route{store: true, live: true, push: true, email: true}},
+		{"quiet hours hold email with push", emailed, night, route{store: true}},
+		{"digest only waits for the digest", digestOnly, day, route{store: true}},
+		{"list only", listOnly, day, route{store: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := routeFromPreference(tt.preference, settings, tt.now); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDigestTypes(t *testing.T) {
+	emailed := domain.DefaultNotificationPreference(1, domain.NotificationTypeTransferCreated)
+	emailed.Email = true
+	digested := domain.DefaultNotificationPreference(1, domain.NotificationTypeInventoryReminder)
+	digested.DigestOnly = true
+	inApp := domain.DefaultNotificationPreference(1, domain.NotificationTypePropertyUpdate)
+
+	got := digestTypes([]domain.NotificationPreference{emailed, inApp, digested})
+	if len(got) != 2 || got[0] != domain.NotificationTypeTransferCreated || got[1] != domain.NotificationTypeInventoryReminder {
+		t.Errorf("got %v, want the emailed and digest-only types", got)
+	}
+	if got := digestTypes(nil); len(got) != 0 {
+		t.Errorf("defaults opt into %v, want nothing", got)
+	}
+}
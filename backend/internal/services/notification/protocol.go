@@ -0,0 +1,211 @@
+package notification
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Commands a client may send over its WebSocket connection. Every command with an
+// id is answered with an ack or error event carrying the same id.
+const (
+	CommandSubscribe   = "subscribe"   // Start receiving events for the given topics
+	CommandUnsubscribe = "unsubscribe" // Stop receiving events for the given topics
+	CommandPing        = "ping"        // Heartbeat; answered with a pong
+)
+
+// ClientCommand is a message from the client
+type ClientCommand struct {
+	Type   string   `json:"type"`
+	ID     string   `json:"id,omitempty"`
+	Topics []string `json:"topics,omitempty"`
+}
+
+// handleMessage processes one message read from the connection
+func (c *Client) handleMessage(raw []byte) {
+	// Older clients send a bare "ping" text frame
+	if string(raw) == "ping" {
+		c.enqueue([]byte("pong"))
+		return
+	}
+
+	var command ClientCommand
+	if err := json.Unmarshal(raw, &command); err != nil {
+		c.reply(commandError("", ErrMalformedCommand))
+		return
+	}
+
+	switch command.Type {
+	case CommandPing:
+		c.reply(Event{
+			Type:      EventTypePong,
+			Data:      PongData{ID: command.ID},
+			Timestamp: time.Now(),
+		})
+	case CommandSubscribe:
+		if err := c.subscribe(command.Topics); err != nil {
+			c.reply(commandError(command.ID, err))
+			return
+		}
+		c.reply(commandAck(command.ID, c.Topics()))
+	case CommandUnsubscribe:
+		c.unsubscribe(command.Topics)
+		c.reply(commandAck(command.ID, c.Topics()))
+	default:
+		c.reply(commandError(command.ID, fmt.Errorf("%w: %q", ErrUnknownCommand, command.Type)))
+	}
+}
+
+// subscribe adds topics after checking the user may watch every one of them. If
+// any topic is refused none are added.
+func (c *Client) subscribe(names []string) error {
+	if len(names) == 0 {
+		return fmt.Errorf("%w: no topics given", ErrMalformedCommand)
+	}
+
+	authorizer := c.Hub.topicAuthorizer()
+	if authorizer == nil {
+		return ErrNoTopicAuthorizer
+	}
+
+	topics := make([]string, 0, len(names))
+	for _, name := range names {
+		topic, err := ParseTopic(name)
+		if err != nil {
+			return err
+		}
+		allowed, err := authorizer.CanSubscribe(c.UserID, topic)
+		if err != nil {
+			return fmt.Errorf("failed to check access to %s: %w", topic, err)
+		}
+		if !allowed {
+			return fmt.Errorf("%w: %s", ErrTopicForbidden, topic)
+		}
+		topics = append(topics, topic.String())
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.topics == nil {
+		c.topics = make(map[string]struct{})
+	}
+	added := 0
+	for _, topic := range topics {
+		if _, ok := c.topics[topic]; !ok {
+			added++
+		}
+	}
+	if len(c.topics)+added > maxTopicsPerClient {
+		return fmt.Errorf("%w: limit is %d", ErrTooManyTopics, maxTopicsPerClient)
+	}
+	for _, topic := range topics {
+		c.topics[topic] = struct{}{}
+	}
+	return nil
+}
+
+func (c *Client) unsubscribe(names []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, name := range names {
+		if topic, err := ParseTopic(name); err == nil {
+			delete(c.topics, topic.String())
+		}
+	}
+}
+
+// subscribedTo reports whether the connection is watching the topic
+func (c *Client) subscribedTo(topic string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.topics[topic]
+	return ok
+}
+
+// Topics returns the topics the connection is watching, sorted
+func (c *Client) Topics() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	topics := make([]string, 0, len(c.topics))
+	for topic := range c.topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// reply queues a response to a command. A client too slow to take it is dropped by
+// the hub on its next delivery.
+func (c *Client) reply(event Event) {
+	event.UserID = c.UserID
+	c.enqueue(event.ToJSON())
+}
+
+func commandAck(id string, topics []string) Event {
+	return Event{
+		Type:      EventTypeAck,
+		Data:      CommandAckData{ID: id, Topics: topics},
+		Timestamp: time.Now(),
+	}
+}
+
+func commandError(id string, err error) Event {
+	code := "internal"
+	switch {
+	case errors.Is(err, ErrMalformedCommand), errors.Is(err, ErrUnknownCommand), errors.Is(err, ErrInvalidTopic):
+		code = "bad_request"
+	case errors.Is(err, ErrTopicForbidden):
+		code = "forbidden"
+	case errors.Is(err, ErrTooManyTopics):
+		code = "limit_exceeded"
+	case errors.Is(err, ErrNoTopicAuthorizer):
+		code = "unavailable"
+	}
+	return Event{
+		Type:      EventTypeError,
+		Data:      CommandErrorData{ID: id, Code: code, Message: err.Error()},
+		Timestamp: time.Now(),
+	}
+}
+
+// SetTopicAuthorizer sets what decides which topics users may subscribe to. Until
+// one is set, subscriptions are refused.
+func (h *Hub) SetTopicAuthorizer(authorizer TopicAuthorizer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.authorizer = authorizer
+}
+
+func (h *Hub) topicAuthorizer() TopicAuthorizer {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.authorizer
+}
+
+// PublishTopic sends an event to every connection subscribed to the topic, on any
+// node. Topic events are not persisted or sequenced, so a client that was
+// disconnected re-fetches current state after subscribing again.
+func (h *Hub) PublishTopic(topic string, event Event) {
+	event.Topic = topic
+	event.UserID = 0
+	event.Seq = 0
+	h.sendTopicLocal(event)
+	h.publish(nil, event)
+}
+
+// sendTopicLocal delivers a topic event to subscribed connections on this node
+func (h *Hub) sendTopicLocal(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, connections := range h.clients {
+		for client := range connections {
+			if !client.subscribedTo(event.Topic) {
+				continue
+			}
+			h.deliverLocked(client, event)
+		}
+	}
+}
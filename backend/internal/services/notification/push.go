@@ -0,0 +1,158 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"github.com/toole-brendan/handreceipt-go/internal/services/jobs"
+)
+
+var (
+	ErrInvalidDeviceToken  = errors.New("device token is no longer valid")
+	ErrUnsupportedPlatform = errors.New("push platform must be ios or android")
+)
+
+const (
+	defaultPushAttempts = 3
+	defaultPushBackoff  = time.Second
+	maxPushBackoff      = 30 * time.Second
+)
+
+// PushMessage is the platform-neutral content of a push notification
+type PushMessage struct {
+	Title    string
+	Body     string
+	Data     map[string]string // Delivered to the app alongside the alert
+	Category string            // Notification type, used by the apps to route a tap
+	Urgent   bool              // Deliver immediately even if the device is saving power
+}
+
+// PushProvider delivers a message to one device through APNs or FCM
+type PushProvider interface {
+	Send(ctx context.Context, token string, message PushMessage) error
+}
+
+// PushError is a delivery failure reported by a push service
+type PushError struct {
+	StatusCode int
+	Reason     string
+	Retryable  bool          // The same request may succeed if tried again
+	RetryAfter time.Duration // From the Retry-After header, when the service sent one
+}
+
+func (e *PushError) Error() string {
+	return fmt.Sprintf("push service returned %d: %s", e.StatusCode, e.Reason)
+}
+
+// newPushError describes a failed response. Throttling and server errors are retryable.
+func newPushError(resp *http.Response, reason string) *PushError {
+	return &PushError{
+		StatusCode: resp.StatusCode,
+		Reason:     reason,
+		Retryable:  resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// PushSender delivers push notifications to a user's registered devices, retrying
+// temporary failures with exponential backoff
+type PushSender struct {
+	providers   map[string]PushProvider
+	maxAttempts int
+	backoff     jobs.Backoff
+}
+
+// NewPushSender creates a sender with no platforms configured. maxAttempts and
+// backoff fall back to defaults when zero.
+func NewPushSender(maxAttempts int, backoff time.Duration) *PushSender {
+	if maxAttempts <= 0 {
+		maxAttempts = defaultPushAttempts
+	}
+	if backoff <= 0 {
+		backoff = defaultPushBackoff
+	}
+	return &PushSender{
+		providers:   make(map[string]PushProvider),
+		maxAttempts: maxAttempts,
+		backoff:     jobs.Backoff{Base: backoff, Max: maxPushBackoff},
+	}
+}
+
+// SetProvider configures delivery for a platform
+func (p *PushSender) SetProvider(platform string, provider PushProvider) {
+	p.providers[platform] = provider
+}
+
+// Supports reports whether pushes can be delivered to the platform
+func (p *PushSender) Supports(platform string) bool {
+	_, ok := p.providers[platform]
+	return ok
+}
+
+// Send delivers the message to each device and returns the tokens the push
+// services reported as invalid so the caller can remove them. Other failures are
+// logged; one device failing does not stop delivery to the rest.
+func (p *PushSender) Send(ctx context.Context, devices []domain.DeviceToken, message PushMessage) []string {
+	var invalid []string
+	for _, device := range devices {
+		provider, ok := p.providers[device.Platform]
+		if !ok {
+			continue
+		}
+		err := p.sendWithRetry(ctx, provider, device.Token, message)
+		switch {
+		case errors.Is(err, ErrInvalidDeviceToken):
+			invalid = append(invalid, device.Token)
+		case err != nil:
+			log.Printf("WARNING: Failed to push to %s device %d of user %d: %v", device.Platform, device.ID, device.UserID, err)
+		}
+	}
+	return invalid
+}
+
+func (p *PushSender) sendWithRetry(ctx context.Context, provider PushProvider, token string, message PushMessage) error {
+	var err error
+	for attempt := 0; attempt < p.maxAttempts; attempt++ {
+		err = provider.Send(ctx, token, message)
+		if err == nil || errors.Is(err, ErrInvalidDeviceToken) {
+			return err
+		}
+
+		var pushErr *PushError
+		if errors.As(err, &pushErr) && !pushErr.Retryable {
+			return err
+		}
+		if attempt == p.maxAttempts-1 {
+			break
+		}
+
+		wait := p.backoff.Delay(attempt + 1)
+		if pushErr != nil && pushErr.RetryAfter > wait {
+			wait = pushErr.RetryAfter
+		}
+		if wait > p.backoff.Max {
+			wait = p.backoff.Max
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", p.maxAttempts, err)
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
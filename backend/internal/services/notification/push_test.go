@@ -0,0 +1,131 @@
+package notification
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"github.com/toole-brendan/handreceipt-go/internal/services/notification/pushstub"
+)
+
+// TestPushSenderAgainstStub delivers through both providers to the stub over
+// HTTP/2, retrying transient failures and reporting unregistered tokens
+func TestPushSenderAgainstStub(t *testing.T) {
+	stub := pushstub.New()
+	server := httptest.NewUnstartedServer(stub)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	keyPEM, err := pushstub.GenerateAPNsKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(keyPEM)
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apns, err := NewAPNsProvider(APNsConfig{
+		BaseURL: server.URL,
+		Topic:   "mil.handreceipt.app",
+		KeyID:   "STUBKEY",
+		TeamID:  "STUBTEAM",
+		Key:     parsed.(*ecdsa.PrivateKey),
+	}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := pushstub.GenerateFCMCredentials(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var credentials FCMCredentials
+	if err := json.Unmarshal(raw, &credentials); err != nil {
+		t.Fatal(err)
+	}
+	fcm, err := NewFCMProvider(server.URL, &credentials, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sender := NewPushSender(3, time.Millisecond)
+	sender.SetProvider(domain.DevicePlatformIOS, apns)
+	sender.SetProvider(domain.DevicePlatformAndroid, fcm)
+
+	stub.MarkInvalid("ios-stale")
+	stub.MarkInvalid("android-stale")
+	stub.FailNext("android-flaky", 2)
+	stub.FailNext("ios-down", 10)
+
+	devices := []domain.DeviceToken{
+		{ID: 1, UserID: 7, Platform: domain.DevicePlatformIOS, Token: "ios-ok"},
+		{ID: 2, UserID: 7, Platform: domain.DevicePlatformIOS, Token: "ios-stale"},
+		{ID: 3, UserID: 7, Platform: domain.DevicePlatformIOS, Token: "ios-down"},
+		{ID: 4, UserID: 7, Platform: domain.DevicePlatformAndroid, Token: "android-flaky"},
+		{ID: 5, UserID: 7, Platform: domain.DevicePlatformAndroid, Token: "android-stale"},
+		{ID: 6, UserID: 7, Platform: "windows", Token: "unsupported"},
+	}
+	message := PushMessage{
+		Title:    "Transfer Request",
+		Body:     "You have a new transfer request for M4 Carbine (W123456)",
+		Category: domain.NotificationTypeTransferCreated,
+		Data:     map[string]string{"type": domain.NotificationTypeTransferCreated, "notificationId": "42"},
+		Urgent:   true,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	invalid := sender.Send(ctx, devices, message)
+
+	sort.Strings(invalid)
+	if len(invalid) != 2 || invalid[0] != "android-stale" || invalid[1] != "ios-stale" {
+		t.Errorf("invalid tokens = %v, want [android-stale ios-stale]", invalid)
+	}
+
+	delivered := make(map[string]pushstub.Delivery)
+	for _, delivery := range stub.Deliveries() {
+		delivered[delivery.Token] = delivery
+	}
+	if len(delivered) != 2 {
+		t.Fatalf("delivered to %d devices, want 2: %v", len(delivered), delivered)
+	}
+	ios, ok := delivered["ios-ok"]
+	if !ok {
+		t.Fatal("no delivery to ios-ok")
+	}
+	if ios.Proto != "HTTP/2.0" {
+		t.Errorf("APNs request used %s, want HTTP/2.0", ios.Proto)
+	}
+	if ios.Title != message.Title || ios.Body != message.Body || ios.Data["notificationId"] != "42" {
+		t.Errorf("APNs delivery = %+v", ios)
+	}
+	android, ok := delivered["android-flaky"]
+	if !ok {
+		t.Fatal("no delivery to android-flaky after retries")
+	}
+	if android.Title != message.Title || android.Data["type"] != domain.NotificationTypeTransferCreated {
+		t.Errorf("FCM delivery = %+v", android)
+	}
+
+	if got := stub.Attempts("android-flaky"); got != 3 {
+		t.Errorf("android-flaky attempts = %d, want 3", got)
+	}
+	if got := stub.Attempts("ios-down"); got != 3 {
+		t.Errorf("ios-down attempts = %d, want 3 before giving up", got)
+	}
+	if got := stub.Attempts("ios-stale"); got != 1 {
+		t.Errorf("ios-stale attempts = %d, want 1 since invalid tokens are not retried", got)
+	}
+	if got := stub.Attempts("unsupported"); got != 0 {
+		t.Errorf("unsupported platform attempts = %d, want 0", got)
+	}
+}
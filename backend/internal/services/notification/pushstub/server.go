@@ -0,0 +1,265 @@
+// Package pushstub is a stand-in for APNs and FCM that records pushes instead of
+// delivering them, so push notifications can be exercised without Apple or Google
+// credentials. Point push.apns.base_url and push.fcm.base_url at it and use the
+// generated credentials.
+package pushstub
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Delivery is one push the stub accepted
+type Delivery struct {
+	Platform   string // ios or android
+	Token      string
+	Title      string
+	Body       string
+	Data       map[string]string
+	Proto      string // HTTP protocol the request arrived over
+	ReceivedAt time.Time
+}
+
+// Server imitates the APNs and FCM HTTP APIs and the Google OAuth token endpoint
+type Server struct {
+	// OnDelivery, when set, is called for each accepted push
+	OnDelivery func(Delivery)
+
+	mu         sync.Mutex
+	deliveries []Delivery
+	invalid    map[string]bool
+	failures   map[string]int
+	attempts   map[string]int
+}
+
+// New creates a stub that accepts every push
+func New() *Server {
+	return &Server{
+		invalid:  make(map[string]bool),
+		failures: make(map[string]int),
+		attempts: make(map[string]int),
+	}
+}
+
+// MarkInvalid makes the stub reject the token as unregistered
+func (s *Server) MarkInvalid(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.invalid[token] = true
+}
+
+// FailNext makes the stub answer the next n pushes to the token with 503
+func (s *Server) FailNext(token string, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[token] = n
+}
+
+// Deliveries returns the pushes accepted so far
+func (s *Server) Deliveries() []Delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Delivery(nil), s.deliveries...)
+}
+
+// Attempts returns how many pushes to the token the stub has received, accepted or not
+func (s *Server) Attempts(token string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts[token]
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/token":
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"access_token": "stub-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/3/device/"):
+		s.serveAPNs(w, r, strings.TrimPrefix(r.URL.Path, "/3/device/"))
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/v1/projects/") && strings.HasSuffix(r.URL.Path, "/messages:send"):
+		s.serveFCM(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) serveAPNs(w http.ResponseWriter, r *http.Request, token string) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "bearer ") {
+		writeJSON(w, http.StatusForbidden, map[string]string{"reason": "MissingProviderToken"})
+		return
+	}
+	if r.Header.Get("apns-topic") == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"reason": "MissingTopic"})
+		return
+	}
+
+	var payload struct {
+		APS struct {
+			Alert struct {
+				Title string `json:"title"`
+				Body  string `json:"body"`
+			} `json:"alert"`
+		} `json:"aps"`
+		Data map[string]string `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"reason": "PayloadEmpty"})
+		return
+	}
+
+	switch s.outcome(token) {
+	case http.StatusGone:
+		writeJSON(w, http.StatusGone, map[string]interface{}{"reason": "Unregistered", "timestamp": time.Now().UnixMilli()})
+	case http.StatusServiceUnavailable:
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"reason": "ServiceUnavailable"})
+	default:
+		s.record(Delivery{
+			Platform: "ios",
+			Token:    token,
+			Title:    payload.APS.Alert.Title,
+			Body:     payload.APS.Alert.Body,
+			Data:     payload.Data,
+			Proto:    r.Proto,
+		})
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (s *Server) serveFCM(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		writeFCMError(w, http.StatusUnauthorized, "UNAUTHENTICATED", "")
+		return
+	}
+
+	var request struct {
+		Message struct {
+			Token        string `json:"token"`
+			Notification struct {
+				Title string `json:"title"`
+				Body  string `json:"body"`
+			} `json:"notification"`
+			Data map[string]string `json:"data"`
+		} `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Message.Token == "" {
+		writeFCMError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "INVALID_ARGUMENT")
+		return
+	}
+	token := request.Message.Token
+
+	switch s.outcome(token) {
+	case http.StatusGone:
+		writeFCMError(w, http.StatusNotFound, "NOT_FOUND", "UNREGISTERED")
+	case http.StatusServiceUnavailable:
+		writeFCMError(w, http.StatusServiceUnavailable, "UNAVAILABLE", "UNAVAILABLE")
+	default:
+		id := s.record(Delivery{
+			Platform: "android",
+			Token:    token,
+			Title:    request.Message.Notification.Title,
+			Body:     request.Message.Notification.Body,
+			Data:     request.Message.Data,
+			Proto:    r.Proto,
+		})
+		writeJSON(w, http.StatusOK, map[string]string{
+			"name": fmt.Sprintf("%s/%d", strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/"), ":send"), id),
+		})
+	}
+}
+
+// outcome counts the attempt and decides how to answer it: 410 for invalid tokens,
+// 503 while failures are pending, otherwise 200
+func (s *Server) outcome(token string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts[token]++
+	if s.invalid[token] {
+		return http.StatusGone
+	}
+	if s.failures[token] > 0 {
+		s.failures[token]--
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
+
+func (s *Server) record(delivery Delivery) int {
+	delivery.ReceivedAt = time.Now()
+	s.mu.Lock()
+	s.deliveries = append(s.deliveries, delivery)
+	id := len(s.deliveries)
+	s.mu.Unlock()
+
+	if s.OnDelivery != nil {
+		s.OnDelivery(delivery)
+	}
+	return id
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeFCMError(w http.ResponseWriter, status int, grpcStatus, errorCode string) {
+	body := map[string]interface{}{
+		"code":    status,
+		"status":  grpcStatus,
+		"message": http.StatusText(status),
+	}
+	if errorCode != "" {
+		body["details"] = []map[string]string{{
+			"@type":     "type.googleapis.com/google.firebase.fcm.v1.FcmError",
+			"errorCode": errorCode,
+		}}
+	}
+	writeJSON(w, status, map[string]interface{}{"error": body})
+}
+
+// GenerateAPNsKey returns a throwaway .p8 signing key
+func GenerateAPNsKey() ([]byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// GenerateFCMCredentials returns a throwaway service account key file whose token
+// requests go to the stub at baseURL
+func GenerateFCMCredentials(baseURL string) ([]byte, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(map[string]string{
+		"type":         "service_account",
+		"project_id":   "handreceipt-stub",
+		"client_email": "push@handreceipt-stub.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    strings.TrimRight(baseURL, "/") + "/token",
+	}, "", "  ")
+}
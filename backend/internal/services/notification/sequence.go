@@ -0,0 +1,136 @@
+package notification
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrClientClosed  = errors.New("websocket client is closed")
+	ErrClientTooSlow = errors.New("websocket client is not reading fast enough")
+)
+
+// replayBatchSize is how many missed events are loaded at a time during replay
+const replayBatchSize = 200
+
+// EventStore gives the hub access to persisted events so reconnecting clients can
+// replay the ones they missed
+type EventStore interface {
+	// EventsSince returns up to limit events for the user with a sequence number
+	// greater than afterSeq, in sequence order
+	EventsSince(userID int, afterSeq int64, limit int) ([]Event, error)
+}
+
+// Persist saves a notification under the next sequence number for its user. When an
+// event is given it is stamped with the same sequence and stored with the row so it
+// can be replayed exactly as pushed. db may be a transaction the caller is already in.
+func Persist(db *gorm.DB, notification *domain.Notification, event *Event) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		seq, err := nextSequence(tx, notification.UserID)
+		if err != nil {
+			return err
+		}
+
+		notification.Sequence = seq
+		if event != nil {
+			event.Seq = seq
+			event.UserID = int(notification.UserID)
+			notification.Event = event.ToJSON()
+		}
+		return tx.Create(notification).Error
+	})
+}
+
+// nextSequence allocates the user's next sequence number. The upsert takes a row
+// lock, so concurrent writers for the same user are numbered one after the other.
+func nextSequence(tx *gorm.DB, userID uint) (int64, error) {
+	var seq int64
+	err := tx.Raw(`INSERT INTO notification_sequences (user_id, last_sequence) VALUES (?, 1)
+		ON CONFLICT (user_id) DO UPDATE SET last_sequence = notification_sequences.last_sequence + 1
+		RETURNING last_sequence`, userID).Scan(&seq).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to allocate notification sequence for user %d: %w", userID, err)
+	}
+	return seq, nil
+}
+
+// eventFromNotification rebuilds the pushed event for a stored notification. Rows
+// written without an event, such as worker reminders, replay as general notifications.
+func eventFromNotification(notification domain.Notification) Event {
+	if len(notification.Event) > 0 {
+		var event Event
+		if err := json.Unmarshal(notification.Event, &event); err == nil {
+			event.Seq = notification.Sequence
+			return event
+		}
+	}
+	return Event{
+		Type:      EventTypeNotification,
+		Data:      notification,
+		Timestamp: notification.CreatedAt,
+		UserID:    int(notification.UserID),
+		Seq:       notification.Sequence,
+	}
+}
+
+// EventsSince implements EventStore from the notifications table
+func (s *DBService) EventsSince(userID int, afterSeq int64, limit int) ([]Event, error) {
+	var notifications []domain.Notification
+	err := s.db.Where("user_id = ? AND sequence > ?", userID, afterSeq).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Order("sequence ASC").
+		Limit(limit).
+		Find(&notifications).Error
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]Event, 0, len(notifications))
+	for _, notification := range notifications {
+		events = append(events, eventFromNotification(notification))
+	}
+	return events, nil
+}
+
+// deliver saves a notification and sends it to the recipient's connections, mobile
+// devices and email as their preferences allow. Pushed notifications are stored
+// under a sequence number so they can be replayed; ones the recipient chose not to
+// receive live are stored without one so a reconnect does not push them after all.
+// Mandatory notifications bypass preferences and quiet hours.
+func (s *DBService) deliver(notification *domain.Notification, event Event, mandatory bool) error {
+	r := s.routeFor(notification.UserID, notification.Type, mandatory, time.Now())
+
+	switch {
+	case r.store && r.live:
+		if err := Persist(s.db, notification, &event); err != nil {
+			return err
+		}
+		s.hub.SendToUser(int(notification.UserID), event)
+	case r.store:
+		if err := s.db.Create(notification).Error; err != nil {
+			return err
+		}
+	case r.live:
+		s.echo(int(notification.UserID), event)
+	}
+	if r.push {
+		s.sendPush(notification)
+	}
+	if r.email {
+		s.sendEmail(notification)
+	}
+	return nil
+}
+
+// echo pushes an unsequenced copy of an event that has no stored notification, such
+// as the copy shown to the user whose own action caused it.
+func (s *DBService) echo(userID int, event Event) {
+	event.UserID = userID
+	event.Seq = 0
+	s.hub.SendToUser(userID, event)
+}
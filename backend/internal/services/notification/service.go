@@ -18,33 +18,19 @@ func NewService(hub *Hub) *Service {
 
 // NotifyTransferUpdate sends a transfer update notification
 func (s *Service) NotifyTransferUpdate(transferID, fromUserID, toUserID int, status, serialNumber, itemName string) {
-	event := Event{
-		Type: EventTypeTransferUpdate,
-		Data: TransferUpdateData{
-			TransferID:   transferID,
-			FromUserID:   fromUserID,
-			ToUserID:     toUserID,
-			Status:       status,
-			SerialNumber: serialNumber,
-			ItemName:     itemName,
-		},
-		Timestamp: time.Now(),
-	}
-	s.hub.BroadcastEvent(event)
+	s.hub.BroadcastEvent(transferEvent(EventTypeTransferUpdate, transferID, fromUserID, toUserID, status, serialNumber, itemName))
 }
 
 // NotifyTransferCreated sends a new transfer notification
 func (s *Service) NotifyTransferCreated(transferID, fromUserID, toUserID int, serialNumber, itemName string) {
+	s.hub.BroadcastEvent(transferEvent(EventTypeTransferCreated, transferID, fromUserID, toUserID, "pending", serialNumber, itemName))
+}
+
+// NotifyTransferComment sends a new transfer comment to everyone following the transfer
+func (s *Service) NotifyTransferComment(data TransferCommentData) {
 	event := Event{
-		Type: EventTypeTransferCreated,
-		Data: TransferUpdateData{
-			TransferID:   transferID,
-			FromUserID:   fromUserID,
-			ToUserID:     toUserID,
-			Status:       "pending",
-			SerialNumber: serialNumber,
-			ItemName:     itemName,
-		},
+		Type:      EventTypeTransferComment,
+		Data:      data,
 		Timestamp: time.Now(),
 	}
 	s.hub.BroadcastEvent(event)
@@ -68,34 +54,12 @@ func (s *Service) NotifyPropertyUpdate(propertyID, ownerID int, serialNumber, st
 
 // NotifyConnectionRequest sends a connection request notification
 func (s *Service) NotifyConnectionRequest(connectionID, fromUserID int, fromUserName string, targetUserID int) {
-	event := Event{
-		Type: EventTypeConnectionRequest,
-		Data: ConnectionRequestData{
-			ConnectionID: connectionID,
-			FromUserID:   fromUserID,
-			FromUserName: fromUserName,
-			TargetUserID: targetUserID,
-			Status:       "pending",
-		},
-		Timestamp: time.Now(),
-	}
-	s.hub.BroadcastEvent(event)
+	s.hub.BroadcastEvent(connectionEvent(EventTypeConnectionRequest, connectionID, fromUserID, fromUserName, targetUserID, "pending"))
 }
 
 // NotifyConnectionAccepted sends a connection accepted notification
 func (s *Service) NotifyConnectionAccepted(connectionID, fromUserID int, fromUserName string, targetUserID int) {
-	event := Event{
-		Type: EventTypeConnectionAccepted,
-		Data: ConnectionRequestData{
-			ConnectionID: connectionID,
-			FromUserID:   fromUserID,
-			FromUserName: fromUserName,
-			TargetUserID: targetUserID,
-			Status:       "accepted",
-		},
-		Timestamp: time.Now(),
-	}
-	s.hub.BroadcastEvent(event)
+	s.hub.BroadcastEvent(connectionEvent(EventTypeConnectionAccepted, connectionID, fromUserID, fromUserName, targetUserID, "accepted"))
 }
 
 // NotifyDocumentReceived sends a document received notification
@@ -133,4 +97,43 @@ func (s *Service) IsUserOnline(userID int) bool {
 // GetOnlineUsers returns a list of online user IDs
 func (s *Service) GetOnlineUsers() []int {
 	return s.hub.GetConnectedUsers()
-}
\ No newline at end of file
+}
+
+// IsDeviceOnline checks if a user is connected from a particular device
+func (s *Service) IsDeviceOnline(userID int, deviceID string) bool {
+	return s.hub.IsDeviceConnected(userID, deviceID)
+}
+
+// GetOnlineDevices returns every live connection for a user
+func (s *Service) GetOnlineDevices(userID int) []DevicePresence {
+	return s.hub.GetUserDevices(userID)
+}
+
+func transferEvent(eventType EventType, transferID, fromUserID, toUserID int, status, serialNumber, itemName string) Event {
+	return Event{
+		Type: eventType,
+		Data: TransferUpdateData{
+			TransferID:   transferID,
+			FromUserID:   fromUserID,
+			ToUserID:     toUserID,
+			Status:       status,
+			SerialNumber: serialNumber,
+			ItemName:     itemName,
+		},
+		Timestamp: time.Now(),
+	}
+}
+
+func connectionEvent(eventType EventType, connectionID, fromUserID int, fromUserName string, targetUserID int, status string) Event {
+	return Event{
+		Type: eventType,
+		Data: ConnectionRequestData{
+			ConnectionID: connectionID,
+			FromUserID:   fromUserID,
+			FromUserName: fromUserName,
+			TargetUserID: targetUserID,
+			Status:       status,
+		},
+		Timestamp: time.Now(),
+	}
+}
@@ -0,0 +1,151 @@
+package notification
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Reasons sent in the close frame when the hub ends a connection
+const (
+	closeReasonSessionRevoked  = "session revoked"
+	closeReasonSessionExpired  = "session expired"
+	closeReasonConnectionLimit = "connection limit reached"
+)
+
+// SessionValidator reports which sign-in sessions have been logged out
+type SessionValidator interface {
+	RevokedSessions(sessionIDs []string) (map[string]bool, error)
+}
+
+// SetSessionValidator sets what the hub asks, while watching sessions, whether the
+// sessions behind open connections are still valid
+func (h *Hub) SetSessionValidator(validator SessionValidator) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sessions = validator
+}
+
+// SetMaxConnectionsPerUser caps how many connections one user may hold on this node.
+// New connections over the cap are refused (see AtConnectionLimit); only when two
+// race past that check is the oldest closed to make room. Zero removes the cap.
+func (h *Hub) SetMaxConnectionsPerUser(max int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.maxPerUser = max
+}
+
+// AtConnectionLimit reports whether a new connection from the device would exceed
+// the user's limit. A device reconnecting replaces its old connection, so it never
+// counts against the limit.
+func (h *Hub) AtConnectionLimit(userID int, deviceID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.maxPerUser <= 0 {
+		return false
+	}
+	connections := h.clients[userID]
+	for client := range connections {
+		if deviceID != "" && client.DeviceID == deviceID {
+			return false
+		}
+	}
+	return len(connections) >= h.maxPerUser
+}
+
+// WatchSessions re-validates every open connection each interval until ctx is
+// cancelled, closing those whose credential has expired or whose session has been
+// logged out
+func (h *Hub) WatchSessions(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.revalidateSessions(time.Now())
+		}
+	}
+}
+
+func (h *Hub) revalidateSessions(now time.Time) {
+	h.mu.RLock()
+	validator := h.sessions
+	var clients []*Client
+	sessionIDs := make(map[string]struct{})
+	for _, connections := range h.clients {
+		for client := range connections {
+			clients = append(clients, client)
+			if client.AuthSession != "" {
+				sessionIDs[client.AuthSession] = struct{}{}
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	var revoked map[string]bool
+	if validator != nil && len(sessionIDs) > 0 {
+		ids := make([]string, 0, len(sessionIDs))
+		for id := range sessionIDs {
+			ids = append(ids, id)
+		}
+		var err error
+		revoked, err = validator.RevokedSessions(ids)
+		if err != nil {
+			// Leave connections open rather than dropping everyone on a database blip
+			log.Printf("WARNING: Failed to re-validate WebSocket sessions: %v", err)
+		}
+	}
+
+	for _, client := range clients {
+		switch {
+		case revoked[client.AuthSession]:
+			h.closeClient(client, closeReasonSessionRevoked)
+		case !client.AuthExpiry.IsZero() && now.After(client.AuthExpiry):
+			h.closeClient(client, closeReasonSessionExpired)
+		}
+	}
+}
+
+// closeClient ends a connection, telling the client why in the close frame
+func (h *Hub) closeClient(client *Client, reason string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[client.UserID][client]; !ok {
+		return
+	}
+	client.setCloseReason(websocket.ClosePolicyViolation, reason)
+	h.removeClientLocked(client)
+	log.Printf("Closed connection for user %d on device %s: %s", client.UserID, client.DeviceID, reason)
+}
+
+// oldestClient returns the connection that has been open longest
+func oldestClient(connections map[*Client]struct{}) *Client {
+	var oldest *Client
+	for client := range connections {
+		if oldest == nil || client.ConnectedAt.Before(oldest.ConnectedAt) {
+			oldest = client
+		}
+	}
+	return oldest
+}
+
+// setCloseReason records the close frame to send once the send channel closes
+func (c *Client) setCloseReason(code int, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closeCode = code
+	c.closeReason = reason
+}
+
+func (c *Client) closeMessage() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closeCode == 0 {
+		return []byte{}
+	}
+	return websocket.FormatCloseMessage(c.closeCode, c.closeReason)
+}
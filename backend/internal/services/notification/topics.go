@@ -0,0 +1,130 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+)
+
+var (
+	ErrInvalidTopic      = errors.New("invalid topic")
+	ErrTopicForbidden    = errors.New("not allowed to subscribe to topic")
+	ErrTooManyTopics     = errors.New("too many topic subscriptions")
+	ErrUnknownCommand    = errors.New("unknown command")
+	ErrMalformedCommand  = errors.New("malformed command")
+	ErrNoTopicAuthorizer = errors.New("topic subscriptions are not available")
+)
+
+// Topic kinds a client can subscribe to
+const (
+	TopicKindProperty = "property" // property:<property id>
+	TopicKindUnit     = "unit"     // unit:<unit name>
+	TopicKindImport   = "import"   // import:<DA 2062 import id>
+	TopicKindLedger   = "ledger"   // ledger, the tail of the audit ledger
+)
+
+// maxTopicsPerClient bounds how many topics one connection may watch
+const maxTopicsPerClient = 50
+
+// Topic is a parsed subscription topic
+type Topic struct {
+	Kind string
+	ID   string // Empty for the ledger
+}
+
+func (t Topic) String() string {
+	if t.ID == "" {
+		return t.Kind
+	}
+	return t.Kind + ":" + t.ID
+}
+
+// ParseTopic validates a topic name such as "property:42" or "ledger"
+func ParseTopic(name string) (Topic, error) {
+	kind, id, _ := strings.Cut(strings.TrimSpace(name), ":")
+	switch kind {
+	case TopicKindLedger:
+		if id != "" {
+			return Topic{}, fmt.Errorf("%w: %q", ErrInvalidTopic, name)
+		}
+		return Topic{Kind: kind}, nil
+	case TopicKindProperty, TopicKindImport:
+		if n, err := strconv.ParseUint(id, 10, 64); err != nil || n == 0 {
+			return Topic{}, fmt.Errorf("%w: %q", ErrInvalidTopic, name)
+		}
+		return Topic{Kind: kind, ID: id}, nil
+	case TopicKindUnit:
+		if id == "" {
+			return Topic{}, fmt.Errorf("%w: %q", ErrInvalidTopic, name)
+		}
+		return Topic{Kind: kind, ID: id}, nil
+	}
+	return Topic{}, fmt.Errorf("%w: %q", ErrInvalidTopic, name)
+}
+
+// PropertyTopic is the topic for events about one property
+func PropertyTopic(propertyID uint) string {
+	return fmt.Sprintf("%s:%d", TopicKindProperty, propertyID)
+}
+
+// UnitTopic is the topic for transfers into or out of a unit
+func UnitTopic(unit string) string {
+	return TopicKindUnit + ":" + unit
+}
+
+// ImportTopic is the topic for progress of one DA 2062 import
+func ImportTopic(importID int64) string {
+	return fmt.Sprintf("%s:%d", TopicKindImport, importID)
+}
+
+// TopicAuthorizer decides whether a user may watch a topic
+type TopicAuthorizer interface {
+	CanSubscribe(userID int, topic Topic) (bool, error)
+}
+
+// CanSubscribe implements TopicAuthorizer. Users may watch property assigned to
+// them or that they are a party to a transfer of, their own unit, imports they
+// started, and the ledger.
+func (s *DBService) CanSubscribe(userID int, topic Topic) (bool, error) {
+	var count int64
+	switch topic.Kind {
+	case TopicKindLedger:
+		return true, nil
+	case TopicKindProperty:
+		err := s.db.Table("properties").
+			Where("id = ?", topic.ID).
+			Where("assigned_to_user_id = ? OR EXISTS (SELECT 1 FROM transfers t WHERE t.property_id = properties.id AND (t.from_user_id = ? OR t.to_user_id = ?))", userID, userID, userID).
+			Count(&count).Error
+		return count > 0, err
+	case TopicKindUnit:
+		err := s.db.Table("users").Where("id = ? AND unit = ?", userID, topic.ID).Count(&count).Error
+		return count > 0, err
+	case TopicKindImport:
+		err := s.db.Table("da2062_imports").Where("id = ? AND imported_by_user_id = ?", topic.ID, userID).Count(&count).Error
+		return count > 0, err
+	}
+	return false, nil
+}
+
+// publishTransferTopics shares a transfer event with watchers of the property and of
+// both parties' units
+func (s *DBService) publishTransferTopics(transfer *domain.Transfer, event Event) {
+	s.hub.PublishTopic(PropertyTopic(transfer.PropertyID), event)
+
+	var units []string
+	err := s.db.Model(&domain.User{}).
+		Where("id IN ? AND unit <> ''", []uint{transfer.FromUserID, transfer.ToUserID}).
+		Distinct("unit").
+		Pluck("unit", &units).Error
+	if err != nil {
+		log.Printf("WARNING: Failed to look up units for transfer %d: %v", transfer.ID, err)
+		return
+	}
+	for _, unit := range units {
+		s.hub.PublishTopic(UnitTopic(unit), event)
+	}
+}
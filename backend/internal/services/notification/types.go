@@ -11,11 +11,20 @@ type EventType string
 const (
 	EventTypeTransferUpdate     EventType = "transfer:update"
 	EventTypeTransferCreated    EventType = "transfer:created"
+	EventTypeTransferComment    EventType = "transfer:comment"
 	EventTypePropertyUpdate     EventType = "property:update"
 	EventTypeConnectionRequest  EventType = "connection:request"
 	EventTypeConnectionAccepted EventType = "connection:accepted"
 	EventTypeDocumentReceived   EventType = "document:received"
 	EventTypeNotification       EventType = "notification:general"
+	EventTypeSyncComplete       EventType = "sync:complete"
+	EventTypeImportUpdate       EventType = "import:update"
+	EventTypeLedgerAppend       EventType = "ledger:append"
+
+	// Replies to client commands
+	EventTypeAck   EventType = "ack"
+	EventTypeError EventType = "error"
+	EventTypePong  EventType = "pong"
 )
 
 // Event represents a WebSocket event to be broadcast
@@ -24,6 +33,8 @@ type Event struct {
 	Data      interface{}            `json:"data"`
 	Timestamp time.Time              `json:"timestamp"`
 	UserID    int                    `json:"userId,omitempty"`
+	Seq       int64                  `json:"seq,omitempty"`   // Per-user sequence number; zero for events that are not persisted
+	Topic     string                 `json:"topic,omitempty"` // Set on events delivered because of a topic subscription
 }
 
 // ToJSON converts the event to JSON bytes
@@ -42,6 +53,17 @@ type TransferUpdateData struct {
 	ItemName     string `json:"itemName"`
 }
 
+// TransferCommentData represents data for transfer comment events
+type TransferCommentData struct {
+	TransferID      int    `json:"transferId"`
+	CommentID       int    `json:"commentId"`
+	ParentCommentID *int   `json:"parentCommentId,omitempty"`
+	AuthorID        int    `json:"authorId"`
+	AuthorName      string `json:"authorName"`
+	Body            string `json:"body"`
+	RecipientIDs    []int  `json:"-"` // Transfer parties and approvers
+}
+
 // PropertyUpdateData represents data for property update events
 type PropertyUpdateData struct {
 	PropertyID   int    `json:"propertyId"`
@@ -67,4 +89,48 @@ type DocumentReceivedData struct {
 	SenderID     int    `json:"senderId"`
 	DocumentType string `json:"documentType"`
 	Title        string `json:"title"`
-}
\ No newline at end of file
+}
+
+// SyncCompleteData tells a reconnecting client that replay has finished and
+// everything after LastSeq will arrive live
+type SyncCompleteData struct {
+	LastSeq int64 `json:"lastSeq"`
+}
+
+// CommandAckData acknowledges a client command
+type CommandAckData struct {
+	ID     string   `json:"id,omitempty"`
+	Topics []string `json:"topics"` // Every topic the connection is now subscribed to
+}
+
+// CommandErrorData reports a client command that could not be carried out
+type CommandErrorData struct {
+	ID      string `json:"id,omitempty"`
+	Code    string `json:"code"` // bad_request, forbidden, limit_exceeded, unavailable or internal
+	Message string `json:"message"`
+}
+
+// PongData answers a heartbeat ping
+type PongData struct {
+	ID string `json:"id,omitempty"`
+}
+
+// ImportUpdateData represents progress of a DA 2062 import
+type ImportUpdateData struct {
+	ImportID       int64  `json:"importId"`
+	Status         string `json:"status"`
+	TotalItems     int    `json:"totalItems"`
+	ProcessedItems int    `json:"processedItems"`
+	FailedItems    int    `json:"failedItems"`
+	PageCount      int    `json:"pageCount,omitempty"`
+	PagesParsed    int    `json:"pagesParsed,omitempty"`
+	PagesFailed    int    `json:"pagesFailed,omitempty"`
+}
+
+// LedgerAppendData represents an entry appended to the audit ledger
+type LedgerAppendData struct {
+	EventID   string `json:"eventId"`
+	EventType string `json:"eventType"`
+	CreatedBy uint   `json:"createdBy,omitempty"`
+	Hash      string `json:"hash"`
+}
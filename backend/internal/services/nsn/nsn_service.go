@@ -504,6 +504,11 @@ func (s *NSNService) batchInsertRecords(ctx context.Context, records []NSNRecord
 	return s.db.WithContext(ctx).CreateInBatches(records, s.config.BulkBatchSize).Error
 }
 
+// PubLog returns the PUB LOG data loaded by Initialize, or nil if none was loaded
+func (s *NSNService) PubLog() *publog.Service {
+	return s.publogService
+}
+
 // LookupNSN performs NSN lookup with caching and fallback to external API
 func (s *NSNService) LookupNSN(ctx context.Context, nsn string) (*NSNDetails, error) {
 	// Validate NSN format
@@ -0,0 +1,158 @@
+package ocr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ErrEngineNotInstalled is returned when the OCR engine binary cannot be found
+var ErrEngineNotInstalled = errors.New("OCR engine is not installed")
+
+// Word is one word read from a page image, with its bounding box in pixels
+type Word struct {
+	Text       string
+	Left       int
+	Top        int
+	Width      int
+	Height     int
+	Confidence float64 // 0-100, as reported by the engine
+	Line       LineKey
+}
+
+// LineKey identifies the line of text a word was read on
+type LineKey struct {
+	Block     int
+	Paragraph int
+	Line      int
+}
+
+// CenterX returns the horizontal middle of the word
+func (w Word) CenterX() int {
+	return w.Left + w.Width/2
+}
+
+// CenterY returns the vertical middle of the word
+func (w Word) CenterY() int {
+	return w.Top + w.Height/2
+}
+
+// Page is the text of one page image, word by word, with the image size in pixels
+type Page struct {
+	Width  int
+	Height int
+	Words  []Word
+}
+
+// Engine reads the words and their positions from a page image
+type Engine interface {
+	Recognize(ctx context.Context, image []byte) (*Page, error)
+}
+
+// TesseractEngine runs the Tesseract command line tool on the local machine. Nothing
+// leaves the box, so it works without a network connection.
+type TesseractEngine struct {
+	path     string
+	language string
+}
+
+// NewTesseractEngine finds the tesseract binary at path, or on PATH when path is
+// empty. Language is a Tesseract language code and defaults to English.
+func NewTesseractEngine(path, language string) (*TesseractEngine, error) {
+	if path == "" {
+		path = "tesseract"
+	}
+	if language == "" {
+		language = "eng"
+	}
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrEngineNotInstalled, err)
+	}
+	return &TesseractEngine{path: resolved, language: language}, nil
+}
+
+// Recognize implements Engine. The image is piped to Tesseract, which reports each
+// word and its box as TSV.
+func (e *TesseractEngine) Recognize(ctx context.Context, image []byte) (*Page, error) {
+	// Page segmentation mode 6 reads the page as one block, keeping table rows whole
+	cmd := exec.CommandContext(ctx, e.path, "stdin", "stdout", "-l", e.language, "--psm", "6", "tsv")
+	cmd.Stdin = bytes.NewReader(image)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("tesseract failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return ParseTSV(stdout.Bytes())
+}
+
+// ParseTSV reads Tesseract's TSV output into a page
+func ParseTSV(data []byte) (*Page, error) {
+	const (
+		levelPage = 1
+		levelWord = 5
+		columns   = 12
+	)
+
+	page := &Page{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	header := true
+	for scanner.Scan() {
+		if header {
+			header = false
+			continue
+		}
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < columns {
+			continue
+		}
+
+		var n [10]int
+		for i := range n {
+			v, err := strconv.Atoi(fields[i])
+			if err != nil {
+				return nil, fmt.Errorf("invalid tesseract output: %q", scanner.Text())
+			}
+			n[i] = v
+		}
+		level, left, top, width, height := n[0], n[6], n[7], n[8], n[9]
+
+		switch level {
+		case levelPage:
+			page.Width, page.Height = width, height
+		case levelWord:
+			text := strings.TrimSpace(fields[11])
+			if text == "" {
+				continue
+			}
+			confidence, _ := strconv.ParseFloat(fields[10], 64)
+			page.Words = append(page.Words, Word{
+				Text:       text,
+				Left:       left,
+				Top:        top,
+				Width:      width,
+				Height:     height,
+				Confidence: confidence,
+				Line:       LineKey{Block: n[2], Paragraph: n[3], Line: n[4]},
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tesseract output: %w", err)
+	}
+	if page.Width == 0 || page.Height == 0 {
+		return nil, fmt.Errorf("tesseract output has no page size")
+	}
+	return page, nil
+}
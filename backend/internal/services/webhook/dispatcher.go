@@ -0,0 +1,209 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"github.com/toole-brendan/handreceipt-go/internal/services/jobs"
+	"gorm.io/gorm"
+)
+
+// Headers sent with every webhook request
+const (
+	HeaderEvent     = "X-HandReceipt-Event"
+	HeaderDelivery  = "X-HandReceipt-Delivery"
+	HeaderTimestamp = "X-HandReceipt-Timestamp"
+	HeaderSignature = "X-HandReceipt-Signature"
+)
+
+// JobDeliverWebhook sends one queued delivery to its receiver
+const JobDeliverWebhook = "webhook.deliver"
+
+// DeliveryJob is the payload of a JobDeliverWebhook job
+type DeliveryJob struct {
+	DeliveryID uint `json:"deliveryId"`
+}
+
+const (
+	requestTimeout = 15 * time.Second
+	// Responses are read this far so the connection can be reused, then discarded
+	maxResponseDrain = 64 << 10
+
+	// How long one delivery job may run, the request included
+	deliveryJobTimeout = time.Minute
+)
+
+// retryBackoff spaces out deliveries to a receiver that keeps failing over several
+// hours, so a short outage on its side does not dead-letter them
+var retryBackoff = jobs.Backoff{Base: 30 * time.Second, Max: 6 * time.Hour}
+
+// Sign returns the signature header value for a request body. The receiver
+// recomputes HMAC-SHA256 over "<timestamp>.<body>" with the subscription secret and
+// compares; checking the timestamp is recent guards against replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher sends queued deliveries as jobs on the background job queue, which
+// claims, retries and dead-letters them
+type Dispatcher struct {
+	db     *gorm.DB
+	client *http.Client
+}
+
+// NewDispatcher creates a dispatcher
+func NewDispatcher(db *gorm.DB) *Dispatcher {
+	return &Dispatcher{db: db, client: newHTTPClient()}
+}
+
+// Register adds the delivery handler to a job worker
+func (d *Dispatcher) Register(worker *jobs.Worker) {
+	worker.Register(JobDeliverWebhook, d.deliver)
+	worker.SetBackoff(JobDeliverWebhook, retryBackoff)
+}
+
+// retryDelivery puts the delivery a retried job sends back to pending; otherwise
+// deliver would find it dead-lettered and skip it. A delivery redelivered since
+// belongs to a newer job, so the old one cannot be retried.
+func retryDelivery(tx *gorm.DB, job *domain.Job) error {
+	var payload DeliveryJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return fmt.Errorf("invalid %s payload: %w", job.Type, err)
+	}
+
+	result := tx.Model(&domain.WebhookDelivery{}).
+		Where("id = ? AND (job_id = ? OR job_id IS NULL)", payload.DeliveryID, job.ID).
+		Where("status IN ?", []string{domain.WebhookDeliveryDead, domain.WebhookDeliveryPending}).
+		Updates(map[string]interface{}{
+			"status":     domain.WebhookDeliveryPending,
+			"attempts":   0,
+			"job_id":     job.ID,
+			"updated_at": time.Now().UTC(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: webhook delivery %d was delivered or redelivered since", jobs.ErrNotRetryable, payload.DeliveryID)
+	}
+	return nil
+}
+
+// deliver sends one delivery and records the outcome. Returning the send error has
+// the worker retry the job; the delivery is dead-lettered along with its last job.
+func (d *Dispatcher) deliver(ctx context.Context, job *domain.Job) error {
+	var payload DeliveryJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return jobs.Permanent(fmt.Errorf("invalid %s payload: %w", job.Type, err))
+	}
+
+	var delivery domain.WebhookDelivery
+	if err := d.db.WithContext(ctx).First(&delivery, payload.DeliveryID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return jobs.Permanent(err)
+		}
+		return err
+	}
+	// Delivered, or dead-lettered and not yet redelivered
+	if delivery.Status != domain.WebhookDeliveryPending || (delivery.JobID != nil && *delivery.JobID != job.ID) {
+		return nil
+	}
+	var subscription domain.WebhookSubscription
+	if err := d.db.WithContext(ctx).First(&subscription, delivery.SubscriptionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return jobs.Permanent(err)
+		}
+		return err
+	}
+
+	started := time.Now()
+	statusCode, sendErr := d.send(ctx, &subscription, &delivery)
+
+	record := domain.WebhookDeliveryAttempt{
+		DeliveryID: delivery.ID,
+		Attempt:    job.Attempts,
+		DurationMs: time.Since(started).Milliseconds(),
+	}
+	if statusCode > 0 {
+		record.StatusCode = &statusCode
+	}
+	if sendErr != nil {
+		message := sendErr.Error()
+		record.Error = &message
+	}
+	if err := d.db.Create(&record).Error; err != nil {
+		log.Printf("WARNING: Failed to log webhook delivery attempt %d: %v", delivery.ID, err)
+	}
+
+	now := time.Now().UTC()
+	updates := map[string]interface{}{
+		"attempts":         job.Attempts,
+		"last_status_code": record.StatusCode,
+		"last_error":       record.Error,
+		"updated_at":       now,
+	}
+	switch {
+	case sendErr == nil:
+		updates["status"] = domain.WebhookDeliveryDelivered
+		updates["delivered_at"] = now
+	case !subscription.Active:
+		sendErr = jobs.Permanent(sendErr)
+		updates["status"] = domain.WebhookDeliveryDead
+	case job.Attempts >= job.MaxAttempts:
+		updates["status"] = domain.WebhookDeliveryDead
+		log.Printf("WARNING: Dead-lettered webhook delivery %d to %s after %d attempt(s): %v", delivery.ID, subscription.URL, job.Attempts, sendErr)
+	}
+	if err := d.db.Model(&delivery).Updates(updates).Error; err != nil {
+		log.Printf("WARNING: Failed to update webhook delivery %d: %v", delivery.ID, err)
+	}
+	return sendErr
+}
+
+// send posts the delivery and returns the receiver's status code. Any response
+// other than 2xx is a failure, redirects included. The response body is never kept,
+// so a subscription cannot be used to read back what a URL returns.
+func (d *Dispatcher) send(ctx context.Context, subscription *domain.WebhookSubscription, delivery *domain.WebhookDelivery) (int, error) {
+	if !subscription.Active {
+		return 0, errors.New("subscription is disabled")
+	}
+
+	timestamp := time.Now().Unix()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "HandReceipt-Webhooks/1.0")
+	request.Header.Set(HeaderEvent, delivery.EventType)
+	request.Header.Set(HeaderDelivery, strconv.FormatUint(uint64(delivery.ID), 10))
+	request.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(HeaderSignature, Sign(subscription.Secret, timestamp, delivery.Payload))
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, maxResponseDrain))
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, fmt.Errorf("receiver responded %s", response.Status)
+	}
+	return response.StatusCode, nil
+}
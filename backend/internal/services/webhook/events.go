@@ -0,0 +1,106 @@
+package webhook
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"github.com/toole-brendan/handreceipt-go/internal/ledger"
+	"github.com/toole-brendan/handreceipt-go/internal/services/notification"
+)
+
+// ledgerEventTypes maps ledger entries to the webhook events they produce. Other
+// ledger entries are not sent.
+var ledgerEventTypes = map[string]string{
+	"ItemCreation":      EventPropertyCreated,
+	"StatusChange":      EventPropertyStatusChanged,
+	"VerificationEvent": EventPropertyVerified,
+	"ComponentAttached": EventPropertyComponentAttached,
+	"ComponentDetached": EventPropertyComponentDetached,
+	"TransferEvent":     EventTransferStatusChanged,
+}
+
+// Ledger event fields naming users and property the event is about
+var (
+	ledgerUserFields     = []string{"user_id", "from_user_id", "to_user_id", "initiating_user_id"}
+	ledgerPropertyFields = []string{"item_id", "property_id", "parent_property_id"}
+)
+
+// PublishLedgerEntry queues webhooks for a property or transfer event appended to
+// the ledger. Its data is the ledger event as recorded, so receivers can check it
+// against the ledger hash.
+func (s *Service) PublishLedgerEntry(entry ledger.LedgerEntry) {
+	eventType, ok := ledgerEventTypes[entry.EventType]
+	if !ok {
+		return
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(entry.EventData), &data); err != nil {
+		log.Printf("WARNING: Skipping webhooks for ledger event %s: %v", entry.EventID, err)
+		return
+	}
+	delete(data, "event_type")
+	data["ledgerEventId"] = entry.EventID
+	data["ledgerHash"] = entry.Hash
+
+	userIDs := idsFrom(data, ledgerUserFields)
+	if propertyIDs := idsFrom(data, ledgerPropertyFields); len(propertyIDs) > 0 {
+		// The holder of the property is involved even when someone else acted on it
+		var holders []uint
+		err := s.db.Model(&domain.Property{}).
+			Where("id IN ? AND assigned_to_user_id IS NOT NULL", propertyIDs).
+			Pluck("assigned_to_user_id", &holders).Error
+		if err != nil {
+			log.Printf("WARNING: Failed to look up holders for ledger event %s: %v", entry.EventID, err)
+		}
+		userIDs = append(userIDs, holders...)
+	}
+
+	if err := s.Publish(eventType, data, entry.CreatedAt, userIDs); err != nil {
+		log.Printf("WARNING: Failed to queue %s webhooks for ledger event %s: %v", eventType, entry.EventID, err)
+	}
+}
+
+// PublishNotificationEvent queues webhooks for notification events the ledger does
+// not record: new transfer requests and transfer comments
+func (s *Service) PublishNotificationEvent(event notification.Event) {
+	var (
+		eventType string
+		data      interface{}
+		userIDs   []uint
+	)
+	switch payload := event.Data.(type) {
+	case notification.TransferUpdateData:
+		if event.Type != notification.EventTypeTransferCreated {
+			return
+		}
+		eventType = EventTransferCreated
+		data = payload
+		userIDs = []uint{uint(payload.FromUserID), uint(payload.ToUserID)}
+	case notification.TransferCommentData:
+		eventType = EventTransferCommentAdded
+		data = payload
+		userIDs = []uint{uint(payload.AuthorID)}
+		for _, id := range payload.RecipientIDs {
+			userIDs = append(userIDs, uint(id))
+		}
+	default:
+		return
+	}
+
+	if err := s.Publish(eventType, data, event.Timestamp.UTC(), userIDs); err != nil {
+		log.Printf("WARNING: Failed to queue %s webhooks: %v", eventType, err)
+	}
+}
+
+// idsFrom collects the positive numeric values of the given fields
+func idsFrom(data map[string]interface{}, fields []string) []uint {
+	var ids []uint
+	for _, field := range fields {
+		if value, ok := data[field].(float64); ok && value > 0 {
+			ids = append(ids, uint(value))
+		}
+	}
+	return ids
+}
@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBlockedIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.9", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true}, // Cloud metadata service
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"::1", true},
+		{"fd00::1", true},
+		{"fe80::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"8.8.8.8", false},
+		{"2606:4700::1111", false},
+	}
+	for _, tt := range tests {
+		if got := blockedIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("blockedIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	for _, host := range []string{"localhost", "api.localhost", "127.0.0.1", "169.254.169.254", "::1"} {
+		if err := checkHost(host); !errors.Is(err, ErrBlockedAddress) {
+			t.Errorf("checkHost(%s) = %v, want ErrBlockedAddress", host, err)
+		}
+	}
+	if err := checkHost("hooks.example.com"); err != nil {
+		t.Errorf("checkHost(hooks.example.com) = %v", err)
+	}
+}
+
+func TestHTTPClientRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := newHTTPClient().Post(server.URL, "application/json", nil)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("expected ErrBlockedAddress, got %v", err)
+	}
+}
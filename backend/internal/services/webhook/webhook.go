@@ -0,0 +1,422 @@
+// Package webhook delivers hand receipt events to outside systems. Events are
+// queued in the database as they happen and sent by background jobs, signed with
+// each subscription's secret, retried with exponential backoff and dead-lettered
+// when the receiver keeps failing.
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/toole-brendan/handreceipt-go/internal/domain"
+	"github.com/toole-brendan/handreceipt-go/internal/services/jobs"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidURL           = errors.New("webhook URL must be an absolute http or https URL")
+	ErrInsecureURL          = errors.New("webhook URL must use https")
+	ErrUnknownEventType     = errors.New("unknown webhook event type")
+	ErrNoUnit               = errors.New("user must belong to a unit to create webhooks")
+	ErrNotSubscriptionOwner = errors.New("webhook subscription belongs to another user")
+	ErrDeliveryNotDead      = errors.New("only dead-lettered deliveries can be redelivered")
+)
+
+// Webhook event types
+const (
+	EventPropertyCreated           = "property.created"
+	EventPropertyStatusChanged     = "property.status_changed"
+	EventPropertyVerified          = "property.verified"
+	EventPropertyComponentAttached = "property.component_attached"
+	EventPropertyComponentDetached = "property.component_detached"
+	EventTransferCreated           = "transfer.created"
+	EventTransferStatusChanged     = "transfer.status_changed" // Every status a transfer moves through, the initial request included
+	EventTransferCommentAdded      = "transfer.comment_added"
+	EventPing                      = "ping" // Sent on request to test a subscription
+)
+
+// EventTypes lists the event types a subscription can choose from
+var EventTypes = []string{
+	EventPropertyCreated,
+	EventPropertyStatusChanged,
+	EventPropertyVerified,
+	EventPropertyComponentAttached,
+	EventPropertyComponentDetached,
+	EventTransferCreated,
+	EventTransferStatusChanged,
+	EventTransferCommentAdded,
+}
+
+// allEvents subscribes to every event type, including ones added later
+const allEvents = "*"
+
+// Event is the body of every webhook request
+type Event struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	OccurredAt time.Time   `json:"occurredAt"`
+	Data       interface{} `json:"data"`
+}
+
+// SubscriptionInput represents input for creating or changing a subscription. A
+// secret is generated when none is given.
+type SubscriptionInput struct {
+	URL         string   `json:"url" binding:"required"`
+	Secret      string   `json:"secret"`
+	EventTypes  []string `json:"eventTypes"`
+	Description string   `json:"description"`
+	Active      *bool    `json:"active"`
+}
+
+// Service manages webhook subscriptions and queues events for them
+type Service struct {
+	db          *gorm.DB
+	jobs        *jobs.Queue
+	maxAttempts int
+	requireTLS  bool
+}
+
+// NewService creates a webhook service that queues a delivery job for every event,
+// dead-lettering it after maxAttempts failed requests. With requireTLS set,
+// subscription URLs must use https.
+func NewService(db *gorm.DB, queue *jobs.Queue, maxAttempts int, requireTLS bool) *Service {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	if queue != nil {
+		queue.OnRetry(JobDeliverWebhook, retryDelivery)
+	}
+	return &Service{db: db, jobs: queue, maxAttempts: maxAttempts, requireTLS: requireTLS}
+}
+
+// CreateSubscription registers a URL to receive events for the user's unit. The
+// returned secret is shown only here.
+func (s *Service) CreateSubscription(userID uint, input SubscriptionInput) (*domain.WebhookSubscription, string, error) {
+	var user domain.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, "", err
+	}
+	if strings.TrimSpace(user.Unit) == "" {
+		return nil, "", ErrNoUnit
+	}
+
+	subscription := &domain.WebhookSubscription{
+		UserID:      userID,
+		Unit:        user.Unit,
+		Description: input.Description,
+		Active:      true,
+	}
+	secret, err := s.apply(subscription, input)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.db.Create(subscription).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	return subscription, secret, nil
+}
+
+// UpdateSubscription changes a subscription. A new secret is returned only when the
+// input replaced it.
+func (s *Service) UpdateSubscription(userID, subscriptionID uint, input SubscriptionInput) (*domain.WebhookSubscription, string, error) {
+	subscription, err := s.GetSubscription(userID, subscriptionID)
+	if err != nil {
+		return nil, "", err
+	}
+	previousSecret := subscription.Secret
+	subscription.Description = input.Description
+	if input.Secret == "" {
+		input.Secret = previousSecret
+	}
+	secret, err := s.apply(subscription, input)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.db.Save(subscription).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+	if secret == previousSecret {
+		secret = ""
+	}
+	return subscription, secret, nil
+}
+
+// apply validates input onto a subscription and returns its secret
+func (s *Service) apply(subscription *domain.WebhookSubscription, input SubscriptionInput) (string, error) {
+	endpoint, err := url.Parse(strings.TrimSpace(input.URL))
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return "", ErrInvalidURL
+	}
+	if s.requireTLS && endpoint.Scheme != "https" {
+		return "", ErrInsecureURL
+	}
+	if err := checkHost(endpoint.Hostname()); err != nil {
+		return "", err
+	}
+	subscription.URL = endpoint.String()
+
+	eventTypes, err := normalizeEventTypes(input.EventTypes)
+	if err != nil {
+		return "", err
+	}
+	subscription.EventTypes = eventTypes
+
+	if input.Active != nil {
+		subscription.Active = *input.Active
+	}
+
+	subscription.Secret = input.Secret
+	if subscription.Secret == "" {
+		raw := make([]byte, 32)
+		if _, err := rand.Read(raw); err != nil {
+			return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+		subscription.Secret = "whsec_" + hex.EncodeToString(raw)
+	}
+	return subscription.Secret, nil
+}
+
+func normalizeEventTypes(eventTypes []string) (domain.JSONStringArray, error) {
+	if len(eventTypes) == 0 {
+		return domain.JSONStringArray{allEvents}, nil
+	}
+	seen := make(map[string]bool)
+	normalized := domain.JSONStringArray{}
+	for _, eventType := range eventTypes {
+		eventType = strings.TrimSpace(eventType)
+		if eventType != allEvents && !isEventType(eventType) {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownEventType, eventType)
+		}
+		if !seen[eventType] {
+			seen[eventType] = true
+			normalized = append(normalized, eventType)
+		}
+	}
+	return normalized, nil
+}
+
+func isEventType(eventType string) bool {
+	for _, known := range EventTypes {
+		if eventType == known {
+			return true
+		}
+	}
+	return false
+}
+
+// ListSubscriptions returns the user's subscriptions
+func (s *Service) ListSubscriptions(userID uint) ([]domain.WebhookSubscription, error) {
+	var subscriptions []domain.WebhookSubscription
+	err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// GetSubscription returns one of the user's subscriptions
+func (s *Service) GetSubscription(userID, subscriptionID uint) (*domain.WebhookSubscription, error) {
+	var subscription domain.WebhookSubscription
+	if err := s.db.First(&subscription, subscriptionID).Error; err != nil {
+		return nil, err
+	}
+	if subscription.UserID != userID {
+		return nil, ErrNotSubscriptionOwner
+	}
+	return &subscription, nil
+}
+
+// DeleteSubscription removes a subscription along with its queued deliveries and log
+func (s *Service) DeleteSubscription(userID, subscriptionID uint) error {
+	subscription, err := s.GetSubscription(userID, subscriptionID)
+	if err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		deliveries := tx.Model(&domain.WebhookDelivery{}).Select("id").Where("subscription_id = ?", subscription.ID)
+		if err := tx.Where("delivery_id IN (?)", deliveries).Delete(&domain.WebhookDeliveryAttempt{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("subscription_id = ?", subscription.ID).Delete(&domain.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(subscription).Error
+	})
+}
+
+// ListDeliveries returns a subscription's delivery log, newest first, with every
+// attempt made for each delivery
+func (s *Service) ListDeliveries(userID, subscriptionID uint, status string, limit, offset int) ([]domain.WebhookDelivery, error) {
+	if _, err := s.GetSubscription(userID, subscriptionID); err != nil {
+		return nil, err
+	}
+	query := s.db.Where("subscription_id = ?", subscriptionID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var deliveries []domain.WebhookDelivery
+	err := query.
+		Preload("AttemptLog", func(db *gorm.DB) *gorm.DB { return db.Order("attempt") }).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// Redeliver puts a dead-lettered delivery back in the queue with a fresh set of
+// attempts
+func (s *Service) Redeliver(userID, subscriptionID, deliveryID uint) (*domain.WebhookDelivery, error) {
+	if _, err := s.GetSubscription(userID, subscriptionID); err != nil {
+		return nil, err
+	}
+	var delivery domain.WebhookDelivery
+	if err := s.db.Where("id = ? AND subscription_id = ?", deliveryID, subscriptionID).First(&delivery).Error; err != nil {
+		return nil, err
+	}
+	if delivery.Status != domain.WebhookDeliveryDead {
+		return nil, ErrDeliveryNotDead
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Only one of two concurrent redeliveries gets the dead row
+		result := tx.Model(&domain.WebhookDelivery{}).
+			Where("id = ? AND status = ?", delivery.ID, domain.WebhookDeliveryDead).
+			Updates(map[string]interface{}{
+				"status":     domain.WebhookDeliveryPending,
+				"attempts":   0,
+				"updated_at": time.Now().UTC(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrDeliveryNotDead
+		}
+		job, err := s.enqueueTx(tx, delivery.ID)
+		if err != nil {
+			return err
+		}
+		delivery.Status = domain.WebhookDeliveryPending
+		delivery.Attempts = 0
+		delivery.JobID = &job.ID
+		return tx.Model(&delivery).Update("job_id", job.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// SendPing queues a ping event for one subscription so the receiver can check its
+// signature handling
+func (s *Service) SendPing(userID, subscriptionID uint) (*domain.WebhookDelivery, error) {
+	subscription, err := s.GetSubscription(userID, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	event := newEvent(EventPing, map[string]interface{}{"subscriptionId": subscription.ID}, time.Now().UTC())
+	deliveries, err := s.queue(event, []domain.WebhookSubscription{*subscription})
+	if err != nil {
+		return nil, err
+	}
+	return &deliveries[0], nil
+}
+
+// Publish queues an event for every active subscription that wants its type and
+// whose unit one of the involved users belongs to
+func (s *Service) Publish(eventType string, data interface{}, occurredAt time.Time, involvedUserIDs []uint) error {
+	if len(involvedUserIDs) == 0 {
+		return nil
+	}
+
+	var units []string
+	err := s.db.Model(&domain.User{}).
+		Where("id IN ? AND unit <> ''", involvedUserIDs).
+		Distinct("unit").
+		Pluck("unit", &units).Error
+	if err != nil {
+		return fmt.Errorf("failed to look up units for webhook event: %w", err)
+	}
+	if len(units) == 0 {
+		return nil
+	}
+
+	var subscriptions []domain.WebhookSubscription
+	err = s.db.
+		Where("active AND unit IN ?", units).
+		Where("event_types @> ? OR event_types @> ?", jsonArray(eventType), jsonArray(allEvents)).
+		Find(&subscriptions).Error
+	if err != nil {
+		return fmt.Errorf("failed to find webhook subscriptions: %w", err)
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	_, err = s.queue(newEvent(eventType, data, occurredAt), subscriptions)
+	return err
+}
+
+// queue stores a delivery of the event for each subscription, along with the job
+// that sends it
+func (s *Service) queue(event Event, subscriptions []domain.WebhookSubscription) ([]domain.WebhookDelivery, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+	deliveries := make([]domain.WebhookDelivery, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		deliveries = append(deliveries, domain.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        payload,
+			Status:         domain.WebhookDeliveryPending,
+		})
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&deliveries).Error; err != nil {
+			return err
+		}
+		for i := range deliveries {
+			job, err := s.enqueueTx(tx, deliveries[i].ID)
+			if err != nil {
+				return err
+			}
+			deliveries[i].JobID = &job.ID
+			if err := tx.Model(&deliveries[i]).Update("job_id", job.ID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to queue webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (s *Service) enqueueTx(tx *gorm.DB, deliveryID uint) (*domain.Job, error) {
+	return s.jobs.EnqueueTx(tx, JobDeliverWebhook, DeliveryJob{DeliveryID: deliveryID}, jobs.EnqueueOptions{
+		MaxAttempts: s.maxAttempts,
+		Timeout:     deliveryJobTimeout,
+	})
+}
+
+func newEvent(eventType string, data interface{}, occurredAt time.Time) Event {
+	return Event{
+		ID:         "evt_" + strings.ReplaceAll(uuid.New().String(), "-", ""),
+		Type:       eventType,
+		OccurredAt: occurredAt,
+		Data:       data,
+	}
+}
+
+func jsonArray(values ...string) string {
+	encoded, _ := json.Marshal(values)
+	return string(encoded)
+}
@@ -1561,6 +1561,9 @@ public class APIService: APIServiceProtocol {
         let endpoint = baseURL.appendingPathComponent("/api/da2062/upload")
         var request = URLRequest(url: endpoint)
         request.httpMethod = "POST"
+        // The server answers once the form has been parsed, which can take up to two
+        // minutes for a long form, so wait longer than the default request timeout
+        request.timeoutInterval = 150
         
         // Create multipart form data
         let boundary = UUID().uuidString
@@ -1865,7 +1868,7 @@ public struct AzureOCRItem: Codable {
 public struct AzureImportMetadata: Codable {
     public let source: String
     public let importDate: Date?  // Added to match backend response
-    public let formNumber: String
+    public let formNumber: String?         // Omitted when no form number could be read
     public let scanConfidence: Double      // Changed from 'confidence' to match backend
     public let itemConfidence: Double?     // Added this field to match backend (optional for compatibility)
     public let serialSource: String
@@ -393,7 +393,8 @@ extension APIService {
             throw APIError.invalidResponse
         }
         
-        guard httpResponse.statusCode == 200 else {
+        // The server queues the email and answers 202 Accepted
+        guard (200...299).contains(httpResponse.statusCode) else {
             if let errorData = try? JSONDecoder().decode(DA2062ErrorResponse.self, from: data) {
                 throw APIError.serverError(statusCode: httpResponse.statusCode, message: errorData.error)
             }
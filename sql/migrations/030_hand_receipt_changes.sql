@@ -0,0 +1,57 @@
+-- Migration: Change of hand receipt holder inventory
+-- Description: Tracks the joint 100% inventory performed when a primary hand receipt
+-- holder rotates out, and the per-item marks recorded by both parties
+
+CREATE TABLE IF NOT EXISTS hand_receipt_changes (
+    id SERIAL PRIMARY KEY,
+    outgoing_user_id INTEGER NOT NULL REFERENCES users(id),
+    incoming_user_id INTEGER NOT NULL REFERENCES users(id),
+    initiated_by_user_id INTEGER NOT NULL REFERENCES users(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'completed', 'cancelled')),
+    due_date TIMESTAMP WITH TIME ZONE,
+    notes TEXT,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    hand_receipt_url TEXT,
+    shortage_annex_url TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT chk_hand_receipt_change_users CHECK (outgoing_user_id <> incoming_user_id)
+);
+
+-- Only one open change per outgoing holder
+CREATE UNIQUE INDEX IF NOT EXISTS idx_hand_receipt_changes_open_outgoing
+    ON hand_receipt_changes(outgoing_user_id) WHERE status = 'open';
+
+CREATE INDEX IF NOT EXISTS idx_hand_receipt_changes_incoming_user_id
+    ON hand_receipt_changes(incoming_user_id);
+
+CREATE TABLE IF NOT EXISTS hand_receipt_change_items (
+    id SERIAL PRIMARY KEY,
+    change_id INTEGER NOT NULL REFERENCES hand_receipt_changes(id) ON DELETE CASCADE,
+    property_id INTEGER NOT NULL REFERENCES properties(id),
+    parent_property_id INTEGER REFERENCES properties(id),
+    name VARCHAR(255) NOT NULL,
+    serial_number VARCHAR(255) NOT NULL,
+    nsn VARCHAR(20),
+    quantity INTEGER DEFAULT 1,
+    unit_price DECIMAL(12,2) DEFAULT 0,
+    outgoing_mark VARCHAR(20) CHECK (outgoing_mark IN ('found', 'missing', 'discrepant')),
+    incoming_mark VARCHAR(20) CHECK (incoming_mark IN ('found', 'missing', 'discrepant')),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'disputed', 'found', 'missing', 'discrepant')),
+    remarks TEXT,
+    last_marked_at TIMESTAMP WITH TIME ZONE,
+    last_marked_by INTEGER REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT uq_hand_receipt_change_item UNIQUE (change_id, property_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_hand_receipt_change_items_change_status
+    ON hand_receipt_change_items(change_id, status);
+
+COMMENT ON TABLE hand_receipt_changes IS 'Change of primary hand receipt holder with joint 100% inventory';
+COMMENT ON TABLE hand_receipt_change_items IS 'Snapshot of each property item counted during a hand receipt change';
+COMMENT ON COLUMN hand_receipt_change_items.status IS 'Agreed mark once both holders match, otherwise pending or disputed';
@@ -0,0 +1,52 @@
+-- Migration: Cyclic and sensitive-item inventory campaigns
+-- Description: Scheduled inventories with property selected up front, counters
+-- assigned per item and per-item verification results (serial scan, photo)
+
+CREATE TABLE IF NOT EXISTS inventory_campaigns (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    campaign_type VARCHAR(20) NOT NULL CHECK (campaign_type IN ('cyclic', 'sensitive_item')),
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'closed', 'cancelled')),
+    selection_criteria JSONB NOT NULL DEFAULT '{}',
+    created_by_user_id INTEGER NOT NULL REFERENCES users(id),
+    due_date TIMESTAMP WITH TIME ZONE NOT NULL,
+    closed_at TIMESTAMP WITH TIME ZONE,
+    closed_by_user_id INTEGER REFERENCES users(id),
+    report_url TEXT,
+    report_sha256 VARCHAR(64),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_campaigns_status_due
+    ON inventory_campaigns(status, due_date);
+
+CREATE TABLE IF NOT EXISTS inventory_campaign_items (
+    id SERIAL PRIMARY KEY,
+    campaign_id INTEGER NOT NULL REFERENCES inventory_campaigns(id) ON DELETE CASCADE,
+    property_id INTEGER NOT NULL REFERENCES properties(id),
+    holder_user_id INTEGER REFERENCES users(id),
+    counter_user_id INTEGER REFERENCES users(id),
+    serial_number VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'verified', 'discrepant', 'not_found')),
+    scanned_serial VARCHAR(255),
+    serial_matched BOOLEAN,
+    photo_url TEXT,
+    condition VARCHAR(50),
+    notes TEXT,
+    counted_at TIMESTAMP WITH TIME ZONE,
+    reminder_sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT uq_inventory_campaign_item UNIQUE (campaign_id, property_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_campaign_items_counter_status
+    ON inventory_campaign_items(counter_user_id, status);
+
+COMMENT ON TABLE inventory_campaigns IS 'Scheduled cyclic and monthly sensitive-item inventories';
+COMMENT ON COLUMN inventory_campaigns.selection_criteria IS 'Categories, holders, unit and sample percentage used to select property';
+COMMENT ON COLUMN inventory_campaigns.report_sha256 IS 'Digest of the signed inventory report, also recorded in the ledger';
+COMMENT ON COLUMN inventory_campaign_items.reminder_sent_at IS 'Last overdue reminder sent to the counter by the worker';
@@ -0,0 +1,63 @@
+-- Migration: Loss, damage and destruction cases (FLIPL)
+-- Description: Tracks Financial Liability Investigations of Property Loss from the
+-- initial report through investigation and approval, and the steps taken on each case
+
+CREATE TABLE IF NOT EXISTS loss_cases (
+    id SERIAL PRIMARY KEY,
+    case_number VARCHAR(30) NOT NULL UNIQUE,
+    property_id INTEGER NOT NULL REFERENCES properties(id),
+    holder_user_id INTEGER REFERENCES users(id),
+    initiated_by_user_id INTEGER NOT NULL REFERENCES users(id),
+    investigating_officer_id INTEGER REFERENCES users(id),
+    approving_authority_id INTEGER NOT NULL REFERENCES users(id),
+    case_type VARCHAR(20) NOT NULL CHECK (case_type IN ('lost', 'damaged', 'destroyed')),
+    status VARCHAR(20) NOT NULL DEFAULT 'initiated'
+        CHECK (status IN ('initiated', 'investigating', 'pending_approval', 'approved', 'withdrawn')),
+    circumstances TEXT NOT NULL,
+    incident_date TIMESTAMP WITH TIME ZONE NOT NULL,
+    incident_location TEXT,
+    previous_status VARCHAR(50) NOT NULL,
+    quantity INTEGER DEFAULT 1,
+    unit_price DECIMAL(12,2) DEFAULT 0,
+    total_loss DECIMAL(12,2) DEFAULT 0,
+    findings TEXT,
+    liability_decision VARCHAR(20) CHECK (liability_decision IN ('liable', 'not_liable')),
+    liability_amount DECIMAL(12,2) DEFAULT 0,
+    form_url TEXT,
+    approved_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT chk_loss_case_liability CHECK (liability_amount >= 0 AND liability_amount <= total_loss)
+);
+
+-- Only one case in progress per property
+CREATE UNIQUE INDEX IF NOT EXISTS idx_loss_cases_open_property
+    ON loss_cases(property_id) WHERE status IN ('initiated', 'investigating', 'pending_approval');
+
+CREATE INDEX IF NOT EXISTS idx_loss_cases_investigating_officer_id ON loss_cases(investigating_officer_id);
+CREATE INDEX IF NOT EXISTS idx_loss_cases_approving_authority_id ON loss_cases(approving_authority_id);
+
+CREATE TABLE IF NOT EXISTS loss_case_actions (
+    id SERIAL PRIMARY KEY,
+    case_id INTEGER NOT NULL REFERENCES loss_cases(id) ON DELETE CASCADE,
+    action VARCHAR(30) NOT NULL,
+    actor_user_id INTEGER NOT NULL REFERENCES users(id),
+    from_status VARCHAR(20),
+    to_status VARCHAR(20) NOT NULL,
+    comments TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_loss_case_actions_case_id ON loss_case_actions(case_id, created_at);
+
+-- Allow the DD Form 200 to be delivered through documents
+ALTER TABLE documents DROP CONSTRAINT IF EXISTS documents_type_check;
+ALTER TABLE documents
+    ADD CONSTRAINT documents_type_check
+    CHECK (type IN ('maintenance_form', 'transfer_form', 'loss_form'));
+
+COMMENT ON TABLE loss_cases IS 'Financial Liability Investigation of Property Loss (FLIPL) cases';
+COMMENT ON COLUMN loss_cases.previous_status IS 'Property status before the case opened, restored if the case is withdrawn';
+COMMENT ON COLUMN loss_cases.total_loss IS 'Quantity times unit price at the time the case was opened';
+COMMENT ON TABLE loss_case_actions IS 'Ordered approval steps taken on a loss case';
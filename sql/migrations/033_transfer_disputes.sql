@@ -0,0 +1,52 @@
+-- Migration: Transfer disputes
+-- Description: Structured rejection reasons, amend-and-resubmit revision history,
+-- threaded comments and approvers following a transfer
+
+ALTER TABLE transfers ADD COLUMN IF NOT EXISTS rejection_reason VARCHAR(30)
+    CHECK (rejection_reason IN ('wrong_serial', 'damaged', 'missing_components', 'not_mine', 'other'));
+ALTER TABLE transfers ADD COLUMN IF NOT EXISTS revision INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS transfer_revisions (
+    id SERIAL PRIMARY KEY,
+    transfer_id INTEGER NOT NULL REFERENCES transfers(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    property_id INTEGER NOT NULL REFERENCES properties(id),
+    include_components BOOLEAN DEFAULT FALSE,
+    notes TEXT,
+    status VARCHAR(50) NOT NULL,
+    rejection_reason VARCHAR(30),
+    amended_by_user_id INTEGER NOT NULL REFERENCES users(id),
+    amendment_notes TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT uq_transfer_revision UNIQUE (transfer_id, revision)
+);
+
+CREATE TABLE IF NOT EXISTS transfer_comments (
+    id SERIAL PRIMARY KEY,
+    transfer_id INTEGER NOT NULL REFERENCES transfers(id) ON DELETE CASCADE,
+    parent_comment_id INTEGER REFERENCES transfer_comments(id) ON DELETE CASCADE,
+    author_user_id INTEGER NOT NULL REFERENCES users(id),
+    body TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfer_comments_transfer_id ON transfer_comments(transfer_id, created_at);
+
+CREATE TABLE IF NOT EXISTS transfer_approvers (
+    id SERIAL PRIMARY KEY,
+    transfer_id INTEGER NOT NULL REFERENCES transfers(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    added_by_user_id INTEGER NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT idx_transfer_approver UNIQUE (transfer_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfer_approvers_user_id ON transfer_approvers(user_id);
+
+COMMENT ON COLUMN transfers.rejection_reason IS 'Structured reason given by the receiver when rejecting';
+COMMENT ON COLUMN transfers.revision IS 'Current revision; earlier revisions are kept in transfer_revisions';
+COMMENT ON TABLE transfer_revisions IS 'Snapshot of each transfer revision superseded by an amendment';
+COMMENT ON TABLE transfer_comments IS 'Threaded comments visible to both transfer parties and approvers';
+COMMENT ON TABLE transfer_approvers IS 'Users following a transfer in addition to the two parties';
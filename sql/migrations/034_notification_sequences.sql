@@ -0,0 +1,32 @@
+-- Migration: Notification sequence numbers
+-- Description: Gives every notification a per-user sequence number and keeps the
+-- pushed WebSocket event so clients can replay what they missed on reconnect
+
+CREATE TABLE IF NOT EXISTS notification_sequences (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    last_sequence BIGINT NOT NULL DEFAULT 0
+);
+
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS sequence BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS event JSONB;
+
+-- Number existing notifications in creation order so replay has a starting point
+WITH numbered AS (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created_at, id) AS seq
+    FROM notifications
+    WHERE sequence = 0
+)
+UPDATE notifications n
+SET sequence = numbered.seq
+FROM numbered
+WHERE n.id = numbered.id;
+
+INSERT INTO notification_sequences (user_id, last_sequence)
+SELECT user_id, MAX(sequence) FROM notifications GROUP BY user_id
+ON CONFLICT (user_id) DO UPDATE SET last_sequence = GREATEST(notification_sequences.last_sequence, EXCLUDED.last_sequence);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_user_sequence ON notifications(user_id, sequence) WHERE sequence > 0;
+
+COMMENT ON TABLE notification_sequences IS 'Last notification sequence number allocated per user';
+COMMENT ON COLUMN notifications.sequence IS 'Monotonically increasing per user; WebSocket clients resume from the last sequence they saw';
+COMMENT ON COLUMN notifications.event IS 'WebSocket event payload as pushed, replayed to clients that missed it';
@@ -0,0 +1,12 @@
+-- Migration: Notification digests
+-- Description: Daily or weekly email digests of unread notifications
+
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS digested_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE notification_settings ADD COLUMN IF NOT EXISTS digest_frequency VARCHAR(10) NOT NULL DEFAULT 'daily'
+    CHECK (digest_frequency IN ('none', 'daily', 'weekly'));
+
+CREATE INDEX IF NOT EXISTS idx_notifications_undigested ON notifications(user_id) WHERE read = FALSE AND digested_at IS NULL;
+
+COMMENT ON COLUMN notifications.digested_at IS 'When the notification was rolled up into an email digest';
+COMMENT ON COLUMN notification_settings.digest_frequency IS 'How often unread notifications are emailed as a digest: none, daily or weekly';
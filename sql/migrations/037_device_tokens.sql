@@ -0,0 +1,20 @@
+-- Migration: Device tokens
+-- Description: Mobile device tokens for APNs and FCM push notifications
+
+CREATE TABLE IF NOT EXISTS device_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    platform VARCHAR(10) NOT NULL CHECK (platform IN ('ios', 'android')),
+    token TEXT NOT NULL,
+    device_id VARCHAR(255),
+    app_version VARCHAR(50),
+    last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_device_tokens_token ON device_tokens(token);
+CREATE INDEX IF NOT EXISTS idx_device_tokens_user_id ON device_tokens(user_id);
+
+COMMENT ON TABLE device_tokens IS 'Mobile devices registered for push notifications; rows are pruned when APNs or FCM reports the token invalid';
+COMMENT ON COLUMN device_tokens.device_id IS 'Device id also sent on the WebSocket connection, used to skip pushes to devices that are connected';
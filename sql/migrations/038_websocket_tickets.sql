@@ -0,0 +1,30 @@
+-- Migration: WebSocket tickets and revoked sessions
+-- Description: Single-use tickets for opening a WebSocket connection, and sign-in sessions ended by logout
+
+CREATE TABLE IF NOT EXISTS websocket_tickets (
+    id SERIAL PRIMARY KEY,
+    token_hash VARCHAR(64) NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    session_id VARCHAR(255),
+    credential_expires_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_websocket_tickets_token_hash ON websocket_tickets(token_hash);
+CREATE INDEX IF NOT EXISTS idx_websocket_tickets_expires_at ON websocket_tickets(expires_at);
+
+COMMENT ON TABLE websocket_tickets IS 'Short-lived tickets exchanged for a WebSocket connection so credentials never appear in the connection URL';
+COMMENT ON COLUMN websocket_tickets.token_hash IS 'SHA-256 of the ticket; the ticket itself is only returned to the client';
+COMMENT ON COLUMN websocket_tickets.credential_expires_at IS 'Expiry of the access token the ticket was issued against; the connection is closed when it passes';
+
+CREATE TABLE IF NOT EXISTS revoked_sessions (
+    session_id VARCHAR(255) PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    revoked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_sessions_revoked_at ON revoked_sessions(revoked_at);
+
+COMMENT ON TABLE revoked_sessions IS 'Sign-in sessions ended by logout; open WebSocket connections belonging to them are closed';
@@ -0,0 +1,7 @@
+-- Migration: DA 2062 import item page numbers
+-- Description: Record which page of a multi-page DA 2062 each imported item was read from
+
+ALTER TABLE da2062_import_items ADD COLUMN IF NOT EXISTS page_number INTEGER
+    CHECK (page_number IS NULL OR page_number > 0);
+
+COMMENT ON COLUMN da2062_import_items.page_number IS 'Page of the source form the item was read from; NULL for single-page imports';
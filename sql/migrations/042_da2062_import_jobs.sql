@@ -0,0 +1,30 @@
+-- Migration: Background DA 2062 imports
+-- Description: Track the job, stored upload and page progress of each import, and keep parsed pages so an interrupted import resumes
+
+ALTER TABLE da2062_imports
+    ADD COLUMN IF NOT EXISTS job_id INTEGER REFERENCES jobs(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS storage_key TEXT,
+    ADD COLUMN IF NOT EXISTS content_type VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS page_count INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS pages_parsed INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS form_info JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_da2062_imports_job ON da2062_imports(job_id) WHERE job_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_da2062_import_items_import_line ON da2062_import_items(import_id, line_number);
+
+CREATE TABLE IF NOT EXISTS da2062_import_pages (
+    id BIGSERIAL PRIMARY KEY,
+    import_id BIGINT NOT NULL REFERENCES da2062_imports(id) ON DELETE CASCADE,
+    page_number INTEGER NOT NULL CHECK (page_number > 0),
+    items JSONB NOT NULL,
+    meta JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_da2062_import_pages_page ON da2062_import_pages(import_id, page_number);
+
+COMMENT ON COLUMN da2062_imports.job_id IS 'Background job parsing the upload';
+COMMENT ON COLUMN da2062_imports.storage_key IS 'Object storage key of the uploaded form';
+COMMENT ON COLUMN da2062_imports.processed_items IS 'Items read into the import that can be created as property';
+COMMENT ON COLUMN da2062_imports.failed_items IS 'Items read from the form that cannot be used as they are';
+COMMENT ON TABLE da2062_import_pages IS 'Items and form fields parsed from each page of an import, kept so a retried import skips pages already parsed';
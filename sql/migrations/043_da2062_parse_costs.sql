@@ -0,0 +1,15 @@
+-- Migration: DA 2062 parsing providers and cost
+-- Description: Record which form parsing provider read each import page, the tokens it used and what it cost
+
+ALTER TABLE da2062_import_pages
+    ADD COLUMN IF NOT EXISTS provider VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS input_tokens BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS output_tokens BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS cost_usd NUMERIC(12, 6) NOT NULL DEFAULT 0;
+
+ALTER TABLE da2062_imports
+    ADD COLUMN IF NOT EXISTS parse_cost_usd NUMERIC(12, 6) NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN da2062_import_pages.provider IS 'Form parsing back end that read the page: anthropic, azure_openai or fake';
+COMMENT ON COLUMN da2062_import_pages.cost_usd IS 'Provider charge for the page at the configured per-token price';
+COMMENT ON COLUMN da2062_imports.parse_cost_usd IS 'Total provider charges for parsing the import';
@@ -0,0 +1,21 @@
+-- Migration: DA 2062 import review
+-- Description: Stage imported lines for review, recording reviewer corrections and merges, and commit the accepted lines as property in one step
+
+ALTER TABLE da2062_import_items
+    ADD COLUMN IF NOT EXISTS row_region JSONB,
+    ADD COLUMN IF NOT EXISTS corrections JSONB,
+    ADD COLUMN IF NOT EXISTS merged_into_item_id BIGINT REFERENCES da2062_import_items(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS reviewed_by_user_id INTEGER REFERENCES users(id),
+    ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE da2062_imports
+    ADD COLUMN IF NOT EXISTS committed_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS committed_by_user_id INTEGER REFERENCES users(id);
+
+CREATE INDEX IF NOT EXISTS idx_da2062_import_items_merged_into ON da2062_import_items(merged_into_item_id) WHERE merged_into_item_id IS NOT NULL;
+
+COMMENT ON COLUMN da2062_import_items.status IS 'pending, failed, accepted, ignored, merged, processed or duplicate';
+COMMENT ON COLUMN da2062_import_items.row_region IS 'Top and bottom of the line on its scanned page, as fractions of the page height';
+COMMENT ON COLUMN da2062_import_items.corrections IS 'Changes the reviewer made to the line as read from the form';
+COMMENT ON COLUMN da2062_import_items.merged_into_item_id IS 'Line this line''s quantity was merged into';
+COMMENT ON COLUMN da2062_imports.committed_at IS 'When the accepted lines were created as property';
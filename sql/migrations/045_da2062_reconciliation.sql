@@ -0,0 +1,22 @@
+-- Migration: DA 2062 reconciliation
+-- Description: Let an import be read as a property book printout and compared with the importer's hand receipt, recording the actions taken from the report
+
+ALTER TABLE da2062_imports
+    ADD COLUMN IF NOT EXISTS mode VARCHAR(20) NOT NULL DEFAULT 'review' CHECK (mode IN ('review', 'reconcile'));
+
+CREATE TABLE IF NOT EXISTS da2062_reconciliation_actions (
+    id BIGSERIAL PRIMARY KEY,
+    import_id BIGINT NOT NULL REFERENCES da2062_imports(id) ON DELETE CASCADE,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('create', 'inventory', 'loss_case')),
+    item_id BIGINT REFERENCES da2062_import_items(id) ON DELETE SET NULL,
+    property_id INTEGER REFERENCES properties(id) ON DELETE SET NULL,
+    campaign_id INTEGER REFERENCES inventory_campaigns(id) ON DELETE SET NULL,
+    loss_case_id INTEGER REFERENCES loss_cases(id) ON DELETE SET NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_da2062_reconciliation_actions_import ON da2062_reconciliation_actions(import_id);
+
+COMMENT ON COLUMN da2062_imports.mode IS 'review to stage the lines for creation as property, reconcile to compare the form with the importer''s hand receipt';
+COMMENT ON TABLE da2062_reconciliation_actions IS 'Property created, inventories requested and loss cases opened from a reconciliation report';
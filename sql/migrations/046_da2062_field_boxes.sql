@@ -0,0 +1,7 @@
+-- Migration: DA 2062 field boxes
+-- Description: Keep where on the scanned page each field of an imported line was read, so the source of a value can be shown
+
+ALTER TABLE da2062_import_items
+    ADD COLUMN IF NOT EXISTS field_boxes JSONB;
+
+COMMENT ON COLUMN da2062_import_items.field_boxes IS 'Bounding box of each field on its scanned page, keyed by field name, with edges as fractions of the page width and height';
@@ -0,0 +1,25 @@
+-- Migration: DA 2062 correction examples
+-- Description: Keep each reviewer correction of an imported line as a labeled example, by unit, to guide later parsing and measure accuracy
+
+CREATE TABLE IF NOT EXISTS da2062_correction_examples (
+    id BIGSERIAL PRIMARY KEY,
+    unit VARCHAR(255) NOT NULL DEFAULT '',
+    import_id BIGINT NOT NULL REFERENCES da2062_imports(id) ON DELETE CASCADE,
+    item_id BIGINT REFERENCES da2062_import_items(id) ON DELETE SET NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    field VARCHAR(50) NOT NULL,
+    read_value TEXT NOT NULL,
+    corrected_value TEXT NOT NULL,
+    nsn VARCHAR(20),
+    source VARCHAR(50),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_da2062_correction_examples_unit ON da2062_correction_examples(unit, created_at);
+CREATE INDEX IF NOT EXISTS idx_da2062_correction_examples_import ON da2062_correction_examples(import_id);
+
+COMMENT ON TABLE da2062_correction_examples IS 'Values reviewers corrected on imported DA 2062 lines, used as examples when parsing the unit''s later forms';
+COMMENT ON COLUMN da2062_correction_examples.unit IS 'Unit of the user who imported the form';
+COMMENT ON COLUMN da2062_correction_examples.read_value IS 'Value as read from the form';
+COMMENT ON COLUMN da2062_correction_examples.corrected_value IS 'Value the reviewer entered';
+COMMENT ON COLUMN da2062_correction_examples.source IS 'How the line was read, from its import metadata';
@@ -0,0 +1,8 @@
+-- Migration: User supervisors
+-- Description: Record each user's immediate supervisor so actions on property can be limited to the holder and their chain of command
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS supervisor_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_users_supervisor ON users(supervisor_id);
+
+COMMENT ON COLUMN users.supervisor_id IS 'Immediate supervisor; the chain of command is followed up through supervisors';
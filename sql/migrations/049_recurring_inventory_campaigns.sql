@@ -0,0 +1,12 @@
+-- Migration: Recurring inventory campaigns
+-- Description: Let a campaign repeat on a fixed interval, opening the next campaign over the same selection when it falls due
+
+ALTER TABLE inventory_campaigns ADD COLUMN IF NOT EXISTS repeat_every_days INTEGER;
+ALTER TABLE inventory_campaigns ADD COLUMN IF NOT EXISTS next_run_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE inventory_campaigns ADD COLUMN IF NOT EXISTS previous_campaign_id INTEGER REFERENCES inventory_campaigns(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_inventory_campaigns_next_run ON inventory_campaigns(next_run_at) WHERE next_run_at IS NOT NULL;
+
+COMMENT ON COLUMN inventory_campaigns.repeat_every_days IS 'Days between recurring campaigns; NULL for a one-off campaign';
+COMMENT ON COLUMN inventory_campaigns.next_run_at IS 'When the next campaign in the series opens; cleared once it has';
+COMMENT ON COLUMN inventory_campaigns.previous_campaign_id IS 'Campaign this one was scheduled from';
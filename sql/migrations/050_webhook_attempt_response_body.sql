@@ -0,0 +1,4 @@
+-- Migration: Drop webhook response bodies
+-- Description: Stop keeping what webhook receivers respond with; only the status code is recorded
+
+ALTER TABLE webhook_delivery_attempts DROP COLUMN IF EXISTS response_body;
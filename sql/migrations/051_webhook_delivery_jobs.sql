@@ -0,0 +1,31 @@
+-- Migration: Webhook deliveries on the job queue
+-- Description: Send webhook deliveries as background jobs instead of claiming rows from webhook_deliveries
+
+ALTER TABLE webhook_deliveries
+    ADD COLUMN IF NOT EXISTS job_id INTEGER REFERENCES jobs(id) ON DELETE SET NULL;
+
+-- Deliveries still waiting are handed to the job queue, keeping when they were due.
+-- They get the default webhooks.max_attempts of 8.
+WITH queued AS (
+    INSERT INTO jobs (type, payload, status, max_attempts, timeout_seconds, run_at)
+    SELECT 'webhook.deliver', jsonb_build_object('deliveryId', id), 'queued', 8, 60, next_attempt_at
+    FROM webhook_deliveries
+    WHERE status IN ('pending', 'delivering')
+    RETURNING id, (payload->>'deliveryId')::INTEGER AS delivery_id
+)
+UPDATE webhook_deliveries d SET
+    status = 'pending',
+    job_id = queued.id
+FROM queued
+WHERE d.id = queued.delivery_id;
+
+DROP INDEX IF EXISTS idx_webhook_deliveries_due;
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS next_attempt_at;
+ALTER TABLE webhook_deliveries DROP CONSTRAINT IF EXISTS webhook_deliveries_status_check;
+ALTER TABLE webhook_deliveries ADD CONSTRAINT webhook_deliveries_status_check
+    CHECK (status IN ('pending', 'delivered', 'dead'));
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_job ON webhook_deliveries(job_id) WHERE job_id IS NOT NULL;
+
+COMMENT ON TABLE webhook_deliveries IS 'Webhook events queued for each subscription; each is sent by a webhook.deliver job';
+COMMENT ON COLUMN webhook_deliveries.job_id IS 'Background job sending the delivery; a redelivery replaces it';
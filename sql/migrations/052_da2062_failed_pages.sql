@@ -0,0 +1,8 @@
+-- Migration: Failed DA 2062 import pages
+-- Description: Record pages of an import that could not be read, such as scans in an image encoding that cannot be decoded
+
+ALTER TABLE da2062_import_pages ADD COLUMN IF NOT EXISTS error TEXT;
+ALTER TABLE da2062_imports ADD COLUMN IF NOT EXISTS pages_failed INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN da2062_import_pages.error IS 'Why the page could not be read; such a page has no items';
+COMMENT ON COLUMN da2062_imports.pages_failed IS 'Pages that could not be read and are missing from the items';
@@ -0,0 +1,8 @@
+-- Migration: DA 2062 import page images
+-- Description: Keep the image each page of an import was read from, so review crops do not split the upload again
+
+ALTER TABLE da2062_import_pages ADD COLUMN IF NOT EXISTS image_key TEXT;
+ALTER TABLE da2062_import_pages ADD COLUMN IF NOT EXISTS image_content_type TEXT;
+
+COMMENT ON COLUMN da2062_import_pages.image_key IS 'Storage key of the page image the items were read from; empty for pages with only a text layer, NULL for pages parsed before images were kept';
+COMMENT ON COLUMN da2062_import_pages.image_content_type IS 'Content type of the page image';
@@ -1,8 +1,7 @@
-import React, { createContext, useContext, useEffect, useState } from 'react';
+import React, { createContext, useContext, useEffect, useRef, useState } from 'react';
 import { useAuth } from './AuthContext';
 import { useQueryClient } from '@tanstack/react-query';
 import webSocketService, { WebSocketEvent } from '@/services/websocket';
-import tokenService from '@/services/tokenService';
 
 interface WebSocketContextType {
   isConnected: boolean;
@@ -23,10 +22,14 @@ interface WebSocketProviderProps {
 }
 
 export function WebSocketProvider({ children }: WebSocketProviderProps) {
-  const { user, isAuthenticated } = useAuth();
+  const { user, isAuthenticated, logout } = useAuth();
   const queryClient = useQueryClient();
   const [isConnected, setIsConnected] = useState(false);
   const [lastMessage, setLastMessage] = useState<WebSocketEvent | null>(null);
+  // logout changes identity every render, so read it through a ref rather than
+  // reconnecting whenever it does
+  const logoutRef = useRef(logout);
+  logoutRef.current = logout;
 
   useEffect(() => {
     if (!isAuthenticated || !user) {
@@ -34,9 +37,9 @@ export function WebSocketProvider({ children }: WebSocketProviderProps) {
       return;
     }
 
-    // Connect to WebSocket when authenticated with JWT token
-    const token = tokenService.getAccessToken();
-    webSocketService.connect(token || undefined);
+    // Connect to WebSocket when authenticated; the service exchanges the current
+    // credentials for a single-use ticket
+    webSocketService.connect();
 
     const handleConnected = () => {
       setIsConnected(true);
@@ -48,6 +51,14 @@ export function WebSocketProvider({ children }: WebSocketProviderProps) {
       console.log('WebSocket disconnected');
     };
 
+    // The server ended the session (logged out elsewhere, or the token could not be
+    // refreshed), so sign out here too rather than leave a page that cannot update
+    const handleSessionEnded = (reason: string) => {
+      console.log('WebSocket session ended:', reason);
+      setIsConnected(false);
+      void logoutRef.current();
+    };
+
     const handleMessage = (message: WebSocketEvent) => {
       setLastMessage(message);
     };
@@ -88,6 +99,7 @@ export function WebSocketProvider({ children }: WebSocketProviderProps) {
     // Subscribe to events
     webSocketService.on('connected', handleConnected);
     webSocketService.on('disconnected', handleDisconnected);
+    webSocketService.on('session-ended', handleSessionEnded);
     webSocketService.on('message', handleMessage);
     webSocketService.on('transfer:update', handleTransferUpdate);
     webSocketService.on('transfer:created', handleTransferCreated);
@@ -100,6 +112,7 @@ export function WebSocketProvider({ children }: WebSocketProviderProps) {
     return () => {
       webSocketService.off('connected', handleConnected);
       webSocketService.off('disconnected', handleDisconnected);
+      webSocketService.off('session-ended', handleSessionEnded);
       webSocketService.off('message', handleMessage);
       webSocketService.off('transfer:update', handleTransferUpdate);
       webSocketService.off('transfer:created', handleTransferCreated);
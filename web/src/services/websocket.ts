@@ -1,3 +1,5 @@
+import apiClient from './apiClient';
+
 export type WebSocketEventType = 
   | 'transfer:update'
   | 'transfer:created'
@@ -100,12 +102,28 @@ class WebSocketService extends EventEmitter {
     super();
   }
 
-  connect(token?: string): void {
+  connect(): void {
     if (this.ws?.readyState === WebSocket.OPEN) {
       console.log('[WebSocket] Already connected');
       return;
     }
 
+    void this.openConnection();
+  }
+
+  // Tickets are single-use, so every attempt (including reconnects) asks for a new one
+  private async openConnection(): Promise<void> {
+    let ticket: string;
+    try {
+      const response = await apiClient.fetch<{ ticket: string; expiresAt: string }>('/ws/ticket', { method: 'POST' });
+      ticket = response.ticket;
+    } catch (error) {
+      console.error('[WebSocket] Failed to get connection ticket:', error);
+      this.emit('error', error);
+      this.scheduleReconnect();
+      return;
+    }
+
     // Get API URL from environment or use current host for local development
     const apiUrl = import.meta.env.VITE_API_URL || '';
     let wsUrl: string;
@@ -122,16 +140,11 @@ class WebSocketService extends EventEmitter {
       wsUrl = `${protocol}//${host}/api/ws`;
     }
 
-    // Add token as query parameter if provided
-    if (token) {
-      const separator = wsUrl.includes('?') ? '&' : '?';
-      wsUrl = `${wsUrl}${separator}token=${encodeURIComponent(token)}`;
-    }
+    wsUrl = `${wsUrl}?ticket=${encodeURIComponent(ticket)}`;
 
     console.log('[WebSocket] Attempting to connect:', {
-      wsUrl: wsUrl.replace(/token=[^&]+/, 'token=***'), // Hide token in logs
-      timestamp: new Date().toISOString(),
-      hasToken: !!token
+      wsUrl: wsUrl.replace(/ticket=[^&]+/, 'ticket=***'),
+      timestamp: new Date().toISOString()
     });
 
     try {
@@ -188,12 +201,20 @@ class WebSocketService extends EventEmitter {
         this.stopPing();
         this.emit('disconnected');
 
-        // Attempt to reconnect if not intentionally closed
-        if (this.shouldReconnect && this.reconnectAttempts < this.maxReconnectAttempts) {
-          this.reconnectAttempts++;
-          console.log(`Reconnecting in ${this.reconnectInterval}ms... (attempt ${this.reconnectAttempts})`);
-          setTimeout(() => this.connect(token), this.reconnectInterval);
+        // The server closes with a policy violation when the session is revoked or
+        // expires. An expired access token can be refreshed and a new ticket fetched;
+        // a revoked session has been logged out and reconnecting would only be refused
+        if (event.code === 1008 && event.reason === 'session expired') {
+          void this.resumeAfterExpiry();
+          return;
+        }
+        if (event.code === 1008 && event.reason === 'session revoked') {
+          this.emit('session-ended', event.reason);
+          return;
         }
+
+        // Attempt to reconnect if not intentionally closed
+        this.scheduleReconnect();
       };
     } catch (error) {
       console.error('Failed to create WebSocket connection:', error);
@@ -201,6 +222,43 @@ class WebSocketService extends EventEmitter {
     }
   }
 
+  // Refreshes the access token and reconnects with a ticket issued for it. If the
+  // refresh does not succeed the session is over.
+  private async resumeAfterExpiry(): Promise<void> {
+    if (!this.shouldReconnect) return;
+
+    const refreshed = await new Promise<boolean>((resolve) => {
+      const handler = () => {
+        clearTimeout(timeout);
+        window.removeEventListener('token-refreshed', handler);
+        resolve(true);
+      };
+      const timeout = setTimeout(() => {
+        window.removeEventListener('token-refreshed', handler);
+        resolve(false);
+      }, 5000);
+      window.addEventListener('token-refreshed', handler);
+      window.dispatchEvent(new Event('token-refresh-needed'));
+    });
+
+    if (!refreshed) {
+      this.emit('session-ended', 'session expired');
+      return;
+    }
+    if (this.shouldReconnect) {
+      this.reconnectAttempts = 0;
+      void this.openConnection();
+    }
+  }
+
+  private scheduleReconnect(): void {
+    if (this.shouldReconnect && this.reconnectAttempts < this.maxReconnectAttempts) {
+      this.reconnectAttempts++;
+      console.log(`Reconnecting in ${this.reconnectInterval}ms... (attempt ${this.reconnectAttempts})`);
+      setTimeout(() => this.connect(), this.reconnectInterval);
+    }
+  }
+
   private handleMessage(message: WebSocketEvent): void {
     // Emit specific event based on message type
     this.emit(message.type, message.data);